@@ -0,0 +1,57 @@
+// Command blockat prints the first Hive block produced at or after a given time,
+// e.g. to pick a HIVE_START_BLOCK when only the approximate date of an upload is known.
+//
+//	blockat [-rpc https://api.hive.blog,https://api.deathwing.me] 2024-05-01T12:00:00Z
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"hivemoji/internal/config"
+	"hivemoji/internal/hive"
+)
+
+func main() {
+	rpc := flag.String("rpc", envOr("HIVE_RPC_URLS", envOr("HIVE_RPC_URL", "https://api.hive.blog")), "Hive RPC endpoints, comma-separated, in order of preference")
+	timeout := flag.Duration("timeout", 2*time.Minute, "overall lookup timeout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <RFC 3339 time>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	at, err := time.Parse(time.RFC3339, flag.Arg(0))
+	if err != nil {
+		log.Fatalf("invalid time %q: %v", flag.Arg(0), err)
+	}
+
+	urls, err := config.ParseRPCURLs(*rpc)
+	if err != nil {
+		log.Fatalf("invalid -rpc %q: %v", *rpc, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	n, err := hive.NewClient(urls).BlockNumberAtTime(ctx, at)
+	if err != nil {
+		log.Fatalf("lookup block: %v", err)
+	}
+	fmt.Println(n)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"hivemoji/internal/config"
+	"hivemoji/internal/storage"
+)
+
+// blobStore returns where emoji image bytes are kept: nil keeps them inline in
+// hivemoji_assets, the default.
+func blobStore(cfg config.Config, pool *pgxpool.Pool) (storage.BlobStore, error) {
+	switch cfg.BlobStore {
+	case "postgres":
+		return storage.NewPostgresBlobs(pool), nil
+	case "fs":
+		return storage.NewFileBlobs(cfg.BlobDir)
+	default:
+		return nil, nil
+	}
+}
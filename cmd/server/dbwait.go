@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	dbWaitInitialBackoff = 500 * time.Millisecond
+	dbWaitMaxBackoff     = 5 * time.Second
+)
+
+// waitForDB pings until the database accepts connections, doubling the pause between
+// attempts up to dbWaitMaxBackoff. pgxpool connects lazily, so without this a server started
+// alongside a still-booting Postgres dies in EnsureSchema. It gives up after timeout.
+func waitForDB(ctx context.Context, ping func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := dbWaitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("postgres ready after %d attempts", attempt)
+			}
+			return nil
+		}
+		log.Printf("postgres not ready (attempt %d): %v; retrying in %s", attempt, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("postgres not ready after %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > dbWaitMaxBackoff {
+			backoff = dbWaitMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForDB_RetriesUntilReady(t *testing.T) {
+	calls := 0
+	ping := func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	if err := waitForDB(context.Background(), ping, 10*time.Second); err != nil {
+		t.Fatalf("waitForDB error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 pings, got %d", calls)
+	}
+}
+
+func TestWaitForDB_GivesUp(t *testing.T) {
+	refused := errors.New("connection refused")
+	err := waitForDB(context.Background(), func(ctx context.Context) error { return refused }, 50*time.Millisecond)
+	if !errors.Is(err, refused) {
+		t.Fatalf("expected timeout wrapping the last ping error, got %v", err)
+	}
+}
@@ -3,26 +3,41 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/crypto/acme/autocert"
 
 	"hivemoji/internal/api"
 	"hivemoji/internal/config"
 	"hivemoji/internal/hive"
+	"hivemoji/internal/ingest"
+	"hivemoji/internal/metrics"
+	"hivemoji/internal/moderation"
 	"hivemoji/internal/processor"
 	"hivemoji/internal/storage"
+	"hivemoji/internal/webhook"
 )
 
+// registerWebhookQueue bounds how many registration events wait for the webhook; more are dropped.
+const registerWebhookQueue = 256
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:]))
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("config error: %v", err)
@@ -36,31 +51,112 @@ func main() {
 		log.Fatalf("connect postgres: %v", err)
 	}
 	defer pool.Close()
+	if err := waitForDB(ctx, pool.Ping, cfg.DBReadyTimeout); err != nil {
+		log.Fatalf("connect postgres: %v", err)
+	}
 
 	store := storage.NewStore(pool)
+	blobs, err := blobStore(cfg, pool)
+	if err != nil {
+		log.Fatalf("HIVEMOJI_BLOB_STORE: %v", err)
+	}
+	store.SetBlobStore(blobs)
+	store.SetMaxChunks(cfg.MaxChunks)
+	if err := store.SetCompression(cfg.Compression); err != nil {
+		log.Fatalf("HIVEMOJI_COMPRESSION: %v", err)
+	}
 	if err := store.EnsureSchema(ctx); err != nil {
 		log.Fatalf("ensure schema: %v", err)
 	}
 
-	hiveClient := hive.NewClient(cfg.HiveRPCURL)
-	proc := processor.New(store, hiveClient)
+	hiveClient := hive.NewClient(cfg.HiveRPCURLs)
+	if err := hiveClient.EnableOps(cfg.ExtraOps...); err != nil {
+		log.Fatalf("HIVE_EXTRA_OPS: %v", err)
+	}
+	mimeRules, err := processor.ParseMimeRules(cfg.MimeRules)
+	if err != nil {
+		log.Fatalf("HIVEMOJI_MIME_RULES: %v", err)
+	}
+	registry := metrics.NewRegistry()
+	ingestMetrics := metrics.NewIngest(registry)
+
+	procOpts := processor.Options{
+		CustomJSONID:         cfg.CustomJSONID,
+		ModerationFailClosed: cfg.ModerationFailClosed,
+		StrictAuthors:        cfg.StrictAuthors,
+		StripMetadata:        cfg.StripMetadata,
+		OptimizeGIF:          cfg.OptimizeGIF,
+		Metrics:              ingestMetrics,
+		SlowBlockThreshold:   cfg.SlowBlockThreshold,
+		Keys:                 hive.NewKeyCache(hiveClient, cfg.AccountKeyTTL),
+		RequireSignature:     cfg.RequireSignature,
+		MaxChunks:            cfg.MaxChunks,
+		ConfirmationDepth:    cfg.ConfirmationDepth,
+		NamePrefix:           cfg.NamePrefix,
+		AuthorAllowlist:      cfg.AuthorAllowlist,
+		Validation:           cfg.Validation,
+		MaxImageBytes:        cfg.MaxImageBytes,
+		MimeRules:            mimeRules,
+	}
+	if cfg.MinReputation != nil {
+		procOpts.Accounts = hive.NewAccountCache(hiveClient, cfg.AccountKeyTTL)
+		procOpts.MinReputation = *cfg.MinReputation
+	}
+	if cfg.ModerationWebhookURL != "" {
+		procOpts.Scanner = moderation.NewClient(cfg.ModerationWebhookURL, cfg.ModerationTimeout, cfg.ModerationRetries)
+	}
+	if cfg.RegisterWebhookURL != "" {
+		notifier := webhook.NewNotifier(cfg.RegisterWebhookURL, cfg.RegisterWebhookTimeout, cfg.RegisterWebhookRetries, registerWebhookQueue)
+		go notifier.Run(ctx)
+		procOpts.Notifier = notifier
+	}
+	proc := processor.New(store, hiveClient, procOpts)
 
-	go ingestLoop(ctx, proc, store, cfg)
+	timings := newIngestTimings(cfg)
+	go reloadOnHangup(ctx, hiveClient, timings)
+	ingestState := ingest.NewState()
+	go ingestLoop(ctx, proc, store, cfg, timings, ingestMetrics, ingestState)
+	go cleanupLoop(ctx, store, cfg, metrics.NewUploads(registry))
+	go backfillChecksums(ctx, store)
 
 	e := echo.New()
 	e.HideBanner = true
 	e.Use(middleware.Logger(), middleware.Recover(), middleware.CORS())
 
-	apiServer := api.New(store)
+	apiOpts := api.Options{
+		AdminToken:           cfg.AdminToken,
+		URLSigningKey:        []byte(cfg.URLSigningKey),
+		Metrics:              metrics.NewAPI(registry),
+		CustomJSONID:         cfg.CustomJSONID,
+		Uploads:              proc,
+		Ingest:               ingestState,
+		IdempotencyTTL:       cfg.IdempotencyTTL,
+		ExportTimeout:        cfg.ExportTimeout,
+		TranscodeConcurrency: cfg.TranscodeConcurrency,
+		MaxImageBytes:        cfg.MaxImageBytes,
+		MaxChunks:            cfg.MaxChunks,
+		NamePrefix:           cfg.NamePrefix,
+		ReadOnly:             cfg.ReadOnly,
+		CacheStableAfter:     cfg.CacheStableAfter,
+		CacheRecentMaxAge:    cfg.CacheRecentMaxAge,
+	}
+	if cfg.PlaceholderPath != "" {
+		apiOpts.Placeholder, err = api.LoadPlaceholder(cfg.PlaceholderPath, cfg.PlaceholderStatus)
+		if err != nil {
+			log.Fatalf("load placeholder: %v", err)
+		}
+	}
+	apiServer := api.New(store, apiOpts)
 	apiServer.Register(e)
+	go flushFetchCounts(ctx, apiServer)
+	e.GET("/metrics", echo.WrapHandler(registry.Handler()))
 
 	webDir := assetDir()
 	e.File("/", filepath.Join(webDir, "index.html"))
 	e.Static("/", webDir)
 
 	go func() {
-		log.Printf("listening on %s", cfg.ServerAddr)
-		if err := e.Start(cfg.ServerAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := startServer(e, cfg); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("http server: %v", err)
 		}
 	}()
@@ -72,6 +168,27 @@ func main() {
 	if err := e.Shutdown(shutdownCtx); err != nil {
 		log.Printf("server shutdown error: %v", err)
 	}
+	if err := apiServer.FlushFetchCounts(shutdownCtx); err != nil {
+		log.Printf("flush fetch counts: %v", err)
+	}
+}
+
+// startServer starts Echo over plain HTTP, static TLS certificates, or autocert depending on config.
+func startServer(e *echo.Echo, cfg config.Config) error {
+	switch {
+	case len(cfg.TLSAutocertDomains) > 0:
+		e.AutoTLSManager.Prompt = autocert.AcceptTOS
+		e.AutoTLSManager.HostPolicy = autocert.HostWhitelist(cfg.TLSAutocertDomains...)
+		e.AutoTLSManager.Cache = autocert.DirCache(cfg.TLSAutocertCacheDir)
+		log.Printf("listening on %s (autocert for %s)", cfg.ServerAddr, strings.Join(cfg.TLSAutocertDomains, ","))
+		return e.StartAutoTLS(cfg.ServerAddr)
+	case cfg.TLSCertFile != "":
+		log.Printf("listening on %s (tls)", cfg.ServerAddr)
+		return e.StartTLS(cfg.ServerAddr, cfg.TLSCertFile, cfg.TLSKeyFile)
+	default:
+		log.Printf("listening on %s", cfg.ServerAddr)
+		return e.Start(cfg.ServerAddr)
+	}
 }
 
 func assetDir() string {
@@ -94,21 +211,108 @@ func assetDir() string {
 	return ""
 }
 
-func ingestLoop(ctx context.Context, proc *processor.Processor, store *storage.Store, cfg config.Config) {
+// ingestTimings holds the ingest loop intervals so they can be swapped at runtime.
+type ingestTimings struct {
+	poll    atomic.Int64
+	catchup atomic.Int64
+}
+
+func newIngestTimings(cfg config.Config) *ingestTimings {
+	t := &ingestTimings{}
+	t.poll.Store(int64(cfg.PollInterval))
+	t.catchup.Store(int64(cfg.CatchupPollInterval))
+	return t
+}
+
+func (t *ingestTimings) Poll() time.Duration    { return time.Duration(t.poll.Load()) }
+func (t *ingestTimings) Catchup() time.Duration { return time.Duration(t.catchup.Load()) }
+
+// wait returns how long to sleep before asking again for block current: the catch-up interval
+// while the known head is past it, the poll interval once caught up or when head is unknown (0).
+func (t *ingestTimings) wait(current, head int64) time.Duration {
+	if head > current {
+		return t.Catchup()
+	}
+	return t.Poll()
+}
+
+// reloadOnHangup re-reads config on SIGHUP and applies the Hive endpoint and poll intervals.
+func reloadOnHangup(ctx context.Context, client *hive.Client, timings *ingestTimings) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			log.Printf("reload: config error, keeping current settings: %v", err)
+			continue
+		}
+
+		if prev := client.Endpoints(); client.SetEndpoints(cfg.HiveRPCURLs) {
+			log.Printf("reload: hive rpc endpoints %s -> %s", strings.Join(prev, ","), strings.Join(cfg.HiveRPCURLs, ","))
+		}
+		if prev := timings.Poll(); prev != cfg.PollInterval {
+			timings.poll.Store(int64(cfg.PollInterval))
+			log.Printf("reload: poll interval %s -> %s", prev, cfg.PollInterval)
+		}
+		if prev := timings.Catchup(); prev != cfg.CatchupPollInterval {
+			timings.catchup.Store(int64(cfg.CatchupPollInterval))
+			log.Printf("reload: catch-up interval %s -> %s", prev, cfg.CatchupPollInterval)
+		}
+	}
+}
+
+// headObserver receives every chain head the ingest loop learns, to report ingestion lag.
+type headObserver interface {
+	ObserveHead(head int64)
+}
+
+func ingestLoop(ctx context.Context, proc *processor.Processor, store *storage.Store, cfg config.Config, timings *ingestTimings, heads headObserver, state *ingest.State) {
 	last, err := store.LastBlock(ctx)
 	if err != nil {
 		log.Printf("read last block: %v", err)
+		// Without the stored progress, jumping to head could skip blocks never processed.
+		cfg.StartFromHead = false
 	}
 
-	current := cfg.StartBlock
-	if last > 0 && last+1 > current {
-		current = last + 1
+	current, err := startBlock(ctx, proc, store, cfg, last)
+	for err != nil {
+		log.Printf("resolve start block: %v; retrying in %s", err, timings.Poll())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(timings.Poll()):
+		}
+		current, err = startBlock(ctx, proc, store, cfg, last)
 	}
 
 	log.Printf("starting ingestion from block %d", current)
+	state.Started(current)
 
 	behindLogged := false
-	lastCleanup := time.Now()
+	// Knowing the head up front lets a node that starts far behind batch its first fetches.
+	head, err := proc.HeadBlockNumber(ctx)
+	if err != nil {
+		log.Printf("head block number: %v", err)
+		head = 0
+	}
+	var pending []*hive.Block
+	blockLog := newBlockLogger(cfg.LogEveryBlock, cfg.LogProgressInterval)
+	observeHead := func(head int64) {
+		blockLog.setHead(head)
+		state.Head(head)
+		heads.ObserveHead(head)
+	}
+	if head > 0 {
+		observeHead(head)
+	}
 
 	for {
 		select {
@@ -118,25 +322,55 @@ func ingestLoop(ctx context.Context, proc *processor.Processor, store *storage.S
 		default:
 		}
 
-		block, err := proc.FetchBlock(ctx, current)
-		if err != nil {
-			log.Printf("fetch block %d: %v", current, err)
-			time.Sleep(cfg.PollInterval)
-			continue
+		// With HIVE_IRREVERSIBLE_LAG, blocks are only processed once that far behind the head.
+		target := head - cfg.IrreversibleLag
+
+		// Behind a known head, fetch the head and the next blocks in one batched round-trip.
+		if len(pending) == 0 && target > current {
+			h, blocks, err := proc.FetchBlocks(ctx, current, int(min(target-current+1, catchupBatchSize)))
+			if err != nil {
+				log.Printf("fetch blocks from %d: %v", current, err)
+				state.Failed(fmt.Errorf("fetch blocks from %d: %w", current, err))
+				time.Sleep(timings.Poll())
+				continue
+			}
+			head = h
+			observeHead(head)
+			pending = blocks
+		}
+
+		var block *hive.Block
+		if len(pending) > 0 {
+			block, pending = pending[0], pending[1:]
+		} else if cfg.IrreversibleLag > 0 && current > target {
+			// Not deep enough yet: wait below for the head to move on.
+		} else {
+			block, err = proc.FetchBlock(ctx, current)
+			if err != nil {
+				log.Printf("fetch block %d: %v", current, err)
+				state.Failed(fmt.Errorf("fetch block %d: %w", current, err))
+				time.Sleep(timings.Poll())
+				continue
+			}
 		}
 		if block == nil {
-			interval := cfg.PollInterval
-			head, err := proc.HeadBlockNumber(ctx)
+			head, err = proc.HeadBlockNumber(ctx)
+			if err == nil {
+				observeHead(head)
+			} else {
+				head = 0
+			}
+			target = head - cfg.IrreversibleLag
+			interval := timings.wait(current, target)
 			if err != nil {
 				log.Printf("head block number: %v", err)
-			} else if head > current {
-				interval = cfg.CatchupPollInterval
+			} else if target > current {
 				if !behindLogged {
 					log.Printf("behind head: at %d, head %d (lag %d); polling every %s", current, head, head-current, interval)
 					behindLogged = true
 				}
 			} else if behindLogged {
-				log.Printf("caught up to head (head %d); returning to interval %s", head, cfg.PollInterval)
+				log.Printf("caught up to head (head %d); returning to interval %s", head, timings.Poll())
 				behindLogged = false
 			}
 
@@ -144,26 +378,201 @@ func ingestLoop(ctx context.Context, proc *processor.Processor, store *storage.S
 			continue
 		}
 
-		log.Printf("block %d: fetched (%d transactions)", block.Number, len(block.Transactions))
+		if blockLog.headStale() {
+			if h, err := proc.HeadBlockNumber(ctx); err == nil {
+				head = h
+				observeHead(head)
+			}
+		}
+		blockLog.fetched(block.Number, len(block.Transactions))
+
+		forkPoint, err := findForkPoint(ctx, store, proc.FetchBlock, block)
+		if err != nil {
+			log.Printf("check block %d for a fork: %v", current, err)
+			state.Failed(fmt.Errorf("check block %d for a fork: %w", current, err))
+			pending = nil
+			time.Sleep(timings.Poll())
+			continue
+		}
+		if forkPoint < block.Number-1 {
+			log.Printf("micro-fork: block %d does not build on the recorded block %d; rolling back to block %d", block.Number, block.Number-1, forkPoint)
+			if err := store.RollbackToBlock(ctx, forkPoint); err != nil {
+				log.Printf("roll back to block %d: %v", forkPoint, err)
+				state.Failed(fmt.Errorf("roll back to block %d: %w", forkPoint, err))
+				time.Sleep(timings.Poll())
+			} else {
+				current = forkPoint + 1
+			}
+			pending = nil
+			continue
+		}
 
 		if err := proc.ProcessBlock(ctx, block); err != nil {
 			log.Printf("process block %d: %v", current, err)
-			time.Sleep(cfg.PollInterval)
+			state.Failed(fmt.Errorf("process block %d: %w", current, err))
+			pending = nil
+			time.Sleep(timings.Poll())
 			continue
 		}
+		if err := store.RecordBlockID(ctx, block.Number, block.ID); err != nil {
+			log.Printf("record block %d id: %v", block.Number, err)
+		}
 
-		log.Printf("block %d: processed", block.Number)
+		blockLog.processed(block.Number)
+		state.Processed(block.Number)
 		current++
 
-		// Periodically clean up stale incomplete chunk uploads.
-		if time.Since(lastCleanup) >= cfg.IncompleteCleanupInterval {
-			sets, chunks, err := store.CleanupIncomplete(ctx, cfg.IncompleteChunkTTL)
-			if err != nil {
-				log.Printf("cleanup incomplete chunks: %v", err)
-			} else if sets > 0 || chunks > 0 {
-				log.Printf("cleanup incomplete: removed %d chunk_sets and %d chunks older than %s", sets, chunks, cfg.IncompleteChunkTTL)
+	}
+}
+
+// catchupBatchSize caps how many blocks one batched fetch asks the node for.
+const catchupBatchSize = 100
+
+// maxForkDepth bounds how far back findForkPoint looks for a common block, and how many
+// recent block ids are kept. Hive blocks become irreversible well within it.
+const maxForkDepth = 100
+
+// blockIDs reads the ids recorded for processed blocks.
+type blockIDs interface {
+	GetBlockID(ctx context.Context, number int64) (string, error)
+}
+
+// findForkPoint checks that block builds on the processed block before it. It returns
+// block.Number-1 when it does, or when there is nothing recorded to check against.
+// Otherwise it walks back, comparing recorded ids with the node's current blocks, and
+// returns the last block both agree on.
+func findForkPoint(ctx context.Context, ids blockIDs, fetch func(context.Context, int64) (*hive.Block, error), block *hive.Block) (int64, error) {
+	parent := block.Number - 1
+	recorded, err := ids.GetBlockID(ctx, parent)
+	if errors.Is(err, storage.ErrNotFound) || block.Previous == "" {
+		return parent, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if recorded == block.Previous {
+		return parent, nil
+	}
+	for n := parent - 1; n > 0 && n >= parent-maxForkDepth; n-- {
+		recorded, err := ids.GetBlockID(ctx, n)
+		if errors.Is(err, storage.ErrNotFound) {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		canonical, err := fetch(ctx, n)
+		if err != nil {
+			return 0, err
+		}
+		if canonical != nil && canonical.ID == recorded {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("no block within %d of block %d matches the recorded ids", maxForkDepth, block.Number)
+}
+
+// checksumBackfillBatch bounds how many assets one backfill transaction touches.
+const checksumBackfillBatch = 100
+
+// backfillChecksums fills in missing asset checksums a batch at a time until none are left.
+// Progress is kept in the store, so a restart picks up where the last run stopped.
+func backfillChecksums(ctx context.Context, store *storage.Store) {
+	total := 0
+	for ctx.Err() == nil {
+		filled, done, err := store.BackfillChecksums(ctx, checksumBackfillBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("checksum backfill: %v", err)
 			}
-			lastCleanup = time.Now()
+			return
 		}
+		total += filled
+		if done {
+			if total > 0 {
+				log.Printf("checksum backfill: done, filled %d checksums", total)
+			}
+			return
+		}
+	}
+}
+
+// fetchCountFlushInterval is how often counted image fetches are written to the store.
+const fetchCountFlushInterval = time.Minute
+
+// flushFetchCounts periodically persists the API's image fetch counts until ctx is done; the
+// final flush happens after the HTTP server has shut down.
+func flushFetchCounts(ctx context.Context, apiServer *api.Server) {
+	ticker := time.NewTicker(fetchCountFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := apiServer.FlushFetchCounts(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("flush fetch counts: %v", err)
+			}
+		}
+	}
+}
+
+// cleanupLoop periodically removes stale uploads, idempotency keys and block ids, and refreshes
+// the upload gauges. It runs apart from ingestion so a slow cleanup never holds back blocks.
+func cleanupLoop(ctx context.Context, store *storage.Store, cfg config.Config, uploads *metrics.Uploads) {
+	ticker := time.NewTicker(cfg.IncompleteCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cleanup(ctx, store, cfg, uploads)
+		}
+	}
+}
+
+func cleanup(ctx context.Context, store *storage.Store, cfg config.Config, uploads *metrics.Uploads) {
+	if sets, err := store.CleanupIncompleteChunks(ctx, cfg.IncompleteChunkTTL); err != nil {
+		log.Printf("cleanup incomplete chunks: %v", err)
+	} else if sets > 0 {
+		log.Printf("cleanup incomplete: removed %d chunk_sets older than %s", sets, cfg.IncompleteChunkTTL)
+	}
+	if sets, chunks, err := store.CompactChunks(ctx, cfg.IncompleteChunkTTL); err != nil {
+		log.Printf("compact chunks: %v", err)
+	} else if sets > 0 || chunks > 0 {
+		log.Printf("cleanup: compacted %d stored chunk_sets and removed %d chunks", sets, chunks)
+	}
+	if n, err := store.PurgeStaleManifests(ctx, cfg.IncompleteChunkTTL); err != nil {
+		log.Printf("purge stale manifests: %v", err)
+	} else if n > 0 {
+		log.Printf("cleanup: removed %d unfinished upload manifests older than %s", n, cfg.IncompleteChunkTTL)
+	}
+	if n, err := store.PurgeIdempotentResponses(ctx, cfg.IdempotencyTTL); err != nil {
+		log.Printf("purge idempotency keys: %v", err)
+	} else if n > 0 {
+		log.Printf("cleanup: removed %d idempotency keys older than %s", n, cfg.IdempotencyTTL)
+	}
+	if last, err := store.LastBlock(ctx); err != nil {
+		log.Printf("prune block ids: %v", err)
+	} else if last > maxForkDepth {
+		if _, err := store.PruneBlockIDs(ctx, last-maxForkDepth); err != nil {
+			log.Printf("prune block ids: %v", err)
+		}
+	}
+	observeUploads(ctx, store, uploads)
+}
+
+// observeUploads refreshes the in-flight upload gauges from the store.
+func observeUploads(ctx context.Context, store *storage.Store, uploads *metrics.Uploads) {
+	stats, err := store.UploadStats(ctx)
+	if err != nil {
+		log.Printf("upload stats: %v", err)
+		return
+	}
+	var oldestAge time.Duration
+	if !stats.OldestUpdate.IsZero() {
+		oldestAge = time.Since(stats.OldestUpdate)
 	}
+	uploads.ObserveUploads(stats.InFlight, stats.Chunks, oldestAge)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"hivemoji/internal/config"
+	"hivemoji/internal/hive"
+	"hivemoji/internal/storage"
+)
+
+func TestIngestTimings_Wait(t *testing.T) {
+	timings := newIngestTimings(config.Config{PollInterval: 3 * time.Second, CatchupPollInterval: 500 * time.Millisecond})
+
+	cases := []struct {
+		name    string
+		current int64
+		head    int64
+		want    time.Duration
+	}{
+		{"far behind", 100, 5_000, 500 * time.Millisecond},
+		{"one behind", 100, 101, 500 * time.Millisecond},
+		{"at head", 101, 101, 3 * time.Second},
+		{"head unknown", 100, 0, 3 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := timings.wait(tc.current, tc.head); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	timings.catchup.Store(int64(time.Second))
+	if got := timings.wait(100, 200); got != time.Second {
+		t.Fatalf("expected a reloaded catch-up interval to apply, got %s", got)
+	}
+}
+
+// recordedIDs is a blockIDs backed by a map.
+type recordedIDs map[int64]string
+
+func (r recordedIDs) GetBlockID(ctx context.Context, number int64) (string, error) {
+	id, ok := r[number]
+	if !ok {
+		return "", storage.ErrNotFound
+	}
+	return id, nil
+}
+
+func TestFindForkPoint(t *testing.T) {
+	// The node now has blocks 99-100 on a fork that replaced what was processed as 99-100.
+	canonical := map[int64]string{97: "a97", 98: "a98", 99: "b99", 100: "b100"}
+	fetch := func(ctx context.Context, n int64) (*hive.Block, error) {
+		return &hive.Block{Number: n, ID: canonical[n]}, nil
+	}
+	ids := recordedIDs{97: "a97", 98: "a98", 99: "a99", 100: "a100"}
+
+	cases := []struct {
+		name  string
+		block *hive.Block
+		want  int64
+	}{
+		{"builds on recorded parent", &hive.Block{Number: 99, Previous: "a98"}, 98},
+		{"nothing recorded for the parent", &hive.Block{Number: 150, Previous: "x"}, 149},
+		{"fork", &hive.Block{Number: 101, Previous: "b100"}, 98},
+	}
+	for _, tc := range cases {
+		got, err := findForkPoint(context.Background(), ids, fetch, tc.block)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: expected fork point %d, got %d, %v", tc.name, tc.want, got, err)
+		}
+	}
+
+	diverged := recordedIDs{}
+	for n := int64(1); n <= 200; n++ {
+		diverged[n] = "orphan"
+	}
+	if _, err := findForkPoint(context.Background(), diverged, fetch, &hive.Block{Number: 201, Previous: "b200"}); err == nil {
+		t.Fatalf("expected an error when no recent block matches")
+	}
+}
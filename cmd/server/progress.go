@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// catchupLag is how many blocks behind head the ingester must be before per-block
+// logs are folded into periodic progress lines (20 blocks is one minute of chain time).
+const catchupLag = 20
+
+// blockLogger keeps ingest logs readable: at the head every block is logged, while
+// catching up only a progress summary is printed every interval.
+type blockLogger struct {
+	everyBlock bool
+	interval   time.Duration
+	now        func() time.Time
+	logf       func(format string, args ...any)
+
+	head         int64
+	headAt       time.Time
+	lastProgress time.Time
+	sinceLast    int
+}
+
+func newBlockLogger(everyBlock bool, interval time.Duration) *blockLogger {
+	return &blockLogger{everyBlock: everyBlock, interval: interval, now: time.Now, logf: log.Printf}
+}
+
+// headStale reports whether the known head should be refreshed before judging lag.
+func (l *blockLogger) headStale() bool {
+	return !l.everyBlock && l.now().Sub(l.headAt) >= l.interval
+}
+
+// setHead records the latest chain head.
+func (l *blockLogger) setHead(head int64) {
+	l.head = head
+	l.headAt = l.now()
+}
+
+func (l *blockLogger) catchingUp(current int64) bool {
+	return !l.everyBlock && l.head-current > catchupLag
+}
+
+// fetched logs a fetched block unless it is being summarized.
+func (l *blockLogger) fetched(number int64, txs int) {
+	if !l.catchingUp(number) {
+		l.logf("block %d: fetched (%d transactions)", number, txs)
+	}
+}
+
+// processed logs a processed block, or folds it into the next progress line while catching up.
+func (l *blockLogger) processed(number int64) {
+	if !l.catchingUp(number) {
+		l.flush(number)
+		l.logf("block %d: processed", number)
+		return
+	}
+
+	l.sinceLast++
+	now := l.now()
+	if l.lastProgress.IsZero() {
+		l.lastProgress = now
+		return
+	}
+	if elapsed := now.Sub(l.lastProgress); elapsed >= l.interval {
+		l.logf("catch-up progress: block %d, head %d (lag %d), %d blocks in %s", number, l.head, l.head-number, l.sinceLast, elapsed.Round(time.Second))
+		l.lastProgress = now
+		l.sinceLast = 0
+	}
+}
+
+// flush reports blocks summarized since the last progress line once ingest reaches the head.
+func (l *blockLogger) flush(number int64) {
+	if l.sinceLast > 0 {
+		l.logf("catch-up progress: block %d, %d blocks since last report", number-1, l.sinceLast)
+	}
+	l.sinceLast = 0
+	l.lastProgress = time.Time{}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBlockLogger(everyBlock bool) (*blockLogger, *[]string, *time.Time) {
+	var lines []string
+	now := time.Unix(0, 0)
+	l := newBlockLogger(everyBlock, 30*time.Second)
+	l.now = func() time.Time { return now }
+	l.logf = func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	return l, &lines, &now
+}
+
+func TestBlockLogger_SummarizesDuringCatchup(t *testing.T) {
+	l, lines, now := newTestBlockLogger(false)
+	l.setHead(1000)
+
+	for n := int64(100); n < 200; n++ {
+		l.fetched(n, 3)
+		l.processed(n)
+		*now = now.Add(time.Second)
+	}
+	if len(*lines) != 3 {
+		t.Fatalf("expected 3 progress lines for 100s of catch-up, got %d: %q", len(*lines), *lines)
+	}
+	for _, line := range *lines {
+		if !strings.HasPrefix(line, "catch-up progress:") {
+			t.Fatalf("unexpected per-block line during catch-up: %q", line)
+		}
+	}
+
+	*lines = nil
+	l.setHead(990)
+	l.processed(980)
+	if len(*lines) != 2 || !strings.Contains((*lines)[1], "block 980: processed") {
+		t.Fatalf("expected flush then per-block line near head, got %q", *lines)
+	}
+}
+
+func TestBlockLogger_EveryBlock(t *testing.T) {
+	l, lines, _ := newTestBlockLogger(true)
+	l.setHead(1000)
+	l.fetched(100, 1)
+	l.processed(100)
+	if len(*lines) != 2 {
+		t.Fatalf("expected per-block logs when everyBlock is set, got %q", *lines)
+	}
+	if l.headStale() {
+		t.Fatalf("expected no head refresh when everyBlock is set")
+	}
+}
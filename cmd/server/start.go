@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"hivemoji/internal/config"
+)
+
+// chainLocator finds blocks on chain to start ingestion from.
+type chainLocator interface {
+	HeadBlockNumber(ctx context.Context) (int64, error)
+	BlockNumberAtTime(ctx context.Context, t time.Time) (int64, error)
+}
+
+// reprocessMarks remembers the HIVE_REPROCESS_FROM value ingestion last rewound to, and
+// stores the rewound progress before that value is marked as consumed.
+type reprocessMarks interface {
+	ReprocessedFrom(ctx context.Context) (time.Time, error)
+	SetReprocessedFrom(ctx context.Context, t time.Time) error
+	SetLastBlock(ctx context.Context, number int64) error
+}
+
+// startBlock picks the block ingestion begins at: the block after last (the stored progress)
+// or HIVE_START_BLOCK, whichever is later, unless HIVE_REPROCESS_FROM rewinds it. The rewind
+// happens once per value: it is recorded in marks, so restarts with the variable still set
+// resume from the stored progress. With neither progress nor a start block,
+// HIVE_START_FROM_HEAD=1 starts at the chain head instead of replaying from genesis; it
+// fails only when the head can't be read.
+func startBlock(ctx context.Context, chain chainLocator, marks reprocessMarks, cfg config.Config, last int64) (int64, error) {
+	current := cfg.StartBlock
+	if last > 0 && last+1 > current {
+		current = last + 1
+	}
+	if current == 0 && cfg.StartFromHead {
+		head, err := chain.HeadBlockNumber(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("head block number: %w", err)
+		}
+		log.Printf("no stored progress or HIVE_START_BLOCK; starting from chain head %d", head)
+		current = head
+	}
+	if cfg.ReprocessFrom.IsZero() {
+		return current, nil
+	}
+	done, err := marks.ReprocessedFrom(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read consumed HIVE_REPROCESS_FROM: %w", err)
+	}
+	if done.Equal(cfg.ReprocessFrom) {
+		log.Printf("HIVE_REPROCESS_FROM %s was already replayed; continuing from block %d (unset it to silence this)", cfg.ReprocessFrom.Format(time.RFC3339), current)
+		return current, nil
+	}
+	n, err := chain.BlockNumberAtTime(ctx, cfg.ReprocessFrom)
+	if err != nil {
+		log.Printf("resolve HIVE_REPROCESS_FROM %s: %v; continuing from block %d", cfg.ReprocessFrom.Format(time.RFC3339), err, current)
+		return current, nil
+	}
+	// Rewind the stored progress first, so a restart before block n is processed still
+	// replays from n rather than from the old progress.
+	if err := marks.SetLastBlock(ctx, n-1); err != nil {
+		return 0, fmt.Errorf("rewind last block to %d: %w", n-1, err)
+	}
+	if err := marks.SetReprocessedFrom(ctx, cfg.ReprocessFrom); err != nil {
+		return 0, fmt.Errorf("record consumed HIVE_REPROCESS_FROM: %w", err)
+	}
+	log.Printf("reprocessing from %s (block %d)", cfg.ReprocessFrom.Format(time.RFC3339), n)
+	return n, nil
+}
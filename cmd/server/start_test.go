@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"hivemoji/internal/config"
+)
+
+type fakeLocator struct {
+	head    int64
+	headErr error
+	heads   int
+}
+
+func (f *fakeLocator) HeadBlockNumber(ctx context.Context) (int64, error) {
+	f.heads++
+	return f.head, f.headErr
+}
+
+func (f *fakeLocator) BlockNumberAtTime(ctx context.Context, t time.Time) (int64, error) {
+	return 500, nil
+}
+
+// fakeMarks records the consumed HIVE_REPROCESS_FROM value and rewound progress in memory.
+type fakeMarks struct {
+	done      time.Time
+	lastBlock int64
+}
+
+func (f *fakeMarks) ReprocessedFrom(ctx context.Context) (time.Time, error) { return f.done, nil }
+
+func (f *fakeMarks) SetReprocessedFrom(ctx context.Context, t time.Time) error {
+	f.done = t
+	return nil
+}
+
+func (f *fakeMarks) SetLastBlock(ctx context.Context, number int64) error {
+	f.lastBlock = number
+	return nil
+}
+
+func TestStartBlock_ReprocessOnce(t *testing.T) {
+	chain := &fakeLocator{}
+	marks := &fakeMarks{}
+	cfg := config.Config{ReprocessFrom: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	got, err := startBlock(context.Background(), chain, marks, cfg, 9_000)
+	if err != nil || got != 500 {
+		t.Fatalf("expected the first start to rewind to block 500, got %d, %v", got, err)
+	}
+	if marks.lastBlock != 499 || !marks.done.Equal(cfg.ReprocessFrom) {
+		t.Fatalf("expected progress rewound to 499 and the value marked consumed, got %d and %s", marks.lastBlock, marks.done)
+	}
+
+	got, err = startBlock(context.Background(), chain, marks, cfg, 620)
+	if err != nil || got != 621 {
+		t.Fatalf("expected a restart to resume from stored progress, got %d, %v", got, err)
+	}
+
+	cfg.ReprocessFrom = cfg.ReprocessFrom.AddDate(0, 0, 1)
+	if got, err := startBlock(context.Background(), chain, marks, cfg, 620); err != nil || got != 500 {
+		t.Fatalf("expected a new value to rewind again, got %d, %v", got, err)
+	}
+}
+
+func TestStartBlock_FromHead(t *testing.T) {
+	chain := &fakeLocator{head: 90_000_000}
+	cfg := config.Config{StartFromHead: true}
+
+	got, err := startBlock(context.Background(), chain, &fakeMarks{}, cfg, 0)
+	if err != nil {
+		t.Fatalf("startBlock error: %v", err)
+	}
+	if got != 90_000_000 {
+		t.Fatalf("expected to start at head, got %d", got)
+	}
+}
+
+func TestStartBlock_HeadOnlyWithoutProgress(t *testing.T) {
+	chain := &fakeLocator{head: 90_000_000}
+
+	cases := []struct {
+		name string
+		cfg  config.Config
+		last int64
+		want int64
+	}{
+		{"stored progress wins", config.Config{StartFromHead: true}, 1_000, 1_001},
+		{"start block wins", config.Config{StartFromHead: true, StartBlock: 42}, 0, 42},
+		{"disabled replays from start", config.Config{}, 0, 0},
+		{"reprocess rewinds head start", config.Config{StartFromHead: true, ReprocessFrom: time.Unix(1, 0)}, 0, 500},
+	}
+	for _, tc := range cases {
+		chain.heads = 0
+		got, err := startBlock(context.Background(), chain, &fakeMarks{}, tc.cfg, tc.last)
+		if err != nil {
+			t.Fatalf("%s: startBlock error: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected block %d, got %d", tc.name, tc.want, got)
+		}
+		if tc.name != "reprocess rewinds head start" && chain.heads != 0 {
+			t.Fatalf("%s: expected the head not to be fetched", tc.name)
+		}
+	}
+}
+
+func TestStartBlock_HeadError(t *testing.T) {
+	unavailable := errors.New("node unavailable")
+	chain := &fakeLocator{headErr: unavailable}
+	if _, err := startBlock(context.Background(), chain, &fakeMarks{}, config.Config{StartFromHead: true}, 0); !errors.Is(err, unavailable) {
+		t.Fatalf("expected the head error, got %v", err)
+	}
+}
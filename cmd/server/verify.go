@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"hivemoji/internal/config"
+	"hivemoji/internal/storage"
+)
+
+// runVerify implements `server verify`: it recomputes stored checksums, prints a report and
+// exits, so ops can run it as a job. It returns the process exit code: 0 when every checksum
+// matches or was repaired, 2 when mismatches remain.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "overwrite mismatched checksums with the recomputed ones")
+	author := fs.String("author", "", "only verify emojis by this author")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s verify [--repair] [--author name]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, cfg.PostgresDSN)
+	if err != nil {
+		log.Fatalf("connect postgres: %v", err)
+	}
+	defer pool.Close()
+	if err := waitForDB(ctx, pool.Ping, cfg.DBReadyTimeout); err != nil {
+		log.Fatalf("connect postgres: %v", err)
+	}
+
+	store := storage.NewStore(pool)
+	blobs, err := blobStore(cfg, pool)
+	if err != nil {
+		log.Fatalf("HIVEMOJI_BLOB_STORE: %v", err)
+	}
+	store.SetBlobStore(blobs)
+	if err := store.EnsureSchema(ctx); err != nil {
+		log.Fatalf("ensure schema: %v", err)
+	}
+
+	report, err := store.VerifyChecksums(ctx, *author, *repair)
+	if report != nil {
+		printVerifyReport(os.Stdout, report, *repair)
+	}
+	if err != nil {
+		log.Fatalf("verify checksums: %v", err)
+	}
+	if len(report.Mismatches) > report.Repaired {
+		return 2
+	}
+	return 0
+}
+
+func printVerifyReport(w io.Writer, report *storage.VerifyReport, repair bool) {
+	for _, m := range report.Mismatches {
+		name := m.Name
+		if m.Variant != "" {
+			name += "~" + m.Variant
+		}
+		fmt.Fprintf(w, "mismatch %s/%s stored=%s actual=%s\n", m.Author, name, m.Stored, m.Actual)
+	}
+	fmt.Fprintf(w, "checked %d emojis (%d without checksum): %d mismatched", report.Checked, report.Unchecked, len(report.Mismatches))
+	if repair {
+		fmt.Fprintf(w, ", %d repaired", report.Repaired)
+	}
+	fmt.Fprintln(w)
+}
@@ -4,8 +4,12 @@ go 1.21
 
 require (
 	github.com/deathwingtheboss/hivego v0.0.0-20250215220023-851b58ab41d7
+	github.com/decred/dcrd/dcrec/secp256k1/v2 v2.0.0
 	github.com/jackc/pgx/v5 v5.5.4
+	github.com/klauspost/compress v1.15.0
 	github.com/labstack/echo/v4 v4.11.4
+	golang.org/x/crypto v0.17.0
+	golang.org/x/image v0.14.0
 )
 
 require (
@@ -13,19 +17,16 @@ require (
 	github.com/cfoxon/jsonrpc2client v0.0.0-20220410030230-4f361e74821a // indirect
 	github.com/decred/base58 v1.0.4 // indirect
 	github.com/decred/dcrd/crypto/blake256 v1.0.0 // indirect
-	github.com/decred/dcrd/dcrec/secp256k1/v2 v2.0.0 // indirect
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
-	github.com/klauspost/compress v1.15.0 // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasthttp v1.35.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
-	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/net v0.19.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"hivemoji/internal/storage"
+)
+
+// mimeQuality returns the q value an Accept header gives mime, taken from the most specific
+// matching range: image/webp wins over image/*, which wins over */*. It is 0 when no range
+// matches, so q=0 on the exact type excludes it even if image/* is accepted. An empty header
+// accepts everything.
+func mimeQuality(header, mime string) float64 {
+	if strings.TrimSpace(header) == "" {
+		return 1
+	}
+	typ, _, _ := strings.Cut(mime, "/")
+	best, q := 0, 0.0
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		var specificity int
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case mime:
+			specificity = 3
+		case typ + "/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		default:
+			continue
+		}
+		if specificity > best {
+			best, q = specificity, acceptQuality(params[1:])
+		}
+	}
+	return q
+}
+
+// acceptQuality returns the q parameter among params, 1 when absent or malformed.
+func acceptQuality(params []string) float64 {
+	for _, p := range params {
+		key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 1
+		}
+		return q
+	}
+	return 1
+}
+
+// acceptedFallback returns the emoji's fallback image when the request's Accept header
+// prefers the fallback's mime over the main one, i.e. gives it a higher q value. Ties go to
+// the main image.
+func acceptedFallback(header string, asset *storage.Asset, mime string) ([]byte, string, bool) {
+	if asset.FallbackMime == nil || len(asset.FallbackData) == 0 {
+		return nil, "", false
+	}
+	fallbackMime, ok := storage.NormalizeEmojiMime(*asset.FallbackMime)
+	if !ok || mimeQuality(header, fallbackMime) <= mimeQuality(header, mime) {
+		return nil, "", false
+	}
+	return asset.FallbackData, fallbackMime, true
+}
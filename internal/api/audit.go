@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// auditResponse is one audit log entry.
+type auditResponse struct {
+	Action    string    `json:"action"`
+	Author    string    `json:"author"`
+	Name      string    `json:"name"`
+	Target    string    `json:"target,omitempty"`
+	Block     *int64    `json:"block,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleAuditLog lists the most recent ownership changes, such as transfers.
+func (s *Server) handleAuditLog(c echo.Context) error {
+	limit := defaultPageLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+		}
+		limit = n
+	}
+
+	entries, err := s.store.ListAuditLog(c.Request().Context(), limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp := make([]auditResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, auditResponse{Action: e.Action, Author: e.Author, Name: e.Name, Target: e.Target, Block: e.Block, CreatedAt: e.CreatedAt})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+// bannedResponse is one entry of the banned checksum list.
+type bannedResponse struct {
+	Checksum  string    `json:"checksum"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Server) handleListBanned(c echo.Context) error {
+	banned, err := s.store.ListBannedChecksums(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp := make([]bannedResponse, 0, len(banned))
+	for _, b := range banned {
+		resp = append(resp, bannedResponse{Checksum: b.Checksum, Reason: b.Reason, CreatedAt: b.CreatedAt})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleBanChecksum stops the processor from storing images with the given checksum.
+// Emojis already stored with it are not touched; block them through moderation.
+func (s *Server) handleBanChecksum(c echo.Context) error {
+	checksum := strings.ToLower(strings.TrimSpace(c.Param("checksum")))
+	if !isValidChecksum(checksum) {
+		return echo.NewHTTPError(http.StatusBadRequest, "checksum must be a 64-character hex sha256 digest")
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON body")
+	}
+
+	if err := s.store.BanChecksum(c.Request().Context(), checksum, req.Reason); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) handleUnbanChecksum(c echo.Context) error {
+	checksum := strings.ToLower(strings.TrimSpace(c.Param("checksum")))
+	if !isValidChecksum(checksum) {
+		return echo.NewHTTPError(http.StatusBadRequest, "checksum must be a 64-character hex sha256 digest")
+	}
+
+	err := s.store.UnbanChecksum(c.Request().Context(), checksum)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// backfillResponse reports the checksum backfill that runs in the background at startup.
+type backfillResponse struct {
+	Done      bool  `json:"done"`
+	Remaining int64 `json:"remaining"`
+}
+
+func (s *Server) handleBackfillStatus(c echo.Context) error {
+	status, err := s.store.GetBackfillStatus(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, backfillResponse{Done: status.Done, Remaining: status.Remaining})
+}
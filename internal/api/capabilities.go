@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/sprite"
+	"hivemoji/internal/storage"
+)
+
+// capabilitiesResponse describes what this deployment serves, so clients can feature-detect
+// instead of probing routes. Everything in it follows the server's options.
+type capabilitiesResponse struct {
+	Protocol protocolResponse `json:"protocol"`
+	// Formats are the ?format values the raw image routes convert to.
+	Formats []string `json:"formats"`
+	// RawParams are the query parameters the raw image routes honour.
+	RawParams []string             `json:"raw_params"`
+	Features  capabilitiesFeatures `json:"features"`
+	Limits    capabilitiesLimits   `json:"limits"`
+}
+
+type capabilitiesFeatures struct {
+	Sprites    bool `json:"sprites"`
+	Preview    bool `json:"preview"`
+	Search     bool `json:"search"`
+	Export     bool `json:"export"`
+	SignedURLs bool `json:"signed_urls"`
+	// Placeholder reports whether ?default=1 serves a placeholder for missing emojis.
+	Placeholder bool `json:"placeholder"`
+	ReadOnly    bool `json:"read_only"`
+	Admin       bool `json:"admin"`
+}
+
+type capabilitiesLimits struct {
+	// MaxImageBytes is zero when ingested images aren't size-limited.
+	MaxImageBytes        int `json:"max_image_bytes"`
+	MaxChunks            int `json:"max_chunks"`
+	MaxSpriteWidth       int `json:"max_sprite_width"`
+	PreviewCell          int `json:"preview_cell"`
+	MaxPreviewEmojis     int `json:"max_preview_emojis"`
+	MaxSearchLimit       int `json:"max_search_limit"`
+	MaxAutocompleteLimit int `json:"max_autocomplete_limit"`
+}
+
+func (s *Server) handleCapabilities(c echo.Context) error {
+	maxChunks := s.opts.MaxChunks
+	if maxChunks <= 0 {
+		maxChunks = storage.DefaultMaxChunks
+	}
+
+	rawParams := []string{"format"}
+	if s.opts.Placeholder != nil {
+		rawParams = append(rawParams, "default")
+	}
+	if len(s.opts.URLSigningKey) > 0 {
+		rawParams = append(rawParams, "exp", "sig")
+	}
+
+	return c.JSON(http.StatusOK, capabilitiesResponse{
+		Protocol:  s.protocol(),
+		Formats:   []string{"apng"},
+		RawParams: rawParams,
+		Features: capabilitiesFeatures{
+			Sprites:     true,
+			Preview:     true,
+			Search:      true,
+			Export:      true,
+			SignedURLs:  len(s.opts.URLSigningKey) > 0,
+			Placeholder: s.opts.Placeholder != nil,
+			ReadOnly:    s.opts.ReadOnly,
+			Admin:       s.opts.AdminToken != "" && !s.opts.ReadOnly,
+		},
+		Limits: capabilitiesLimits{
+			MaxImageBytes:        s.opts.MaxImageBytes,
+			MaxChunks:            maxChunks,
+			MaxSpriteWidth:       sprite.MaxSide,
+			PreviewCell:          previewCell,
+			MaxPreviewEmojis:     maxPreviewEmojis,
+			MaxSearchLimit:       maxSearchLimit,
+			MaxAutocompleteLimit: maxAutocompleteLimit,
+		},
+	})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+const maxCollectionSlug = 64
+
+type collectionResponse struct {
+	Slug        string          `json:"slug"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Emojis      []emojiResponse `json:"emojis"`
+}
+
+// isValidSlug reports whether slug is 1-64 lowercase letters, digits and dashes.
+func isValidSlug(slug string) bool {
+	if slug == "" || len(slug) > maxCollectionSlug {
+		return false
+	}
+	for _, r := range slug {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// handleGetCollection returns a curated collection with its emojis in order.
+func (s *Server) handleGetCollection(c echo.Context) error {
+	slug := c.Param("slug")
+	if !isValidSlug(slug) {
+		return echo.ErrNotFound
+	}
+	format := parseResponseData(c)
+
+	collection, assets, err := s.store.GetCollection(c.Request().Context(), slug, format.any())
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := collectionResponse{
+		Slug:        collection.Slug,
+		Title:       collection.Title,
+		Description: collection.Description,
+		UpdatedAt:   collection.UpdatedAt,
+		Emojis:      make([]emojiResponse, 0, len(assets)),
+	}
+	for _, a := range assets {
+		resp.Emojis = append(resp.Emojis, s.toResponse(a, format))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handlePutCollection creates a collection or updates its title and description.
+func (s *Server) handlePutCollection(c echo.Context) error {
+	slug := c.Param("slug")
+	if !isValidSlug(slug) {
+		return echo.NewHTTPError(http.StatusBadRequest, "slug must be 1-64 lowercase letters, digits or dashes")
+	}
+
+	var req struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON body")
+	}
+
+	collection := storage.Collection{Slug: slug, Title: strings.TrimSpace(req.Title), Description: strings.TrimSpace(req.Description)}
+	if err := s.store.UpsertCollection(c.Request().Context(), collection); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteCollection(c echo.Context) error {
+	slug := c.Param("slug")
+	if !isValidSlug(slug) {
+		return echo.ErrNotFound
+	}
+
+	err := s.store.DeleteCollection(c.Request().Context(), slug)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handleAddCollectionItem adds an emoji to a collection at `position`, or moves it there.
+func (s *Server) handleAddCollectionItem(c echo.Context) error {
+	slug := c.Param("slug")
+	author := c.Param("author")
+	name := c.Param("name")
+	if !isValidSlug(slug) || strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+
+	var req struct {
+		Position int `json:"position"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON body")
+	}
+
+	err := s.store.AddCollectionItem(c.Request().Context(), slug, author, s.storedName(name), req.Position)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "collection or emoji not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) handleRemoveCollectionItem(c echo.Context) error {
+	slug := c.Param("slug")
+	author := c.Param("author")
+	name := c.Param("name")
+	if !isValidSlug(slug) || strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+
+	err := s.store.RemoveCollectionItem(c.Request().Context(), slug, author, s.storedName(name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
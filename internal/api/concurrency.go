@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultTranscodeConcurrency is how many image conversions may run at once when
+// Options.TranscodeConcurrency is zero.
+const DefaultTranscodeConcurrency = 4
+
+// transcodeRetryAfter is the Retry-After, in seconds, sent when every transcode slot is taken.
+const transcodeRetryAfter = 1
+
+// errTranscodeBusy answers requests that would convert images while every slot is taken.
+var errTranscodeBusy = echo.NewHTTPError(http.StatusServiceUnavailable, "too many image conversions in progress, retry shortly")
+
+func (s *Server) transcodeConcurrency() int {
+	if s.opts.TranscodeConcurrency > 0 {
+		return s.opts.TranscodeConcurrency
+	}
+	return DefaultTranscodeConcurrency
+}
+
+// acquireTranscode takes one of the slots bounding CPU-heavy image work (APNG conversion,
+// sprite sheets, previews) without waiting, so a burst of conversions can't starve cheap
+// requests. It returns errTranscodeBusy, with Retry-After set on c, when none is free;
+// otherwise callers must call release once the work is done.
+func (s *Server) acquireTranscode(c echo.Context) (release func(), err error) {
+	select {
+	case s.transcodes <- struct{}{}:
+		return func() { <-s.transcodes }, nil
+	default:
+		c.Response().Header().Set("Retry-After", strconv.Itoa(transcodeRetryAfter))
+		return nil, errTranscodeBusy
+	}
+}
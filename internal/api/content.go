@@ -0,0 +1,58 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+// contentPathPrefix is where emojis are served by checksum.
+const contentPathPrefix = "/ipfs-like/"
+
+// contentURL returns the content-addressed path of an asset, or nil when it has no checksum.
+func contentURL(asset storage.Asset) *string {
+	if asset.Checksum == nil || *asset.Checksum == "" {
+		return nil
+	}
+	url := contentPathPrefix + strings.ToLower(*asset.Checksum)
+	return &url
+}
+
+// handleContent serves the image whose sha256 is the requested checksum. The bytes behind a
+// checksum never change, so responses are cacheable forever; an updated emoji gets a new URL.
+func (s *Server) handleContent(c echo.Context) error {
+	checksum := strings.ToLower(strings.TrimSpace(c.Param("checksum")))
+	if !isValidChecksum(checksum) {
+		return echo.NewHTTPError(http.StatusBadRequest, "checksum must be a 64-character hex sha256 digest")
+	}
+
+	etag := `"` + checksum + `"`
+	if match := c.Request().Header.Get("If-None-Match"); match == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	assets, err := s.store.GetAssetsByChecksum(c.Request().Context(), checksum, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	for _, a := range assets {
+		// Only serve bytes that really hash to the checksum; a mismatched row would poison caches.
+		sum := sha256.Sum256(a.Data)
+		if len(a.Data) == 0 || hex.EncodeToString(sum[:]) != checksum {
+			continue
+		}
+		mime, ok := storage.NormalizeEmojiMime(a.Mime)
+		if !ok {
+			continue
+		}
+		c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		c.Response().Header().Set("ETag", etag)
+		return c.Blob(http.StatusOK, mime, a.Data)
+	}
+	return echo.ErrNotFound
+}
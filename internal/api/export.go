@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+// DefaultExportTimeout bounds how long one export stream may run.
+const DefaultExportTimeout = 5 * time.Minute
+
+// exportError is the last line of an export the server cut short, so clients can tell a
+// truncated export from a complete one.
+type exportError struct {
+	Error string `json:"error"`
+}
+
+// handleExport streams every public emoji as newline-delimited JSON, reading them from
+// storage as they are written. The stream stops, and its query is cancelled, when the client
+// disconnects or it runs longer than ExportTimeout.
+func (s *Server) handleExport(c echo.Context) error {
+	authors, err := authorsParam(c)
+	if err != nil {
+		return err
+	}
+	format := parseResponseData(c)
+	timeout := s.exportTimeout()
+	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	defer cancel()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	enc := json.NewEncoder(res)
+	var sent int
+	err = s.store.StreamAssets(ctx, storage.AssetFilter{Authors: authors}, format.any(), func(a storage.Asset) error {
+		if err := enc.Encode(s.toResponse(a, format)); err != nil {
+			return err
+		}
+		sent++
+		return nil
+	})
+	switch {
+	case err == nil:
+		if !res.Committed {
+			res.WriteHeader(http.StatusOK)
+		}
+		return nil
+	case c.Request().Context().Err() != nil:
+		// The client went away; there is no one left to answer.
+		log.Printf("export: client disconnected after %d emojis", sent)
+		return nil
+	}
+
+	msg := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg = fmt.Sprintf("export exceeded %s", timeout)
+	}
+	if !res.Committed {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, msg)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, msg)
+	}
+	log.Printf("export: stopped after %d emojis: %v", sent, err)
+	return enc.Encode(exportError{Error: msg})
+}
+
+func (s *Server) exportTimeout() time.Duration {
+	if s.opts.ExportTimeout > 0 {
+		return s.opts.ExportTimeout
+	}
+	return DefaultExportTimeout
+}
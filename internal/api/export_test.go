@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hivemoji/internal/storage"
+)
+
+func exportAssets() []storage.Asset {
+	return []storage.Asset{
+		{Name: "a", Author: strPtr("alice"), Mime: "image/png"},
+		{Name: "b", Author: strPtr("bob"), Mime: "image/png"},
+		{Name: "c", Author: strPtr("alice"), Mime: "image/png"},
+	}
+}
+
+// exportLines decodes each line of an NDJSON body into a generic map.
+func exportLines(t *testing.T, body string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestExport(t *testing.T) {
+	store := &fakeStore{assets: exportAssets()}
+	rec := serve(store, http.MethodGet, "/api/emojis/export.ndjson?author=alice")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	lines := exportLines(t, rec.Body.String())
+	if len(lines) != 2 || lines[0]["name"] != "a" || lines[1]["name"] != "c" {
+		t.Fatalf("unexpected export: %v", lines)
+	}
+}
+
+func TestExport_ClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &fakeStore{assets: exportAssets()}
+	store.onStream = func(_ context.Context, i int) {
+		if i == 1 {
+			cancel()
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/emojis/export.ndjson", nil).WithContext(ctx)
+	rec := serveRequest(store, Options{}, req)
+	if !errors.Is(store.streamErr, context.Canceled) {
+		t.Fatalf("expected the stream to observe the cancellation, got %v", store.streamErr)
+	}
+	lines := exportLines(t, rec.Body.String())
+	if len(lines) != 1 || lines[0]["name"] != "a" {
+		t.Fatalf("expected only the emoji sent before the disconnect, got %v", lines)
+	}
+}
+
+func TestExport_Timeout(t *testing.T) {
+	store := &fakeStore{assets: exportAssets()}
+	store.onStream = func(ctx context.Context, i int) {
+		if i == 1 {
+			<-ctx.Done()
+		}
+	}
+
+	rec := serveRequest(store, Options{ExportTimeout: 20 * time.Millisecond}, httptest.NewRequest(http.MethodGet, "/api/emojis/export.ndjson", nil))
+	if !errors.Is(store.streamErr, context.DeadlineExceeded) {
+		t.Fatalf("expected the stream to hit the deadline, got %v", store.streamErr)
+	}
+	lines := exportLines(t, rec.Body.String())
+	if len(lines) != 2 || lines[0]["name"] != "a" || lines[1]["error"] != "export exceeded 20ms" {
+		t.Fatalf("expected one emoji and a trailing error line, got %v", lines)
+	}
+
+	// A deadline hit before anything was written is answered as a plain error.
+	store.onStream = func(ctx context.Context, i int) { <-ctx.Done() }
+	rec = serveRequest(store, Options{ExportTimeout: 20 * time.Millisecond}, httptest.NewRequest(http.MethodGet, "/api/emojis/export.ndjson", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long a recorded admin response can be replayed.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+)
+
+// idempotent replays the recorded response when a mutating admin request is retried with
+// the same Idempotency-Key, instead of applying it twice. Requests without the header run
+// normally. Error responses are not recorded so a failed request can be retried.
+func (s *Server) idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		key := req.Header.Get("Idempotency-Key")
+		if key == "" || req.Method == http.MethodGet || req.Method == http.MethodHead {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+		}
+
+		ctx := req.Context()
+		recorded, err := s.store.GetIdempotentResponse(ctx, key, s.idempotencyTTL())
+		switch {
+		case err == nil:
+			if recorded.Method != req.Method || recorded.Path != req.URL.Path {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			}
+			c.Response().Header().Set("Idempotent-Replayed", "true")
+			if recorded.ContentType != "" {
+				c.Response().Header().Set(echo.HeaderContentType, recorded.ContentType)
+			}
+			c.Response().WriteHeader(recorded.Status)
+			_, err := c.Response().Write(recorded.Body)
+			return err
+		case !errors.Is(err, storage.ErrNotFound):
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		var body bytes.Buffer
+		res := c.Response()
+		original := res.Writer
+		res.Writer = &teeWriter{ResponseWriter: original, w: io.MultiWriter(original, &body)}
+		defer func() { res.Writer = original }()
+
+		if err := next(c); err != nil {
+			// Let echo render the error; errors are not recorded, so a retry runs the handler again.
+			return err
+		}
+		if res.Status >= http.StatusInternalServerError {
+			return nil
+		}
+
+		err = s.store.SaveIdempotentResponse(ctx, storage.IdempotentResponse{
+			Key:         key,
+			Method:      req.Method,
+			Path:        req.URL.Path,
+			Status:      res.Status,
+			ContentType: res.Header().Get(echo.HeaderContentType),
+			Body:        body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("save idempotency key: %v", err)
+		}
+		return nil
+	}
+}
+
+func (s *Server) idempotencyTTL() time.Duration {
+	if s.opts.IdempotencyTTL > 0 {
+		return s.opts.IdempotencyTTL
+	}
+	return DefaultIdempotencyTTL
+}
+
+// teeWriter copies the response body while it is written to the client.
+type teeWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (t *teeWriter) Write(b []byte) (int, error) {
+	return t.w.Write(b)
+}
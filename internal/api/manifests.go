@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPendingLimit = 50
+	maxPendingLimit     = 200
+)
+
+// pendingUploadResponse is a v2 upload announced by a register manifest but not stored yet.
+type pendingUploadResponse struct {
+	UploadID    string    `json:"upload_id"`
+	Name        string    `json:"name"`
+	Author      string    `json:"author"`
+	Mime        *string   `json:"mime,omitempty"`
+	Width       *int      `json:"width,omitempty"`
+	Height      *int      `json:"height,omitempty"`
+	Animated    bool      `json:"animated"`
+	Total       *int      `json:"total,omitempty"`
+	Block       *int64    `json:"block,omitempty"`
+	AnnouncedAt time.Time `json:"announced_at"`
+}
+
+// handleListPendingUploads lists announced uploads that have not been stored as emojis yet,
+// most recently announced first.
+func (s *Server) handleListPendingUploads(c echo.Context) error {
+	limit := defaultPendingLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPendingLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPendingLimit))
+		}
+		limit = n
+	}
+
+	manifests, err := s.store.ListPendingManifests(c.Request().Context(), limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp := make([]pendingUploadResponse, 0, len(manifests))
+	for _, m := range manifests {
+		resp = append(resp, pendingUploadResponse{
+			UploadID:    m.UploadID,
+			Name:        s.publicName(m.Name),
+			Author:      m.Author,
+			Mime:        m.Mime,
+			Width:       m.Width,
+			Height:      m.Height,
+			Animated:    m.Animated,
+			Total:       m.Total,
+			Block:       m.FirstBlock,
+			AnnouncedAt: m.CreatedAt,
+		})
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
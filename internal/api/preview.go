@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/hive"
+	"hivemoji/internal/sprite"
+)
+
+const (
+	// previewCell is the side of the square each emoji is scaled into on a preview.
+	previewCell = 64
+	// maxPreviewEmojis caps how many emojis one preview may combine.
+	maxPreviewEmojis = 16
+)
+
+// handlePreview draws the emojis listed in ?emojis=author/name,... side by side on one PNG,
+// for checking how they look together. Missing, hidden and undecodable emojis are drawn as
+// a placeholder cell rather than failing the whole preview.
+func (s *Server) handlePreview(c echo.Context) error {
+	param := strings.TrimSpace(c.QueryParam("emojis"))
+	if param == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "emojis is required, e.g. ?emojis=alice/wave,bob/smile")
+	}
+	refs := strings.Split(param, ",")
+	if len(refs) > maxPreviewEmojis {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d emojis can be previewed together", maxPreviewEmojis))
+	}
+
+	sources := make([]sprite.Source, 0, len(refs))
+	for _, ref := range refs {
+		author, name, ok := strings.Cut(strings.TrimSpace(ref), "/")
+		if !ok || !hive.ValidAccountName(author) || name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid emoji %q: expected author/name", ref))
+		}
+		src := sprite.Source{Name: name}
+		asset, err := s.publicAsset(c, author, name)
+		switch {
+		case err == nil:
+			src.Mime, src.Data = asset.Mime, asset.Data
+		case !errors.Is(err, echo.ErrNotFound):
+			return err
+		}
+		sources = append(sources, src)
+	}
+
+	release, err := s.acquireTranscode(c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	png, err := sprite.Row(sources, previewCell)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.Blob(http.StatusOK, "image/png", png)
+}
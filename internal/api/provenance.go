@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+// transferResponse is one change of owner in an emoji's provenance.
+type transferResponse struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Block     *int64    `json:"block,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type provenanceResponse struct {
+	Author          string             `json:"author"`
+	Name            string             `json:"name"`
+	RegisteredBlock *int64             `json:"registered_block,omitempty"`
+	RegisteredAt    *time.Time         `json:"registered_at,omitempty"`
+	Versions        []versionResponse  `json:"versions"`
+	Transfers       []transferResponse `json:"transfers"`
+}
+
+// handleProvenance assembles an emoji's history: where it was first registered, every
+// recorded revision, and the transfers that brought it to its current owner.
+func (s *Server) handleProvenance(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+
+	asset, err := s.publicAsset(c, author, name)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	versions, err := s.store.ListAssetVersions(ctx, author, s.storedName(name))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	entries, err := s.store.ListNameAudit(ctx, s.storedName(name))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := provenanceResponse{
+		Author:          author,
+		Name:            name,
+		RegisteredBlock: asset.RegisteredBlock,
+		Versions:        make([]versionResponse, 0, len(versions)),
+		Transfers:       ownershipChain(entries, author),
+	}
+	if len(versions) > 0 {
+		first := versions[0]
+		if first.RegisteredBlock != nil {
+			resp.RegisteredBlock = first.RegisteredBlock
+		}
+		resp.RegisteredAt = &first.CreatedAt
+	}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, toVersionResponse(v))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ownershipChain walks entries, oldest first, back from owner and returns the transfers that
+// led to it in the order they happened. Transfers of other emojis sharing the name, between
+// authors outside the chain, are left out.
+func ownershipChain(entries []storage.AuditEntry, owner string) []transferResponse {
+	var chain []transferResponse
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Action != storage.AuditTransfer || e.Target != owner {
+			continue
+		}
+		chain = append(chain, transferResponse{From: e.Author, To: e.Target, Block: e.Block, CreatedAt: e.CreatedAt})
+		owner = e.Author
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	if chain == nil {
+		chain = []transferResponse{}
+	}
+	return chain
+}
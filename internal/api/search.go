@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 100
+	maxSearchQuery     = 64
+)
+
+// handleSearch finds public emojis by name substring or tag. ?sort=popular ranks matches by
+// how often their image has been fetched instead of alphabetically.
+func (s *Server) handleSearch(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q query param is required")
+	}
+	if len(q) > maxSearchQuery {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", maxSearchQuery))
+	}
+
+	sort := c.QueryParam("sort")
+	switch sort {
+	case "", storage.SearchSortName, storage.SearchSortPopular:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown sort %q (want %s or %s)", sort, storage.SearchSortName, storage.SearchSortPopular))
+	}
+
+	limit := defaultSearchLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	format := parseResponseData(c)
+	assets, err := s.store.SearchAssets(c.Request().Context(), s.storedName(q), sort, limit, format.any())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := make([]emojiResponse, 0, len(assets))
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=30")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// fetchCounter tallies image fetches in memory until FlushFetchCounts writes them out, so
+// serving an image never waits on a database write. A nil counter counts nothing.
+type fetchCounter struct {
+	mu     sync.Mutex
+	counts map[storage.EmojiRef]int64
+}
+
+func (f *fetchCounter) add(ref storage.EmojiRef, n int64) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts == nil {
+		f.counts = make(map[storage.EmojiRef]int64)
+	}
+	f.counts[ref] += n
+}
+
+// take returns the counts gathered so far and starts a fresh tally.
+func (f *fetchCounter) take() map[storage.EmojiRef]int64 {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := f.counts
+	f.counts = nil
+	return counts
+}
+
+// FlushFetchCounts adds the image fetches counted since the last flush to the stored
+// popularity totals. Counts that fail to be written are kept for the next flush.
+func (s *Server) FlushFetchCounts(ctx context.Context) error {
+	counts := s.fetches.take()
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := s.store.AddFetchCounts(ctx, counts); err != nil {
+		for ref, n := range counts {
+			s.fetches.add(ref, n)
+		}
+		return err
+	}
+	return nil
+}
@@ -1,58 +1,567 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"hivemoji/internal/encoding"
+	"hivemoji/internal/hive"
+	"hivemoji/internal/ingest"
+	"hivemoji/internal/moderation"
+	"hivemoji/internal/processor"
+	"hivemoji/internal/sprite"
 	"hivemoji/internal/storage"
+	"hivemoji/internal/transcode"
 )
 
+// apngCacheEntries bounds how many ?format=apng conversions are kept in memory.
+const apngCacheEntries = 256
+
 // Server exposes HTTP handlers for querying stored hivemoji data.
 type Server struct {
-	store *storage.Store
+	store store
+	opts  Options
+	// apng caches ?format=apng conversions; nil converts on every request.
+	apng *transcode.Cache
+	// sprites caches author sprite sheets; nil builds on every request.
+	sprites *sprite.Cache
+	// fetches counts raw image fetches for search ranking; nil counts nothing.
+	fetches *fetchCounter
+	// transcodes holds one token per image conversion in progress; see acquireTranscode.
+	transcodes chan struct{}
+}
+
+// Options tunes optional Server behaviour.
+type Options struct {
+	// AdminToken guards /api/admin routes; admin routes are not registered when empty.
+	AdminToken string
+	// Placeholder is served by the raw image routes for missing emojis when ?default=1 is passed.
+	Placeholder *Placeholder
+	// Metrics receives API instrumentation; nil disables it.
+	Metrics Metrics
+	// CustomJSONID is the custom_json id reported by /api/protocol; empty means the default.
+	CustomJSONID string
+	// Uploads re-assembles stuck chunk uploads for admins; the route is not registered when nil.
+	Uploads Uploads
+	// Ingest exposes the ingest loop's progress to admins; the route is not registered when nil.
+	Ingest *ingest.State
+	// CacheStableAfter is how long an emoji must go unchanged before single-emoji responses are
+	// cached for a week; younger emojis get CacheRecentMaxAge so re-uploads show up quickly.
+	// Zero caches every emoji for a week.
+	CacheStableAfter time.Duration
+	// CacheRecentMaxAge is the max-age for emojis changed within CacheStableAfter.
+	CacheRecentMaxAge time.Duration
+	// ReadOnly leaves out every admin route and rejects mutating methods, for public replicas.
+	ReadOnly bool
+	// NamePrefix is the deployment namespace: routes and responses use names without it, while
+	// storage keeps the full on-chain name. Empty disables it.
+	NamePrefix string
+	// URLSigningKey, when set, makes the raw image routes serve only URLs signed with it by
+	// SignRawURL, answering 403 to unsigned, tampered or expired ones.
+	URLSigningKey []byte
+	// IdempotencyTTL is how long admin responses are replayed for a repeated Idempotency-Key;
+	// zero means DefaultIdempotencyTTL.
+	IdempotencyTTL time.Duration
+	// ExportTimeout bounds how long one export stream may run; zero means DefaultExportTimeout.
+	ExportTimeout time.Duration
+	// TranscodeConcurrency bounds how many image conversions run at once; requests beyond it
+	// are answered 503. Zero means DefaultTranscodeConcurrency.
+	TranscodeConcurrency int
+	// MaxImageBytes and MaxChunks are the ingest limits reported by /api/capabilities; zero
+	// means no image size limit and storage.DefaultMaxChunks respectively.
+	MaxImageBytes int
+	MaxChunks     int
+}
+
+// Uploads completes buffered v2 chunk uploads on demand.
+type Uploads interface {
+	CompleteUpload(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error)
+}
+
+// Metrics receives API instrumentation from the Server.
+type Metrics interface {
+	ObserveImage(found bool)
+	ObserveRequest(method, route string, status int, elapsed time.Duration)
+}
+
+// Placeholder is a default image served in place of missing or hidden emojis.
+type Placeholder struct {
+	Data   []byte
+	Mime   string
+	Status int
+}
+
+// LoadPlaceholder reads and validates a placeholder image from disk.
+func LoadPlaceholder(path string, status int) (*Placeholder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("placeholder image is empty")
+	}
+	mime, ok := storage.NormalizeEmojiMime(encoding.SniffMime(data))
+	if !ok {
+		return nil, fmt.Errorf("placeholder must be a png, gif or webp image")
+	}
+	return &Placeholder{Data: data, Mime: mime, Status: status}, nil
+}
+
+// store defines the methods Server needs from storage.Store.
+type store interface {
+	GetAsset(ctx context.Context, author, name string) (*storage.Asset, error)
+	ListVariants(ctx context.Context, author, name string, includeData bool) ([]storage.Asset, error)
+	ListAssetVersions(ctx context.Context, author, name string) ([]storage.AssetVersion, error)
+	ListAssetVersionsPage(ctx context.Context, author, name string, q storage.VersionQuery) ([]storage.AssetVersion, int, error)
+	NameOwner(ctx context.Context, name string) (string, error)
+	AuthorsOfName(ctx context.Context, name string) ([]string, error)
+	GetUploadStatus(ctx context.Context, uploadID, kind string) (*storage.UploadStatus, error)
+	ListPendingManifests(ctx context.Context, limit int) ([]storage.Manifest, error)
+	ListAssets(ctx context.Context, includeData bool) ([]storage.Asset, error)
+	ListAssetsByAuthor(ctx context.Context, author string, includeData bool) ([]storage.Asset, error)
+	ListAssetsByAuthors(ctx context.Context, authors []string, includeData bool) ([]storage.Asset, error)
+	ListAssetsPage(ctx context.Context, filter storage.AssetFilter, page storage.Page, includeData bool) ([]storage.Asset, error)
+	ListAssetsPaged(ctx context.Context, includeData bool, limit, offset int) ([]storage.Asset, int64, error)
+	GetAssetsByChecksum(ctx context.Context, checksum string, includeData bool) ([]storage.Asset, error)
+	GetAuthorLastModified(ctx context.Context, author string) (time.Time, error)
+	ListFeatured(ctx context.Context, includeData bool) ([]storage.Asset, error)
+	RandomAsset(ctx context.Context, author string) (*storage.Asset, error)
+	SetFeatured(ctx context.Context, author, name string, featured bool, order int) error
+	UpdateAssetMetadata(ctx context.Context, author, name string, update storage.AssetMetadataUpdate) error
+	SetModerationStatus(ctx context.Context, author, name, status string) error
+	AutocompleteNames(ctx context.Context, prefix string, limit int) ([]storage.Suggestion, error)
+	SearchAssets(ctx context.Context, query, sort string, limit int, includeData bool) ([]storage.Asset, error)
+	AddFetchCounts(ctx context.Context, counts map[storage.EmojiRef]int64) error
+	Count(ctx context.Context, filter storage.AssetFilter) (storage.AssetCount, error)
+	IngestNotes(ctx context.Context, author, name string) ([]string, error)
+	ListBannedChecksums(ctx context.Context) ([]storage.BannedChecksum, error)
+	BanChecksum(ctx context.Context, checksum, reason string) error
+	UnbanChecksum(ctx context.Context, checksum string) error
+	GetBackfillStatus(ctx context.Context) (storage.BackfillStatus, error)
+	ListAuditLog(ctx context.Context, limit int) ([]storage.AuditEntry, error)
+	ListNameAudit(ctx context.Context, name string) ([]storage.AuditEntry, error)
+	UpsertCollection(ctx context.Context, c storage.Collection) error
+	DeleteCollection(ctx context.Context, slug string) error
+	AddCollectionItem(ctx context.Context, slug, author, name string, position int) error
+	RemoveCollectionItem(ctx context.Context, slug, author, name string) error
+	GetCollection(ctx context.Context, slug string, includeData bool) (*storage.Collection, []storage.Asset, error)
+	ListAssetHistory(ctx context.Context, filter storage.AssetFilter) ([]storage.Asset, error)
+	AuthorUsage(ctx context.Context, author string) (storage.AuthorUsage, error)
+	StreamAssets(ctx context.Context, filter storage.AssetFilter, includeData bool, fn func(storage.Asset) error) error
+	GetIdempotentResponse(ctx context.Context, key string, ttl time.Duration) (*storage.IdempotentResponse, error)
+	SaveIdempotentResponse(ctx context.Context, resp storage.IdempotentResponse) error
 }
 
 // New constructs the API server.
-func New(store *storage.Store) *Server {
-	return &Server{store: store}
+func New(store *storage.Store, opts Options) *Server {
+	return &Server{store: store, opts: opts, apng: transcode.NewCache(apngCacheEntries), sprites: sprite.NewCache(spriteCacheEntries), fetches: &fetchCounter{}}
 }
 
-// Register wires HTTP handlers onto an Echo instance.
+// Register wires HTTP handlers onto an Echo instance. In ReadOnly mode no admin routes are
+// registered and every request that isn't GET, HEAD or OPTIONS is answered 405.
 func (s *Server) Register(e *echo.Echo) {
+	s.transcodes = make(chan struct{}, s.transcodeConcurrency())
+	if s.opts.ReadOnly {
+		e.Pre(rejectMutations)
+	}
+	if s.opts.Metrics != nil {
+		e.Use(s.observeRequests)
+	}
+
 	e.GET("/health", s.handleHealth)
-	e.GET("/@:author/@:name", s.handleGetImage)
-	e.GET("/:author/:name", s.handleGetImage)
+	e.GET("/api/protocol", s.handleProtocol)
+	e.GET("/api/capabilities", s.handleCapabilities)
+	var raw []echo.MiddlewareFunc
+	if len(s.opts.URLSigningKey) > 0 {
+		raw = append(raw, s.requireSignature)
+	}
+	e.GET("/@:author/@:name", s.handleGetImage, raw...)
+	e.GET("/:author/:name", s.handleGetImage, raw...)
 	e.GET("/api/emojis", s.handleList)
+	e.HEAD("/api/emojis", s.handleCount)
 	e.GET("/api/authors/:author/emojis", s.handleListByAuthor)
+	e.HEAD("/api/authors/:author/emojis", s.handleCount)
 	e.GET("/api/authors/:author/emojis/:name", s.handleGetByAuthor)
+	e.GET("/api/authors/:author/emojis/:name/variants", s.handleListVariants)
+	e.GET("/api/authors/:author/emojis/:name/raw", s.handleGetImage, raw...)
+	e.GET("/api/authors/:author/emojis/:name/base64", s.handleBase64)
+	e.GET("/api/authors/:author/emojis/:name/versions", s.handleListVersions)
+	e.GET("/api/authors/:author/emojis/:name/diff", s.handleDiff)
+	e.GET("/api/authors/:author/emojis/:name/provenance", s.handleProvenance)
+	e.GET("/api/authors/:author/packs", s.handleListPacks)
+	e.GET("/api/authors/:author/usage", s.handleAuthorUsage)
+	e.GET("/api/authors/:author/sprite.png", s.handleSprite)
+	e.GET("/api/authors/:author/emoji.css", s.handleSpriteCSS)
+	e.GET("/api/emojis/by-checksum/:checksum", s.handleListByChecksum)
+	e.GET("/ipfs-like/:checksum", s.handleContent)
+	e.GET("/api/emojis/featured", s.handleListFeatured)
+	e.GET("/api/emojis/export.ndjson", s.handleExport)
+	e.GET("/api/collections/:slug", s.handleGetCollection)
+	e.GET("/api/emojis/autocomplete", s.handleAutocomplete)
+	e.GET("/api/emojis/search", s.handleSearch)
+	e.GET("/api/random", s.handleRandom)
+	e.GET("/api/preview", s.handlePreview)
 	e.GET("/api/emojis/:name", s.handleGet)
+	e.GET("/api/emojis/:name/raw", s.handleGetRawByName, raw...)
+	e.GET("/api/names/:name/reservation", s.handleNameReservation)
+	e.GET("/api/uploads/pending", s.handleListPendingUploads)
+	e.GET("/api/uploads/:id/:kind/missing", s.handleMissingChunks)
+
+	if s.opts.AdminToken != "" && !s.opts.ReadOnly {
+		admin := e.Group("/api/admin", s.requireAdmin, s.idempotent)
+		admin.PUT("/authors/:author/emojis/:name/featured", s.handleSetFeatured)
+		admin.GET("/authors/:author/emojis/:name", s.handleAdminGet)
+		admin.GET("/authors/:author/emojis/:name/notes", s.handleIngestNotes)
+		admin.PATCH("/authors/:author/emojis/:name", s.handlePatchMetadata)
+		admin.PUT("/authors/:author/emojis/:name/moderation", s.handleSetModeration)
+		admin.GET("/banned-checksums", s.handleListBanned)
+		admin.PUT("/banned-checksums/:checksum", s.handleBanChecksum)
+		admin.DELETE("/banned-checksums/:checksum", s.handleUnbanChecksum)
+		admin.GET("/checksum-backfill", s.handleBackfillStatus)
+		admin.GET("/audit-log", s.handleAuditLog)
+		admin.PUT("/collections/:slug", s.handlePutCollection)
+		admin.DELETE("/collections/:slug", s.handleDeleteCollection)
+		admin.PUT("/collections/:slug/items/:author/:name", s.handleAddCollectionItem)
+		admin.DELETE("/collections/:slug/items/:author/:name", s.handleRemoveCollectionItem)
+		if s.opts.Ingest != nil {
+			admin.GET("/ingest", s.handleIngestState)
+		}
+		if s.opts.Uploads != nil {
+			admin.POST("/uploads/:id/:kind/assemble", s.handleAssembleUpload)
+		}
+	}
+}
+
+// observeRequests reports every request's duration and final status to Metrics, labelled by
+// the matched route pattern so path parameters don't multiply series.
+func (s *Server) observeRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil {
+			status = http.StatusInternalServerError
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				status = he.Code
+			}
+		}
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		s.opts.Metrics.ObserveRequest(c.Request().Method, route, status, time.Since(start))
+		return err
+	}
+}
+
+// rejectMutations answers 405 to any method that could change state, before routing.
+func rejectMutations(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+		c.Response().Header().Set("Allow", "GET, HEAD, OPTIONS")
+		return echo.NewHTTPError(http.StatusMethodNotAllowed, "server is read-only")
+	}
+}
+
+// requireAdmin rejects requests that do not carry the configured admin bearer token.
+func (s *Server) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.AdminToken)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "admin token required")
+		}
+		return next(c)
+	}
 }
 
 func (s *Server) handleHealth(c echo.Context) error {
 	return c.String(http.StatusOK, "ok")
 }
 
+// protocolResponse tells uploaders how to build custom_json ops this server will ingest.
+type protocolResponse struct {
+	CustomJSONID string               `json:"custom_json_id"`
+	Versions     []processor.Protocol `json:"versions"`
+}
+
+func (s *Server) handleProtocol(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.protocol())
+}
+
+func (s *Server) protocol() protocolResponse {
+	id := s.opts.CustomJSONID
+	if id == "" {
+		id = processor.DefaultCustomJSONID
+	}
+	return protocolResponse{CustomJSONID: id, Versions: processor.Protocols()}
+}
+
 func (s *Server) handleList(c echo.Context) error {
-	includeData := c.QueryParam("with_data") == "1" || strings.EqualFold(c.QueryParam("with_data"), "true")
+	format := parseResponseData(c)
+	authors, err := authorsParam(c)
+	if err != nil {
+		return err
+	}
+	filter := storage.AssetFilter{Authors: authors}
+	if err := blockRangeParams(c, &filter); err != nil {
+		return err
+	}
+	if isTruthy(c.QueryParam("all_versions")) {
+		return s.handleListHistory(c, filter, format)
+	}
+	query := c.QueryParams()
+	if query.Has("offset") && (query.Has("after") || len(authors) > 0 || filter.FromBlock > 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "offset can't be combined with after, author or a block range")
+	}
+	// Block ranges are always paged: an event can add more emojis than fit in one response.
+	if query.Has("after") || filter.FromBlock > 0 || query.Has("limit") && len(authors) > 0 {
+		return s.handleListPage(c, filter, format)
+	}
+	if query.Has("limit") || query.Has("offset") {
+		return s.handleListPaged(c, format)
+	}
 
-	assets, err := s.store.ListAssets(c.Request().Context(), includeData)
+	var assets []storage.Asset
+	if len(authors) > 0 {
+		assets, err = s.store.ListAssetsByAuthors(c.Request().Context(), authors, format.any())
+	} else {
+		assets, err = s.store.ListAssets(c.Request().Context(), format.any())
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	var resp []emojiResponse
 	for _, a := range assets {
-		resp = append(resp, toResponse(a, includeData))
+		resp = append(resp, s.toResponse(a, format))
 	}
 
 	return c.JSON(http.StatusOK, resp)
 }
 
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 500
+)
+
+// pageLimitParam reads ?limit, clamped to [1, maxPageLimit]; it defaults to defaultPageLimit.
+func pageLimitParam(c echo.Context) (int, error) {
+	v := c.QueryParam("limit")
+	if v == "" {
+		return defaultPageLimit, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "limit must be an integer")
+	}
+	return min(max(n, 1), maxPageLimit), nil
+}
+
+// pagedListResponse is one ?limit/?offset page of the emoji listing.
+type pagedListResponse struct {
+	Items  []emojiResponse `json:"items"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// handleListPaged serves one ?limit/?offset page of all emojis ordered by name, then author,
+// wrapped with the listing total. A negative offset is clamped to 0. When more emojis follow,
+// X-Next-Cursor carries the value to continue with ?after= instead.
+func (s *Server) handleListPaged(c echo.Context, format responseData) error {
+	limit, err := pageLimitParam(c)
+	if err != nil {
+		return err
+	}
+	var offset int
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "offset must be an integer")
+		}
+		offset = max(n, 0)
+	}
+
+	assets, total, err := s.store.ListAssetsPaged(c.Request().Context(), format.any(), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(assets) > 0 && int64(offset+len(assets)) < total {
+		c.Response().Header().Set("X-Next-Cursor", storage.KeyOf(assets[len(assets)-1]).Cursor())
+	}
+
+	resp := pagedListResponse{Items: make([]emojiResponse, 0, len(assets)), Total: total, Limit: limit, Offset: offset}
+	for _, a := range assets {
+		resp.Items = append(resp.Items, s.toResponse(a, format))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleListPage serves one keyset page ordered by name, then author; limit is clamped to
+// [1, maxPageLimit]. When more emojis follow, X-Next-Cursor carries the value to pass as
+// ?after= for the next page; X-Total-Count carries how many emojis the whole listing has.
+func (s *Server) handleListPage(c echo.Context, filter storage.AssetFilter, format responseData) error {
+	limit, err := pageLimitParam(c)
+	if err != nil {
+		return err
+	}
+	page := storage.Page{Limit: limit}
+	if v := c.QueryParam("after"); v != "" {
+		after, err := storage.ParseCursor(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "after must be a cursor from X-Next-Cursor")
+		}
+		page.After = after
+	}
+
+	// Fetch one extra row to learn whether another page follows.
+	page.Limit++
+	assets, err := s.store.ListAssetsPage(c.Request().Context(), filter, page, format.any())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(assets) > limit {
+		assets = assets[:limit]
+		c.Response().Header().Set("X-Next-Cursor", storage.KeyOf(assets[limit-1]).Cursor())
+	}
+	count, err := s.store.Count(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(count.Total, 10))
+
+	resp := make([]emojiResponse, 0, len(assets))
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleListHistory lists current emojis followed by their earlier revisions. Revision history
+// keeps metadata only, so there is no image data to include.
+func (s *Server) handleListHistory(c echo.Context, filter storage.AssetFilter, format responseData) error {
+	if c.QueryParams().Has("limit") || c.QueryParams().Has("offset") || c.QueryParams().Has("after") || filter.FromBlock > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "all_versions can't be combined with limit, offset, after or a block range")
+	}
+	if format.any() {
+		return echo.NewHTTPError(http.StatusBadRequest, "all_versions listings don't include image data")
+	}
+
+	assets, err := s.store.ListAssetHistory(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp := make([]emojiResponse, 0, len(assets))
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleCount answers HEAD on the listing routes with X-Total-Count and Last-Modified only,
+// letting clients poll for changes without downloading the list.
+func (s *Server) handleCount(c echo.Context) error {
+	filter := storage.AssetFilter{Author: c.Param("author")}
+	if filter.Author == "" {
+		authors, err := authorsParam(c)
+		if err != nil {
+			return err
+		}
+		filter.Authors = authors
+	}
+	if err := blockRangeParams(c, &filter); err != nil {
+		return err
+	}
+
+	count, err := s.store.Count(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	h := c.Response().Header()
+	h.Set("X-Total-Count", strconv.FormatInt(count.Total, 10))
+	if !count.LastModified.IsZero() {
+		h.Set("Last-Modified", count.LastModified.UTC().Format(http.TimeFormat))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// maxBlockRange caps how many blocks a from_block/to_block listing may span: 31 days of
+// 3-second blocks.
+const maxBlockRange = 31 * 28800
+
+// blockRangeParams reads ?from_block= and ?to_block= into filter. Both are required together,
+// inclusive, and may span at most maxBlockRange blocks.
+func blockRangeParams(c echo.Context, filter *storage.AssetFilter) error {
+	from, to := c.QueryParam("from_block"), c.QueryParam("to_block")
+	if from == "" && to == "" {
+		return nil
+	}
+	if from == "" || to == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "from_block and to_block must be given together")
+	}
+	fromBlock, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || fromBlock < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "from_block must be a positive block number")
+	}
+	toBlock, err := strconv.ParseInt(to, 10, 64)
+	if err != nil || toBlock < fromBlock {
+		return echo.NewHTTPError(http.StatusBadRequest, "to_block must be a block number no lower than from_block")
+	}
+	if toBlock-fromBlock+1 > maxBlockRange {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("block range may span at most %d blocks", maxBlockRange))
+	}
+	filter.FromBlock, filter.ToBlock = fromBlock, toBlock
+	return nil
+}
+
+// maxAuthorsParam caps how many authors one listing request may filter on.
+const maxAuthorsParam = 50
+
+// authorsParam reads repeated ?author= values for a follow-feed style listing, dropping
+// duplicates and rejecting invalid account names.
+func authorsParam(c echo.Context) ([]string, error) {
+	values := c.QueryParams()["author"]
+	if len(values) > maxAuthorsParam {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d authors are allowed", maxAuthorsParam))
+	}
+
+	var authors []string
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		author := strings.ToLower(strings.TrimSpace(v))
+		if !hive.ValidAccountName(author) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid author %q", v))
+		}
+		if !seen[author] {
+			seen[author] = true
+			authors = append(authors, author)
+		}
+	}
+	return authors, nil
+}
+
 func (s *Server) handleListByAuthor(c echo.Context) error {
 	author := c.Param("author")
 	if strings.TrimSpace(author) == "" {
@@ -73,16 +582,16 @@ func (s *Server) handleListByAuthor(c echo.Context) error {
 		return c.NoContent(http.StatusNotModified)
 	}
 
-	includeData := c.QueryParam("with_data") == "1" || strings.EqualFold(c.QueryParam("with_data"), "true")
+	format := parseResponseData(c)
 
-	assets, err := s.store.ListAssetsByAuthor(c.Request().Context(), author, includeData)
+	assets, err := s.store.ListAssetsByAuthor(c.Request().Context(), author, format.any())
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	var resp []emojiResponse
 	for _, a := range assets {
-		resp = append(resp, toResponse(a, includeData))
+		resp = append(resp, s.toResponse(a, format))
 	}
 
 	// Set cache headers
@@ -92,6 +601,432 @@ func (s *Server) handleListByAuthor(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// usageResponse is an author's storage footprint.
+type usageResponse struct {
+	Author   string `json:"author"`
+	Bytes    int64  `json:"bytes"`
+	Emojis   int64  `json:"emojis"`
+	Animated int64  `json:"animated"`
+}
+
+func (s *Server) handleAuthorUsage(c echo.Context) error {
+	author := c.Param("author")
+	if strings.TrimSpace(author) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "author is required")
+	}
+
+	usage, err := s.store.AuthorUsage(c.Request().Context(), author)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, usageResponse{Author: author, Bytes: usage.Bytes, Emojis: usage.Emojis, Animated: usage.Animated})
+}
+
+// packResponse describes an author's emojis sharing a tag, with the emoji to show as its thumbnail.
+type packResponse struct {
+	Tag   string        `json:"tag"`
+	Count int           `json:"count"`
+	Cover emojiResponse `json:"cover"`
+}
+
+func (s *Server) handleListPacks(c echo.Context) error {
+	author := c.Param("author")
+	if strings.TrimSpace(author) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "author is required")
+	}
+
+	assets, err := s.store.ListAssetsByAuthor(c.Request().Context(), author, false)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, s.groupPacks(assets))
+}
+
+// groupPacks groups assets by tag. The cover is the first flagged cover emoji in the pack,
+// or its first emoji when none is flagged. Untagged emojis belong to no pack.
+func (s *Server) groupPacks(assets []storage.Asset) []packResponse {
+	index := make(map[string]int)
+	packs := []packResponse{}
+	var flagged []bool
+	for _, a := range assets {
+		for _, tag := range a.Tags {
+			i, ok := index[tag]
+			if !ok {
+				i = len(packs)
+				index[tag] = i
+				packs = append(packs, packResponse{Tag: tag, Cover: s.toResponse(a, responseData{})})
+				flagged = append(flagged, a.Cover)
+			} else if a.Cover && !flagged[i] {
+				packs[i].Cover = s.toResponse(a, responseData{})
+				flagged[i] = true
+			}
+			packs[i].Count++
+		}
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Tag < packs[j].Tag })
+	return packs
+}
+
+func (s *Server) handleListByChecksum(c echo.Context) error {
+	checksum := strings.TrimSpace(c.Param("checksum"))
+	if !isValidChecksum(checksum) {
+		return echo.NewHTTPError(http.StatusBadRequest, "checksum must be a 64-character hex sha256 digest")
+	}
+
+	format := parseResponseData(c)
+
+	assets, err := s.store.GetAssetsByChecksum(c.Request().Context(), checksum, format.any())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := []emojiResponse{}
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) handleListFeatured(c echo.Context) error {
+	format := parseResponseData(c)
+
+	assets, err := s.store.ListFeatured(c.Request().Context(), format.any())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := []emojiResponse{}
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+	maxAutocompletePrefix    = 64
+)
+
+type suggestionResponse struct {
+	Name     string `json:"name"`
+	Author   string `json:"author"`
+	Mime     string `json:"mime"`
+	Animated bool   `json:"animated"`
+}
+
+func (s *Server) handleAutocomplete(c echo.Context) error {
+	prefix := strings.TrimSpace(c.QueryParam("prefix"))
+	if prefix == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "prefix query param is required")
+	}
+	if len(prefix) > maxAutocompletePrefix {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("prefix must be at most %d characters", maxAutocompletePrefix))
+	}
+
+	limit := defaultAutocompleteLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(n, maxAutocompleteLimit)
+	}
+
+	suggestions, err := s.store.AutocompleteNames(c.Request().Context(), s.storedName(prefix), limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := make([]suggestionResponse, 0, len(suggestions))
+	for _, sug := range suggestions {
+		resp = append(resp, suggestionResponse{Name: s.publicName(sug.Name), Author: sug.Author, Mime: sug.Mime, Animated: sug.Animated})
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=30")
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) handleSetFeatured(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+	name = s.storedName(name)
+
+	var req struct {
+		Featured *bool `json:"featured"`
+		Order    int   `json:"order"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON body")
+	}
+	if req.Featured == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "featured is required")
+	}
+
+	err := s.store.SetFeatured(c.Request().Context(), author, name, *req.Featured, req.Order)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) handleSetModeration(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+	name = s.storedName(name)
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON body")
+	}
+	if !moderation.ValidStatus(req.Status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be approved, pending or blocked")
+	}
+
+	err := s.store.SetModerationStatus(c.Request().Context(), author, name, req.Status)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// assembleResponse summarizes a re-assembled chunk set.
+type assembleResponse struct {
+	UploadID string `json:"upload_id"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Author   string `json:"author"`
+	Mime     string `json:"mime"`
+	Bytes    int    `json:"bytes"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// missingResponse tells an uploader which chunks of an upload to (re)send.
+type missingResponse struct {
+	UploadID  string `json:"upload_id"`
+	Kind      string `json:"kind"`
+	Total     int    `json:"total"`
+	Completed bool   `json:"completed"`
+	Missing   []int  `json:"missing"`
+}
+
+// handleMissingChunks lists the seqs of a v2 upload not received yet, so an uploader resuming
+// with the same upload_id only resends those.
+func (s *Server) handleMissingChunks(c echo.Context) error {
+	id := c.Param("id")
+	kind := c.Param("kind")
+	if strings.TrimSpace(id) == "" {
+		return echo.ErrNotFound
+	}
+	if kind != "main" && kind != "fallback" {
+		return echo.NewHTTPError(http.StatusBadRequest, "kind must be main or fallback")
+	}
+
+	status, err := s.store.GetUploadStatus(c.Request().Context(), id, kind)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, missingResponse{
+		UploadID:  status.UploadID,
+		Kind:      status.Kind,
+		Total:     status.Total,
+		Completed: status.Completed,
+		Missing:   status.Missing,
+	})
+}
+
+func (s *Server) handleAssembleUpload(c echo.Context) error {
+	id := c.Param("id")
+	kind := c.Param("kind")
+	if strings.TrimSpace(id) == "" {
+		return echo.ErrNotFound
+	}
+	if kind != "main" && kind != "fallback" {
+		return echo.NewHTTPError(http.StatusBadRequest, "kind must be main or fallback")
+	}
+
+	set, err := s.opts.Uploads.CompleteUpload(c.Request().Context(), id, kind)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if errors.Is(err, storage.ErrAssemble) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, assembleResponse{
+		UploadID: set.UploadID,
+		Kind:     set.Kind,
+		Name:     s.publicName(set.Name),
+		Author:   set.Author,
+		Mime:     set.Mime,
+		Bytes:    len(set.Data),
+		Checksum: set.Checksum,
+	})
+}
+
+func (s *Server) handlePatchMetadata(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+	name = s.storedName(name)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPatchBody))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to read body")
+	}
+	update, err := parseMetadataPatch(body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err = s.store.UpdateAssetMetadata(c.Request().Context(), author, name, update)
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	asset, err := s.store.GetAsset(c.Request().Context(), author, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, s.toResponse(*asset, responseData{}))
+}
+
+const (
+	maxPatchBody      = 16 << 10
+	maxDimension      = 4096
+	maxDescriptionLen = 280
+	maxTags           = 16
+	maxTagLen         = 32
+)
+
+// parseMetadataPatch validates a sparse metadata correction. Only mime, width, height,
+// description and tags may be changed; image bytes must be re-registered on chain.
+func parseMetadataPatch(body []byte) (storage.AssetMetadataUpdate, error) {
+	var update storage.AssetMetadataUpdate
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return update, errors.New("body must be a JSON object")
+	}
+	if len(fields) == 0 {
+		return update, errors.New("no fields to update")
+	}
+
+	for key, raw := range fields {
+		switch key {
+		case "mime":
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return update, errors.New("mime must be a string")
+			}
+			mime, ok := storage.NormalizeEmojiMime(v)
+			if !ok {
+				return update, fmt.Errorf("unsupported mime %q", v)
+			}
+			update.Mime = &mime
+		case "width", "height":
+			var v int
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return update, fmt.Errorf("%s must be an integer", key)
+			}
+			if v <= 0 || v > maxDimension {
+				return update, fmt.Errorf("%s must be between 1 and %d", key, maxDimension)
+			}
+			if key == "width" {
+				update.Width = &v
+			} else {
+				update.Height = &v
+			}
+		case "description":
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return update, errors.New("description must be a string")
+			}
+			v = strings.TrimSpace(v)
+			if len([]rune(v)) > maxDescriptionLen {
+				return update, fmt.Errorf("description must be at most %d characters", maxDescriptionLen)
+			}
+			update.Description = &v
+		case "tags":
+			var v []string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return update, errors.New("tags must be an array of strings")
+			}
+			tags, err := normalizeTags(v)
+			if err != nil {
+				return update, err
+			}
+			update.Tags = &tags
+		case "data", "fallback_data":
+			return update, fmt.Errorf("%s cannot be changed here; re-register the emoji on chain", key)
+		default:
+			return update, fmt.Errorf("field %q is not editable", key)
+		}
+	}
+
+	return update, nil
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags, rejecting unsafe values.
+func normalizeTags(raw []string) ([]string, error) {
+	tags := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, t := range raw {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if len(t) > maxTagLen {
+			return nil, fmt.Errorf("tag %q must be at most %d characters", t, maxTagLen)
+		}
+		for _, r := range t {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return nil, fmt.Errorf("tag %q may only contain a-z, 0-9, '-' and '_'", t)
+			}
+		}
+		if _, dup := seen[t]; dup {
+			continue
+		}
+		seen[t] = struct{}{}
+		tags = append(tags, t)
+	}
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxTags)
+	}
+	return tags, nil
+}
+
 func (s *Server) handleGet(c echo.Context) error {
 	name := c.Param("name")
 	if name == "" {
@@ -103,39 +1038,258 @@ func (s *Server) handleGet(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "author query param is required")
 	}
 
-	asset, err := s.store.GetAsset(c.Request().Context(), author, name)
+	asset, err := s.publicAsset(c, author, name)
+	if err != nil {
+		return err
+	}
+
+	format := parseResponseData(c)
+
+	s.setAssetCacheControl(c, asset)
+	return c.JSON(http.StatusOK, s.toResponse(*asset, format))
+}
+
+// nameChoicesResponse answers /api/emojis/:name/raw when several authors have the name.
+type nameChoicesResponse struct {
+	Name    string       `json:"name"`
+	Choices []nameChoice `json:"choices"`
+}
+
+type nameChoice struct {
+	Author string `json:"author"`
+	URL    string `json:"url"`
+}
+
+// handleGetRawByName serves an emoji's image by name alone. With exactly one author using the
+// name the image is served as by the raw routes; with several, 300 Multiple Choices lists their
+// raw URLs, most recently updated first, and Location points at the first.
+func (s *Server) handleGetRawByName(c echo.Context) error {
+	name := c.Param("name")
+	if strings.TrimSpace(name) == "" {
+		return echo.ErrNotFound
+	}
+	authors, err := s.store.AuthorsOfName(c.Request().Context(), s.storedName(name))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-	if asset == nil {
+
+	switch len(authors) {
+	case 0:
+		err = echo.ErrNotFound
+	case 1:
+		err = s.writeImage(c, authors[0], name)
+	default:
+		resp := nameChoicesResponse{Name: name, Choices: make([]nameChoice, 0, len(authors))}
+		for _, author := range authors {
+			resp.Choices = append(resp.Choices, nameChoice{Author: author, URL: "/@" + url.PathEscape(author) + "/@" + url.PathEscape(name)})
+		}
+		c.Response().Header().Set("Location", resp.Choices[0].URL)
+		c.Response().Header().Set("Cache-Control", "public, max-age=60")
+		return c.JSON(http.StatusMultipleChoices, resp)
+	}
+	if errors.Is(err, echo.ErrNotFound) && s.opts.Placeholder != nil && isTruthy(c.QueryParam("default")) {
+		return s.servePlaceholder(c)
+	}
+	return err
+}
+
+func (s *Server) handleGetByAuthor(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
 		return echo.ErrNotFound
 	}
 
-	includeData := c.QueryParam("with_data") == "1" || strings.EqualFold(c.QueryParam("with_data"), "true")
+	format := parseResponseData(c)
 
-	return c.JSON(http.StatusOK, toResponse(*asset, includeData))
+	asset, err := s.publicAsset(c, author, name)
+	if err != nil {
+		return err
+	}
+	s.setAssetCacheControl(c, asset)
+	return c.JSON(http.StatusOK, s.toResponse(*asset, format))
 }
 
-func (s *Server) handleGetByAuthor(c echo.Context) error {
+// handleListVariants returns the base emoji followed by its variants.
+func (s *Server) handleListVariants(c echo.Context) error {
 	author := c.Param("author")
 	name := c.Param("name")
 	if strings.TrimSpace(author) == "" || name == "" {
 		return echo.ErrNotFound
 	}
 
-	includeData := c.QueryParam("with_data") == "1" || strings.EqualFold(c.QueryParam("with_data"), "true")
+	format := parseResponseData(c)
 
-	asset, err := s.store.GetAsset(c.Request().Context(), author, name)
+	assets, err := s.store.ListVariants(c.Request().Context(), author, s.storedName(name), format.any())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(assets) == 0 {
+		return echo.ErrNotFound
+	}
+
+	resp := make([]emojiResponse, 0, len(assets))
+	for _, a := range assets {
+		resp = append(resp, s.toResponse(a, format))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleBase64 returns just the base64-encoded image as text/plain, for piping into shell
+// tools. `?fallback=1` selects the fallback image instead.
+func (s *Server) handleBase64(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+
+	asset, err := s.publicAsset(c, author, name)
+	if err != nil {
+		return err
+	}
+	data := asset.Data
+	if isTruthy(c.QueryParam("fallback")) {
+		data = asset.FallbackData
+	}
+	if len(data) == 0 {
+		return echo.ErrNotFound
+	}
+
+	s.setAssetCacheControl(c, asset)
+	return c.String(http.StatusOK, base64.StdEncoding.EncodeToString(data))
+}
+
+// reservationResponse names the author holding a global reservation on an emoji name.
+type reservationResponse struct {
+	Name   string `json:"name"`
+	Author string `json:"author"`
+}
+
+func (s *Server) handleNameReservation(c echo.Context) error {
+	name := c.Param("name")
+	owner, err := s.store.NameOwner(c.Request().Context(), s.storedName(name))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-	if asset == nil {
+	if owner == "" {
+		return echo.ErrNotFound
+	}
+	return c.JSON(http.StatusOK, reservationResponse{Name: name, Author: owner})
+}
+
+// handleAdminGet returns an emoji regardless of moderation status or expiry.
+func (s *Server) handleAdminGet(c echo.Context) error {
+	asset, err := s.store.GetAsset(c.Request().Context(), c.Param("author"), s.storedName(c.Param("name")))
+	if errors.Is(err, storage.ErrNotFound) {
 		return echo.ErrNotFound
 	}
-	return c.JSON(http.StatusOK, toResponse(*asset, includeData))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := s.toResponse(*asset, parseResponseData(c))
+	resp.ModerationStatus = asset.ModerationStatus
+	resp.PendingConfirmation = asset.PendingConfirmation
+	return c.JSON(http.StatusOK, resp)
+}
+
+// notesResponse lists what the processor changed or dropped when it last stored an emoji.
+type notesResponse struct {
+	Author string   `json:"author"`
+	Name   string   `json:"name"`
+	Notes  []string `json:"notes"`
+}
+
+func (s *Server) handleIngestNotes(c echo.Context) error {
+	author, name := c.Param("author"), c.Param("name")
+	notes, err := s.store.IngestNotes(c.Request().Context(), author, s.storedName(name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, notesResponse{Author: author, Name: name, Notes: notes})
+}
+
+// ingestResponse reports the ingest loop's progress. Timestamps are omitted until the event
+// they describe has happened.
+type ingestResponse struct {
+	CurrentBlock        int64      `json:"current_block"`
+	LastProcessedBlock  int64      `json:"last_processed_block"`
+	LastProcessedAt     *time.Time `json:"last_processed_at,omitempty"`
+	HeadBlock           int64      `json:"head_block"`
+	HeadCheckedAt       *time.Time `json:"head_checked_at,omitempty"`
+	Lag                 int64      `json:"lag"`
+	CatchingUp          bool       `json:"catching_up"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+}
+
+func (s *Server) handleIngestState(c echo.Context) error {
+	snap := s.opts.Ingest.Snapshot()
+	resp := ingestResponse{
+		CurrentBlock:        snap.CurrentBlock,
+		LastProcessedBlock:  snap.LastProcessed,
+		LastProcessedAt:     timeOrNil(snap.LastProcessedAt),
+		HeadBlock:           snap.HeadBlock,
+		HeadCheckedAt:       timeOrNil(snap.HeadCheckedAt),
+		CatchingUp:          snap.CatchingUp(),
+		ConsecutiveFailures: snap.ConsecutiveFailures,
+		LastError:           snap.LastError,
+		LastErrorAt:         timeOrNil(snap.LastErrorAt),
+		StartedAt:           timeOrNil(snap.StartedAt),
+	}
+	if snap.HeadBlock > snap.LastProcessed && snap.LastProcessed > 0 {
+		resp.Lag = snap.HeadBlock - snap.LastProcessed
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (s *Server) handleRandom(c echo.Context) error {
+	asset, err := s.store.RandomAsset(c.Request().Context(), strings.TrimSpace(c.QueryParam("author")))
+	if errors.Is(err, storage.ErrNotFound) {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	if !isTruthy(c.QueryParam("raw")) {
+		return c.JSON(http.StatusOK, s.toResponse(*asset, parseResponseData(c)))
+	}
+
+	mime, ok := storage.NormalizeEmojiMime(asset.Mime)
+	if !ok || len(asset.Data) == 0 {
+		return echo.ErrNotFound
+	}
+	return c.Blob(http.StatusOK, mime, asset.Data)
 }
 
 func (s *Server) handleGetImage(c echo.Context) error {
+	err := s.serveImage(c)
+	if s.opts.Metrics != nil && (err == nil || errors.Is(err, echo.ErrNotFound)) {
+		s.opts.Metrics.ObserveImage(err == nil)
+	}
+	if errors.Is(err, echo.ErrNotFound) && s.opts.Placeholder != nil && isTruthy(c.QueryParam("default")) {
+		return s.servePlaceholder(c)
+	}
+	return err
+}
+
+func (s *Server) serveImage(c echo.Context) error {
 	rawAuthor := c.Param("author")
 	rawName := c.Param("name")
 	if strings.TrimSpace(rawAuthor) == "" || strings.TrimSpace(rawName) == "" {
@@ -155,12 +1309,16 @@ func (s *Server) handleGetImage(c echo.Context) error {
 			return echo.ErrNotFound
 		}
 	}
+	return s.writeImage(c, author, name)
+}
 
-	asset, err := s.store.GetAsset(c.Request().Context(), author, name)
+// writeImage serves the image bytes of a public emoji, honouring ?format= and caching headers.
+func (s *Server) writeImage(c echo.Context, author, name string) error {
+	asset, err := s.publicAsset(c, author, name)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	if asset == nil || len(asset.Data) == 0 {
+	if len(asset.Data) == 0 {
 		return echo.ErrNotFound
 	}
 
@@ -169,13 +1327,104 @@ func (s *Server) handleGetImage(c echo.Context) error {
 		return echo.ErrNotFound
 	}
 
+	data, etagSuffix := asset.Data, ""
+	if asset.FallbackMime != nil {
+		c.Response().Header().Add("Vary", "Accept")
+	}
+	switch format := c.QueryParam("format"); format {
+	case "":
+		if fallback, fallbackMime, ok := acceptedFallback(c.Request().Header.Get("Accept"), asset, mime); ok {
+			data, mime, etagSuffix = fallback, fallbackMime, "-fallback"
+		}
+	case "apng":
+		release, err := s.acquireTranscode(c)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(asset.Data)
+		converted, err := s.apng.APNG(hex.EncodeToString(sum[:]), asset.Data, mime)
+		release()
+		switch {
+		case err == nil:
+			data, mime, etagSuffix = converted, "image/png", "-apng"
+		case errors.Is(err, transcode.ErrStatic):
+			// Static images and PNGs render everywhere APNG does; serve them as stored.
+		default:
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "cannot convert to apng: "+err.Error())
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown format %q (want apng)", format))
+	}
+
 	// Set cache headers for Cloudflare and browsers
-	c.Response().Header().Set("Cache-Control", "public, max-age=604800")
+	s.setAssetCacheControl(c, asset)
 	if asset.Checksum != nil && *asset.Checksum != "" {
-		c.Response().Header().Set("ETag", `"`+*asset.Checksum+`"`)
+		c.Response().Header().Set("ETag", `"`+*asset.Checksum+etagSuffix+`"`)
 	}
 
-	return c.Blob(http.StatusOK, mime, asset.Data)
+	s.fetches.add(storage.EmojiRef{Author: author, Name: asset.Name}, 1)
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.Itoa(len(data)))
+	return c.Blob(http.StatusOK, mime, data)
+}
+
+// stableMaxAge is the max-age, in seconds, of emojis that are no longer expected to change.
+const stableMaxAge = 7 * 24 * 60 * 60
+
+// setAssetCacheControl picks a max-age from how long ago the emoji last changed; see
+// Options.CacheStableAfter.
+func (s *Server) setAssetCacheControl(c echo.Context, asset *storage.Asset) {
+	maxAge := stableMaxAge
+	if s.opts.CacheStableAfter > 0 && !asset.UpdatedAt.IsZero() && time.Since(asset.UpdatedAt) < s.opts.CacheStableAfter {
+		maxAge = int(s.opts.CacheRecentMaxAge / time.Second)
+	}
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+}
+
+func (s *Server) servePlaceholder(c echo.Context) error {
+	p := s.opts.Placeholder
+	c.Response().Header().Set("Cache-Control", "public, max-age=60")
+	return c.Blob(p.Status, p.Mime, p.Data)
+}
+
+func isTruthy(v string) bool {
+	return v == "1" || strings.EqualFold(v, "true")
+}
+
+// isValidChecksum reports whether value looks like a hex-encoded sha256 digest.
+func isValidChecksum(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// publicAsset loads an emoji for public endpoints, hiding assets that are not approved or have expired.
+func (s *Server) publicAsset(c echo.Context, author, name string) (*storage.Asset, error) {
+	asset, err := s.store.GetAsset(c.Request().Context(), author, s.storedName(name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, echo.ErrNotFound
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if asset.ModerationStatus != "" && asset.ModerationStatus != moderation.StatusApproved {
+		return nil, echo.ErrNotFound
+	}
+	if asset.Expired(time.Now()) || asset.PendingConfirmation {
+		return nil, echo.ErrNotFound
+	}
+	return asset, nil
+}
+
+// storedName maps a name used in routes and queries to the name stored under NamePrefix.
+func (s *Server) storedName(name string) string {
+	return s.opts.NamePrefix + name
+}
+
+// publicName strips NamePrefix from a stored name for responses.
+func (s *Server) publicName(name string) string {
+	return strings.TrimPrefix(name, s.opts.NamePrefix)
 }
 
 func trimAtPrefix(raw string) (string, bool) {
@@ -197,40 +1446,129 @@ func trimAtPrefix(raw string) (string, bool) {
 }
 
 type emojiResponse struct {
-	Name         string  `json:"name"`
-	Version      int     `json:"version"`
-	Author       *string `json:"author,omitempty"`
-	UploadID     *string `json:"upload_id,omitempty"`
-	Mime         string  `json:"mime"`
-	Width        *int    `json:"width,omitempty"`
-	Height       *int    `json:"height,omitempty"`
-	Animated     bool    `json:"animated"`
-	Loop         *int    `json:"loop,omitempty"`
-	Checksum     *string `json:"checksum,omitempty"`
-	FallbackMime *string `json:"fallback_mime,omitempty"`
-	Data         string  `json:"data,omitempty"`
-	FallbackData string  `json:"fallback_data,omitempty"`
-}
-
-func toResponse(asset storage.Asset, includeData bool) emojiResponse {
+	Name            string     `json:"name"`
+	Variant         string     `json:"variant,omitempty"`
+	Version         int        `json:"version"`
+	Author          *string    `json:"author,omitempty"`
+	UploadID        *string    `json:"upload_id,omitempty"`
+	Mime            string     `json:"mime"`
+	Width           *int       `json:"width,omitempty"`
+	Height          *int       `json:"height,omitempty"`
+	Animated        bool       `json:"animated"`
+	Loop            *int       `json:"loop,omitempty"`
+	Checksum        *string    `json:"checksum,omitempty"`
+	ContentURL      *string    `json:"content_url,omitempty"`
+	FallbackMime    *string    `json:"fallback_mime,omitempty"`
+	Featured        bool       `json:"featured,omitempty"`
+	Description     *string    `json:"description,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	Cover           bool       `json:"cover,omitempty"`
+	License         *string    `json:"license,omitempty"`
+	Attribution     *string    `json:"attribution,omitempty"`
+	Unofficial      bool       `json:"unofficial,omitempty"`
+	ChunkCount      *int       `json:"chunk_count,omitempty"`
+	RegisteredBlock *int64     `json:"registered_block,omitempty"`
+	Revision        *int       `json:"revision,omitempty"`
+	Historical      bool       `json:"historical,omitempty"`
+	Fetches         *int64     `json:"fetches,omitempty"`
+	Data            string     `json:"data,omitempty"`
+	FallbackData    string     `json:"fallback_data,omitempty"`
+
+	// ModerationStatus and PendingConfirmation are only reported by admin endpoints.
+	ModerationStatus    string `json:"moderation_status,omitempty"`
+	PendingConfirmation bool   `json:"pending_confirmation,omitempty"`
+}
+
+// dataFormat selects how image bytes are embedded in JSON responses.
+type dataFormat int
+
+const (
+	dataNone dataFormat = iota
+	dataBase64
+	dataURI
+)
+
+// parseDataFormat reads the with_data query value: 1/true for plain base64, uri for data: URIs.
+func parseDataFormat(v string) dataFormat {
+	switch {
+	case strings.EqualFold(v, "uri"):
+		return dataURI
+	case isTruthy(v):
+		return dataBase64
+	}
+	return dataNone
+}
+
+// responseData selects which image bytes a JSON response embeds, and how. Fallback bytes are
+// opt-in separately so clients that only render the main image don't download both.
+type responseData struct {
+	main     dataFormat
+	fallback dataFormat
+}
+
+// parseResponseData reads with_data for the main image and with_fallback for the fallback;
+// both accept the values parseDataFormat does.
+func parseResponseData(c echo.Context) responseData {
+	return responseData{
+		main:     parseDataFormat(c.QueryParam("with_data")),
+		fallback: parseDataFormat(c.QueryParam("with_fallback")),
+	}
+}
+
+// any reports whether image bytes must be loaded from storage.
+func (d responseData) any() bool {
+	return d.main != dataNone || d.fallback != dataNone
+}
+
+func (s *Server) toResponse(asset storage.Asset, format responseData) emojiResponse {
 	resp := emojiResponse{
-		Name:         asset.Name,
-		Version:      asset.Version,
-		Author:       asset.Author,
-		UploadID:     asset.UploadID,
-		Mime:         asset.Mime,
-		Width:        asset.Width,
-		Height:       asset.Height,
-		Animated:     asset.Animated,
-		Loop:         asset.Loop,
-		Checksum:     asset.Checksum,
-		FallbackMime: asset.FallbackMime,
-	}
-
-	if includeData {
+		Name:            s.publicName(asset.Name),
+		Variant:         asset.Variant,
+		Version:         asset.Version,
+		Author:          asset.Author,
+		UploadID:        asset.UploadID,
+		Mime:            asset.Mime,
+		Width:           asset.Width,
+		Height:          asset.Height,
+		Animated:        asset.Animated,
+		Loop:            asset.Loop,
+		Checksum:        asset.Checksum,
+		ContentURL:      contentURL(asset),
+		FallbackMime:    asset.FallbackMime,
+		Featured:        asset.Featured,
+		Description:     asset.Description,
+		Tags:            asset.Tags,
+		ExpiresAt:       asset.ExpiresAt,
+		Cover:           asset.Cover,
+		License:         asset.License,
+		Attribution:     asset.Attribution,
+		Unofficial:      asset.Unofficial,
+		ChunkCount:      asset.ChunkCount,
+		RegisteredBlock: asset.RegisteredBlock,
+		Revision:        asset.Revision,
+		Historical:      asset.Historical,
+		Fetches:         asset.Fetches,
+	}
+	if asset.Historical {
+		// Earlier revisions' bytes aren't kept, so their checksum may not resolve.
+		resp.ContentURL = nil
+	}
+
+	switch format.main {
+	case dataBase64:
 		resp.Data = base64.StdEncoding.EncodeToString(asset.Data)
-		if len(asset.FallbackData) > 0 {
+	case dataURI:
+		resp.Data = encoding.EncodeDataURI(asset.Data, asset.Mime)
+	}
+	if len(asset.FallbackData) > 0 {
+		switch format.fallback {
+		case dataBase64:
 			resp.FallbackData = base64.StdEncoding.EncodeToString(asset.FallbackData)
+		case dataURI:
+			if asset.FallbackMime != nil {
+				resp.FallbackData = encoding.EncodeDataURI(asset.FallbackData, *asset.FallbackMime)
+			}
 		}
 	}
 	return resp
@@ -0,0 +1,2725 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/ingest"
+	"hivemoji/internal/metrics"
+	"hivemoji/internal/storage"
+	"hivemoji/internal/transcode"
+)
+
+// fakeStore serves assets from memory for handler tests.
+type fakeStore struct {
+	assets []storage.Asset
+	order  map[string]int
+	err    error
+
+	lastLimit  int
+	pageLimit  int
+	pageOffset int
+
+	idempotent  map[string]storage.IdempotentResponse
+	notes       map[string][]string
+	banned      map[string]string
+	versions    []storage.AssetVersion
+	owners      map[string]string
+	uploads     map[string]*storage.UploadStatus
+	manifests   []storage.Manifest
+	audit       []storage.AuditEntry
+	collections map[string]*fakeCollection
+	fetches     map[storage.EmojiRef]int64
+
+	// onStream, when set, runs before StreamAssets hands over the emoji at index i;
+	// streamErr records what StreamAssets returned.
+	onStream  func(ctx context.Context, i int)
+	streamErr error
+}
+
+// fakeCollection holds a collection's items in the order they were added.
+type fakeCollection struct {
+	storage.Collection
+	items []fakeCollectionItem
+}
+
+type fakeCollectionItem struct {
+	author, name string
+	position     int
+}
+
+func (f *fakeStore) GetAsset(ctx context.Context, author, name string) (*storage.Asset, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for i := range f.assets {
+		a := f.assets[i]
+		if a.Author != nil && *a.Author == author && a.Name == name && a.Variant == "" {
+			return &a, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (f *fakeStore) ListVariants(ctx context.Context, author, name string, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Author != nil && *a.Author == author && a.Name == name {
+			out = append(out, a)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListAssets(ctx context.Context, includeData bool) ([]storage.Asset, error) {
+	return f.assets, f.err
+}
+
+func (f *fakeStore) ListAssetsByAuthor(ctx context.Context, author string, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Author != nil && *a.Author == author {
+			out = append(out, a)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListAssetsByAuthors(ctx context.Context, authors []string, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Author != nil && containsString(authors, *a.Author) {
+			out = append(out, a)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListAssetsPage(ctx context.Context, filter storage.AssetFilter, page storage.Page, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		key := storage.KeyOf(a)
+		if len(filter.Authors) > 0 && !containsString(filter.Authors, key.Author) {
+			continue
+		}
+		if !inBlockRange(a, filter) {
+			continue
+		}
+		if page.After.Name != "" && (key.Name < page.After.Name || key.Name == page.After.Name && key.Author <= page.After.Author) {
+			continue
+		}
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ki, kj := storage.KeyOf(out[i]), storage.KeyOf(out[j])
+		return ki.Name < kj.Name || ki.Name == kj.Name && ki.Author < kj.Author
+	})
+	f.pageLimit = page.Limit
+	if len(out) > page.Limit {
+		out = out[:page.Limit]
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListAssetsPaged(ctx context.Context, includeData bool, limit, offset int) ([]storage.Asset, int64, error) {
+	all, _ := f.ListAssetsPage(ctx, storage.AssetFilter{}, storage.Page{Limit: len(f.assets)}, includeData)
+	f.pageLimit, f.pageOffset = limit, offset
+	out := all[min(offset, len(all)):]
+	return out[:min(limit, len(out))], int64(len(all)), f.err
+}
+
+func (f *fakeStore) AuthorUsage(ctx context.Context, author string) (storage.AuthorUsage, error) {
+	var usage storage.AuthorUsage
+	for _, a := range f.assets {
+		if a.Author == nil || *a.Author != author {
+			continue
+		}
+		usage.Bytes += int64(len(a.Data) + len(a.FallbackData))
+		if a.Variant == "" {
+			usage.Emojis++
+			if a.Animated {
+				usage.Animated++
+			}
+		}
+	}
+	return usage, f.err
+}
+
+func (f *fakeStore) StreamAssets(ctx context.Context, filter storage.AssetFilter, includeData bool, fn func(storage.Asset) error) error {
+	f.streamErr = f.streamAssets(ctx, filter, fn)
+	return f.streamErr
+}
+
+func (f *fakeStore) streamAssets(ctx context.Context, filter storage.AssetFilter, fn func(storage.Asset) error) error {
+	if f.err != nil {
+		return f.err
+	}
+	for i, a := range f.assets {
+		if a.Variant != "" || len(filter.Authors) > 0 && !containsString(filter.Authors, storage.KeyOf(a).Author) {
+			continue
+		}
+		if f.onStream != nil {
+			f.onStream(ctx, i)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) ListAssetHistory(ctx context.Context, filter storage.AssetFilter) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		key := storage.KeyOf(a)
+		if a.Variant != "" || len(filter.Authors) > 0 && !containsString(filter.Authors, key.Author) {
+			continue
+		}
+		versions, _ := f.ListAssetVersions(ctx, key.Author, a.Name)
+		if len(versions) > 0 {
+			latest := versions[len(versions)-1].Revision
+			a.Revision = &latest
+		}
+		out = append(out, a)
+		for i := len(versions) - 2; i >= 0; i-- {
+			v := versions[i]
+			revision, checksum := v.Revision, v.Checksum
+			out = append(out, storage.Asset{Name: v.Name, Author: strPtr(v.Author), Version: v.Version, Mime: v.Mime, Checksum: &checksum, Revision: &revision, Historical: true})
+		}
+	}
+	return out, f.err
+}
+
+func inBlockRange(a storage.Asset, filter storage.AssetFilter) bool {
+	if filter.FromBlock == 0 && filter.ToBlock == 0 {
+		return true
+	}
+	return a.RegisteredBlock != nil && *a.RegisteredBlock >= filter.FromBlock && *a.RegisteredBlock <= filter.ToBlock
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeStore) GetAssetsByChecksum(ctx context.Context, checksum string, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Checksum != nil && *a.Checksum == checksum {
+			out = append(out, a)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) GetAuthorLastModified(ctx context.Context, author string) (time.Time, error) {
+	return time.Time{}, f.err
+}
+
+func (f *fakeStore) ListFeatured(ctx context.Context, includeData bool) ([]storage.Asset, error) {
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Featured {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return f.order[out[i].Name] < f.order[out[j].Name] })
+	return out, f.err
+}
+
+// RandomAsset deterministically returns the first asset matching author.
+func (f *fakeStore) RandomAsset(ctx context.Context, author string) (*storage.Asset, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for i := range f.assets {
+		a := f.assets[i]
+		if author == "" || (a.Author != nil && *a.Author == author) {
+			return &a, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (f *fakeStore) SetFeatured(ctx context.Context, author, name string, featured bool, order int) error {
+	for i := range f.assets {
+		a := &f.assets[i]
+		if a.Author != nil && *a.Author == author && a.Name == name {
+			a.Featured = featured
+			if f.order == nil {
+				f.order = map[string]int{}
+			}
+			f.order[name] = order
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (f *fakeStore) UpdateAssetMetadata(ctx context.Context, author, name string, update storage.AssetMetadataUpdate) error {
+	for i := range f.assets {
+		a := &f.assets[i]
+		if a.Author == nil || *a.Author != author || a.Name != name {
+			continue
+		}
+		if update.Mime != nil {
+			a.Mime = *update.Mime
+		}
+		if update.Width != nil {
+			a.Width = update.Width
+		}
+		if update.Height != nil {
+			a.Height = update.Height
+		}
+		if update.Description != nil {
+			a.Description = update.Description
+		}
+		if update.Tags != nil {
+			a.Tags = *update.Tags
+		}
+		return nil
+	}
+	return storage.ErrNotFound
+}
+
+func (f *fakeStore) SetModerationStatus(ctx context.Context, author, name, status string) error {
+	for i := range f.assets {
+		a := &f.assets[i]
+		if a.Author != nil && *a.Author == author && a.Name == name {
+			a.ModerationStatus = status
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (f *fakeStore) AutocompleteNames(ctx context.Context, prefix string, limit int) ([]storage.Suggestion, error) {
+	f.lastLimit = limit
+	var out []storage.Suggestion
+	for _, a := range f.assets {
+		if strings.HasPrefix(strings.ToLower(a.Name), strings.ToLower(prefix)) && len(out) < limit {
+			out = append(out, storage.Suggestion{Name: a.Name, Author: *a.Author, Mime: a.Mime})
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) SearchAssets(ctx context.Context, query, sortBy string, limit int, includeData bool) ([]storage.Asset, error) {
+	f.lastLimit = limit
+	query = strings.ToLower(query)
+	var out []storage.Asset
+	for _, a := range f.assets {
+		if a.Variant != "" || !(strings.Contains(strings.ToLower(a.Name), query) || containsString(a.Tags, query)) {
+			continue
+		}
+		n := f.fetches[storage.EmojiRef{Author: *a.Author, Name: a.Name}]
+		a.Fetches = &n
+		out = append(out, a)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if sortBy == storage.SearchSortPopular && *out[i].Fetches != *out[j].Fetches {
+			return *out[i].Fetches > *out[j].Fetches
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) AddFetchCounts(ctx context.Context, counts map[storage.EmojiRef]int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.fetches == nil {
+		f.fetches = make(map[storage.EmojiRef]int64)
+	}
+	for ref, n := range counts {
+		f.fetches[ref] += n
+	}
+	return nil
+}
+
+func (f *fakeStore) Count(ctx context.Context, filter storage.AssetFilter) (storage.AssetCount, error) {
+	var count storage.AssetCount
+	for _, a := range f.assets {
+		if filter.Author != "" && (a.Author == nil || *a.Author != filter.Author) {
+			continue
+		}
+		if len(filter.Authors) > 0 && (a.Author == nil || !containsString(filter.Authors, *a.Author)) {
+			continue
+		}
+		if !inBlockRange(a, filter) {
+			continue
+		}
+		count.Total++
+	}
+	if count.Total > 0 {
+		count.LastModified = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return count, f.err
+}
+
+func (f *fakeStore) ListAssetVersions(ctx context.Context, author, name string) ([]storage.AssetVersion, error) {
+	var out []storage.AssetVersion
+	for _, v := range f.versions {
+		if v.Author == author && v.Name == name && v.Variant == "" {
+			out = append(out, v)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListAssetVersionsPage(ctx context.Context, author, name string, q storage.VersionQuery) ([]storage.AssetVersion, int, error) {
+	all, err := f.ListAssetVersions(ctx, author, name)
+	current := 0
+	var out []storage.AssetVersion
+	for i := len(all) - 1; i >= 0; i-- {
+		v := all[i]
+		current = max(current, v.Revision)
+		if (q.Before > 0 && v.Revision >= q.Before) || (!q.Since.IsZero() && v.CreatedAt.Before(q.Since)) || (!q.Until.IsZero() && !v.CreatedAt.Before(q.Until)) {
+			continue
+		}
+		if q.Limit == 0 || len(out) < q.Limit {
+			out = append(out, v)
+		}
+	}
+	return out, current, err
+}
+
+func (f *fakeStore) GetUploadStatus(ctx context.Context, uploadID, kind string) (*storage.UploadStatus, error) {
+	status, ok := f.uploads[uploadID+"/"+kind]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return status, f.err
+}
+
+func (f *fakeStore) ListPendingManifests(ctx context.Context, limit int) ([]storage.Manifest, error) {
+	f.lastLimit = limit
+	if len(f.manifests) > limit {
+		return f.manifests[:limit], f.err
+	}
+	return f.manifests, f.err
+}
+
+func (f *fakeStore) NameOwner(ctx context.Context, name string) (string, error) {
+	return f.owners[name], f.err
+}
+
+func (f *fakeStore) AuthorsOfName(ctx context.Context, name string) ([]string, error) {
+	var matches []storage.Asset
+	for _, a := range f.assets {
+		if a.Name == name && a.Variant == "" && a.Author != nil {
+			matches = append(matches, a)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].UpdatedAt.Equal(matches[j].UpdatedAt) {
+			return matches[i].UpdatedAt.After(matches[j].UpdatedAt)
+		}
+		return *matches[i].Author < *matches[j].Author
+	})
+	var out []string
+	for _, a := range matches {
+		out = append(out, *a.Author)
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) ListBannedChecksums(ctx context.Context) ([]storage.BannedChecksum, error) {
+	out := []storage.BannedChecksum{}
+	for checksum, reason := range f.banned {
+		out = append(out, storage.BannedChecksum{Checksum: checksum, Reason: reason})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Checksum < out[j].Checksum })
+	return out, f.err
+}
+
+func (f *fakeStore) GetBackfillStatus(ctx context.Context) (storage.BackfillStatus, error) {
+	status := storage.BackfillStatus{Done: true}
+	for _, a := range f.assets {
+		if a.Checksum == nil {
+			status.Remaining++
+		}
+	}
+	return status, f.err
+}
+
+func (f *fakeStore) ListAuditLog(ctx context.Context, limit int) ([]storage.AuditEntry, error) {
+	f.lastLimit = limit
+	if len(f.audit) > limit {
+		return f.audit[:limit], f.err
+	}
+	return f.audit, f.err
+}
+
+func (f *fakeStore) ListNameAudit(ctx context.Context, name string) ([]storage.AuditEntry, error) {
+	var out []storage.AuditEntry
+	for _, e := range f.audit {
+		if e.Name == name {
+			out = append(out, e)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeStore) UpsertCollection(ctx context.Context, c storage.Collection) error {
+	if f.collections == nil {
+		f.collections = map[string]*fakeCollection{}
+	}
+	if existing, ok := f.collections[c.Slug]; ok {
+		existing.Title, existing.Description = c.Title, c.Description
+		return f.err
+	}
+	f.collections[c.Slug] = &fakeCollection{Collection: c}
+	return f.err
+}
+
+func (f *fakeStore) DeleteCollection(ctx context.Context, slug string) error {
+	if _, ok := f.collections[slug]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(f.collections, slug)
+	return f.err
+}
+
+func (f *fakeStore) AddCollectionItem(ctx context.Context, slug, author, name string, position int) error {
+	c, ok := f.collections[slug]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if _, err := f.GetAsset(ctx, author, name); err != nil {
+		return err
+	}
+	for i := range c.items {
+		if c.items[i].author == author && c.items[i].name == name {
+			c.items[i].position = position
+			return f.err
+		}
+	}
+	c.items = append(c.items, fakeCollectionItem{author: author, name: name, position: position})
+	return f.err
+}
+
+func (f *fakeStore) RemoveCollectionItem(ctx context.Context, slug, author, name string) error {
+	c, ok := f.collections[slug]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	for i, item := range c.items {
+		if item.author == author && item.name == name {
+			c.items = append(c.items[:i], c.items[i+1:]...)
+			return f.err
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (f *fakeStore) GetCollection(ctx context.Context, slug string, includeData bool) (*storage.Collection, []storage.Asset, error) {
+	c, ok := f.collections[slug]
+	if !ok {
+		return nil, nil, storage.ErrNotFound
+	}
+	items := append([]fakeCollectionItem(nil), c.items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].position < items[j].position })
+	var assets []storage.Asset
+	for _, item := range items {
+		if a, err := f.GetAsset(ctx, item.author, item.name); err == nil {
+			assets = append(assets, *a)
+		}
+	}
+	collection := c.Collection
+	return &collection, assets, f.err
+}
+
+func (f *fakeStore) BanChecksum(ctx context.Context, checksum, reason string) error {
+	if f.banned == nil {
+		f.banned = make(map[string]string)
+	}
+	f.banned[checksum] = reason
+	return f.err
+}
+
+func (f *fakeStore) UnbanChecksum(ctx context.Context, checksum string) error {
+	if _, ok := f.banned[checksum]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(f.banned, checksum)
+	return f.err
+}
+
+func (f *fakeStore) IngestNotes(ctx context.Context, author, name string) ([]string, error) {
+	for _, a := range f.assets {
+		if a.Author != nil && *a.Author == author && a.Name == name {
+			notes := f.notes[author+"/"+name]
+			if notes == nil {
+				notes = []string{}
+			}
+			return notes, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (f *fakeStore) GetIdempotentResponse(ctx context.Context, key string, ttl time.Duration) (*storage.IdempotentResponse, error) {
+	resp, ok := f.idempotent[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &resp, nil
+}
+
+func (f *fakeStore) SaveIdempotentResponse(ctx context.Context, resp storage.IdempotentResponse) error {
+	if f.idempotent == nil {
+		f.idempotent = make(map[string]storage.IdempotentResponse)
+	}
+	f.idempotent[resp.Key] = resp
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+
+const testAdminToken = "secret"
+
+// serve runs a request against a Server backed by store.
+func serve(store *fakeStore, method, target string) *httptest.ResponseRecorder {
+	return serveRequest(store, Options{AdminToken: testAdminToken}, httptest.NewRequest(method, target, nil))
+}
+
+// serveRequest runs req against a Server backed by store with the given options.
+func serveRequest(store *fakeStore, opts Options, req *http.Request) *httptest.ResponseRecorder {
+	e := echo.New()
+	srv := &Server{store: store, opts: opts}
+	srv.Register(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// adminRequest builds a JSON request carrying the test admin token.
+func adminRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+func TestProtocol(t *testing.T) {
+	rec := serveRequest(&fakeStore{}, Options{CustomJSONID: "hivemoji-test"}, httptest.NewRequest(http.MethodGet, "/api/protocol", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp protocolResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.CustomJSONID != "hivemoji-test" {
+		t.Fatalf("expected configured custom_json id, got %q", resp.CustomJSONID)
+	}
+	if len(resp.Versions) != 2 || resp.Versions[0].Version != 1 || resp.Versions[1].Version != 2 {
+		t.Fatalf("unexpected versions %+v", resp.Versions)
+	}
+	if got := strings.Join(resp.Versions[0].Ops, ","); got != "register,delete,reserve,transfer" {
+		t.Fatalf("unexpected v1 ops %q", got)
+	}
+
+	rec = serve(&fakeStore{}, http.MethodGet, "/api/protocol")
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.CustomJSONID != "hivemoji" {
+		t.Fatalf("expected default custom_json id, got %q (err %v)", resp.CustomJSONID, err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	capabilities := func(opts Options) capabilitiesResponse {
+		t.Helper()
+		rec := serveRequest(&fakeStore{}, opts, httptest.NewRequest(http.MethodGet, "/api/capabilities", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var resp capabilitiesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	plain := capabilities(Options{})
+	if plain.Protocol.CustomJSONID != "hivemoji" || len(plain.Protocol.Versions) != 2 {
+		t.Fatalf("unexpected protocol %+v", plain.Protocol)
+	}
+	if !reflect.DeepEqual(plain.Formats, []string{"apng"}) || !reflect.DeepEqual(plain.RawParams, []string{"format"}) {
+		t.Fatalf("unexpected formats %v / raw params %v", plain.Formats, plain.RawParams)
+	}
+	if plain.Features.Admin || plain.Features.SignedURLs || plain.Features.Placeholder || plain.Features.ReadOnly {
+		t.Fatalf("expected optional features off, got %+v", plain.Features)
+	}
+	if plain.Limits.MaxChunks != storage.DefaultMaxChunks || plain.Limits.MaxImageBytes != 0 {
+		t.Fatalf("expected default limits, got %+v", plain.Limits)
+	}
+
+	configured := capabilities(Options{
+		AdminToken:    testAdminToken,
+		CustomJSONID:  "hivemoji-test",
+		URLSigningKey: []byte("key"),
+		Placeholder:   &Placeholder{Data: []byte("x"), Mime: "image/png", Status: http.StatusOK},
+		MaxImageBytes: 1 << 20,
+		MaxChunks:     50,
+	})
+	if configured.Protocol.CustomJSONID != "hivemoji-test" {
+		t.Fatalf("expected configured custom_json id, got %q", configured.Protocol.CustomJSONID)
+	}
+	if want := []string{"format", "default", "exp", "sig"}; !reflect.DeepEqual(configured.RawParams, want) {
+		t.Fatalf("raw params: got %v, want %v", configured.RawParams, want)
+	}
+	if f := configured.Features; !f.Admin || !f.SignedURLs || !f.Placeholder {
+		t.Fatalf("expected configured features on, got %+v", f)
+	}
+	if l := configured.Limits; l.MaxImageBytes != 1<<20 || l.MaxChunks != 50 {
+		t.Fatalf("expected configured limits, got %+v", l)
+	}
+
+	readOnly := capabilities(Options{AdminToken: testAdminToken, ReadOnly: true})
+	if !readOnly.Features.ReadOnly || readOnly.Features.Admin {
+		t.Fatalf("expected read-only without admin, got %+v", readOnly.Features)
+	}
+}
+
+func TestGetRawByName(t *testing.T) {
+	now := time.Now()
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("alice"), Mime: "image/png", Data: []byte("alice-png"), UpdatedAt: now},
+		{Name: "smile", Author: strPtr("alice"), Mime: "image/png", Data: []byte("alice-old"), UpdatedAt: now.Add(-time.Hour)},
+		{Name: "smile", Author: strPtr("bob"), Mime: "image/gif", Data: []byte("bob-gif"), UpdatedAt: now},
+		{Name: "smile", Author: strPtr("carol"), Mime: "image/png", Data: []byte("carol-png"), UpdatedAt: now},
+	}}
+
+	t.Run("single author", func(t *testing.T) {
+		rec := serve(store, http.MethodGet, "/api/emojis/wave/raw")
+		if rec.Code != http.StatusOK || rec.Body.String() != "alice-png" || rec.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("expected alice's image, got %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+		}
+	})
+
+	t.Run("several authors", func(t *testing.T) {
+		rec := serve(store, http.MethodGet, "/api/emojis/smile/raw")
+		if rec.Code != http.StatusMultipleChoices {
+			t.Fatalf("expected 300, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp nameChoicesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		want := []nameChoice{
+			{Author: "bob", URL: "/@bob/@smile"},
+			{Author: "carol", URL: "/@carol/@smile"},
+			{Author: "alice", URL: "/@alice/@smile"},
+		}
+		if resp.Name != "smile" || !reflect.DeepEqual(resp.Choices, want) {
+			t.Fatalf("expected choices newest first, got %+v", resp)
+		}
+		if loc := rec.Header().Get("Location"); loc != "/@bob/@smile" {
+			t.Fatalf("expected Location of the latest emoji, got %q", loc)
+		}
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		if rec := serve(store, http.MethodGet, "/api/emojis/missing/raw"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestGetByAuthor_NotFound(t *testing.T) {
+	rec := serve(&fakeStore{}, http.MethodGet, "/api/authors/mrtats/emojis/missing")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestGetByAuthor_StoreFailure(t *testing.T) {
+	rec := serve(&fakeStore{err: errors.New("db down")}, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestGetByAuthor_Found(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 1, Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestGetByAuthor_LicenseAndAttribution(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 1, Author: strPtr("mrtats"), Mime: "image/png", License: strPtr("CC-BY-4.0"), Attribution: strPtr("drawn by @alice")},
+		{Name: "plain", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+	}}
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.License == nil || *resp.License != "CC-BY-4.0" || resp.Attribution == nil || *resp.Attribution != "drawn by @alice" {
+		t.Fatalf("unexpected credit: %s", rec.Body.String())
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/plain")
+	if strings.Contains(rec.Body.String(), "license") || strings.Contains(rec.Body.String(), "attribution") {
+		t.Fatalf("expected credit fields to be omitted, got %s", rec.Body.String())
+	}
+}
+
+func TestGetByAuthor_DataFormats(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 1, Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+
+	cases := map[string]string{
+		"":          "",
+		"1":         "cG5n",
+		"uri":       "data:image/png;base64,cG5n",
+		"something": "",
+	}
+	for withData, want := range cases {
+		rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave?with_data="+withData)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("with_data=%q: expected 200, got %d", withData, rec.Code)
+		}
+		var resp emojiResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Data != want {
+			t.Fatalf("with_data=%q: expected data %q, got %q", withData, want, resp.Data)
+		}
+	}
+}
+
+func TestGetImage_NotFound(t *testing.T) {
+	rec := serve(&fakeStore{}, http.MethodGet, "/@mrtats/@missing")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestGetImage_AuthorRawRoute(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp", Data: []byte("webp-bytes"), FallbackMime: strPtr("image/gif"), FallbackData: []byte("gif")},
+		{Name: "plain", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+	get := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return serveRequest(store, Options{}, req)
+	}
+
+	tests := []struct {
+		accept   string
+		wantMime string
+		wantBody string
+	}{
+		{"", "image/webp", "webp-bytes"},
+		{"image/webp,image/*;q=0.8", "image/webp", "webp-bytes"},
+		{"image/gif", "image/gif", "gif"},
+		{"image/webp;q=0, image/gif", "image/gif", "gif"},
+		{"image/webp;q=0, image/*", "image/gif", "gif"},
+		{"image/gif, image/*;q=0.5", "image/gif", "gif"},
+		{"image/gif;q=0.5, image/webp", "image/webp", "webp-bytes"},
+		{"image/*", "image/webp", "webp-bytes"},
+		{"image/png", "image/webp", "webp-bytes"},
+	}
+	for _, tc := range tests {
+		rec := get("/api/authors/mrtats/emojis/wave/raw", tc.accept)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Accept %q: expected 200, got %d", tc.accept, rec.Code)
+		}
+		if ct := rec.Header().Get(echo.HeaderContentType); ct != tc.wantMime {
+			t.Fatalf("Accept %q: expected %s, got %q", tc.accept, tc.wantMime, ct)
+		}
+		if rec.Body.String() != tc.wantBody {
+			t.Fatalf("Accept %q: expected body %q, got %q", tc.accept, tc.wantBody, rec.Body.String())
+		}
+		if cl := rec.Header().Get(echo.HeaderContentLength); cl != strconv.Itoa(len(tc.wantBody)) {
+			t.Fatalf("Accept %q: expected Content-Length %d, got %q", tc.accept, len(tc.wantBody), cl)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Fatalf("Accept %q: expected Vary: Accept, got %q", tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+
+	rec := get("/api/authors/mrtats/emojis/plain/raw", "image/gif")
+	if rec.Code != http.StatusOK || rec.Body.String() != "png" || rec.Header().Get("Vary") != "" {
+		t.Fatalf("expected the main image without fallback negotiation, got %d %q vary=%q", rec.Code, rec.Body.String(), rec.Header().Get("Vary"))
+	}
+	if rec := get("/api/authors/mrtats/emojis/missing/raw", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing emoji, got %d", rec.Code)
+	}
+}
+
+func TestSetFeatured_RequiresAdminToken(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats")}}}
+	req := httptest.NewRequest(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/featured", strings.NewReader(`{"featured":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := serveRequest(store, Options{AdminToken: testAdminToken}, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	if store.assets[0].Featured {
+		t.Fatalf("expected emoji to remain unfeatured")
+	}
+}
+
+func TestFeatured_SetAndList(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats")},
+		{Name: "smile", Author: strPtr("mrtats")},
+		{Name: "frown", Author: strPtr("mrtats")},
+	}}
+
+	for _, tc := range []struct {
+		name  string
+		order int
+	}{{"wave", 2}, {"smile", 1}} {
+		body := fmt.Sprintf(`{"featured":true,"order":%d}`, tc.order)
+		rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodPut, "/api/admin/authors/mrtats/emojis/"+tc.name+"/featured", body))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("feature %s: expected 204, got %d", tc.name, rec.Code)
+		}
+	}
+
+	rec := serve(store, http.MethodGet, "/api/emojis/featured")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 2 || resp[0].Name != "smile" || resp[1].Name != "wave" {
+		t.Fatalf("unexpected featured list: %+v", resp)
+	}
+}
+
+func TestSetFeatured_UnknownEmoji(t *testing.T) {
+	rec := serveRequest(&fakeStore{}, Options{AdminToken: testAdminToken}, adminRequest(http.MethodPut, "/api/admin/authors/mrtats/emojis/missing/featured", `{"featured":true}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPatchMetadata_AppliesSparseFields(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+
+	body := `{"mime":"IMAGE/WEBP","width":64,"tags":["Party","party"," hi "]}`
+	rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodPatch, "/api/admin/authors/mrtats/emojis/wave", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	got := store.assets[0]
+	if got.Mime != "image/webp" {
+		t.Fatalf("expected normalized mime, got %q", got.Mime)
+	}
+	if got.Width == nil || *got.Width != 64 {
+		t.Fatalf("expected width 64, got %v", got.Width)
+	}
+	if got.Height != nil {
+		t.Fatalf("expected height untouched, got %v", *got.Height)
+	}
+	if strings.Join(got.Tags, ",") != "party,hi" {
+		t.Fatalf("unexpected tags %v", got.Tags)
+	}
+}
+
+func TestPatchMetadata_Rejects(t *testing.T) {
+	cases := map[string]string{
+		"data":          `{"data":"dGVzdA=="}`,
+		"bad mime":      `{"mime":"image/svg+xml"}`,
+		"zero width":    `{"width":0}`,
+		"unknown field": `{"name":"renamed"}`,
+		"empty":         `{}`,
+		"bad tag":       `{"tags":["<script>"]}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"}}}
+			rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodPatch, "/api/admin/authors/mrtats/emojis/wave", body))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", rec.Code)
+			}
+			if store.assets[0].Mime != "image/png" {
+				t.Fatalf("expected asset unchanged")
+			}
+		})
+	}
+}
+
+func TestGetByAuthor_HidesUnapproved(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), ModerationStatus: "blocked"},
+	}}
+	for _, target := range []string{"/api/authors/mrtats/emojis/wave", "/@mrtats/@wave"} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for blocked emoji, got %d", target, rec.Code)
+		}
+	}
+
+	rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/moderation", `{"status":"approved"}`))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := serve(store, http.MethodGet, "/@mrtats/@wave"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after approval, got %d", rec.Code)
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "party_parrot", Author: strPtr("mrtats"), Mime: "image/gif"},
+		{Name: "Party_Hat", Author: strPtr("alice"), Mime: "image/png"},
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/emojis/autocomplete?prefix=PAR&limit=500")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp []suggestionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", resp)
+	}
+	if store.lastLimit != maxAutocompleteLimit {
+		t.Fatalf("expected limit clamped to %d, got %d", maxAutocompleteLimit, store.lastLimit)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/emojis/autocomplete"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without prefix, got %d", rec.Code)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	store := &fakeStore{
+		assets: []storage.Asset{
+			{Name: "cat_wave", Author: strPtr("alice"), Mime: "image/png"},
+			{Name: "catjam", Author: strPtr("bob"), Mime: "image/gif"},
+			{Name: "smile", Author: strPtr("carol"), Mime: "image/png", Tags: []string{"cat"}},
+			{Name: "dog", Author: strPtr("dave"), Mime: "image/png"},
+		},
+		fetches: map[storage.EmojiRef]int64{
+			{Author: "bob", Name: "catjam"}:  40,
+			{Author: "carol", Name: "smile"}: 7,
+			{Author: "dave", Name: "dog"}:    100,
+		},
+	}
+
+	search := func(target string) []emojiResponse {
+		t.Helper()
+		rec := serve(store, http.MethodGet, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var resp []emojiResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+	names := func(resp []emojiResponse) []string {
+		var out []string
+		for _, e := range resp {
+			out = append(out, e.Name)
+		}
+		return out
+	}
+
+	if got, want := names(search("/api/emojis/search?q=CAT")), []string{"cat_wave", "catjam", "smile"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("name order: got %v, want %v", got, want)
+	}
+	popular := search("/api/emojis/search?q=cat&sort=popular")
+	if got, want := names(popular), []string{"catjam", "smile", "cat_wave"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("popular order: got %v, want %v", got, want)
+	}
+	if popular[0].Fetches == nil || *popular[0].Fetches != 40 {
+		t.Fatalf("expected catjam to report 40 fetches, got %v", popular[0].Fetches)
+	}
+
+	search("/api/emojis/search?q=cat&limit=500")
+	if store.lastLimit != maxSearchLimit {
+		t.Fatalf("expected limit clamped to %d, got %d", maxSearchLimit, store.lastLimit)
+	}
+
+	for _, target := range []string{
+		"/api/emojis/search",
+		"/api/emojis/search?q=cat&sort=endorsed",
+		"/api/emojis/search?q=cat&limit=0",
+		"/api/emojis/search?q=" + strings.Repeat("a", maxSearchQuery+1),
+	} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestFlushFetchCounts(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("alice"), Mime: "image/png", Data: []byte("\x89PNG\r\n\x1a\n")},
+	}}
+	srv := &Server{store: store, fetches: &fetchCounter{}}
+	e := echo.New()
+	srv.Register(e)
+	for _, target := range []string{"/@alice/@wave", "/@alice/@wave", "/@alice/@missing"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	wave := storage.EmojiRef{Author: "alice", Name: "wave"}
+	store.err = errors.New("db down")
+	if err := srv.FlushFetchCounts(context.Background()); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	store.err = nil
+	if err := srv.FlushFetchCounts(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if got := store.fetches; len(got) != 1 || got[wave] != 2 {
+		t.Fatalf("expected 2 fetches of alice/wave kept across the failed flush, got %v", got)
+	}
+}
+
+func TestGetImage_Placeholder(t *testing.T) {
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	path := filepath.Join(t.TempDir(), "placeholder.png")
+	if err := os.WriteFile(path, pngHeader, 0o600); err != nil {
+		t.Fatalf("write placeholder: %v", err)
+	}
+	placeholder, err := LoadPlaceholder(path, http.StatusNotFound)
+	if err != nil {
+		t.Fatalf("LoadPlaceholder error: %v", err)
+	}
+	opts := Options{Placeholder: placeholder}
+
+	rec := serveRequest(&fakeStore{}, opts, httptest.NewRequest(http.MethodGet, "/@mrtats/@missing?default=1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); ct != "image/png" {
+		t.Fatalf("expected image/png placeholder, got %q", ct)
+	}
+	if rec.Body.String() != string(pngHeader) {
+		t.Fatalf("expected placeholder body")
+	}
+
+	rec = serveRequest(&fakeStore{}, opts, httptest.NewRequest(http.MethodGet, "/@mrtats/@missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get(echo.HeaderContentType) == "image/png" {
+		t.Fatalf("expected plain 404 without ?default=1, got %d %q", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+}
+
+func TestGetImage_SignedURL(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png-bytes")},
+	}}
+	key := []byte("test-signing-key")
+	opts := Options{URLSigningKey: key}
+	get := func(target string) *httptest.ResponseRecorder {
+		return serveRequest(store, opts, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	signed := SignRawURL(key, "/@mrtats/@wave", time.Now().Add(time.Hour))
+	rec := get(signed)
+	if rec.Code != http.StatusOK || rec.Body.String() != "png-bytes" {
+		t.Fatalf("expected signed URL to serve the image, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private, max-age=") {
+		t.Fatalf("expected a private cache bounded by the expiry, got %q", cc)
+	}
+
+	cases := map[string]string{
+		"unsigned":      "/@mrtats/@wave",
+		"expired":       SignRawURL(key, "/@mrtats/@wave", time.Now().Add(-time.Minute)),
+		"other path":    strings.Replace(signed, "@wave", "@smile", 1),
+		"extended exp":  strings.Replace(signed, "exp=", "exp=9", 1),
+		"wrong key":     SignRawURL([]byte("other-key"), "/@mrtats/@wave", time.Now().Add(time.Hour)),
+		"malformed exp": "/@mrtats/@wave?exp=soon&sig=abc",
+	}
+	for name, target := range cases {
+		if rec := get(target); rec.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", name, rec.Code)
+		}
+	}
+
+	// Without a key raw routes stay public.
+	if rec := serveRequest(store, Options{}, httptest.NewRequest(http.MethodGet, "/@mrtats/@wave", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("expected unsigned access without a signing key, got %d", rec.Code)
+	}
+}
+
+func TestLoadPlaceholder_RejectsNonImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "placeholder.html")
+	if err := os.WriteFile(path, []byte("<html></html>"), 0o600); err != nil {
+		t.Fatalf("write placeholder: %v", err)
+	}
+	if _, err := LoadPlaceholder(path, http.StatusOK); err == nil {
+		t.Fatalf("expected error for non-image placeholder")
+	}
+}
+
+func TestHeadList_Count(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "smile", Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "wave", Author: strPtr("alice"), Mime: "image/gif"},
+	}}
+
+	rec := serve(store, http.MethodHead, "/api/emojis")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Total-Count"); got != "3" {
+		t.Fatalf("expected X-Total-Count 3, got %q", got)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Fatalf("unexpected Last-Modified %q", got)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got %q", rec.Body.String())
+	}
+
+	rec = serve(store, http.MethodHead, "/api/authors/mrtats/emojis")
+	if got := rec.Header().Get("X-Total-Count"); got != "2" {
+		t.Fatalf("expected author X-Total-Count 2, got %q", got)
+	}
+
+	rec = serve(&fakeStore{}, http.MethodHead, "/api/emojis")
+	if rec.Header().Get("X-Total-Count") != "0" || rec.Header().Get("Last-Modified") != "" {
+		t.Fatalf("expected zero count without Last-Modified, got %v", rec.Header())
+	}
+}
+
+type countingMetrics struct{ served, missed int }
+
+func (m *countingMetrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {}
+
+func (m *countingMetrics) ObserveImage(found bool) {
+	if found {
+		m.served++
+	} else {
+		m.missed++
+	}
+}
+
+func TestGetImage_Metrics(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+	m := &countingMetrics{}
+	opts := Options{Metrics: m}
+
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/@mrtats/@wave", nil))
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/@mrtats/@missing", nil))
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/@mrtats/@missing", nil))
+
+	if m.served != 1 || m.missed != 2 {
+		t.Fatalf("expected 1 served and 2 missed, got %d and %d", m.served, m.missed)
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"},
+	}}
+	registry := metrics.NewRegistry()
+	opts := Options{Metrics: metrics.NewAPI(registry)}
+
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/emojis/wave", nil))
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/alice/emojis/wave", nil))
+	serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/bob/emojis/smile", nil))
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+	for _, want := range []string{
+		`hivemoji_http_requests_total{method="GET",route="/api/authors/:author/emojis/:name",status="200"} 1`,
+		`hivemoji_http_requests_total{method="GET",route="/api/authors/:author/emojis/:name",status="404"} 2`,
+		`hivemoji_http_request_duration_seconds_count{method="GET",route="/api/authors/:author/emojis/:name"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in metrics:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "mrtats") {
+		t.Fatalf("expected path parameters to be normalized out of labels:\n%s", out.String())
+	}
+}
+
+func TestGetByAuthor_HidesExpired(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "xmas", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), ExpiresAt: &past, ModerationStatus: "approved"},
+		{Name: "newyear", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), ExpiresAt: &future},
+	}}
+
+	for _, target := range []string{"/api/authors/mrtats/emojis/xmas", "/@mrtats/@xmas"} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for expired emoji, got %d", target, rec.Code)
+		}
+	}
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/newyear")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"expires_at"`) {
+		t.Fatalf("expected 200 with expires_at for unexpired emoji, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/xmas", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin to see expired emoji, got %d", rec.Code)
+	}
+	var body emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.ExpiresAt == nil || body.ModerationStatus != "approved" {
+		t.Fatalf("expected expiry and moderation status in admin response, got %+v", body)
+	}
+}
+
+// fakeUploads returns canned assembly results keyed by "id/kind".
+type fakeUploads struct {
+	sets map[string]*storage.AssembledSet
+	errs map[string]error
+}
+
+func (f *fakeUploads) CompleteUpload(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error) {
+	key := uploadID + "/" + kind
+	if err, ok := f.errs[key]; ok {
+		return nil, err
+	}
+	if set, ok := f.sets[key]; ok {
+		return set, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func TestAssembleUpload(t *testing.T) {
+	uploads := &fakeUploads{
+		sets: map[string]*storage.AssembledSet{
+			"up1/main": {UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: []byte("png")},
+		},
+		errs: map[string]error{
+			"up2/main": fmt.Errorf("%w: checksum mismatch for upload up2 kind main", storage.ErrAssemble),
+			"up3/main": errors.New("db down"),
+		},
+	}
+	opts := Options{AdminToken: testAdminToken, Uploads: uploads}
+
+	cases := []struct {
+		target string
+		want   int
+	}{
+		{"/api/admin/uploads/up1/main/assemble", http.StatusOK},
+		{"/api/admin/uploads/up2/main/assemble", http.StatusConflict},
+		{"/api/admin/uploads/up3/main/assemble", http.StatusInternalServerError},
+		{"/api/admin/uploads/missing/main/assemble", http.StatusNotFound},
+		{"/api/admin/uploads/up1/thumb/assemble", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := serveRequest(&fakeStore{}, opts, adminRequest(http.MethodPost, tc.target, ""))
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%s)", tc.target, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := serveRequest(&fakeStore{}, opts, adminRequest(http.MethodPost, "/api/admin/uploads/up1/main/assemble", ""))
+	var body assembleResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Name != "wave" || body.Bytes != 3 {
+		t.Fatalf("unexpected response %+v", body)
+	}
+	if !strings.Contains(serveRequest(&fakeStore{}, opts, adminRequest(http.MethodPost, "/api/admin/uploads/up2/main/assemble", "")).Body.String(), "checksum mismatch") {
+		t.Fatalf("expected the assembly failure to be reported")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/uploads/up1/main/assemble", nil)
+	if rec := serveRequest(&fakeStore{}, opts, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without admin token, got %d", rec.Code)
+	}
+}
+
+func TestAuthorUsage(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "still", Author: strPtr("mrtats"), Mime: "image/png", Data: make([]byte, 100)},
+		{Name: "party", Author: strPtr("mrtats"), Mime: "image/gif", Data: make([]byte, 300), Animated: true, FallbackData: make([]byte, 50)},
+		{Name: "party", Variant: "dark", Author: strPtr("mrtats"), Mime: "image/gif", Data: make([]byte, 20), Animated: true},
+		{Name: "other", Author: strPtr("someone"), Mime: "image/png", Data: make([]byte, 1000)},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/usage")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var usage usageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := usageResponse{Author: "mrtats", Bytes: 470, Emojis: 2, Animated: 1}
+	if usage != want {
+		t.Fatalf("expected %+v, got %+v", want, usage)
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/nobody/usage")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"bytes":0`) {
+		t.Fatalf("expected zero usage, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListPacks(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "blob_happy", Author: strPtr("mrtats"), Mime: "image/png", Tags: []string{"blobs"}},
+		{Name: "blob_wave", Author: strPtr("mrtats"), Mime: "image/png", Tags: []string{"blobs", "wave"}, Cover: true},
+		{Name: "cat", Author: strPtr("mrtats"), Mime: "image/png", Tags: []string{"cats"}},
+		{Name: "plain", Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "other", Author: strPtr("someone"), Mime: "image/png", Tags: []string{"blobs"}, Cover: true},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/packs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var packs []packResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &packs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []struct {
+		tag   string
+		count int
+		cover string
+	}{
+		{"blobs", 2, "blob_wave"},
+		{"cats", 1, "cat"},
+		{"wave", 1, "blob_wave"},
+	}
+	if len(packs) != len(want) {
+		t.Fatalf("expected %d packs, got %+v", len(want), packs)
+	}
+	for i, w := range want {
+		if packs[i].Tag != w.tag || packs[i].Count != w.count || packs[i].Cover.Name != w.cover {
+			t.Fatalf("pack %d: expected %s/%d/%s, got %s/%d/%s", i, w.tag, w.count, w.cover, packs[i].Tag, packs[i].Count, packs[i].Cover.Name)
+		}
+	}
+	if !packs[0].Cover.Cover {
+		t.Fatalf("expected flagged cover to report cover=true")
+	}
+
+	rec = serve(&fakeStore{}, http.MethodGet, "/api/authors/nobody/packs")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("expected empty array, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRandom(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+		{Name: "dance", Author: strPtr("someone"), Mime: "image/gif", Data: []byte("gif")},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/random?author=someone")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Name != "dance" || body.Data != "" {
+		t.Fatalf("expected metadata for someone's emoji, got %+v", body)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected random picks not to be cached")
+	}
+
+	rec = serve(store, http.MethodGet, "/api/random?raw=1")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != "image/png" || rec.Body.String() != "png" {
+		t.Fatalf("expected raw png, got %d %q %q", rec.Code, rec.Header().Get(echo.HeaderContentType), rec.Body.String())
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/random?author=nobody"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for author without emojis, got %d", rec.Code)
+	}
+	if rec := serve(&fakeStore{err: errors.New("db down")}, http.MethodGet, "/api/random"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 on store failure, got %d", rec.Code)
+	}
+}
+
+func TestList_MultipleAuthors(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "smile", Author: strPtr("alice"), Mime: "image/png"},
+		{Name: "dance", Author: strPtr("bob"), Mime: "image/gif"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/emojis?author=mrtats&author=Alice&author=mrtats")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body) != 2 || body[0].Name != "wave" || body[1].Name != "smile" {
+		t.Fatalf("expected emojis of mrtats and alice only, got %+v", body)
+	}
+
+	rec = serve(store, http.MethodHead, "/api/emojis?author=mrtats&author=bob")
+	if got := rec.Header().Get("X-Total-Count"); got != "2" {
+		t.Fatalf("expected HEAD count to honour authors, got %q", got)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/emojis?author=not+valid"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid author, got %d", rec.Code)
+	}
+
+	var many []string
+	for i := 0; i <= maxAuthorsParam; i++ {
+		many = append(many, fmt.Sprintf("author=user%d", i))
+	}
+	if rec := serve(store, http.MethodGet, "/api/emojis?"+strings.Join(many, "&")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 above %d authors, got %d", maxAuthorsParam, rec.Code)
+	}
+}
+
+func TestIdempotencyKey_ReplaysAdminRequest(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats")}}}
+	opts := Options{AdminToken: testAdminToken}
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := adminRequest(method, target, body)
+		req.Header.Set("Idempotency-Key", "retry-1")
+		return serveRequest(store, opts, req)
+	}
+
+	rec := send(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/featured", `{"featured":true,"order":1}`)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: expected fresh 204, got %d replayed=%q", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+
+	rec = send(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/featured", `{"featured":true,"order":5}`)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry: expected replayed 204, got %d replayed=%q", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if store.order["wave"] != 1 {
+		t.Fatalf("expected retry not to reach the store, order is %d", store.order["wave"])
+	}
+
+	rec = send(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/moderation", `{"status":"approved"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key on another route: expected 422, got %d", rec.Code)
+	}
+}
+
+func TestIngestNotes(t *testing.T) {
+	store := &fakeStore{
+		assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats")}, {Name: "smile", Author: strPtr("mrtats")}},
+		notes:  map[string][]string{"mrtats/wave": {"fallback dropped: same mime as main (image/png)"}},
+	}
+
+	rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/wave/notes", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp notesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Author != "mrtats" || resp.Name != "wave" || len(resp.Notes) != 1 || resp.Notes[0] != "fallback dropped: same mime as main (image/png)" {
+		t.Fatalf("unexpected notes response: %+v", resp)
+	}
+
+	rec = serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/smile/notes", ""))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"notes":[]`) {
+		t.Fatalf("expected empty notes list, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/missing/notes", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestGetImage_APNG(t *testing.T) {
+	g := &gif.GIF{}
+	for _, idx := range []uint8{1, 2} {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette.Plan9)
+		for i := range frame.Pix {
+			frame.Pix[i] = idx
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5)
+	}
+	var animated bytes.Buffer
+	if err := gif.EncodeAll(&animated, g); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	// An animated WebP header with no frames to convert.
+	framelessWebP := []byte("RIFF\x16\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02\x00\x00\x00\x01\x00\x00\x01\x00\x00")
+
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "dance", Author: strPtr("mrtats"), Mime: "image/gif", Data: animated.Bytes(), Checksum: strPtr("abc")},
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+		{Name: "spin", Author: strPtr("mrtats"), Mime: "image/webp", Data: framelessWebP},
+	}}
+	srv := &Server{store: store, apng: transcode.NewCache(4)}
+	e := echo.New()
+	srv.Register(e)
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/@mrtats/@dance?format=apng")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != "image/png" {
+		t.Fatalf("expected converted png, got %d %s", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("acTL")) || rec.Header().Get("ETag") != `"abc-apng"` {
+		t.Fatalf("expected an APNG with its own ETag, got etag %s", rec.Header().Get("ETag"))
+	}
+
+	rec = get("/@mrtats/@wave?format=apng")
+	if rec.Code != http.StatusOK || rec.Body.String() != "png" {
+		t.Fatalf("expected static image served as stored, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = get("/@mrtats/@spin?format=apng")
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "no frames") {
+		t.Fatalf("expected 422 for a webp that cannot be converted, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := get("/@mrtats/@dance?format=avif"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown format, got %d", rec.Code)
+	}
+}
+
+func TestGetByAuthor_HidesPendingConfirmation(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), PendingConfirmation: true},
+	}}
+
+	for _, target := range []string{"/api/authors/mrtats/emojis/wave", "/@mrtats/@wave"} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 while pending, got %d", target, rec.Code)
+		}
+	}
+
+	rec := serveRequest(store, Options{AdminToken: testAdminToken}, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/wave", ""))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"pending_confirmation":true`) {
+		t.Fatalf("expected admin to see the pending emoji, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	store.assets[0].PendingConfirmation = false
+	if rec := serve(store, http.MethodGet, "/@mrtats/@wave"); rec.Code != http.StatusOK {
+		t.Fatalf("expected confirmed emoji to be served, got %d", rec.Code)
+	}
+}
+
+func TestList_KeysetPagination(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats")},
+		{Name: "smile", Author: strPtr("mrtats")},
+		{Name: "smile", Author: strPtr("alice")},
+		{Name: "frown", Author: strPtr("bob")},
+		{Name: "zzz", Author: strPtr("alice")},
+	}}
+
+	var got []string
+	target := "/api/emojis?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("pagination did not terminate, got %v", got)
+		}
+		rec := serve(store, http.MethodGet, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d %s", target, rec.Code, rec.Body.String())
+		}
+		// The first page is an offset page; the cursor pages after it are bare arrays.
+		var resp []emojiResponse
+		if pages == 0 {
+			var paged pagedListResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &paged); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			resp = paged.Items
+		} else {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if total := rec.Header().Get("X-Total-Count"); total != "5" {
+				t.Fatalf("%s: expected X-Total-Count 5 on every page, got %q", target, total)
+			}
+		}
+		for _, e := range resp {
+			got = append(got, e.Name+"@"+*e.Author)
+		}
+		next := rec.Header().Get("X-Next-Cursor")
+		if next == "" {
+			break
+		}
+		target = "/api/emojis?limit=2&after=" + url.QueryEscape(next)
+	}
+
+	// Duplicate names across authors must neither repeat nor vanish at page boundaries.
+	want := []string{"frown@bob", "smile@alice", "smile@mrtats", "wave@mrtats", "zzz@alice"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v across pages, got %v", want, got)
+	}
+}
+
+func TestList_OffsetPagination(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats")},
+		{Name: "smile", Author: strPtr("alice")},
+		{Name: "frown", Author: strPtr("bob")},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/emojis?limit=2&offset=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	var page pagedListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if page.Total != 3 || page.Limit != 2 || page.Offset != 1 || len(page.Items) != 2 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if page.Items[0].Name != "smile" || page.Items[1].Name != "wave" {
+		t.Fatalf("expected smile and wave after skipping frown, got %s and %s", page.Items[0].Name, page.Items[1].Name)
+	}
+	if rec.Header().Get("X-Next-Cursor") != "" {
+		t.Fatalf("expected no cursor on the last page, got %q", rec.Header().Get("X-Next-Cursor"))
+	}
+
+	// Without limit or offset the listing stays a bare array.
+	rec = serve(store, http.MethodGet, "/api/emojis")
+	var all []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || len(all) != 3 {
+		t.Fatalf("expected a bare array of 3 emojis, got %s (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestList_ClampsPageParams(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats")}}}
+	cases := []struct {
+		target     string
+		wantLimit  int
+		wantOffset int
+	}{
+		{"/api/emojis?limit=0", 1, 0},
+		{"/api/emojis?limit=-1", 1, 0},
+		{"/api/emojis?limit=1000", maxPageLimit, 0},
+		{"/api/emojis?limit=20", 20, 0},
+		{"/api/emojis?offset=-5", defaultPageLimit, 0},
+		{"/api/emojis?offset=7", defaultPageLimit, 7},
+	}
+	for _, tc := range cases {
+		rec := serve(store, http.MethodGet, tc.target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tc.target, rec.Code)
+		}
+		var page pagedListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%s: decode response: %v", tc.target, err)
+		}
+		if page.Limit != tc.wantLimit || page.Offset != tc.wantOffset || store.pageLimit != tc.wantLimit || store.pageOffset != tc.wantOffset {
+			t.Fatalf("%s: expected limit %d offset %d, got %+v (store %d/%d)", tc.target, tc.wantLimit, tc.wantOffset, page, store.pageLimit, store.pageOffset)
+		}
+	}
+
+	// Cursor pages clamp the same way; they ask for one extra row to detect a following page.
+	if rec := serve(store, http.MethodGet, "/api/emojis?limit=1000&after="+storage.KeyOf(store.assets[0]).Cursor()); rec.Code != http.StatusOK || store.pageLimit != maxPageLimit+1 {
+		t.Fatalf("expected cursor page limit %d, got %d (%d)", maxPageLimit, store.pageLimit-1, rec.Code)
+	}
+}
+
+func TestList_PaginationErrors(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats")}}}
+	for _, target := range []string{
+		"/api/emojis?limit=x",
+		"/api/emojis?offset=x",
+		"/api/emojis?after=%21%21",
+		"/api/emojis?offset=1&author=mrtats",
+		"/api/emojis?offset=1&after=" + storage.KeyOf(store.assets[0]).Cursor(),
+		"/api/emojis?offset=1&all_versions=1",
+	} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+
+	rec := serve(store, http.MethodGet, "/api/emojis?limit=1&author=mrtats")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Fatalf("expected a single last page without cursor, got %d cursor=%q", rec.Code, rec.Header().Get("X-Next-Cursor"))
+	}
+}
+
+func TestWithFallback_OptIn(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{
+		Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp", Data: []byte("webp"),
+		FallbackMime: strPtr("image/png"), FallbackData: []byte("png"),
+	}}}
+
+	tests := []struct {
+		query        string
+		wantData     string
+		wantFallback string
+	}{
+		{"with_data=1", "d2VicA==", ""},
+		{"with_data=1&with_fallback=1", "d2VicA==", "cG5n"},
+		{"with_fallback=uri", "", "data:image/png;base64,cG5n"},
+		{"", "", ""},
+	}
+	for _, tc := range tests {
+		for _, path := range []string{"/api/authors/mrtats/emojis/wave", "/api/emojis"} {
+			rec := serve(store, http.MethodGet, path+"?"+tc.query)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s?%s: expected 200, got %d", path, tc.query, rec.Code)
+			}
+			var resp emojiResponse
+			body := rec.Body.Bytes()
+			if path == "/api/emojis" {
+				var list []emojiResponse
+				if err := json.Unmarshal(body, &list); err != nil || len(list) != 1 {
+					t.Fatalf("decode list: %v %s", err, body)
+				}
+				resp = list[0]
+			} else if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Data != tc.wantData || resp.FallbackData != tc.wantFallback {
+				t.Fatalf("%s?%s: got data=%q fallback_data=%q, want %q and %q", path, tc.query, resp.Data, resp.FallbackData, tc.wantData, tc.wantFallback)
+			}
+			if resp.FallbackMime == nil || *resp.FallbackMime != "image/png" {
+				t.Fatalf("%s?%s: expected fallback_mime to always be reported", path, tc.query)
+			}
+		}
+	}
+}
+
+func TestNamePrefix_RoutesUseUnprefixedNames(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "acme_wave", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")}}}
+	opts := Options{NamePrefix: "acme_"}
+
+	rec := serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/emojis/wave", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Name != "wave" {
+		t.Fatalf("expected prefix stripped from response name, got %q", resp.Name)
+	}
+
+	rec = serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/emojis", nil))
+	var list []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].Name != "wave" {
+		t.Fatalf("expected listing to strip the prefix, got %s", rec.Body.String())
+	}
+
+	rec = serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/emojis/acme_wave", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the stored name not to be routable, got %d", rec.Code)
+	}
+}
+
+func TestGetEmoji_ChunkCount(t *testing.T) {
+	chunks := 3
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 2, Author: strPtr("mrtats"), UploadID: strPtr("up1"), Mime: "image/png", ChunkCount: &chunks},
+		{Name: "smile", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	var resp emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ChunkCount == nil || *resp.ChunkCount != 3 {
+		t.Fatalf("expected chunk_count 3 for a v2 emoji, got %s", rec.Body.String())
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/smile")
+	if strings.Contains(rec.Body.String(), "chunk_count") {
+		t.Fatalf("expected chunk_count to be omitted for a v1 emoji, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminBannedChecksums(t *testing.T) {
+	store := &fakeStore{}
+	opts := Options{AdminToken: testAdminToken}
+	checksum := strings.Repeat("AB", 32)
+
+	rec := serveRequest(store, opts, adminRequest(http.MethodPut, "/api/admin/banned-checksums/"+checksum, `{"reason":"spam"}`))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.banned[strings.ToLower(checksum)] != "spam" {
+		t.Fatalf("expected lowercased checksum to be banned, got %v", store.banned)
+	}
+
+	rec = serveRequest(store, opts, adminRequest(http.MethodGet, "/api/admin/banned-checksums", ""))
+	var list []bannedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list) != 1 || list[0].Reason != "spam" {
+		t.Fatalf("expected one banned checksum, got %s", rec.Body.String())
+	}
+
+	if rec := serveRequest(store, opts, adminRequest(http.MethodPut, "/api/admin/banned-checksums/nothex", "")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed checksum, got %d", rec.Code)
+	}
+
+	if rec := serveRequest(store, opts, adminRequest(http.MethodDelete, "/api/admin/banned-checksums/"+checksum, "")); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on unban, got %d", rec.Code)
+	}
+	if rec := serveRequest(store, opts, adminRequest(http.MethodDelete, "/api/admin/banned-checksums/"+checksum, "")); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 unbanning twice, got %d", rec.Code)
+	}
+}
+
+func TestChecksumBackfillStatus(t *testing.T) {
+	checksum := strings.Repeat("ab", 32)
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Checksum: &checksum},
+		{Name: "legacy", Author: strPtr("mrtats")},
+	}}
+	opts := Options{AdminToken: testAdminToken}
+
+	rec := serveRequest(store, opts, adminRequest(http.MethodGet, "/api/admin/checksum-backfill", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp backfillResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Done || resp.Remaining != 1 {
+		t.Fatalf("expected done with one remaining, got %+v", resp)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/admin/checksum-backfill"); rec.Code != http.StatusNotFound && rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the route to need the admin token, got %d", rec.Code)
+	}
+}
+
+func TestListByBlockRange(t *testing.T) {
+	block := func(n int64) *int64 { return &n }
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "before", Author: strPtr("mrtats"), Mime: "image/png", RegisteredBlock: block(99)},
+		{Name: "first", Author: strPtr("mrtats"), Mime: "image/png", RegisteredBlock: block(100)},
+		{Name: "last", Author: strPtr("alice"), Mime: "image/png", RegisteredBlock: block(200)},
+		{Name: "after", Author: strPtr("alice"), Mime: "image/png", RegisteredBlock: block(201)},
+		{Name: "legacy", Author: strPtr("alice"), Mime: "image/png"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/emojis?from_block=100&to_block=200")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var names []string
+	for _, e := range resp {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "first,last" {
+		t.Fatalf("expected the emojis registered in blocks 100-200, got %s", got)
+	}
+	if resp[0].RegisteredBlock == nil || *resp[0].RegisteredBlock != 100 {
+		t.Fatalf("expected registered_block in the response, got %+v", resp[0].RegisteredBlock)
+	}
+
+	rec = serve(store, http.MethodHead, "/api/emojis?from_block=100&to_block=200")
+	if got := rec.Header().Get("X-Total-Count"); got != "2" {
+		t.Fatalf("expected HEAD to count the range, got %q", got)
+	}
+
+	for _, target := range []string{
+		"/api/emojis?from_block=100",
+		"/api/emojis?from_block=0&to_block=10",
+		"/api/emojis?from_block=200&to_block=100",
+		"/api/emojis?from_block=abc&to_block=100",
+		fmt.Sprintf("/api/emojis?from_block=1&to_block=%d", maxBlockRange+1),
+	} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	block := int64(100)
+	store := &fakeStore{audit: []storage.AuditEntry{
+		{ID: 2, Action: storage.AuditTransfer, Author: "mrtats", Name: "wave", Target: "newtats", Block: &block},
+		{ID: 1, Action: storage.AuditTransfer, Author: "alice", Name: "smile", Target: "bob"},
+	}}
+	opts := Options{AdminToken: testAdminToken}
+
+	rec := serveRequest(store, opts, adminRequest(http.MethodGet, "/api/admin/audit-log?limit=1", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var entries []auditResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Target != "newtats" || entries[0].Block == nil || *entries[0].Block != 100 {
+		t.Fatalf("expected the latest transfer, got %s", rec.Body.String())
+	}
+
+	if rec := serveRequest(store, opts, adminRequest(http.MethodGet, "/api/admin/audit-log?limit=0", "")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad limit, got %d", rec.Code)
+	}
+}
+
+func TestContentAddressed(t *testing.T) {
+	data := []byte("png-bytes")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	stale := strings.Repeat("ab", 32)
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png", Checksum: &checksum, Data: data},
+		{Name: "smile", Author: strPtr("mrtats"), Mime: "image/png", Checksum: &stale, Data: data},
+	}}
+
+	rec := serve(store, http.MethodGet, "/ipfs-like/"+checksum)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Fatalf("expected image bytes, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Fatalf("expected immutable caching, got %q", cc)
+	}
+	if rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ipfs-like/"+checksum, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	if rec := serveRequest(store, Options{}, req); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	if rec := serve(store, http.MethodGet, "/ipfs-like/"+stale); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when stored bytes don't hash to the checksum, got %d", rec.Code)
+	}
+	if rec := serve(store, http.MethodGet, "/ipfs-like/nothex"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed checksum, got %d", rec.Code)
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	var resp emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ContentURL == nil || *resp.ContentURL != "/ipfs-like/"+checksum {
+		t.Fatalf("expected content_url in emoji response, got %s", rec.Body.String())
+	}
+}
+
+func TestGetEmojiBase64(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp", Data: []byte("webp"), FallbackData: []byte("gif")},
+		{Name: "smile", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png")},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/base64")
+	if rec.Code != http.StatusOK || rec.Body.String() != "d2VicA==" {
+		t.Fatalf("expected bare base64 of main image, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("expected text/plain, got %q", ct)
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/base64?fallback=1")
+	if rec.Body.String() != "Z2lm" {
+		t.Fatalf("expected base64 of fallback image, got %q", rec.Body.String())
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/smile/base64?fallback=1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a fallback, got %d", rec.Code)
+	}
+}
+
+func TestMissingChunks(t *testing.T) {
+	store := &fakeStore{uploads: map[string]*storage.UploadStatus{
+		"up1/main": {UploadID: "up1", Kind: "main", Total: 4, Missing: []int{2, 4}},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/uploads/up1/main/missing")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp missingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 4 || !reflect.DeepEqual(resp.Missing, []int{2, 4}) {
+		t.Fatalf("unexpected missing chunks %+v", resp)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/uploads/up1/fallback/missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown upload, got %d", rec.Code)
+	}
+	if rec := serve(store, http.MethodGet, "/api/uploads/up1/thumb/missing"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown kind, got %d", rec.Code)
+	}
+}
+
+func TestListPendingUploads(t *testing.T) {
+	width, total, block := 32, 3, int64(120)
+	store := &fakeStore{manifests: []storage.Manifest{
+		{UploadID: "up2", Name: "wave", Author: "mrtats", Mime: strPtr("image/gif"), Width: &width, Height: &width, Animated: true, Total: &total, FirstBlock: &block},
+		{UploadID: "up1", Name: "smile", Author: "alice"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/uploads/pending")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("expected no-store, got %q", got)
+	}
+	var resp []pendingUploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp) != 2 || resp[0].UploadID != "up2" || resp[0].Total == nil || *resp[0].Total != 3 || !resp[0].Animated {
+		t.Fatalf("unexpected pending uploads %+v", resp)
+	}
+	if resp[1].Mime != nil || resp[1].Block != nil {
+		t.Fatalf("expected unannounced fields to be omitted, got %+v", resp[1])
+	}
+	if store.lastLimit != defaultPendingLimit {
+		t.Fatalf("expected default limit %d, got %d", defaultPendingLimit, store.lastLimit)
+	}
+
+	rec = serve(store, http.MethodGet, "/api/uploads/pending?limit=1")
+	if rec.Code != http.StatusOK || store.lastLimit != 1 {
+		t.Fatalf("expected limit 1 to be honoured, got %d (limit %d)", rec.Code, store.lastLimit)
+	}
+	if rec := serve(store, http.MethodGet, "/api/uploads/pending?limit=500"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an oversized limit, got %d", rec.Code)
+	}
+}
+
+func TestNameReservation(t *testing.T) {
+	store := &fakeStore{
+		assets: []storage.Asset{
+			{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"},
+			{Name: "wave", Author: strPtr("squatter"), Mime: "image/png", Unofficial: true},
+		},
+		owners: map[string]string{"wave": "mrtats"},
+	}
+
+	rec := serve(store, http.MethodGet, "/api/names/wave/reservation")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"author":"mrtats"`) {
+		t.Fatalf("expected reservation owner, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(store, http.MethodGet, "/api/names/smile/reservation"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unreserved name, got %d", rec.Code)
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/squatter/emojis/wave")
+	if !strings.Contains(rec.Body.String(), `"unofficial":true`) {
+		t.Fatalf("expected conflicting emoji to be flagged unofficial, got %s", rec.Body.String())
+	}
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	if strings.Contains(rec.Body.String(), "unofficial") {
+		t.Fatalf("expected the owner's emoji not to be flagged, got %s", rec.Body.String())
+	}
+}
+
+func TestListVersions_Pages(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"}}}
+	for i := 1; i <= 5; i++ {
+		store.versions = append(store.versions, storage.AssetVersion{Author: "mrtats", Name: "wave", Revision: i, Version: 1, Mime: "image/png", CreatedAt: start.AddDate(0, 0, i)})
+	}
+
+	list := func(target string) versionsResponse {
+		t.Helper()
+		rec := serve(store, http.MethodGet, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var resp versionsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+	revisions := func(resp versionsResponse) []int {
+		var out []int
+		for _, v := range resp.Versions {
+			out = append(out, v.Revision)
+		}
+		return out
+	}
+
+	var got []int
+	target := "/api/authors/mrtats/emojis/wave/versions?limit=2"
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatalf("expected 3 pages, still paging at %s", target)
+		}
+		resp := list(target)
+		if resp.CurrentRevision != 5 {
+			t.Fatalf("expected current revision 5 on every page, got %d", resp.CurrentRevision)
+		}
+		got = append(got, revisions(resp)...)
+		if resp.NextBefore == 0 {
+			break
+		}
+		target = fmt.Sprintf("/api/authors/mrtats/emojis/wave/versions?limit=2&before=%d", resp.NextBefore)
+	}
+	if !reflect.DeepEqual(got, []int{5, 4, 3, 2, 1}) {
+		t.Fatalf("expected every revision once, newest first, got %v", got)
+	}
+
+	ranged := list("/api/authors/mrtats/emojis/wave/versions?since=2024-01-03T00:00:00Z&until=2024-01-05T00:00:00Z")
+	if !reflect.DeepEqual(revisions(ranged), []int{3, 2}) || ranged.NextBefore != 0 {
+		t.Fatalf("expected revisions 3 and 2 in range, got %v (next %d)", revisions(ranged), ranged.NextBefore)
+	}
+
+	for _, bad := range []string{"limit=0", "limit=201", "before=x", "since=yesterday"} {
+		if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/versions?"+bad); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+	if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/missing/versions"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing emoji, got %d", rec.Code)
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	store := &fakeStore{
+		assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp"}},
+		versions: []storage.AssetVersion{
+			{Author: "mrtats", Name: "wave", Revision: 1, Version: 1, Mime: "image/png", Width: 32, Height: 32, Size: 100, Checksum: "aaa"},
+			{Author: "mrtats", Name: "wave", Revision: 2, Version: 1, Mime: "image/webp", Width: 32, Height: 32, Size: 80, Checksum: "bbb"},
+		},
+	}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/diff?from=1&to=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp diffResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var fields []string
+	for _, c := range resp.Changes {
+		fields = append(fields, c.Field)
+	}
+	if !reflect.DeepEqual(fields, []string{"mime", "size", "checksum"}) {
+		t.Fatalf("unexpected changed fields %v", fields)
+	}
+	if resp.From.Revision != 1 || resp.To.Revision != 2 {
+		t.Fatalf("unexpected revisions %d -> %d", resp.From.Revision, resp.To.Revision)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/diff?from=1&to=3"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing revision, got %d", rec.Code)
+	}
+	if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/diff?from=x&to=2"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed revision, got %d", rec.Code)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	block := func(n int64) *int64 { return &n }
+	registered := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp", RegisteredBlock: block(300)}},
+		versions: []storage.AssetVersion{
+			{Author: "mrtats", Name: "wave", Revision: 1, Version: 1, Mime: "image/png", Checksum: "aaa", RegisteredBlock: block(100), CreatedAt: registered},
+			{Author: "mrtats", Name: "wave", Revision: 2, Version: 2, Mime: "image/webp", Checksum: "bbb", RegisteredBlock: block(300)},
+		},
+		audit: []storage.AuditEntry{
+			{ID: 1, Action: storage.AuditTransfer, Author: "alice", Name: "wave", Target: "bob", Block: block(150)},
+			{ID: 2, Action: storage.AuditTransfer, Author: "carol", Name: "wave", Target: "dave", Block: block(160)},
+			{ID: 3, Action: storage.AuditTransfer, Author: "bob", Name: "wave", Target: "mrtats", Block: block(200)},
+			{ID: 4, Action: storage.AuditTransfer, Author: "alice", Name: "smile", Target: "mrtats", Block: block(210)},
+		},
+	}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/provenance")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp provenanceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RegisteredBlock == nil || *resp.RegisteredBlock != 100 {
+		t.Fatalf("expected the first revision's block 100, got %v", resp.RegisteredBlock)
+	}
+	if resp.RegisteredAt == nil || !resp.RegisteredAt.Equal(registered) {
+		t.Fatalf("expected registered_at %v, got %v", registered, resp.RegisteredAt)
+	}
+	if len(resp.Versions) != 2 || resp.Versions[0].Checksum != "aaa" || resp.Versions[1].Checksum != "bbb" {
+		t.Fatalf("unexpected versions %+v", resp.Versions)
+	}
+	var hops []string
+	for _, tr := range resp.Transfers {
+		hops = append(hops, tr.From+"->"+tr.To)
+	}
+	if !reflect.DeepEqual(hops, []string{"alice->bob", "bob->mrtats"}) {
+		t.Fatalf("unexpected transfers %v", hops)
+	}
+
+	if rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/nope/provenance"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown emoji, got %d", rec.Code)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "smile", Version: 1, Author: strPtr("alice"), Mime: "image/png"},
+		{Name: "party", Version: 2, Author: strPtr("bob"), Mime: "image/gif", Animated: true},
+	}}
+	opts := Options{AdminToken: testAdminToken}
+	admin := func(method, target, body string) int {
+		return serveRequest(store, opts, adminRequest(method, target, body)).Code
+	}
+
+	if code := admin(http.MethodPut, "/api/admin/collections/Best%20Of", `{}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid slug, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of", `{"title":"Best of"}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 creating the collection, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of/items/bob/party", `{"position":2}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 adding an item, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of/items/alice/smile", `{"position":1}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 adding an item, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of/items/mrtats/wave", `{"position":3}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 adding an item, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of/items/mrtats/nope", `{}`); code != http.StatusNotFound {
+		t.Fatalf("expected 404 adding an unknown emoji, got %d", code)
+	}
+	if code := admin(http.MethodPut, "/api/admin/collections/missing/items/mrtats/wave", `{}`); code != http.StatusNotFound {
+		t.Fatalf("expected 404 adding to an unknown collection, got %d", code)
+	}
+
+	order := func() []string {
+		t.Helper()
+		rec := serve(store, http.MethodGet, "/api/collections/best-of")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp collectionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Title != "Best of" {
+			t.Fatalf("unexpected title %q", resp.Title)
+		}
+		var names []string
+		for _, e := range resp.Emojis {
+			names = append(names, *e.Author+"/"+e.Name)
+		}
+		return names
+	}
+	if got := order(); !reflect.DeepEqual(got, []string{"alice/smile", "bob/party", "mrtats/wave"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+
+	// Re-adding an item moves it; removing one drops it.
+	if code := admin(http.MethodPut, "/api/admin/collections/best-of/items/mrtats/wave", `{"position":0}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 moving an item, got %d", code)
+	}
+	if code := admin(http.MethodDelete, "/api/admin/collections/best-of/items/alice/smile", ""); code != http.StatusNoContent {
+		t.Fatalf("expected 204 removing an item, got %d", code)
+	}
+	if code := admin(http.MethodDelete, "/api/admin/collections/best-of/items/alice/smile", ""); code != http.StatusNotFound {
+		t.Fatalf("expected 404 removing an unlisted item, got %d", code)
+	}
+	if got := order(); !reflect.DeepEqual(got, []string{"mrtats/wave", "bob/party"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+
+	if code := admin(http.MethodDelete, "/api/admin/collections/best-of", ""); code != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting the collection, got %d", code)
+	}
+	if rec := serve(store, http.MethodGet, "/api/collections/best-of"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted collection, got %d", rec.Code)
+	}
+}
+
+func TestListAllVersions(t *testing.T) {
+	store := &fakeStore{
+		assets: []storage.Asset{
+			{Name: "smile", Author: strPtr("alice"), Mime: "image/png", Checksum: strPtr("ccc")},
+			{Name: "wave", Author: strPtr("mrtats"), Mime: "image/webp", Checksum: strPtr("bbb")},
+		},
+		versions: []storage.AssetVersion{
+			{Author: "mrtats", Name: "wave", Revision: 1, Version: 1, Mime: "image/png", Checksum: "aaa"},
+			{Author: "mrtats", Name: "wave", Revision: 2, Version: 2, Mime: "image/webp", Checksum: "bbb"},
+		},
+	}
+	list := func(target string) []emojiResponse {
+		t.Helper()
+		rec := serve(store, http.MethodGet, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var resp []emojiResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+
+	current := list("/api/emojis")
+	if len(current) != 2 {
+		t.Fatalf("expected the default listing to show current emojis only, got %d", len(current))
+	}
+	for _, e := range current {
+		if e.Historical || e.Revision != nil {
+			t.Fatalf("expected no revision fields by default, got %+v", e)
+		}
+	}
+
+	all := list("/api/emojis?all_versions=1")
+	var rows []string
+	for _, e := range all {
+		row := fmt.Sprintf("%s/%s", *e.Author, e.Name)
+		if e.Revision != nil {
+			row += fmt.Sprintf("@%d", *e.Revision)
+		}
+		if e.Historical {
+			row += " historical"
+			if e.ContentURL != nil {
+				t.Fatalf("expected no content URL for an earlier revision, got %s", *e.ContentURL)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if want := []string{"alice/smile", "mrtats/wave@2", "mrtats/wave@1 historical"}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("unexpected all_versions listing %v, want %v", rows, want)
+	}
+
+	for _, target := range []string{"/api/emojis?all_versions=1&limit=5", "/api/emojis?all_versions=1&with_data=1"} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestListVariants(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "wave", Variant: "dark", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+		{Name: "smile", Version: 1, Author: strPtr("mrtats"), Mime: "image/png"},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave/variants")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp []emojiResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp) != 2 || resp[0].Variant != "" || resp[1].Variant != "dark" {
+		t.Fatalf("expected base and dark variant, got %s", rec.Body.String())
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/wave")
+	if strings.Contains(rec.Body.String(), "variant") {
+		t.Fatalf("expected the base emoji for a plain lookup, got %s", rec.Body.String())
+	}
+
+	rec = serve(store, http.MethodGet, "/api/authors/mrtats/emojis/missing/variants")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown emoji, got %d", rec.Code)
+	}
+}
+
+func TestAuthorSprite(t *testing.T) {
+	var still bytes.Buffer
+	if err := gif.Encode(&still, image.NewPaletted(image.Rect(0, 0, 8, 8), palette.Plan9), nil); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "acme_wave", Author: strPtr("mrtats"), Mime: "image/gif", Data: still.Bytes()},
+	}}
+	opts := Options{NamePrefix: "acme_"}
+
+	rec := serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/emoji.css", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("expected css, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	want := `.emoji-wave { background: url("sprite.png") 0 0 no-repeat; width: 8px; height: 8px; display: inline-block; }`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("unexpected css:\n got %s\nwant %s", got, want)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/sprite.png", nil)
+	rec = serveRequest(store, opts, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected png sheet, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	if rec = serveRequest(store, opts, req); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	if rec = serve(store, http.MethodGet, "/api/authors/nobody/emoji.css"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an author without emojis, got %d", rec.Code)
+	}
+}
+
+func TestTranscodeConcurrency(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewNRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "wave", Author: strPtr("alice"), Mime: "image/png", Data: pngData.Bytes()},
+	}}
+	srv := &Server{store: store, opts: Options{TranscodeConcurrency: 2}}
+	e := echo.New()
+	srv.Register(e)
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// Two conversions in flight take every slot.
+	srv.transcodes <- struct{}{}
+	srv.transcodes <- struct{}{}
+	heavy := []string{"/api/preview?emojis=alice/wave", "/api/authors/alice/sprite.png", "/@alice/@wave?format=apng"}
+	for _, target := range heavy {
+		rec := get(target)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+			t.Fatalf("%s: expected 503 with Retry-After while saturated, got %d %q", target, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	for _, target := range []string{"/@alice/@wave", "/api/emojis"} {
+		if rec := get(target); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected cheap requests to be served while saturated, got %d", target, rec.Code)
+		}
+	}
+
+	<-srv.transcodes
+	for _, target := range heavy {
+		if rec := get(target); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200 with a free slot, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+	if n := len(srv.transcodes); n != 1 {
+		t.Fatalf("expected finished conversions to release their slots, %d still taken", n)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	solid := func(w, h int, c color.NRGBA) []byte {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("encode png: %v", err)
+		}
+		return buf.Bytes()
+	}
+	red, blue := color.NRGBA{R: 0xff, A: 0xff}, color.NRGBA{B: 0xff, A: 0xff}
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "square", Author: strPtr("alice"), Mime: "image/png", Data: solid(16, 16, red)},
+		{Name: "wide", Author: strPtr("bob"), Mime: "image/png", Data: solid(32, 16, blue)},
+	}}
+
+	rec := serve(store, http.MethodGet, "/api/preview?emojis=alice/square,bob/wide,carol/missing")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a png, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("decode preview: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 3*previewCell || b.Dy() != previewCell {
+		t.Fatalf("expected three %dpx cells, got %v", previewCell, b)
+	}
+
+	at := func(x, y int) color.NRGBA { return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA) }
+	checks := []struct {
+		x, y int
+		want color.NRGBA
+		what string
+	}{
+		{0, 0, red, "square emoji filling its cell"},
+		{previewCell + previewCell/2, previewCell / 2, blue, "wide emoji centred in its cell"},
+		{previewCell + previewCell/2, 2, color.NRGBA{}, "transparent margin above the wide emoji"},
+		{2*previewCell + previewCell/2, previewCell / 2, color.NRGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}, "placeholder for the missing emoji"},
+	}
+	for _, c := range checks {
+		if got := at(c.x, c.y); got != c.want {
+			t.Fatalf("%s: pixel (%d,%d) is %v, want %v", c.what, c.x, c.y, got, c.want)
+		}
+	}
+
+	for _, target := range []string{"/api/preview", "/api/preview?emojis=alice", "/api/preview?emojis=Not%20Valid/x"} {
+		if rec := serve(store, http.MethodGet, target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestReadOnly_OmitsAdminRoutes(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{{Name: "wave", Author: strPtr("mrtats"), Mime: "image/png"}}}
+	opts := Options{AdminToken: "secret", ReadOnly: true}
+
+	e := echo.New()
+	(&Server{store: store, opts: opts}).Register(e)
+	for _, r := range e.Routes() {
+		if strings.HasPrefix(r.Path, "/api/admin") {
+			t.Fatalf("expected no admin routes in read-only mode, found %s %s", r.Method, r.Path)
+		}
+	}
+
+	rec := serveRequest(store, opts, adminRequest(http.MethodGet, "/api/admin/authors/mrtats/emojis/wave", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected admin GET to be unrouted, got %d", rec.Code)
+	}
+	rec = serveRequest(store, opts, adminRequest(http.MethodPut, "/api/admin/authors/mrtats/emojis/wave/featured", `{"featured":true}`))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected 405 for a mutating request, got %d allow=%q", rec.Code, rec.Header().Get("Allow"))
+	}
+	if rec = serveRequest(store, opts, httptest.NewRequest(http.MethodGet, "/api/authors/mrtats/emojis/wave", nil)); rec.Code != http.StatusOK {
+		t.Fatalf("expected public reads to keep working, got %d", rec.Code)
+	}
+}
+
+func TestAssetCacheControl_ByAge(t *testing.T) {
+	store := &fakeStore{assets: []storage.Asset{
+		{Name: "fresh", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), UpdatedAt: time.Now().Add(-time.Hour)},
+		{Name: "old", Author: strPtr("mrtats"), Mime: "image/png", Data: []byte("png"), UpdatedAt: time.Now().Add(-48 * time.Hour)},
+	}}
+	opts := Options{CacheStableAfter: 24 * time.Hour, CacheRecentMaxAge: 5 * time.Minute}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/@mrtats/@fresh", "public, max-age=300"},
+		{"/api/authors/mrtats/emojis/fresh", "public, max-age=300"},
+		{"/@mrtats/@old", "public, max-age=604800"},
+		{"/api/emojis/old?author=mrtats", "public, max-age=604800"},
+	}
+	for _, tc := range tests {
+		rec := serveRequest(store, opts, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tc.path, rec.Code)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tc.want {
+			t.Fatalf("%s: expected Cache-Control %q, got %q", tc.path, tc.want, got)
+		}
+	}
+
+	rec := serveRequest(store, Options{}, httptest.NewRequest(http.MethodGet, "/@mrtats/@fresh", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=604800" {
+		t.Fatalf("expected a week without CacheStableAfter, got %q", got)
+	}
+}
+
+func TestAdminIngestState(t *testing.T) {
+	state := ingest.NewState()
+	state.Started(100)
+	state.Head(120)
+	state.Processed(100)
+	state.Failed(errors.New("fetch block 101: timeout"))
+	opts := Options{AdminToken: "secret", Ingest: state}
+
+	rec := serveRequest(&fakeStore{}, opts, adminRequest(http.MethodGet, "/api/admin/ingest", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp ingestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.CurrentBlock != 101 || resp.HeadBlock != 120 || resp.Lag != 20 || !resp.CatchingUp {
+		t.Fatalf("unexpected progress: %+v", resp)
+	}
+	if resp.ConsecutiveFailures != 1 || resp.LastError != "fetch block 101: timeout" || resp.LastErrorAt == nil {
+		t.Fatalf("unexpected failure state: %+v", resp)
+	}
+
+	if rec := serveRequest(&fakeStore{}, Options{AdminToken: "secret"}, adminRequest(http.MethodGet, "/api/admin/ingest", "")); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no route without ingest state, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SignRawURL returns path with the exp and sig query parameters that make a raw image route
+// accept it until expires, for servers configured with the same key. path is the escaped URL
+// path, e.g. "/@mrtats/@wave".
+func SignRawURL(key []byte, path string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"exp": {exp}, "sig": {rawURLSignature(key, path, exp)}}
+	return path + "?" + q.Encode()
+}
+
+// rawURLSignature is the HMAC-SHA256 of the path and expiry, base64url-encoded.
+func rawURLSignature(key []byte, path, exp string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// requireSignature rejects raw image requests without a valid, unexpired signature from
+// SignRawURL. Signed responses may only be cached privately and until the link expires, so
+// a shared cache can't keep serving an emoji past its expiry.
+func (s *Server) requireSignature(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		exp := c.QueryParam("exp")
+		sig := c.QueryParam("sig")
+		if exp == "" || sig == "" {
+			return echo.NewHTTPError(http.StatusForbidden, "signed URL required")
+		}
+		expires, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, "invalid signature")
+		}
+		want := rawURLSignature(s.opts.URLSigningKey, c.Request().URL.EscapedPath(), exp)
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			return echo.NewHTTPError(http.StatusForbidden, "invalid signature")
+		}
+		remaining := time.Until(time.Unix(expires, 0))
+		if remaining <= 0 {
+			return echo.NewHTTPError(http.StatusForbidden, "signed URL expired")
+		}
+
+		c.Response().Before(func() {
+			c.Response().Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(remaining.Seconds())))
+		})
+		return next(c)
+	}
+}
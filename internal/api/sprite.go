@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/sprite"
+)
+
+// spriteCacheEntries bounds how many author sprite sheets are kept in memory.
+const spriteCacheEntries = 64
+
+// handleSprite serves an author's emojis packed into one PNG atlas.
+func (s *Server) handleSprite(c echo.Context) error {
+	return s.serveSheet(c, "sprite", func(sheet *sprite.Sheet) error {
+		return c.Blob(http.StatusOK, "image/png", sheet.PNG)
+	})
+}
+
+// handleSpriteCSS serves `.emoji-<name>` rules positioning the author's sprite sheet. The
+// sheet is referenced relatively, so the stylesheet works wherever the API is mounted.
+func (s *Server) handleSpriteCSS(c echo.Context) error {
+	return s.serveSheet(c, "css", func(sheet *sprite.Sheet) error {
+		return c.Blob(http.StatusOK, "text/css; charset=utf-8", sheet.CSS("sprite.png"))
+	})
+}
+
+// serveSheet builds (or reuses) the author's sheet and hands it to write, answering
+// conditional requests from the author's last-modified time without building anything.
+func (s *Server) serveSheet(c echo.Context, variant string, write func(*sprite.Sheet) error) error {
+	author := c.Param("author")
+	if strings.TrimSpace(author) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "author is required")
+	}
+	ctx := c.Request().Context()
+
+	lastModified, err := s.store.GetAuthorLastModified(ctx, author)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	version := fmt.Sprintf("%d", lastModified.UnixNano())
+	etag := fmt.Sprintf(`"%s-%s"`, version, variant)
+	if match := c.Request().Header.Get("If-None-Match"); match == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	sheet, err := s.sprites.Sheet(author+"\x00"+version, func() (*sprite.Sheet, error) {
+		release, err := s.acquireTranscode(c)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		assets, err := s.store.ListAssetsByAuthor(ctx, author, true)
+		if err != nil {
+			return nil, err
+		}
+		sources := make([]sprite.Source, 0, len(assets))
+		for _, a := range assets {
+			sources = append(sources, sprite.Source{Name: s.publicName(a.Name), Mime: a.Mime, Data: a.Data})
+		}
+		return sprite.Build(sources)
+	})
+	if errors.Is(err, errTranscodeBusy) {
+		return err
+	}
+	if errors.Is(err, sprite.ErrEmpty) {
+		return echo.NewHTTPError(http.StatusNotFound, "author has no emojis that can be packed")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=0, must-revalidate")
+	c.Response().Header().Set("ETag", etag)
+	return write(sheet)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"hivemoji/internal/storage"
+)
+
+// versionResponse is one recorded revision of an emoji.
+type versionResponse struct {
+	Revision        int       `json:"revision"`
+	Version         int       `json:"version"`
+	Mime            string    `json:"mime"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	Animated        bool      `json:"animated"`
+	Size            int       `json:"size"`
+	Checksum        string    `json:"checksum"`
+	FallbackMime    *string   `json:"fallback_mime,omitempty"`
+	FallbackSize    int       `json:"fallback_size,omitempty"`
+	RegisteredBlock *int64    `json:"registered_block,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// versionChange is one field that differs between two revisions.
+type versionChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+type diffResponse struct {
+	Author  string          `json:"author"`
+	Name    string          `json:"name"`
+	From    versionResponse `json:"from"`
+	To      versionResponse `json:"to"`
+	Changes []versionChange `json:"changes"`
+}
+
+func toVersionResponse(v storage.AssetVersion) versionResponse {
+	return versionResponse{
+		Revision:        v.Revision,
+		Version:         v.Version,
+		Mime:            v.Mime,
+		Width:           v.Width,
+		Height:          v.Height,
+		Animated:        v.Animated,
+		Size:            v.Size,
+		Checksum:        v.Checksum,
+		FallbackMime:    v.FallbackMime,
+		FallbackSize:    v.FallbackSize,
+		RegisteredBlock: v.RegisteredBlock,
+		CreatedAt:       v.CreatedAt,
+	}
+}
+
+const (
+	defaultVersionLimit = 50
+	maxVersionLimit     = 200
+)
+
+type versionsResponse struct {
+	Author string `json:"author"`
+	Name   string `json:"name"`
+	// CurrentRevision is the emoji's latest revision, whichever page is returned.
+	CurrentRevision int               `json:"current_revision"`
+	Versions        []versionResponse `json:"versions"`
+	// NextBefore is the ?before= value of the next, older page; omitted on the last page.
+	NextBefore int `json:"next_before,omitempty"`
+}
+
+// handleListVersions pages through an emoji's recorded revisions, newest first. ?before= takes
+// a revision number and ?since=/?until= an RFC 3339 time range on when revisions were recorded.
+func (s *Server) handleListVersions(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+
+	q := storage.VersionQuery{Limit: defaultVersionLimit}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxVersionLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxVersionLimit))
+		}
+		q.Limit = n
+	}
+	if v := c.QueryParam("before"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "before must be a positive revision number")
+		}
+		q.Before = n
+	}
+	for param, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := c.QueryParam(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, param+" must be an RFC 3339 time")
+			}
+			*t = parsed
+		}
+	}
+
+	if _, err := s.publicAsset(c, author, name); err != nil {
+		return err
+	}
+	// Fetch one extra revision to learn whether an older page follows.
+	limit := q.Limit
+	q.Limit++
+	versions, current, err := s.store.ListAssetVersionsPage(c.Request().Context(), author, s.storedName(name), q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	resp := versionsResponse{Author: author, Name: name, CurrentRevision: current, Versions: make([]versionResponse, 0, len(versions))}
+	if len(versions) > limit {
+		versions = versions[:limit]
+		resp.NextBefore = versions[limit-1].Revision
+	}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, toVersionResponse(v))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleDiff compares the metadata of two revisions of an emoji, `?from=` and `?to=`.
+func (s *Server) handleDiff(c echo.Context) error {
+	author := c.Param("author")
+	name := c.Param("name")
+	if strings.TrimSpace(author) == "" || name == "" {
+		return echo.ErrNotFound
+	}
+	from, err := strconv.Atoi(c.QueryParam("from"))
+	if err != nil || from < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "from must be a positive revision number")
+	}
+	to, err := strconv.Atoi(c.QueryParam("to"))
+	if err != nil || to < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must be a positive revision number")
+	}
+
+	if _, err := s.publicAsset(c, author, name); err != nil {
+		return err
+	}
+	versions, err := s.store.ListAssetVersions(c.Request().Context(), author, s.storedName(name))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	var a, b *storage.AssetVersion
+	for i := range versions {
+		if versions[i].Revision == from {
+			a = &versions[i]
+		}
+		if versions[i].Revision == to {
+			b = &versions[i]
+		}
+	}
+	if a == nil || b == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "revision not found")
+	}
+
+	return c.JSON(http.StatusOK, diffResponse{
+		Author:  author,
+		Name:    name,
+		From:    toVersionResponse(*a),
+		To:      toVersionResponse(*b),
+		Changes: diffVersions(*a, *b),
+	})
+}
+
+// diffVersions lists the metadata fields that differ from a to b.
+func diffVersions(a, b storage.AssetVersion) []versionChange {
+	changes := []versionChange{}
+	add := func(field string, from, to any) {
+		if from != to {
+			changes = append(changes, versionChange{Field: field, From: from, To: to})
+		}
+	}
+	add("version", a.Version, b.Version)
+	add("mime", a.Mime, b.Mime)
+	add("width", a.Width, b.Width)
+	add("height", a.Height, b.Height)
+	add("animated", a.Animated, b.Animated)
+	add("size", a.Size, b.Size)
+	add("checksum", a.Checksum, b.Checksum)
+	add("fallback_mime", derefString(a.FallbackMime), derefString(b.FallbackMime))
+	add("fallback_size", a.FallbackSize, b.FallbackSize)
+	return changes
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -1,35 +1,125 @@
 package config
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Config holds runtime configuration for the hivemoji service.
 type Config struct {
-	HiveRPCURL                string
+	HiveRPCURLs               []string
 	PostgresDSN               string
 	StartBlock                int64
+	StartFromHead             bool
+	ReprocessFrom             time.Time
 	PollInterval              time.Duration
 	CatchupPollInterval       time.Duration
 	IncompleteChunkTTL        time.Duration
 	IncompleteCleanupInterval time.Duration
 	ServerAddr                string
+	TLSCertFile               string
+	TLSKeyFile                string
+	TLSAutocertDomains        []string
+	TLSAutocertCacheDir       string
+	AdminToken                string
+	URLSigningKey             string
+	ReadOnly                  bool
+	CacheStableAfter          time.Duration
+	CacheRecentMaxAge         time.Duration
+	ModerationWebhookURL      string
+	ModerationTimeout         time.Duration
+	ModerationRetries         int
+	ModerationFailClosed      bool
+	RegisterWebhookURL        string
+	RegisterWebhookTimeout    time.Duration
+	RegisterWebhookRetries    int
+	StrictAuthors             bool
+	PlaceholderPath           string
+	PlaceholderStatus         int
+	StripMetadata             bool
+	OptimizeGIF               bool
+	SlowBlockThreshold        time.Duration
+	CustomJSONID              string
+	NamePrefix                string
+	AuthorAllowlist           []string
+	RequireSignature          bool
+	AccountKeyTTL             time.Duration
+	MinReputation             *float64 // nil disables the reputation check
+	LogEveryBlock             bool
+	LogProgressInterval       time.Duration
+	MaxChunks                 int
+	Validation                string
+	MaxImageBytes             int
+	IdempotencyTTL            time.Duration
+	ExportTimeout             time.Duration
+	TranscodeConcurrency      int
+	ConfirmationDepth         int64
+	IrreversibleLag           int64
+	DBReadyTimeout            time.Duration
+	ExtraOps                  []string
+	Compression               string
+	BlobStore                 string
+	BlobDir                   string
+	MimeRules                 string
 }
 
-// Load reads environment variables and applies defaults.
+// Load reads environment variables and applies defaults. When HIVEMOJI_ENV_FILE is set,
+// its KEY=VALUE lines are applied to the environment first so a reload picks up edits.
 func Load() (Config, error) {
+	if path := os.Getenv("HIVEMOJI_ENV_FILE"); path != "" {
+		if err := applyEnvFile(path); err != nil {
+			return Config{}, fmt.Errorf("read HIVEMOJI_ENV_FILE: %w", err)
+		}
+	}
+
 	cfg := Config{
-		HiveRPCURL:                envOr("HIVE_RPC_URL", "https://api.hive.blog"),
 		PostgresDSN:               os.Getenv("POSTGRES_DSN"),
 		ServerAddr:                envOr("SERVER_ADDR", ":8080"),
+		TLSCertFile:               os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:                os.Getenv("TLS_KEY_FILE"),
+		TLSAutocertCacheDir:       envOr("TLS_AUTOCERT_CACHE_DIR", "autocert-cache"),
+		AdminToken:                os.Getenv("HIVEMOJI_ADMIN_TOKEN"),
+		URLSigningKey:             os.Getenv("HIVEMOJI_URL_SIGNING_KEY"),
+		ReadOnly:                  os.Getenv("HIVEMOJI_READONLY") == "1",
+		CacheStableAfter:          24 * time.Hour,
+		CacheRecentMaxAge:         5 * time.Minute,
+		ModerationWebhookURL:      os.Getenv("MODERATION_WEBHOOK_URL"),
+		ModerationTimeout:         10 * time.Second,
+		ModerationRetries:         2,
+		ModerationFailClosed:      os.Getenv("MODERATION_FAIL_CLOSED") == "1",
+		RegisterWebhookURL:        os.Getenv("HIVEMOJI_REGISTER_WEBHOOK_URL"),
+		RegisterWebhookTimeout:    5 * time.Second,
+		RegisterWebhookRetries:    2,
+		StrictAuthors:             os.Getenv("HIVEMOJI_STRICT_AUTHORS") == "1",
+		PlaceholderPath:           os.Getenv("HIVEMOJI_PLACEHOLDER_PATH"),
+		PlaceholderStatus:         404,
+		StripMetadata:             os.Getenv("HIVEMOJI_STRIP_METADATA") == "1",
+		OptimizeGIF:               os.Getenv("HIVEMOJI_OPTIMIZE_GIF") == "1",
+		SlowBlockThreshold:        2 * time.Second,
+		NamePrefix:                os.Getenv("HIVEMOJI_NAME_PREFIX"),
+		RequireSignature:          os.Getenv("HIVEMOJI_REQUIRE_SIGNATURE") == "1",
+		AccountKeyTTL:             10 * time.Minute,
+		LogEveryBlock:             os.Getenv("HIVE_LOG_EVERY_BLOCK") == "1",
+		Compression:               os.Getenv("HIVEMOJI_COMPRESSION"),
+		BlobDir:                   os.Getenv("HIVEMOJI_BLOB_DIR"),
+		MimeRules:                 os.Getenv("HIVEMOJI_MIME_RULES"),
+		LogProgressInterval:       30 * time.Second,
+		IdempotencyTTL:            24 * time.Hour,
+		ExportTimeout:             5 * time.Minute,
+		DBReadyTimeout:            time.Minute,
 		PollInterval:              3 * time.Second,
 		CatchupPollInterval:       500 * time.Millisecond,
 		IncompleteChunkTTL:        1 * time.Hour,
 		IncompleteCleanupInterval: 10 * time.Minute,
 		StartBlock:                0,
+		StartFromHead:             os.Getenv("HIVE_START_FROM_HEAD") == "1",
 	}
 
 	if v := os.Getenv("HIVE_POLL_INTERVAL"); v != "" {
@@ -61,9 +151,183 @@ func Load() (Config, error) {
 		if err != nil {
 			return cfg, fmt.Errorf("invalid HIVE_INCOMPLETE_CLEANUP_INTERVAL: %w", err)
 		}
+		if d <= 0 {
+			return cfg, fmt.Errorf("invalid HIVE_INCOMPLETE_CLEANUP_INTERVAL: must be positive")
+		}
 		cfg.IncompleteCleanupInterval = d
 	}
 
+	if v := os.Getenv("HIVEMOJI_SLOW_BLOCK_THRESHOLD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_SLOW_BLOCK_THRESHOLD: %w", err)
+		}
+		cfg.SlowBlockThreshold = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_ACCOUNT_KEY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_ACCOUNT_KEY_TTL: %w", err)
+		}
+		cfg.AccountKeyTTL = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_MIN_REPUTATION"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_MIN_REPUTATION: %q", v)
+		}
+		cfg.MinReputation = &f
+	}
+
+	if v := os.Getenv("HIVE_LOG_PROGRESS_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVE_LOG_PROGRESS_INTERVAL: %w", err)
+		}
+		cfg.LogProgressInterval = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_DB_READY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_DB_READY_TIMEOUT: %w", err)
+		}
+		cfg.DBReadyTimeout = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_IDEMPOTENCY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_IDEMPOTENCY_TTL: %w", err)
+		}
+		cfg.IdempotencyTTL = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_EXPORT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_EXPORT_TIMEOUT: %w", err)
+		}
+		cfg.ExportTimeout = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_CACHE_STABLE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_CACHE_STABLE_AFTER: %w", err)
+		}
+		cfg.CacheStableAfter = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_CACHE_RECENT_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_CACHE_RECENT_MAX_AGE: %w", err)
+		}
+		cfg.CacheRecentMaxAge = d
+	}
+
+	if v := os.Getenv("MODERATION_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid MODERATION_TIMEOUT: %w", err)
+		}
+		cfg.ModerationTimeout = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_REGISTER_WEBHOOK_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_REGISTER_WEBHOOK_TIMEOUT: %w", err)
+		}
+		cfg.RegisterWebhookTimeout = d
+	}
+
+	if v := os.Getenv("HIVEMOJI_REGISTER_WEBHOOK_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_REGISTER_WEBHOOK_RETRIES: %q", v)
+		}
+		cfg.RegisterWebhookRetries = n
+	}
+
+	if v := os.Getenv("MODERATION_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid MODERATION_RETRIES: %q", v)
+		}
+		cfg.ModerationRetries = n
+	}
+
+	if v := os.Getenv("HIVEMOJI_MAX_CHUNKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_MAX_CHUNKS: %q", v)
+		}
+		cfg.MaxChunks = n
+	}
+
+	switch v := os.Getenv("HIVEMOJI_VALIDATION"); v {
+	case "", "off", "lenient", "strict":
+		cfg.Validation = v
+	default:
+		return cfg, fmt.Errorf("invalid HIVEMOJI_VALIDATION: %q (want off, lenient or strict)", v)
+	}
+
+	switch v := os.Getenv("HIVEMOJI_BLOB_STORE"); v {
+	case "", "inline", "postgres":
+		cfg.BlobStore = v
+	case "fs":
+		if cfg.BlobDir == "" {
+			return cfg, fmt.Errorf("HIVEMOJI_BLOB_STORE=fs requires HIVEMOJI_BLOB_DIR")
+		}
+		cfg.BlobStore = v
+	default:
+		return cfg, fmt.Errorf("invalid HIVEMOJI_BLOB_STORE: %q (want inline, postgres or fs)", v)
+	}
+
+	if v := os.Getenv("HIVEMOJI_TRANSCODE_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_TRANSCODE_CONCURRENCY: %q", v)
+		}
+		cfg.TranscodeConcurrency = n
+	}
+
+	if v := os.Getenv("HIVEMOJI_MAX_IMAGE_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_MAX_IMAGE_BYTES: %q", v)
+		}
+		cfg.MaxImageBytes = n
+	}
+
+	if v := os.Getenv("HIVEMOJI_CONFIRMATION_DEPTH"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_CONFIRMATION_DEPTH: %q", v)
+		}
+		cfg.ConfirmationDepth = n
+	}
+
+	if v := os.Getenv("HIVE_IRREVERSIBLE_LAG"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HIVE_IRREVERSIBLE_LAG: %q", v)
+		}
+		cfg.IrreversibleLag = n
+	}
+
+	if v := os.Getenv("HIVEMOJI_PLACEHOLDER_STATUS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || (n != 200 && n != 404) {
+			return cfg, fmt.Errorf("invalid HIVEMOJI_PLACEHOLDER_STATUS: must be 200 or 404")
+		}
+		cfg.PlaceholderStatus = n
+	}
+
 	if v := os.Getenv("HIVE_START_BLOCK"); v != "" {
 		n, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
@@ -72,6 +336,64 @@ func Load() (Config, error) {
 		cfg.StartBlock = n
 	}
 
+	if v := os.Getenv("HIVE_REPROCESS_FROM"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HIVE_REPROCESS_FROM: %w", err)
+		}
+		cfg.ReprocessFrom = t
+	}
+
+	// HIVEMOJI_CUSTOM_ID is accepted as an alias of HIVEMOJI_CUSTOM_JSON_ID.
+	jsonID, alias := os.Getenv("HIVEMOJI_CUSTOM_JSON_ID"), os.Getenv("HIVEMOJI_CUSTOM_ID")
+	if jsonID != "" && alias != "" && jsonID != alias {
+		return cfg, fmt.Errorf("HIVEMOJI_CUSTOM_JSON_ID=%q and HIVEMOJI_CUSTOM_ID=%q disagree; set only one", jsonID, alias)
+	}
+	cfg.CustomJSONID = "hivemoji"
+	if jsonID != "" {
+		cfg.CustomJSONID = jsonID
+	} else if alias != "" {
+		cfg.CustomJSONID = alias
+	}
+
+	// HIVE_RPC_URLS lists nodes in order of preference; HIVE_RPC_URL is the single-node form.
+	urls, err := ParseRPCURLs(envOr("HIVE_RPC_URLS", envOr("HIVE_RPC_URL", "https://api.hive.blog")))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HIVE_RPC_URLS: %w", err)
+	}
+	cfg.HiveRPCURLs = urls
+
+	if v := os.Getenv("HIVE_EXTRA_OPS"); v != "" {
+		for _, op := range strings.Split(v, ",") {
+			if op = strings.TrimSpace(op); op != "" {
+				cfg.ExtraOps = append(cfg.ExtraOps, op)
+			}
+		}
+	}
+
+	if v := os.Getenv("HIVEMOJI_AUTHOR_ALLOWLIST"); v != "" {
+		for _, author := range strings.Split(v, ",") {
+			if author = strings.ToLower(strings.TrimSpace(author)); author != "" {
+				cfg.AuthorAllowlist = append(cfg.AuthorAllowlist, author)
+			}
+		}
+	}
+
+	if v := os.Getenv("TLS_AUTOCERT_DOMAIN"); v != "" {
+		for _, domain := range strings.Split(v, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				cfg.TLSAutocertDomains = append(cfg.TLSAutocertDomains, domain)
+			}
+		}
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return cfg, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSCertFile != "" && len(cfg.TLSAutocertDomains) > 0 {
+		return cfg, fmt.Errorf("TLS_AUTOCERT_DOMAIN cannot be combined with TLS_CERT_FILE/TLS_KEY_FILE")
+	}
+
 	if cfg.PostgresDSN == "" {
 		return cfg, fmt.Errorf("POSTGRES_DSN is required")
 	}
@@ -79,9 +401,93 @@ func Load() (Config, error) {
 	return cfg, nil
 }
 
+// envFileKeys holds, for every key the env file set on the last Load, the value the process
+// had before it, or nil when the key was unset. A key dropped from the file gets it back on
+// the next Load.
+var (
+	envFileMu   sync.Mutex
+	envFileKeys = map[string]*string{}
+)
+
+// applyEnvFile sets process environment variables from KEY=VALUE lines, skipping blanks and # comments.
+// Keys set by the previous call that are no longer in the file are restored to what they were
+// before, so removing an override takes effect on reload.
+func applyEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		values[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	envFileMu.Lock()
+	defer envFileMu.Unlock()
+	for key, prev := range envFileKeys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if prev != nil {
+			err = os.Setenv(key, *prev)
+		} else {
+			err = os.Unsetenv(key)
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s: %w", key, err)
+		}
+		delete(envFileKeys, key)
+	}
+	for key, value := range values {
+		if _, ok := envFileKeys[key]; !ok {
+			var prev *string
+			if v, set := os.LookupEnv(key); set {
+				prev = &v
+			}
+			envFileKeys[key] = prev
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
 func envOr(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return def
 }
+
+// ParseRPCURLs splits a comma-separated list of Hive RPC endpoints, trimming spaces and
+// dropping empty entries. It fails when no endpoint is left.
+func ParseRPCURLs(v string) ([]string, error) {
+	var urls []string
+	for _, url := range strings.Split(v, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no endpoint listed")
+	}
+	return urls, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_EnvFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hivemoji.env")
+	t.Setenv("HIVEMOJI_ENV_FILE", path)
+	t.Setenv("POSTGRES_DSN", "postgres://localhost/hivemoji")
+	t.Setenv("HIVE_POLL_INTERVAL", "7s")
+	t.Setenv("HIVE_CATCHUP_INTERVAL", "")
+	os.Unsetenv("HIVE_CATCHUP_INTERVAL")
+	t.Cleanup(func() { envFileKeys = map[string]*string{} })
+
+	load := func(contents string) Config {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("write env file: %v", err)
+		}
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		return cfg
+	}
+
+	cfg := load("# overrides\nHIVE_POLL_INTERVAL=1s\nHIVE_CATCHUP_INTERVAL=\"200ms\"\n")
+	if cfg.PollInterval != time.Second || cfg.CatchupPollInterval != 200*time.Millisecond {
+		t.Fatalf("expected the file's intervals, got %s and %s", cfg.PollInterval, cfg.CatchupPollInterval)
+	}
+
+	// Removing the overrides brings back the process environment and the defaults.
+	cfg = load("\n")
+	if cfg.PollInterval != 7*time.Second {
+		t.Fatalf("expected the poll interval from the environment, got %s", cfg.PollInterval)
+	}
+	if cfg.CatchupPollInterval != 500*time.Millisecond {
+		t.Fatalf("expected the default catch-up interval, got %s", cfg.CatchupPollInterval)
+	}
+	if _, set := os.LookupEnv("HIVE_CATCHUP_INTERVAL"); set {
+		t.Fatal("expected HIVE_CATCHUP_INTERVAL to be unset again")
+	}
+
+	cfg = load("HIVE_POLL_INTERVAL=2s\n")
+	if cfg.PollInterval != 2*time.Second {
+		t.Fatalf("expected a re-added override to apply, got %s", cfg.PollInterval)
+	}
+}
+
+func TestLoad_EnvFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hivemoji.env")
+	t.Setenv("HIVEMOJI_ENV_FILE", path)
+	t.Setenv("POSTGRES_DSN", "postgres://localhost/hivemoji")
+	t.Setenv("HIVE_POLL_INTERVAL", "7s")
+	t.Cleanup(func() { envFileKeys = map[string]*string{} })
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected a missing env file to fail")
+	}
+	if err := os.WriteFile(path, []byte("HIVE_POLL_INTERVAL=1s\nnot a pair\n"), 0o600); err != nil {
+		t.Fatalf("write env file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Fatal("expected a malformed line to fail")
+	}
+	// A file that fails to parse changes nothing.
+	if v := os.Getenv("HIVE_POLL_INTERVAL"); v != "7s" {
+		t.Fatalf("expected the environment untouched, got HIVE_POLL_INTERVAL=%q", v)
+	}
+}
@@ -0,0 +1,69 @@
+// Package encoding converts emoji image bytes to and from the base64 forms used on
+// chain and over the API.
+package encoding
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrEmpty is returned when there is no image data to decode.
+var ErrEmpty = errors.New("image data is empty")
+
+// DecodeImage decodes base64 image data and sniffs its mime type from the bytes.
+// It tolerates what clients commonly send: a data: URI prefix, embedded whitespace or
+// line breaks, missing padding and the URL-safe alphabet.
+func DecodeImage(s string) ([]byte, string, error) {
+	s = stripDataURI(strings.TrimSpace(s))
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nil, "", ErrEmpty
+	}
+
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	data, err := enc.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) == 0 {
+		return nil, "", ErrEmpty
+	}
+	return data, SniffMime(data), nil
+}
+
+// EncodeDataURI renders data as a data: URI with the given mime type.
+func EncodeDataURI(data []byte, mime string) string {
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// SniffMime returns the media type detected from the leading bytes of data, without parameters.
+func SniffMime(data []byte) string {
+	detected := http.DetectContentType(data)
+	if mediaType, _, err := mime.ParseMediaType(detected); err == nil {
+		return mediaType
+	}
+	return detected
+}
+
+// stripDataURI drops a "data:<mime>;base64," prefix, leaving plain base64 untouched.
+func stripDataURI(s string) string {
+	if !strings.HasPrefix(strings.ToLower(s), "data:") {
+		return s
+	}
+	if i := strings.Index(s, ","); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
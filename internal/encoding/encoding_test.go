@@ -0,0 +1,58 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+
+func TestDecodeImage(t *testing.T) {
+	std := base64.StdEncoding.EncodeToString(pngHeader)
+	urlSafe := base64.RawURLEncoding.EncodeToString(pngHeader)
+
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"standard", std},
+		{"data uri", "data:image/png;base64," + std},
+		{"line breaks", std[:20] + "\n" + std[20:40] + "\r\n " + std[40:]},
+		{"missing padding", base64.RawStdEncoding.EncodeToString(pngHeader)},
+		{"url safe", urlSafe},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, mime, err := DecodeImage(tc.input)
+			if err != nil {
+				t.Fatalf("DecodeImage error: %v", err)
+			}
+			if !bytes.Equal(data, pngHeader) {
+				t.Fatalf("decoded bytes differ")
+			}
+			if mime != "image/png" {
+				t.Fatalf("expected sniffed image/png, got %q", mime)
+			}
+		})
+	}
+}
+
+func TestDecodeImage_Invalid(t *testing.T) {
+	for _, input := range []string{"", "   ", "data:image/png;base64,", "!!", "abc$def"} {
+		if _, _, err := DecodeImage(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestEncodeDataURI_RoundTrip(t *testing.T) {
+	uri := EncodeDataURI(pngHeader, "image/png")
+	if want := "data:image/png;base64,"; uri[:len(want)] != want {
+		t.Fatalf("unexpected prefix in %q", uri)
+	}
+	data, mime, err := DecodeImage(uri)
+	if err != nil || !bytes.Equal(data, pngHeader) || mime != "image/png" {
+		t.Fatalf("round trip failed: mime=%q err=%v", mime, err)
+	}
+}
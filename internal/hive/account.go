@@ -0,0 +1,31 @@
+package hive
+
+import "strings"
+
+// ValidAccountName reports whether name satisfies Hive's account naming rules:
+// 3-16 characters overall, dot-separated segments of at least 3 characters that
+// start with a letter, end with a letter or digit, and contain only a-z, 0-9 and '-'.
+func ValidAccountName(name string) bool {
+	if len(name) < 3 || len(name) > 16 {
+		return false
+	}
+	for _, seg := range strings.Split(name, ".") {
+		if len(seg) < 3 {
+			return false
+		}
+		if seg[0] < 'a' || seg[0] > 'z' {
+			return false
+		}
+		last := seg[len(seg)-1]
+		if !(last >= 'a' && last <= 'z' || last >= '0' && last <= '9') {
+			return false
+		}
+		for i := 1; i < len(seg); i++ {
+			c := seg[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
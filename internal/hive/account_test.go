@@ -0,0 +1,19 @@
+package hive
+
+import "testing"
+
+func TestValidAccountName(t *testing.T) {
+	valid := []string{"mrtats", "abc", "hive-io", "a1b2c3", "peak.snaps", "sixteen-chars-ok"}
+	for _, name := range valid {
+		if !ValidAccountName(name) {
+			t.Errorf("expected %q to be valid", name)
+		}
+	}
+
+	invalid := []string{"", "ab", "MrTats", "1abc", "abc-", "ab.cde", "-abc", "seventeen-chars-x", "with space", "emoji😀"}
+	for _, name := range invalid {
+		if ValidAccountName(name) {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
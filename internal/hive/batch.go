@@ -0,0 +1,240 @@
+package hive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/deathwingtheboss/hivego/types"
+)
+
+// batchTimeout bounds one batched JSON-RPC round-trip.
+const batchTimeout = 30 * time.Second
+
+var batchHTTPClient = &http.Client{Timeout: batchTimeout}
+
+// errBatchUnsupported marks a node that answered a batch with something other than an array
+// of responses, e.g. a single error object from a proxy that caps batch size at one.
+var errBatchUnsupported = errors.New("node does not support JSON-RPC batches")
+
+// rpcCall is one method call within a batch.
+type rpcCall struct {
+	Method string
+	Params any
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// batch sends calls to the node in a single HTTP POST and returns their results in call
+// order. A call the node rejected yields an error for the whole batch.
+func (c *Client) batch(ctx context.Context, calls []rpcCall) ([]json.RawMessage, error) {
+	reqs := make([]rpcRequest, len(calls))
+	for i, call := range calls {
+		reqs[i] = rpcRequest{JSONRPC: "2.0", ID: i, Method: call.Method, Params: call.Params}
+	}
+	status, raw, err := c.post(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", errBatchUnsupported, status)
+	}
+
+	var resps []rpcResponse
+	if err := json.Unmarshal(raw, &resps); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBatchUnsupported, err)
+	}
+	results := make([]json.RawMessage, len(calls))
+	seen := 0
+	for _, r := range resps {
+		if r.ID < 0 || r.ID >= len(calls) || results[r.ID] != nil {
+			return nil, fmt.Errorf("unexpected response id %d in batch", r.ID)
+		}
+		if r.Error != nil {
+			return nil, fmt.Errorf("%s: %d %s", calls[r.ID].Method, r.Error.Code, r.Error.Message)
+		}
+		results[r.ID] = r.Result
+		seen++
+	}
+	if seen != len(calls) {
+		return nil, fmt.Errorf("%w: got %d responses for %d calls", errBatchUnsupported, seen, len(calls))
+	}
+	return results, nil
+}
+
+// call sends a single JSON-RPC request and returns its result.
+func (c *Client) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	status, raw, err := c.post(ctx, rpcRequest{JSONRPC: "2.0", ID: 0, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", method, status)
+	}
+	var resp rpcResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s: %d %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	return resp.Result, nil
+}
+
+// post sends payload as JSON to the node and returns the HTTP status and body.
+func (c *Client) post(ctx context.Context, payload any) (int, []byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ep := c.active()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := batchHTTPClient.Do(httpReq)
+	if err != nil {
+		c.observe(ep, err)
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		// Server errors count against the node; other statuses answer the request itself.
+		c.observe(ep, fmt.Errorf("status %d", resp.StatusCode))
+	} else {
+		c.observe(ep, err)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, raw, nil
+}
+
+// maxBlockRange is the most blocks block_api.get_block_range returns for one call.
+const maxBlockRange = 1000
+
+// GetBlockRange fetches blocks from through to (inclusive) with one block_api.get_block_range
+// call, at most maxBlockRange of them. The returned blocks are consecutive from from and stop
+// before the first block the node has not produced yet, which may leave the result empty.
+func (c *Client) GetBlockRange(ctx context.Context, from, to int64) ([]*Block, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	count := int(min(to-from+1, maxBlockRange))
+	if count < 1 {
+		return nil, fmt.Errorf("get block range: empty range %d-%d", from, to)
+	}
+	result, err := c.call(ctx, "block_api.get_block_range", blockRangeParams(from, count))
+	if err != nil {
+		return nil, fmt.Errorf("get block range %d-%d: %w", from, to, err)
+	}
+	return c.convertBlockRange(result, from)
+}
+
+func blockRangeParams(from int64, count int) types.GetBlockRangeQueryParams {
+	return types.GetBlockRangeQueryParams{StartingBlockNum: int(from), Count: count}
+}
+
+// convertBlockRange decodes a get_block_range result whose first block is from.
+func (c *Client) convertBlockRange(result json.RawMessage, from int64) ([]*Block, error) {
+	var wrapped struct {
+		Blocks []types.Block `json:"blocks"`
+	}
+	if err := json.Unmarshal(result, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode block range from %d: %w", from, err)
+	}
+	blocks := make([]*Block, 0, len(wrapped.Blocks))
+	for i, raw := range wrapped.Blocks {
+		block, err := c.convertBlock(raw, from+int64(i))
+		if err != nil {
+			return blocks, err
+		}
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// HeadAndBlocks fetches the chain head and up to count blocks starting at from, the blocks
+// with a single get_block_range call. When the node supports JSON-RPC batches both go in one
+// round-trip; otherwise it falls back to two calls and stops batching against that endpoint.
+// The returned blocks are consecutive and stop before the first block the node has not
+// produced yet.
+func (c *Client) HeadAndBlocks(ctx context.Context, from int64, count int) (int64, []*Block, error) {
+	if ctx.Err() != nil {
+		return 0, nil, ctx.Err()
+	}
+	count = max(1, min(count, maxBlockRange))
+	if c.batchSupported() {
+		head, blocks, err := c.headAndBlocksBatch(ctx, from, count)
+		if !errors.Is(err, errBatchUnsupported) {
+			return head, blocks, err
+		}
+		c.disableBatch()
+	}
+
+	head, err := c.HeadBlockNumber(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if from > head {
+		return head, nil, nil
+	}
+	blocks, err := c.GetBlockRange(ctx, from, min(from+int64(count)-1, head))
+	return head, blocks, err
+}
+
+func (c *Client) headAndBlocksBatch(ctx context.Context, from int64, count int) (int64, []*Block, error) {
+	results, err := c.batch(ctx, []rpcCall{
+		{Method: "condenser_api.get_dynamic_global_properties", Params: []string{}},
+		{Method: "block_api.get_block_range", Params: blockRangeParams(from, count)},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	head, err := parseHeadBlockNumber(results[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	blocks, err := c.convertBlockRange(results[1], from)
+	return head, blocks, err
+}
+
+func (c *Client) batchSupported() bool {
+	ep := c.active()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !ep.noBatch
+}
+
+func (c *Client) disableBatch() {
+	ep := c.active()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ep.noBatch = true
+}
@@ -0,0 +1,224 @@
+package hive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// stubNode answers condenser_api.get_dynamic_global_properties, block_api.get_block and
+// block_api.get_block_range for a chain whose head is head, counting HTTP round-trips. With noBatch it rejects batches of
+// more than one call, as some public proxies do.
+type stubNode struct {
+	head     int64
+	noBatch  bool
+	requests atomic.Int64
+}
+
+func (s *stubNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+
+	var reqs []rpcRequest
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.answer(req))
+		return
+	}
+	if s.noBatch && len(reqs) > 1 {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch requests are not allowed"}}`))
+		return
+	}
+	resps := make([]map[string]any, 0, len(reqs))
+	for _, req := range reqs {
+		resps = append(resps, s.answer(req))
+	}
+	_ = json.NewEncoder(w).Encode(resps)
+}
+
+func (s *stubNode) answer(req rpcRequest) map[string]any {
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	switch req.Method {
+	case "condenser_api.get_dynamic_global_properties":
+		resp["result"] = map[string]any{"head_block_number": s.head}
+	case "block_api.get_block":
+		params, _ := json.Marshal(req.Params)
+		var p struct {
+			BlockNum int64 `json:"block_num"`
+		}
+		_ = json.Unmarshal(params, &p)
+		if p.BlockNum > s.head {
+			resp["result"] = map[string]any{}
+			break
+		}
+		resp["result"] = map[string]any{"block": stubBlock(p.BlockNum)}
+	case "block_api.get_block_range":
+		params, _ := json.Marshal(req.Params)
+		var p struct {
+			Start int64 `json:"starting_block_num"`
+			Count int64 `json:"count"`
+		}
+		_ = json.Unmarshal(params, &p)
+		blocks := []any{}
+		for n := p.Start; n < p.Start+p.Count && n <= s.head; n++ {
+			blocks = append(blocks, stubBlock(n))
+		}
+		resp["result"] = map[string]any{"blocks": blocks}
+	default:
+		resp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+	}
+	return resp
+}
+
+func stubBlock(number int64) map[string]any {
+	return map[string]any{
+		"block_id":  fmt.Sprintf("%08x", number),
+		"previous":  fmt.Sprintf("%08x", number-1),
+		"timestamp": "2024-01-01T00:00:00",
+		"transactions": []any{map[string]any{"operations": []any{
+			map[string]any{"type": "custom_json_operation", "value": map[string]any{"id": "hivemoji"}},
+		}}},
+	}
+}
+
+func TestGetBlockRange(t *testing.T) {
+	node := &stubNode{head: 250}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	client := NewClient([]string{srv.URL})
+	blocks, err := client.GetBlockRange(context.Background(), 101, 200)
+	if err != nil {
+		t.Fatalf("GetBlockRange: %v", err)
+	}
+	if len(blocks) != 100 {
+		t.Fatalf("expected 100 blocks, got %d", len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Number != 101+int64(i) {
+			t.Fatalf("expected blocks in order, got %d at index %d", block.Number, i)
+		}
+		if block.ID != fmt.Sprintf("%08x", block.Number) || block.Previous != fmt.Sprintf("%08x", block.Number-1) {
+			t.Fatalf("expected block %d to carry its id and parent id, got %q and %q", block.Number, block.ID, block.Previous)
+		}
+	}
+	if n := node.requests.Load(); n != 1 {
+		t.Fatalf("expected a single round-trip, got %d", n)
+	}
+
+	// The tail past the head is left off rather than failing the range.
+	blocks, err = client.GetBlockRange(context.Background(), 241, 340)
+	if err != nil {
+		t.Fatalf("GetBlockRange: %v", err)
+	}
+	if len(blocks) != 10 || blocks[9].Number != 250 {
+		t.Fatalf("expected blocks 241-250, got %d blocks", len(blocks))
+	}
+	blocks, err = client.GetBlockRange(context.Background(), 251, 260)
+	if err != nil || len(blocks) != 0 {
+		t.Fatalf("expected no blocks past the head, got %d, %v", len(blocks), err)
+	}
+}
+
+func TestHeadAndBlocks_Batched(t *testing.T) {
+	node := &stubNode{head: 105}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	client := NewClient([]string{srv.URL})
+	head, blocks, err := client.HeadAndBlocks(context.Background(), 100, 10)
+	if err != nil {
+		t.Fatalf("HeadAndBlocks: %v", err)
+	}
+	if head != 105 {
+		t.Fatalf("expected head 105, got %d", head)
+	}
+	if len(blocks) != 6 || blocks[0].Number != 100 || blocks[5].Number != 105 {
+		t.Fatalf("expected blocks 100-105, got %d blocks", len(blocks))
+	}
+	if ops := blocks[0].Transactions[0].Operations; len(ops) != 1 || ops[0].Type != "custom_json" {
+		t.Fatalf("expected one custom_json op, got %+v", ops)
+	}
+	if n := node.requests.Load(); n != 1 {
+		t.Fatalf("expected a single round-trip, got %d", n)
+	}
+}
+
+func TestHeadAndBlocks_FallsBackWithoutBatch(t *testing.T) {
+	node := &stubNode{head: 102, noBatch: true}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	client := NewClient([]string{srv.URL})
+	head, blocks, err := client.HeadAndBlocks(context.Background(), 100, 5)
+	if err != nil {
+		t.Fatalf("HeadAndBlocks: %v", err)
+	}
+	if head != 102 || len(blocks) != 3 {
+		t.Fatalf("expected head 102 and 3 blocks, got head %d and %d blocks", head, len(blocks))
+	}
+	// The rejected batch, then the head and the block range.
+	if n := node.requests.Load(); n != 3 {
+		t.Fatalf("expected 3 round-trips, got %d", n)
+	}
+
+	node.requests.Store(0)
+	if _, _, err := client.HeadAndBlocks(context.Background(), 100, 1); err != nil {
+		t.Fatalf("HeadAndBlocks: %v", err)
+	}
+	if n := node.requests.Load(); n != 2 {
+		t.Fatalf("expected batching to stay off for the endpoint, got %d round-trips", n)
+	}
+}
+
+func TestHeadAndBlocks_RPCError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":0,"error":{"code":-32003,"message":"overloaded"}},{"jsonrpc":"2.0","id":1,"result":{}}]`))
+	}))
+	defer srv.Close()
+
+	client := NewClient([]string{srv.URL})
+	if _, _, err := client.HeadAndBlocks(context.Background(), 1, 1); err == nil {
+		t.Fatalf("expected the RPC error to be returned")
+	}
+	if !client.batchSupported() {
+		t.Fatalf("an RPC error must not disable batching")
+	}
+}
+
+// BenchmarkHeadAndBlocks compares catching up on 20 blocks with one batch against separate
+// head and block range calls; round-trips/op shows the reduction.
+func BenchmarkHeadAndBlocks(b *testing.B) {
+	for _, tc := range []struct {
+		name    string
+		noBatch bool
+	}{{"batched", false}, {"individual", true}} {
+		b.Run(tc.name, func(b *testing.B) {
+			node := &stubNode{head: 1_000_000, noBatch: tc.noBatch}
+			srv := httptest.NewServer(node)
+			defer srv.Close()
+			client := NewClient([]string{srv.URL})
+			if tc.noBatch {
+				client.disableBatch()
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := client.HeadAndBlocks(context.Background(), 100, 20); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(node.requests.Load())/float64(b.N), "round-trips/op")
+		})
+	}
+}
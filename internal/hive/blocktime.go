@@ -0,0 +1,56 @@
+package hive
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// blockSource is the subset of Client needed to search blocks by time.
+type blockSource interface {
+	GetBlock(ctx context.Context, number int64) (*Block, error)
+	HeadBlockNumber(ctx context.Context) (int64, error)
+}
+
+// BlockNumberAtTime returns the first block produced at or after t. Times before the
+// first block resolve to block 1 and times after the head resolve to the head block.
+func (c *Client) BlockNumberAtTime(ctx context.Context, t time.Time) (int64, error) {
+	return blockNumberAtTime(ctx, c, t)
+}
+
+// blockNumberAtTime binary-searches block timestamps, which increase monotonically with height.
+func blockNumberAtTime(ctx context.Context, src blockSource, t time.Time) (int64, error) {
+	head, err := src.HeadBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	lo, hi := int64(1), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ts, err := blockTime(ctx, src, mid)
+		if err != nil {
+			return 0, err
+		}
+		if ts.Before(t) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+func blockTime(ctx context.Context, src blockSource, number int64) (time.Time, error) {
+	block, err := src.GetBlock(ctx, number)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if block == nil {
+		return time.Time{}, fmt.Errorf("block %d not available", number)
+	}
+	if block.Timestamp.IsZero() {
+		return time.Time{}, fmt.Errorf("block %d has no timestamp", number)
+	}
+	return block.Timestamp, nil
+}
@@ -0,0 +1,73 @@
+package hive
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeChain produces one block every three seconds starting at genesis.
+type fakeChain struct {
+	genesis time.Time
+	head    int64
+	fetches int
+}
+
+func (f *fakeChain) GetBlock(ctx context.Context, number int64) (*Block, error) {
+	f.fetches++
+	if number < 1 || number > f.head {
+		return nil, nil
+	}
+	return &Block{Number: number, Timestamp: f.genesis.Add(time.Duration(number-1) * 3 * time.Second)}, nil
+}
+
+func (f *fakeChain) HeadBlockNumber(ctx context.Context) (int64, error) {
+	return f.head, nil
+}
+
+func TestBlockNumberAtTime(t *testing.T) {
+	genesis := time.Date(2020, 3, 20, 14, 0, 0, 0, time.UTC)
+	chain := &fakeChain{genesis: genesis, head: 90_000_000}
+
+	cases := []struct {
+		name string
+		at   time.Time
+		want int64
+	}{
+		{"before genesis", genesis.Add(-time.Hour), 1},
+		{"genesis", genesis, 1},
+		{"exact block time", genesis.Add(3 * 1000 * time.Second), 1001},
+		{"between blocks", genesis.Add(3*1000*time.Second + time.Second), 1002},
+		{"head", genesis.Add(3 * (90_000_000 - 1) * time.Second), 90_000_000},
+		{"after head", genesis.Add(3 * 100_000_000 * time.Second), 90_000_000},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain.fetches = 0
+			got, err := blockNumberAtTime(context.Background(), chain, tc.at)
+			if err != nil {
+				t.Fatalf("blockNumberAtTime error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected block %d, got %d", tc.want, got)
+			}
+			if chain.fetches > 30 {
+				t.Fatalf("expected a logarithmic number of fetches, got %d", chain.fetches)
+			}
+		})
+	}
+}
+
+// gappyChain reports a head it cannot serve blocks for.
+type gappyChain struct{ fakeChain }
+
+func (g *gappyChain) GetBlock(ctx context.Context, number int64) (*Block, error) {
+	return nil, nil
+}
+
+func TestBlockNumberAtTime_MissingBlock(t *testing.T) {
+	src := &gappyChain{fakeChain{genesis: time.Now(), head: 10}}
+	if _, err := blockNumberAtTime(context.Background(), src, time.Now()); err == nil {
+		t.Fatalf("expected error when a block cannot be fetched")
+	}
+}
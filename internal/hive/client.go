@@ -5,34 +5,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
-	hivego "github.com/deathwingtheboss/hivego"
 	"github.com/deathwingtheboss/hivego/types"
 )
 
-// Client wraps hivego RPC calls to a Hive node.
+// blockTimeLayout is the UTC timestamp format used in Hive block headers.
+const blockTimeLayout = "2006-01-02T15:04:05"
+
+// Client wraps hivego RPC calls to Hive nodes, failing over between the configured endpoints.
 type Client struct {
-	node *hivego.HiveRpcNode
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	opNames   map[string]string
+	now       func() time.Time
 }
 
-// NewClient builds a Hive RPC client using the given endpoint.
-func NewClient(baseURL string) *Client {
+// NewClient builds a Hive RPC client using the given endpoints, preferred in order. urls must
+// not be empty.
+func NewClient(urls []string) *Client {
 	return &Client{
-		node: hivego.NewHiveRpc(baseURL),
+		endpoints: newEndpoints(urls),
+		opNames:   defaultOpNames(),
+		now:       time.Now,
 	}
 }
 
+// Endpoint returns the RPC endpoint calls currently go to.
+func (c *Client) Endpoint() string {
+	return c.active().url
+}
+
 // GetBlock fetches a block by number. It returns (nil, nil) when the node has not produced the block yet.
 func (c *Client) GetBlock(ctx context.Context, number int64) (*Block, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	raw, err := c.node.GetBlock(int(number))
+	ep := c.active()
+	raw, err := ep.node.GetBlock(int(number))
+	c.observe(ep, err)
 	if err != nil {
 		return nil, fmt.Errorf("get block %d: %w", number, err)
 	}
 
+	return c.convertBlock(raw, number)
+}
+
+// convertBlock turns a node block into a Block. It returns (nil, nil) for the empty block a
+// node answers with when number has not been produced yet.
+func (c *Client) convertBlock(raw types.Block, number int64) (*Block, error) {
 	if raw.BlockID == "" {
 		// Not yet produced.
 		return nil, nil
@@ -40,35 +63,28 @@ func (c *Client) GetBlock(ctx context.Context, number int64) (*Block, error) {
 
 	block := Block{
 		Number:       int64(raw.BlockNumber),
+		ID:           raw.BlockID,
+		Previous:     raw.Previous,
 		Transactions: make([]Transaction, 0, len(raw.Transactions)),
 	}
 	if block.Number == 0 {
 		block.Number = number
 	}
+	if raw.Timestamp != "" {
+		ts, err := time.Parse(blockTimeLayout, raw.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("block %d timestamp: %w", number, err)
+		}
+		block.Timestamp = ts
+	}
 
+	opNames := c.enabledOps()
 	for _, tx := range raw.Transactions {
-		t := Transaction{}
-		for _, op := range tx.Operations {
-			if op.Type == "" {
-				continue
-			}
-
-			payload, err := json.Marshal(op.Value)
-			if err != nil {
-				return nil, fmt.Errorf("marshal op %s: %w", op.Type, err)
-			}
-
-			opType := op.Type
-			if opType == types.OperationType.CustomJson {
-				opType = "custom_json"
-			}
-
-			t.Operations = append(t.Operations, Operation{
-				Type:  opType,
-				Value: payload,
-			})
+		ops, err := convertOperations(tx.Operations, opNames)
+		if err != nil {
+			return nil, err
 		}
-		block.Transactions = append(block.Transactions, t)
+		block.Transactions = append(block.Transactions, Transaction{Operations: ops})
 	}
 
 	return &block, nil
@@ -80,11 +96,18 @@ func (c *Client) HeadBlockNumber(ctx context.Context) (int64, error) {
 		return 0, ctx.Err()
 	}
 
-	raw, err := c.node.GetDynamicGlobalProps()
+	ep := c.active()
+	raw, err := ep.node.GetDynamicGlobalProps()
+	c.observe(ep, err)
 	if err != nil {
 		return 0, fmt.Errorf("head block props: %w", err)
 	}
 
+	return parseHeadBlockNumber(raw)
+}
+
+// parseHeadBlockNumber reads head_block_number out of the dynamic global properties.
+func parseHeadBlockNumber(raw []byte) (int64, error) {
 	var props map[string]json.RawMessage
 	if err := json.Unmarshal(raw, &props); err != nil {
 		return 0, fmt.Errorf("decode global props: %w", err)
@@ -0,0 +1,101 @@
+package hive
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	hivego "github.com/deathwingtheboss/hivego"
+)
+
+// failoverThreshold is how many consecutive failed calls move the client off an endpoint.
+const failoverThreshold = 3
+
+// endpointCooldown is how long a failing endpoint is passed over before it is tried again.
+const endpointCooldown = time.Minute
+
+// endpoint is one configured RPC node and its recent health.
+type endpoint struct {
+	url  string
+	node *hivego.HiveRpcNode
+	// failures counts consecutive failed calls. Once it reaches failoverThreshold the endpoint
+	// is passed over until downUntil.
+	failures  int
+	downUntil time.Time
+	// noBatch is set once the endpoint rejected a JSON-RPC batch.
+	noBatch bool
+}
+
+func newEndpoints(urls []string) []*endpoint {
+	eps := make([]*endpoint, 0, len(urls))
+	for _, url := range urls {
+		eps = append(eps, &endpoint{url: url, node: hivego.NewHiveRpc(url)})
+	}
+	return eps
+}
+
+// Endpoints returns the configured RPC endpoints in order of preference.
+func (c *Client) Endpoints() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	urls := make([]string, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+// SetEndpoints replaces the RPC endpoints used by subsequent calls, forgetting their health.
+// It reports whether the list changed; an empty list is ignored.
+func (c *Client) SetEndpoints(urls []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := make([]string, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		current[i] = ep.url
+	}
+	if len(urls) == 0 || slices.Equal(urls, current) {
+		return false
+	}
+	c.endpoints = newEndpoints(urls)
+	return true
+}
+
+// active returns the endpoint calls go to: the first in configured order that isn't cooling
+// down, or the one whose cooldown ends first when all are. A node that has been failing is
+// thus retried once its cooldown ends, and the preferred node is used again once it recovers.
+func (c *Client) active() *endpoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now()
+	var next *endpoint
+	for _, ep := range c.endpoints {
+		if !now.Before(ep.downUntil) {
+			return ep
+		}
+		if next == nil || ep.downUntil.Before(next.downUntil) {
+			next = ep
+		}
+	}
+	return next
+}
+
+// observe records the outcome of a call to ep. After failoverThreshold consecutive failures
+// ep cools down and calls move on to the next endpoint.
+func (c *Client) observe(ep *endpoint, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		ep.failures = 0
+		return
+	}
+	ep.failures++
+	if ep.failures < failoverThreshold {
+		return
+	}
+	ep.failures = 0
+	ep.downUntil = c.now().Add(endpointCooldown)
+	if len(c.endpoints) > 1 {
+		log.Printf("hive rpc %s failed %d calls in a row (last: %v); passing it over for %s", ep.url, failoverThreshold, err, endpointCooldown)
+	}
+}
@@ -0,0 +1,69 @@
+package hive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_FailsOverToHealthyEndpoint(t *testing.T) {
+	var brokenCalls atomic.Int64
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenCalls.Add(1)
+		http.Error(w, "node down", http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	node := &stubNode{head: 250}
+	healthy := httptest.NewServer(node)
+	defer healthy.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := NewClient([]string{broken.URL, healthy.URL})
+	client.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < failoverThreshold; i++ {
+		if _, err := client.HeadBlockNumber(ctx); err == nil {
+			t.Fatalf("call %d: expected the broken endpoint to fail", i+1)
+		}
+	}
+	if got := client.Endpoint(); got != healthy.URL {
+		t.Fatalf("expected failover to %s after %d failures, got %s", healthy.URL, failoverThreshold, got)
+	}
+	head, err := client.HeadBlockNumber(ctx)
+	if err != nil || head != 250 {
+		t.Fatalf("expected head 250 from the healthy endpoint, got %d, %v", head, err)
+	}
+	block, err := client.GetBlock(ctx, 100)
+	if err != nil || block == nil || block.Number != 100 {
+		t.Fatalf("expected block 100 from the healthy endpoint, got %+v, %v", block, err)
+	}
+	if _, blocks, err := client.HeadAndBlocks(ctx, 101, 10); err != nil || len(blocks) != 10 {
+		t.Fatalf("expected batched fetches from the healthy endpoint, got %d blocks, %v", len(blocks), err)
+	}
+	if n := brokenCalls.Load(); n != failoverThreshold {
+		t.Fatalf("expected the broken endpoint to be passed over, got %d calls", n)
+	}
+
+	// Once the cooldown ends the preferred endpoint is tried again.
+	now = now.Add(endpointCooldown)
+	if got := client.Endpoint(); got != broken.URL {
+		t.Fatalf("expected %s to be retried after its cooldown, got %s", broken.URL, got)
+	}
+}
+
+func TestClient_SetEndpoints(t *testing.T) {
+	client := NewClient([]string{"http://a", "http://b"})
+	if client.SetEndpoints([]string{"http://a", "http://b"}) {
+		t.Fatalf("expected an unchanged list to report no change")
+	}
+	if client.SetEndpoints(nil) {
+		t.Fatalf("expected an empty list to be ignored")
+	}
+	if !client.SetEndpoints([]string{"http://b"}) || client.Endpoint() != "http://b" {
+		t.Fatalf("expected the new endpoint to be used, got %v", client.Endpoints())
+	}
+}
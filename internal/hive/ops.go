@@ -0,0 +1,105 @@
+package hive
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deathwingtheboss/hivego/types"
+)
+
+// opType describes how a node operation type is surfaced on Block.
+type opType struct {
+	name     string // short name the processor matches on
+	optional bool   // only normalized once enabled with EnableOps
+}
+
+// opTypes maps node operation types ("custom_json_operation") to the names used in Block.
+// Optional entries stay under their node name until enabled, so default output is unchanged.
+var opTypes = map[string]opType{
+	types.OperationType.CustomJson:             {name: "custom_json"},
+	types.OperationType.AccountCreate:          {name: "account_create", optional: true},
+	"account_create_with_delegation_operation": {name: "account_create_with_delegation", optional: true},
+	types.OperationType.CreateClaimedAccount:   {name: "create_claimed_account", optional: true},
+	types.OperationType.AccountUpdate:          {name: "account_update", optional: true},
+}
+
+// defaultOpNames returns the node-to-short name mapping used before any EnableOps call.
+func defaultOpNames() map[string]string {
+	names := make(map[string]string)
+	for nodeType, t := range opTypes {
+		if !t.optional {
+			names[nodeType] = t.name
+		}
+	}
+	return names
+}
+
+// EnableOps normalizes the given optional operation types (by short name, e.g. "account_create")
+// in fetched blocks, on top of custom_json.
+func (c *Client) EnableOps(names ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	enabled := make(map[string]string, len(c.opNames)+len(names))
+	for nodeType, name := range c.opNames {
+		enabled[nodeType] = name
+	}
+	for _, name := range names {
+		nodeType, ok := nodeOpType(name)
+		if !ok {
+			return fmt.Errorf("unknown op type %q (supported: %s)", name, strings.Join(supportedOps(), ", "))
+		}
+		enabled[nodeType] = name
+	}
+	c.opNames = enabled
+	return nil
+}
+
+func (c *Client) enabledOps() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.opNames
+}
+
+func nodeOpType(name string) (string, bool) {
+	for nodeType, t := range opTypes {
+		if t.name == name {
+			return nodeType, true
+		}
+	}
+	return "", false
+}
+
+func supportedOps() []string {
+	out := make([]string, 0, len(opTypes))
+	for _, t := range opTypes {
+		out = append(out, t.name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// convertOperations turns node operations into Operations, renaming the types in names
+// and passing every other op through under its node type with its raw value.
+func convertOperations(ops []types.Operation, names map[string]string) ([]Operation, error) {
+	var out []Operation
+	for _, op := range ops {
+		if op.Type == "" {
+			continue
+		}
+
+		payload, err := json.Marshal(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("marshal op %s: %w", op.Type, err)
+		}
+
+		opType := op.Type
+		if name, ok := names[op.Type]; ok {
+			opType = name
+		}
+		out = append(out, Operation{Type: opType, Value: payload})
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package hive
+
+import (
+	"testing"
+
+	"github.com/deathwingtheboss/hivego/types"
+)
+
+func TestConvertOperations(t *testing.T) {
+	ops := []types.Operation{
+		{Type: "custom_json_operation", Value: map[string]interface{}{"id": "hivemoji"}},
+		{Type: "account_create_operation", Value: map[string]interface{}{"new_account_name": "newbie"}},
+		{Type: "", Value: nil},
+		{Type: "vote_operation", Value: map[string]interface{}{"voter": "mrtats"}},
+	}
+
+	client := NewClient([]string{"http://localhost"})
+	got, err := convertOperations(ops, client.enabledOps())
+	if err != nil {
+		t.Fatalf("convertOperations error: %v", err)
+	}
+	want := []string{"custom_json", "account_create_operation", "vote_operation"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d ops, got %d", len(want), len(got))
+	}
+	for i, op := range got {
+		if op.Type != want[i] {
+			t.Fatalf("op %d: expected type %q by default, got %q", i, want[i], op.Type)
+		}
+	}
+	if string(got[1].Value) != `{"new_account_name":"newbie"}` {
+		t.Fatalf("expected raw op value to be preserved, got %s", got[1].Value)
+	}
+
+	if err := client.EnableOps("account_create"); err != nil {
+		t.Fatalf("EnableOps error: %v", err)
+	}
+	got, err = convertOperations(ops, client.enabledOps())
+	if err != nil {
+		t.Fatalf("convertOperations error: %v", err)
+	}
+	if got[0].Type != "custom_json" || got[1].Type != "account_create" || got[2].Type != "vote_operation" {
+		t.Fatalf("unexpected types after EnableOps: %q %q %q", got[0].Type, got[1].Type, got[2].Type)
+	}
+
+	if err := client.EnableOps("vote"); err == nil {
+		t.Fatalf("expected unknown op type to be rejected")
+	}
+}
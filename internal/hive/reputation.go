@@ -0,0 +1,97 @@
+package hive
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Account is the part of a Hive account's state hivemoji acts on.
+type Account struct {
+	Name string
+	// Reputation is the score Hive front ends display (25 for a new account); see ReputationScore.
+	Reputation float64
+	Created    time.Time
+}
+
+// ReputationScore converts a raw on-chain reputation into the displayed score: 25 for zero,
+// moving 9 points per order of magnitude above 10^9, and mirrored for negative values.
+func ReputationScore(raw int64) float64 {
+	if raw == 0 {
+		return 25
+	}
+	score := math.Max(math.Log10(math.Abs(float64(raw)))-9, 0)
+	if raw < 0 {
+		score = -score
+	}
+	return score*9 + 25
+}
+
+// Account fetches an account's reputation and creation time.
+func (c *Client) Account(ctx context.Context, account string) (Account, error) {
+	if ctx.Err() != nil {
+		return Account{}, ctx.Err()
+	}
+
+	ep := c.active()
+	accounts, err := ep.node.GetAccount([]string{account})
+	c.observe(ep, err)
+	if err != nil {
+		return Account{}, fmt.Errorf("get account %s: %w", account, err)
+	}
+	if len(accounts) == 0 {
+		return Account{}, fmt.Errorf("%w: %s", ErrAccountNotFound, account)
+	}
+	return Account{
+		Name:       accounts[0].Name,
+		Reputation: ReputationScore(accounts[0].Reputation),
+		Created:    time.Time(accounts[0].Created),
+	}, nil
+}
+
+// AccountCache caches accounts so repeated registers from one author don't hit the node.
+type AccountCache struct {
+	fetch func(ctx context.Context, account string) (Account, error)
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedAccount
+}
+
+type cachedAccount struct {
+	account Account
+	expires time.Time
+}
+
+// NewAccountCache builds a cache over client.Account that keeps entries for ttl.
+func NewAccountCache(client *Client, ttl time.Duration) *AccountCache {
+	return &AccountCache{
+		fetch:   client.Account,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedAccount),
+	}
+}
+
+// Account returns the cached account, fetching it when missing or stale.
+func (c *AccountCache) Account(ctx context.Context, account string) (Account, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[account]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.account, nil
+	}
+
+	acct, err := c.fetch(ctx, account)
+	if err != nil {
+		return Account{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[account] = cachedAccount{account: acct, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return acct, nil
+}
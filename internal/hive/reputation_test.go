@@ -0,0 +1,59 @@
+package hive
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReputationScore(t *testing.T) {
+	cases := []struct {
+		raw  int64
+		want float64
+	}{
+		{0, 25},
+		{1_000_000_000, 25},
+		{10_000_000_000, 34},
+		{1_000_000_000_000, 52},
+		{-10_000_000_000, 16},
+		{500, 25},
+	}
+	for _, tc := range cases {
+		if got := ReputationScore(tc.raw); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("ReputationScore(%d) = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestAccountCache(t *testing.T) {
+	calls := 0
+	now := time.Unix(1000, 0)
+	cache := &AccountCache{
+		fetch: func(ctx context.Context, account string) (Account, error) {
+			calls++
+			return Account{Name: account, Reputation: 50}, nil
+		},
+		ttl:     time.Minute,
+		now:     func() time.Time { return now },
+		entries: make(map[string]cachedAccount),
+	}
+
+	for i := 0; i < 2; i++ {
+		acct, err := cache.Account(context.Background(), "mrtats")
+		if err != nil || acct.Name != "mrtats" || acct.Reputation != 50 {
+			t.Fatalf("unexpected account %+v err %v", acct, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 fetch while fresh, got %d", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.Account(context.Background(), "mrtats"); err != nil {
+		t.Fatalf("Account error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected refetch after ttl, got %d fetches", calls)
+	}
+}
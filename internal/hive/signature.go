@@ -0,0 +1,124 @@
+package hive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	hivego "github.com/deathwingtheboss/hivego"
+	"github.com/decred/dcrd/dcrec/secp256k1/v2"
+)
+
+// ErrAccountNotFound is returned when the node does not know the requested account.
+var ErrAccountNotFound = errors.New("account not found")
+
+// RegisterMessage is the text an author signs to vouch for a registered image. It is
+// what Hive Keychain's signBuffer receives; the signature covers its sha256 digest.
+func RegisterMessage(author, name, checksum string) string {
+	return fmt.Sprintf("hivemoji:register:%s:%s:%s", author, name, checksum)
+}
+
+// VerifySignature checks a hex-encoded 65-byte compact secp256k1 signature over
+// sha256(message) and reports an error unless it recovers to one of keys.
+func VerifySignature(message, signature string, keys []string) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	if len(sig) != 65 {
+		return fmt.Errorf("signature must be 65 bytes, got %d", len(sig))
+	}
+
+	digest := sha256.Sum256([]byte(message))
+	pub, _, err := secp256k1.RecoverCompact(sig, digest[:])
+	if err != nil {
+		return fmt.Errorf("recover public key: %w", err)
+	}
+	recovered := hivego.GetPublicKeyString(pub)
+	if recovered == nil {
+		return errors.New("encode recovered public key")
+	}
+	for _, key := range keys {
+		if key == *recovered {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature key %s is not an account key", *recovered)
+}
+
+// PostingKeys fetches the public keys in an account's posting authority.
+func (c *Client) PostingKeys(ctx context.Context, account string) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	ep := c.active()
+	accounts, err := ep.node.GetAccount([]string{account})
+	c.observe(ep, err)
+	if err != nil {
+		return nil, fmt.Errorf("get account %s: %w", account, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, account)
+	}
+
+	var keys []string
+	for _, auth := range accounts[0].Posting.KeyAuths {
+		if len(auth) == 0 {
+			continue
+		}
+		if key, ok := auth[0].(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// KeyCache caches account posting keys so repeated registers don't hit the node.
+type KeyCache struct {
+	fetch func(ctx context.Context, account string) ([]string, error)
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedKeys
+}
+
+type cachedKeys struct {
+	keys    []string
+	expires time.Time
+}
+
+// NewKeyCache builds a cache over client.PostingKeys that keeps entries for ttl.
+func NewKeyCache(client *Client, ttl time.Duration) *KeyCache {
+	return &KeyCache{
+		fetch:   client.PostingKeys,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedKeys),
+	}
+}
+
+// PostingKeys returns the cached posting keys for account, fetching them when missing or stale.
+func (c *KeyCache) PostingKeys(ctx context.Context, account string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[account]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.keys, nil
+	}
+
+	keys, err := c.fetch(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[account] = cachedKeys{keys: keys, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return keys, nil
+}
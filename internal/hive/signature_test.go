@@ -0,0 +1,84 @@
+package hive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	hivego "github.com/deathwingtheboss/hivego"
+)
+
+const testWIF = "5JuMt237G3m3BaT7zH4YdoycUtbw4AEPy6DLdCrKAnFGAtXyQ1W"
+
+func signMessage(t *testing.T, message string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(message))
+	wif := testWIF
+	sig, err := hivego.SignDigest(digest[:], &wif)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+func testPublicKey(t *testing.T) string {
+	t.Helper()
+	kp, err := hivego.KeyPairFromWif(testWIF)
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	return *kp.GetPublicKeyString()
+}
+
+func TestVerifySignature(t *testing.T) {
+	msg := RegisterMessage("mrtats", "wave", "abc123")
+	sig := signMessage(t, msg)
+	pub := testPublicKey(t)
+
+	if err := VerifySignature(msg, sig, []string{"STMother", pub}); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+	if err := VerifySignature(RegisterMessage("mrtats", "wave", "def456"), sig, []string{pub}); err == nil {
+		t.Fatalf("expected signature over a different checksum to fail")
+	}
+	if err := VerifySignature(msg, sig, []string{"STMother"}); err == nil {
+		t.Fatalf("expected signature from an unlisted key to fail")
+	}
+	if err := VerifySignature(msg, "zz", []string{pub}); err == nil {
+		t.Fatalf("expected malformed signature to fail")
+	}
+}
+
+func TestKeyCache(t *testing.T) {
+	calls := 0
+	now := time.Unix(1000, 0)
+	cache := &KeyCache{
+		fetch: func(ctx context.Context, account string) ([]string, error) {
+			calls++
+			return []string{"STM" + account}, nil
+		},
+		ttl:     time.Minute,
+		now:     func() time.Time { return now },
+		entries: make(map[string]cachedKeys),
+	}
+
+	for i := 0; i < 2; i++ {
+		keys, err := cache.PostingKeys(context.Background(), "mrtats")
+		if err != nil || len(keys) != 1 || keys[0] != "STMmrtats" {
+			t.Fatalf("unexpected keys %v err %v", keys, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 fetch while fresh, got %d", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.PostingKeys(context.Background(), "mrtats"); err != nil {
+		t.Fatalf("PostingKeys error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected refetch after ttl, got %d fetches", calls)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Block represents the portion of a Hive block we care about.
 type Block struct {
-	Number       int64         `json:"-"`
+	Number    int64     `json:"-"`
+	Timestamp time.Time `json:"-"`
+	// ID and Previous are the block's id and its parent's, used to detect micro-forks.
+	ID           string        `json:"-"`
+	Previous     string        `json:"-"`
 	Transactions []Transaction `json:"transactions"`
 }
 
@@ -0,0 +1,172 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownFormat is returned by Inspect for data that isn't a PNG, GIF or WebP image.
+var ErrUnknownFormat = errors.New("not a png, gif or webp image")
+
+// Info is what an image's headers say about it.
+type Info struct {
+	Mime     string
+	Width    int
+	Height   int
+	Animated bool
+}
+
+// Inspect reads the dimensions and animation flag of a PNG, GIF or WebP image from its
+// headers without decoding pixels. Truncated or malformed headers yield an error; a panic
+// while parsing is recovered and reported as one, like Strip.
+func Inspect(data []byte) (info Info, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			info, err = Info{}, fmt.Errorf("inspect: parser panic: %v", r)
+		}
+	}()
+
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		return inspectPNG(data)
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return inspectGIF(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return inspectWebP(data)
+	default:
+		return Info{}, ErrUnknownFormat
+	}
+}
+
+// inspectPNG reads IHDR, which must come first, and looks for the APNG acTL chunk, which
+// must come before the first IDAT.
+func inspectPNG(data []byte) (Info, error) {
+	if len(data) < 8+8+13 || string(data[12:16]) != "IHDR" {
+		return Info{}, errors.New("png: missing IHDR")
+	}
+	info := Info{
+		Mime:   "image/png",
+		Width:  int(binary.BigEndian.Uint32(data[16:20])),
+		Height: int(binary.BigEndian.Uint32(data[20:24])),
+	}
+	for off := 8; ; {
+		if off+8 > len(data) {
+			return Info{}, errors.New("png: truncated before IDAT")
+		}
+		length := int(binary.BigEndian.Uint32(data[off:]))
+		switch string(data[off+4 : off+8]) {
+		case "acTL":
+			info.Animated = true
+			return info, nil
+		case "IDAT":
+			return info, nil
+		}
+		if length < 0 || length > len(data)-off-12 {
+			return Info{}, errors.New("png: truncated chunk")
+		}
+		off += 12 + length
+	}
+}
+
+// inspectGIF reads the logical screen size and walks the blocks, counting frames, up to the
+// trailer.
+func inspectGIF(data []byte) (Info, error) {
+	if len(data) < 13 {
+		return Info{}, errors.New("gif: truncated header")
+	}
+	info := Info{
+		Mime:   "image/gif",
+		Width:  int(binary.LittleEndian.Uint16(data[6:8])),
+		Height: int(binary.LittleEndian.Uint16(data[8:10])),
+	}
+	off := 13 + colorTableSize(data[10])
+	frames := 0
+	for {
+		if off >= len(data) {
+			return Info{}, errors.New("gif: truncated before trailer")
+		}
+		switch data[off] {
+		case 0x3B: // trailer
+			info.Animated = frames > 1
+			return info, nil
+		case 0x21: // extension: label, then sub-blocks
+			next, err := skipSubBlocks(data, off+2)
+			if err != nil {
+				return Info{}, err
+			}
+			off = next
+		case 0x2C: // image descriptor, optional local color table, LZW code size, sub-blocks
+			if off+10 > len(data) {
+				return Info{}, errors.New("gif: truncated image descriptor")
+			}
+			frames++
+			next, err := skipSubBlocks(data, off+10+colorTableSize(data[off+9])+1)
+			if err != nil {
+				return Info{}, err
+			}
+			off = next
+		default:
+			return Info{}, fmt.Errorf("gif: unexpected block 0x%02x", data[off])
+		}
+	}
+}
+
+// colorTableSize returns the byte size of the color table a GIF packed field announces.
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << (int(packed&0x07) + 1)
+}
+
+// skipSubBlocks returns the offset just past the sub-block sequence starting at off.
+func skipSubBlocks(data []byte, off int) (int, error) {
+	for {
+		if off >= len(data) {
+			return 0, errors.New("gif: truncated data sub-blocks")
+		}
+		size := int(data[off])
+		off++
+		if size == 0 {
+			return off, nil
+		}
+		off += size
+	}
+}
+
+// inspectWebP reads the first chunk: the VP8X canvas and animation flag for extended files,
+// otherwise the lossy (VP8) or lossless (VP8L) bitstream header.
+func inspectWebP(data []byte) (Info, error) {
+	if len(data) < 20 {
+		return Info{}, errors.New("webp: truncated header")
+	}
+	info := Info{Mime: "image/webp"}
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8X":
+		if len(chunk) < 10 {
+			return Info{}, errors.New("webp: truncated VP8X chunk")
+		}
+		info.Animated = chunk[0]&0x02 != 0
+		info.Width = int(uint32(chunk[4])|uint32(chunk[5])<<8|uint32(chunk[6])<<16) + 1
+		info.Height = int(uint32(chunk[7])|uint32(chunk[8])<<8|uint32(chunk[9])<<16) + 1
+	case "VP8 ":
+		if len(chunk) < 10 || !bytes.Equal(chunk[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return Info{}, errors.New("webp: truncated or invalid VP8 frame header")
+		}
+		info.Width = int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		info.Height = int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+	case "VP8L":
+		if len(chunk) < 5 || chunk[0] != 0x2f {
+			return Info{}, errors.New("webp: truncated or invalid VP8L header")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		info.Width = int(bits&0x3fff) + 1
+		info.Height = int(bits>>14&0x3fff) + 1
+	default:
+		return Info{}, fmt.Errorf("webp: unexpected first chunk %q", data[12:16])
+	}
+	return info, nil
+}
@@ -0,0 +1,115 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t testing.TB, w, h, frames int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, w, h), color.Palette{color.Black, color.White}))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	still := encodePNG(t, 4, 3)
+	ihdrEnd := len(pngSignature) + 12 + 13
+	apng := append(append(append([]byte{}, still[:ihdrEnd]...), pngChunk("acTL", make([]byte, 8))...), still[ihdrEnd:]...)
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x02 // animation
+	vp8x[4], vp8x[7] = 99, 49
+	lossless := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(lossless[1:], uint32(31)|uint32(15)<<14)
+	lossy := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 64, 0, 48, 0}
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{"png", still, Info{Mime: "image/png", Width: 4, Height: 3}},
+		{"apng", apng, Info{Mime: "image/png", Width: 4, Height: 3, Animated: true}},
+		{"gif", encodeGIF(t, 5, 6, 1), Info{Mime: "image/gif", Width: 5, Height: 6}},
+		{"animated gif", encodeGIF(t, 5, 6, 3), Info{Mime: "image/gif", Width: 5, Height: 6, Animated: true}},
+		{"webp extended", webpFile(webpChunk("VP8X", vp8x), webpChunk("ANIM", make([]byte, 6))), Info{Mime: "image/webp", Width: 100, Height: 50, Animated: true}},
+		{"webp lossless", webpFile(webpChunk("VP8L", lossless)), Info{Mime: "image/webp", Width: 32, Height: 16}},
+		{"webp lossy", webpFile(webpChunk("VP8 ", lossy)), Info{Mime: "image/webp", Width: 64, Height: 48}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Inspect(tc.data)
+			if err != nil {
+				t.Fatalf("Inspect error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestInspect_UnknownFormat(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("png"), []byte("<svg></svg>"), []byte("\xff\xd8\xff\xe0")} {
+		if _, err := Inspect(data); !errors.Is(err, ErrUnknownFormat) {
+			t.Fatalf("%q: expected ErrUnknownFormat, got %v", data, err)
+		}
+	}
+}
+
+func TestInspect_TruncatedHeadersFail(t *testing.T) {
+	inputs := map[string][]byte{
+		"png":  encodePNG(t, 2, 2),
+		"gif":  encodeGIF(t, 2, 2, 2),
+		"webp": webpFile(webpChunk("VP8X", make([]byte, 10))),
+	}
+	for name, full := range inputs {
+		// Every prefix that cuts into the signature's format must be reported, not guessed at.
+		minLen := len(pngSignature)
+		if name != "png" {
+			minLen = 12
+		}
+		for n := minLen; n < len(full); n++ {
+			info, err := Inspect(full[:n])
+			if name == "png" && err == nil && n >= len(pngSignature)+12+13+8 {
+				continue // IDAT reached: the header is complete even if pixel data is cut
+			}
+			if err == nil {
+				t.Fatalf("%s: expected an error for a %d of %d byte prefix, got %+v", name, n, len(full), info)
+			}
+		}
+	}
+}
+
+func FuzzInspect(f *testing.F) {
+	f.Add(encodePNG(f, 1, 1))
+	f.Add(encodeGIF(f, 1, 1, 2))
+	f.Add(webpFile(webpChunk("VP8X", make([]byte, 10))))
+	f.Add([]byte("GIF89a\x01\x00\x01\x00\xff"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Inspect(data)
+	})
+}
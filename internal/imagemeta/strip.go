@@ -0,0 +1,148 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngKeep lists ancillary PNG chunks needed to render the image faithfully.
+// Critical chunks (uppercase first letter) are always kept.
+var pngKeep = map[string]struct{}{
+	"tRNS": {}, // transparency
+	"gAMA": {}, // gamma
+	"cHRM": {}, // chromaticities
+	"sRGB": {}, // rendering intent
+	"acTL": {}, // APNG animation control
+	"fcTL": {}, // APNG frame control
+	"fdAT": {}, // APNG frame data
+}
+
+// webpDrop lists WebP chunks that carry metadata only, with their VP8X flag bit.
+var webpDrop = map[string]byte{
+	"ICCP": 0x20,
+	"EXIF": 0x08,
+	"XMP ": 0x04,
+}
+
+// Strip removes non-essential metadata (ICC profiles, EXIF, XMP, text and time chunks)
+// from PNG and WebP images while leaving pixel data untouched. Other mime types are
+// returned unchanged. An error is returned if the container is malformed; a panic while
+// parsing is recovered and reported as an error so a bad upload can never halt ingest.
+func Strip(data []byte, mime string) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("%s: parser panic: %v", mime, r)
+		}
+	}()
+
+	switch mime {
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	default:
+		return data, nil
+	}
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("png: missing signature")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	rest := data[len(pngSignature):]
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, errors.New("png: truncated chunk header")
+		}
+		length := binary.BigEndian.Uint32(rest[:4])
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, fmt.Errorf("png: chunk length %d exceeds data", length)
+		}
+		chunk := rest[:12+length]
+		typ := string(chunk[4:8])
+		if crc32.ChecksumIEEE(chunk[4:8+length]) != binary.BigEndian.Uint32(chunk[8+length:]) {
+			return nil, fmt.Errorf("png: bad crc in %s chunk", typ)
+		}
+		rest = rest[12+length:]
+
+		_, keep := pngKeep[typ]
+		if isCriticalPNGChunk(typ) || keep {
+			out = append(out, chunk...)
+		}
+		if typ == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, errors.New("png: missing IEND chunk")
+}
+
+func isCriticalPNGChunk(typ string) bool {
+	return typ[0] >= 'A' && typ[0] <= 'Z'
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.New("webp: missing RIFF/WEBP header")
+	}
+	riffSize := binary.LittleEndian.Uint32(data[4:8])
+	if uint64(riffSize)+8 > uint64(len(data)) {
+		return nil, errors.New("webp: truncated RIFF payload")
+	}
+
+	body := make([]byte, 0, riffSize)
+	body = append(body, "WEBP"...)
+	vp8xFlagsAt := -1
+	var cleared byte
+
+	rest := data[12 : 8+riffSize]
+	for len(rest) > 0 {
+		if len(rest) < 8 {
+			return nil, errors.New("webp: truncated chunk header")
+		}
+		fourCC := string(rest[:4])
+		size := binary.LittleEndian.Uint32(rest[4:8])
+		padded := uint64(size) + uint64(size&1)
+		if 8+padded > uint64(len(rest)) {
+			// The final chunk may omit its padding byte.
+			if 8+uint64(size) != uint64(len(rest)) {
+				return nil, fmt.Errorf("webp: chunk %q length %d exceeds data", fourCC, size)
+			}
+			padded = uint64(size)
+		}
+		chunk := rest[:8+padded]
+		rest = rest[8+padded:]
+
+		if flag, drop := webpDrop[fourCC]; drop {
+			cleared |= flag
+			continue
+		}
+		if fourCC == "VP8X" {
+			if size < 10 {
+				return nil, errors.New("webp: short VP8X chunk")
+			}
+			vp8xFlagsAt = len(body) + 8
+		}
+		body = append(body, chunk...)
+		if padded == uint64(size) && size&1 == 1 {
+			body = append(body, 0)
+		}
+	}
+
+	if vp8xFlagsAt >= 0 {
+		body[vp8xFlagsAt] &^= cleared
+	}
+
+	out := make([]byte, 8, 8+len(body))
+	copy(out, "RIFF")
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(body)))
+	return append(out, body...), nil
+}
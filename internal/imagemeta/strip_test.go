@@ -0,0 +1,189 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func pngChunk(typ string, payload []byte) []byte {
+	buf := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	copy(buf[4:8], typ)
+	buf = append(buf, payload...)
+	crc := crc32.ChecksumIEEE(buf[4:])
+	return binary.BigEndian.AppendUint32(buf, crc)
+}
+
+// pngWithMetadata encodes img and inserts iCCP, tEXt and eXIf chunks after IHDR.
+func pngWithMetadata(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	raw := buf.Bytes()
+	ihdrEnd := len(pngSignature) + 12 + 13
+
+	var out []byte
+	out = append(out, raw[:ihdrEnd]...)
+	out = append(out, pngChunk("iCCP", append([]byte("profile\x00\x00"), bytes.Repeat([]byte{0xAB}, 512)...))...)
+	out = append(out, pngChunk("tEXt", []byte("Author\x00someone@example.com"))...)
+	out = append(out, pngChunk("eXIf", bytes.Repeat([]byte{0x01}, 256))...)
+	out = append(out, raw[ihdrEnd:]...)
+	return out
+}
+
+func TestStripPNG_RemovesMetadataAndPreservesPixels(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 60), G: uint8(y * 80), B: 200, A: uint8(100 + x*30)})
+		}
+	}
+	input := pngWithMetadata(t, img)
+
+	out, err := Strip(input, "image/png")
+	if err != nil {
+		t.Fatalf("Strip error: %v", err)
+	}
+	if len(out) >= len(input) {
+		t.Fatalf("expected stripped png to be smaller: %d >= %d", len(out), len(input))
+	}
+	for _, typ := range []string{"iCCP", "tEXt", "eXIf"} {
+		if bytes.Contains(out, []byte(typ)) {
+			t.Fatalf("expected %s chunk to be removed", typ)
+		}
+	}
+
+	decoded, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode stripped png: %v", err)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			want := color.NRGBAModel.Convert(img.At(x, y))
+			got := color.NRGBAModel.Convert(decoded.At(x, y))
+			if want != got {
+				t.Fatalf("pixel (%d,%d) changed: want %v got %v", x, y, want, got)
+			}
+		}
+	}
+}
+
+func TestStripPNG_Malformed(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	input := pngWithMetadata(t, img)
+
+	if _, err := Strip(input[:len(input)-5], "image/png"); err == nil {
+		t.Fatalf("expected error for truncated png")
+	}
+	if _, err := Strip([]byte("not a png"), "image/png"); err == nil {
+		t.Fatalf("expected error for missing signature")
+	}
+}
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	buf := make([]byte, 8, 8+len(payload)+1)
+	copy(buf[:4], fourCC)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(payload)))
+	buf = append(buf, payload...)
+	if len(payload)%2 == 1 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	out := []byte("RIFF")
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(body)))
+	return append(out, body...)
+}
+
+func TestStripWebP_RemovesMetadataChunks(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x20 | 0x10 | 0x08 | 0x04 // ICC, alpha, EXIF, XMP
+	alph := []byte{0x00, 0x01, 0x02}
+	vp8l := []byte{0x2f, 0x00, 0x00, 0x00, 0x10, 0x07, 0x10, 0x11, 0x11, 0x88, 0x88}
+
+	input := webpFile(
+		webpChunk("VP8X", vp8x),
+		webpChunk("ICCP", bytes.Repeat([]byte{0xCC}, 300)),
+		webpChunk("ALPH", alph),
+		webpChunk("VP8L", vp8l),
+		webpChunk("EXIF", bytes.Repeat([]byte{0xEE}, 101)),
+		webpChunk("XMP ", []byte("<x:xmpmeta/>")),
+	)
+
+	out, err := Strip(input, "image/webp")
+	if err != nil {
+		t.Fatalf("Strip error: %v", err)
+	}
+
+	wantVP8X := append([]byte{}, vp8x...)
+	wantVP8X[0] = 0x10
+	want := webpFile(webpChunk("VP8X", wantVP8X), webpChunk("ALPH", alph), webpChunk("VP8L", vp8l))
+	if !bytes.Equal(out, want) {
+		t.Fatalf("unexpected stripped webp:\n got %x\nwant %x", out, want)
+	}
+	if len(out) >= len(input) {
+		t.Fatalf("expected stripped webp to be smaller")
+	}
+}
+
+func TestStripWebP_SimpleFormatUnchanged(t *testing.T) {
+	input := webpFile(webpChunk("VP8L", []byte{0x2f, 0x00, 0x00, 0x00, 0x10}))
+	out, err := Strip(input, "image/webp")
+	if err != nil {
+		t.Fatalf("Strip error: %v", err)
+	}
+	if !bytes.Equal(out, input) {
+		t.Fatalf("expected simple webp to be unchanged")
+	}
+}
+
+func TestStrip_OtherMimePassthrough(t *testing.T) {
+	in := []byte("GIF89a...")
+	out, err := Strip(in, "image/gif")
+	if err != nil || !bytes.Equal(out, in) {
+		t.Fatalf("expected gif passthrough, got %v %q", err, out)
+	}
+}
+
+func TestStrip_TruncatedInputsNeverPanic(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	inputs := map[string][]byte{
+		"image/png": pngWithMetadata(t, img),
+		"image/webp": webpFile(
+			webpChunk("VP8X", make([]byte, 10)),
+			webpChunk("EXIF", bytes.Repeat([]byte{0xEE}, 33)),
+			webpChunk("VP8L", []byte{0x2f, 0x00, 0x00, 0x00, 0x10}),
+		),
+	}
+	for mime, full := range inputs {
+		for n := 0; n < len(full); n++ {
+			// Errors are expected for most prefixes; the contract is only that Strip returns.
+			_, _ = Strip(full[:n], mime)
+		}
+	}
+}
+
+func FuzzStrip(f *testing.F) {
+	f.Add(pngWithMetadata(f, image.NewGray(image.Rect(0, 0, 1, 1))), "image/png")
+	f.Add(webpFile(webpChunk("VP8X", make([]byte, 10)), webpChunk("VP8L", []byte{0x2f})), "image/webp")
+	f.Add([]byte("RIFF\xff\xff\xff\xffWEBP"), "image/webp")
+	f.Fuzz(func(t *testing.T, data []byte, mime string) {
+		out, err := Strip(data, mime)
+		if err == nil && mime != "image/png" && mime != "image/webp" && !bytes.Equal(out, data) {
+			t.Fatalf("expected passthrough for %q", mime)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+// Package ingest shares the ingest loop's progress with the rest of the server.
+package ingest
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the ingest loop's progress, updated by the loop and read by diagnostics. It is
+// safe for concurrent use; a nil State ignores updates.
+type State struct {
+	mu   sync.Mutex
+	snap Snapshot
+}
+
+// Snapshot is a point-in-time copy of State.
+type Snapshot struct {
+	// CurrentBlock is the next block the loop will fetch.
+	CurrentBlock int64
+	// LastProcessed is the last block processed successfully; zero before the first one.
+	LastProcessed   int64
+	LastProcessedAt time.Time
+	// HeadBlock is the last head block number seen; zero until it has been read.
+	HeadBlock     int64
+	HeadCheckedAt time.Time
+	// ConsecutiveFailures counts fetch or process errors since the last processed block.
+	ConsecutiveFailures int
+	LastError           string
+	LastErrorAt         time.Time
+	StartedAt           time.Time
+}
+
+// CatchingUp reports whether the loop is known to be behind the head block.
+func (s Snapshot) CatchingUp() bool {
+	return s.HeadBlock > s.CurrentBlock
+}
+
+// NewState returns an empty State.
+func NewState() *State {
+	return &State{}
+}
+
+// Started records that the loop begins ingesting from block.
+func (s *State) Started(block int64) {
+	s.update(func(snap *Snapshot) {
+		snap.CurrentBlock = block
+		snap.StartedAt = time.Now()
+	})
+}
+
+// Head records the latest head block number.
+func (s *State) Head(block int64) {
+	s.update(func(snap *Snapshot) {
+		snap.HeadBlock = block
+		snap.HeadCheckedAt = time.Now()
+	})
+}
+
+// Processed records a successfully processed block and resets the failure count.
+func (s *State) Processed(block int64) {
+	s.update(func(snap *Snapshot) {
+		snap.LastProcessed = block
+		snap.LastProcessedAt = time.Now()
+		snap.CurrentBlock = block + 1
+		snap.ConsecutiveFailures = 0
+		if block > snap.HeadBlock {
+			snap.HeadBlock = block
+		}
+	})
+}
+
+// Failed records an error fetching or processing the current block.
+func (s *State) Failed(err error) {
+	s.update(func(snap *Snapshot) {
+		snap.ConsecutiveFailures++
+		snap.LastError = err.Error()
+		snap.LastErrorAt = time.Now()
+	})
+}
+
+// Snapshot returns a copy of the current state.
+func (s *State) Snapshot() Snapshot {
+	if s == nil {
+		return Snapshot{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap
+}
+
+func (s *State) update(fn func(*Snapshot)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.snap)
+}
@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestState_TracksProgressAndFailures(t *testing.T) {
+	s := NewState()
+	s.Started(100)
+	s.Head(150)
+
+	snap := s.Snapshot()
+	if snap.CurrentBlock != 100 || snap.StartedAt.IsZero() || !snap.CatchingUp() {
+		t.Fatalf("unexpected state after start: %+v", snap)
+	}
+
+	s.Failed(errors.New("rpc timeout"))
+	s.Failed(errors.New("rpc timeout again"))
+	snap = s.Snapshot()
+	if snap.ConsecutiveFailures != 2 || snap.LastError != "rpc timeout again" || snap.LastErrorAt.IsZero() {
+		t.Fatalf("expected two recorded failures, got %+v", snap)
+	}
+
+	s.Processed(100)
+	snap = s.Snapshot()
+	if snap.CurrentBlock != 101 || snap.LastProcessed != 100 || snap.ConsecutiveFailures != 0 {
+		t.Fatalf("expected processing to advance and reset failures, got %+v", snap)
+	}
+	if snap.LastError != "rpc timeout again" {
+		t.Fatalf("expected the last error to be kept for diagnosis, got %q", snap.LastError)
+	}
+
+	s.Processed(150)
+	if s.Snapshot().CatchingUp() {
+		t.Fatalf("expected not to be catching up once past head")
+	}
+}
+
+func TestState_Concurrent(t *testing.T) {
+	s := NewState()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for b := int64(1); b <= 500; b++ {
+				s.Processed(b)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				_ = s.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Snapshot().LastProcessed; got < 1 || got > 500 {
+		t.Fatalf("unexpected last processed block %d", got)
+	}
+}
+
+func TestState_Nil(t *testing.T) {
+	var s *State
+	s.Processed(1)
+	if snap := s.Snapshot(); snap.LastProcessed != 0 {
+		t.Fatalf("expected a nil state to ignore updates, got %+v", snap)
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// API groups metrics reported by the HTTP API.
+type API struct {
+	imagesServed    *Counter
+	imagesMissed    *Counter
+	requests        *CounterVec
+	requestDuration *HistogramVec
+}
+
+// NewAPI registers API metrics on r.
+func NewAPI(r *Registry) *API {
+	return &API{
+		imagesServed:    r.Counter("hivemoji_images_served_total", "Raw emoji images served."),
+		imagesMissed:    r.Counter("hivemoji_image_misses_total", "Raw emoji image requests for missing or hidden emojis."),
+		requests:        r.CounterVec("hivemoji_http_requests_total", "HTTP requests by method, route and status code.", "method", "route", "status"),
+		requestDuration: r.HistogramVec("hivemoji_http_request_duration_seconds", "HTTP request duration by method and route.", DefaultBuckets, "method", "route"),
+	}
+}
+
+// ObserveRequest records one HTTP request. route must be the registered route pattern
+// (e.g. /api/authors/:author/emojis/:name), not the raw path, to keep label sets bounded.
+func (m *API) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	m.requests.With(method, route, strconv.Itoa(status)).Inc()
+	m.requestDuration.With(method, route).Observe(elapsed.Seconds())
+}
+
+// ObserveImage counts a raw image request by whether the emoji was found.
+func (m *API) ObserveImage(found bool) {
+	if found {
+		m.imagesServed.Inc()
+		return
+	}
+	m.imagesMissed.Inc()
+}
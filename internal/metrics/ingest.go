@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Ingest groups block-processing metrics reported by the processor and the ingest loop.
+type Ingest struct {
+	blockDuration  *Histogram
+	blocksScanned  *Counter
+	blocksWithOps  *Counter
+	decodeFailures *Counter
+	ops            *Counter
+	payloadBytes   *Counter
+	skippedOps     *CounterVec
+	assembledSets  *Counter
+	lastBlock      *Gauge
+	lag            *Gauge
+
+	// mu guards head and last, the inputs of lag.
+	mu   sync.Mutex
+	head int64
+	last int64
+}
+
+// NewIngest registers ingest metrics on r.
+func NewIngest(r *Registry) *Ingest {
+	return &Ingest{
+		blockDuration:  r.Histogram("hivemoji_block_process_seconds", "Time spent processing a single Hive block.", DefaultBuckets),
+		blocksScanned:  r.Counter("hivemoji_blocks_scanned_total", "Hive blocks processed, with or without hivemoji ops."),
+		blocksWithOps:  r.Counter("hivemoji_blocks_with_ops_total", "Hive blocks that carried at least one hivemoji op."),
+		decodeFailures: r.Counter("hivemoji_image_decode_failures_total", "Images whose container could not be parsed during ingest."),
+		ops:            r.Counter("hivemoji_ops_total", "Hivemoji custom_json ops seen."),
+		payloadBytes:   r.Counter("hivemoji_payload_bytes_total", "Bytes of hivemoji custom_json payloads seen."),
+		skippedOps:     r.CounterVec("hivemoji_ops_skipped_total", "Hivemoji ops rejected, by reason.", "reason"),
+		assembledSets:  r.Counter("hivemoji_chunk_sets_assembled_total", "v2 chunk sets that received their last chunk."),
+		lastBlock:      r.Gauge("hivemoji_last_processed_block", "Number of the last fully processed Hive block."),
+		lag:            r.Gauge("hivemoji_ingest_lag_blocks", "Blocks between the chain head and the last processed block."),
+	}
+}
+
+// ObserveBlock records a processed block: its number, how long it took and its hivemoji op volume.
+func (m *Ingest) ObserveBlock(number int64, d time.Duration, ops, bytes int) {
+	m.blockDuration.Observe(d.Seconds())
+	m.blocksScanned.Inc()
+	if ops > 0 {
+		m.blocksWithOps.Inc()
+	}
+	m.ops.Add(uint64(ops))
+	m.payloadBytes.Add(uint64(bytes))
+	m.lastBlock.Set(float64(number))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = number
+	m.updateLag()
+}
+
+// ObserveHead records the latest known head block number.
+func (m *Ingest) ObserveHead(head int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.head = head
+	m.updateLag()
+}
+
+// updateLag sets the lag gauge once both the head and a processed block are known. m.mu must be held.
+func (m *Ingest) updateLag() {
+	if m.head == 0 || m.last == 0 {
+		return
+	}
+	m.lag.Set(float64(max(m.head-m.last, 0)))
+}
+
+// SkippedOp counts a hivemoji op that was not applied, labelled with why.
+func (m *Ingest) SkippedOp(reason string) {
+	m.skippedOps.With(reason).Inc()
+}
+
+// ChunkSetAssembled counts a v2 chunk set completed by its last chunk.
+func (m *Ingest) ChunkSetAssembled() {
+	m.assembledSets.Inc()
+}
+
+// DecodeFailure counts an image that failed to parse.
+func (m *Ingest) DecodeFailure() {
+	m.decodeFailures.Inc()
+}
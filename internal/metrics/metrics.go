@@ -0,0 +1,315 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds suited to request and block timings.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds named metrics and renders them in the Prometheus text exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+type metric interface {
+	write(w *bufio.Writer, name string)
+}
+
+type described struct {
+	help string
+	typ  string
+	m    metric
+}
+
+func (d described) write(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, d.help, name, d.typ)
+	d.m.write(w, name)
+}
+
+// NewRegistry builds an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) register(name, help, typ string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.metrics[name]; exists {
+		panic(fmt.Sprintf("metrics: duplicate registration of %q", name))
+	}
+	r.metrics[name] = described{help: help, typ: typ, m: m}
+}
+
+// Histogram registers and returns a histogram with the given upper bounds.
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(buckets)
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+// Counter registers and returns a monotonically increasing counter.
+func (r *Registry) Counter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+// Gauge registers and returns a gauge that can go up and down.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	g := &Gauge{}
+	r.register(name, help, "gauge", g)
+	return g
+}
+
+// CounterVec registers and returns a family of counters partitioned by labels.
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{vec: newVec(labels, func() metric { return &Counter{} })}
+	r.register(name, help, "counter", v)
+	return v
+}
+
+// HistogramVec registers and returns a family of histograms partitioned by labels.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{vec: newVec(labels, func() metric { return newHistogram(buckets) })}
+	r.register(name, help, "histogram", v)
+	return v
+}
+
+// WriteText renders all metrics, sorted by name, in Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]metric, len(names))
+	for i, name := range names {
+		entries[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for i, name := range names {
+		entries[i].write(bw, name)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry in Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	return &Histogram{bounds: b, buckets: make([]uint64, len(b))}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer, name string) {
+	h.writeLabeled(w, name, "")
+}
+
+// writeLabeled renders the histogram with labels (already formatted as `k="v",...`) added to
+// every series.
+func (h *Histogram) writeLabeled(w *bufio.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	bucketLabels, series := "", ""
+	if labels != "" {
+		bucketLabels, series = labels+",", "{"+labels+"}"
+	}
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, bucketLabels, formatFloat(bound), h.buckets[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, bucketLabels, h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, series, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, series, h.count)
+}
+
+// Counter is a monotonically increasing count.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+func (c *Counter) write(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "%s %d\n", name, c.Value())
+}
+
+// vec holds one child metric per distinct combination of label values.
+type vec struct {
+	labels   []string
+	newChild func() metric
+
+	mu       sync.Mutex
+	children map[string]metric
+	values   map[string][]string
+}
+
+func newVec(labels []string, newChild func() metric) *vec {
+	return &vec{labels: labels, newChild: newChild, children: make(map[string]metric), values: make(map[string][]string)}
+}
+
+// child returns the metric for values, creating it on first use. It panics if the number of
+// values doesn't match the labels, like registering a duplicate name.
+func (v *vec) child(values []string) metric {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for %d labels", len(values), len(v.labels)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m, ok := v.children[key]
+	if !ok {
+		m = v.newChild()
+		v.children[key] = m
+		v.values[key] = append([]string(nil), values...)
+	}
+	return m
+}
+
+// each calls fn for every child in label order with its formatted labels.
+func (v *vec) each(fn func(labels string, m metric)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]metric, len(keys))
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		children[i] = v.children[key]
+		pairs := make([]string, len(v.labels))
+		for j, name := range v.labels {
+			pairs[j] = fmt.Sprintf("%s=%q", name, v.values[key][j])
+		}
+		labels[i] = strings.Join(pairs, ",")
+	}
+	v.mu.Unlock()
+
+	for i := range keys {
+		fn(labels[i], children[i])
+	}
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	vec *vec
+}
+
+// With returns the counter for the given label values, in label order.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.vec.child(values).(*Counter)
+}
+
+func (c *CounterVec) write(w *bufio.Writer, name string) {
+	c.vec.each(func(labels string, m metric) {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels, m.(*Counter).Value())
+	})
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	vec *vec
+}
+
+// With returns the histogram for the given label values, in label order.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.vec.child(values).(*Histogram)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer, name string) {
+	h.vec.each(func(labels string, m metric) {
+		m.(*Histogram).writeLabeled(w, name, labels)
+	})
+}
+
+// Gauge holds a value that can be set or adjusted.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the gauge value.
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Add adjusts the gauge by delta, which may be negative.
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		if g.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Value returns the current gauge value.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) write(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(g.Value()))
+}
+
+// formatFloat renders v compactly, keeping whole numbers such as block heights out of exponent form.
+func formatFloat(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("test_events_total", "Events seen.")
+	g := r.Gauge("test_in_flight", "Work in flight.")
+	h := r.Histogram("test_duration_seconds", "Work duration.", []float64{1, 0.5})
+
+	c.Add(3)
+	c.Inc()
+	g.Set(10)
+	g.Add(-2.5)
+	h.Observe(0.25)
+	h.Observe(0.75)
+	h.Observe(3)
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+
+	want := `# HELP test_duration_seconds Work duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.5"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 4
+test_duration_seconds_count 3
+# HELP test_events_total Events seen.
+# TYPE test_events_total counter
+test_events_total 4
+# HELP test_in_flight Work in flight.
+# TYPE test_in_flight gauge
+test_in_flight 7.5
+`
+	if out.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteText_Vecs(t *testing.T) {
+	r := NewRegistry()
+	c := r.CounterVec("test_requests_total", "Requests.", "route", "status")
+	h := r.HistogramVec("test_latency_seconds", "Latency.", []float64{1}, "route")
+
+	c.With("/b", "200").Inc()
+	c.With("/a", "404").Add(2)
+	c.With("/b", "200").Inc()
+	h.With("/a").Observe(0.5)
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+
+	want := `# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{route="/a",le="1"} 1
+test_latency_seconds_bucket{route="/a",le="+Inf"} 1
+test_latency_seconds_sum{route="/a"} 0.5
+test_latency_seconds_count{route="/a"} 1
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="/a",status="404"} 2
+test_requests_total{route="/b",status="200"} 2
+`
+	if out.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("test_total", "Total.")
+	g := r.Gauge("test_gauge", "Gauge.")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc()
+				g.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.Value() != 8000 || g.Value() != 8000 {
+		t.Fatalf("expected 8000/8000, got %d/%v", c.Value(), g.Value())
+	}
+}
+
+func TestIngest(t *testing.T) {
+	r := NewRegistry()
+	m := NewIngest(r)
+	m.ObserveHead(101482220)
+	m.ObserveBlock(101482211, 5*time.Millisecond, 0, 0)
+	m.ObserveBlock(101482212, 40*time.Millisecond, 2, 512)
+	m.SkippedOp("bad_mime")
+	m.SkippedOp("bad_mime")
+	m.SkippedOp("unsupported_version")
+	m.ChunkSetAssembled()
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+	for _, line := range []string{
+		"hivemoji_last_processed_block 101482212\n",
+		"hivemoji_ops_total 2\n",
+		"hivemoji_payload_bytes_total 512\n",
+		"hivemoji_ops_skipped_total{reason=\"bad_mime\"} 2\n",
+		"hivemoji_ops_skipped_total{reason=\"unsupported_version\"} 1\n",
+		"hivemoji_chunk_sets_assembled_total 1\n",
+		"hivemoji_ingest_lag_blocks 8\n",
+		"hivemoji_block_process_seconds_count 2\n",
+		"hivemoji_blocks_scanned_total 2\n",
+		"hivemoji_blocks_with_ops_total 1\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("expected %q in exposition:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestUploads(t *testing.T) {
+	r := NewRegistry()
+	m := NewUploads(r)
+	m.ObserveUploads(3, 42, 90*time.Second)
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText error: %v", err)
+	}
+	for _, line := range []string{
+		"hivemoji_uploads_in_flight 3\n",
+		"hivemoji_chunks_stored 42\n",
+		"hivemoji_oldest_incomplete_upload_seconds 90\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("expected %q in exposition:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import "time"
+
+// Uploads groups gauges describing incomplete v2 chunk uploads.
+type Uploads struct {
+	inFlight  *Gauge
+	oldestAge *Gauge
+	chunks    *Gauge
+}
+
+// NewUploads registers upload metrics on r.
+func NewUploads(r *Registry) *Uploads {
+	return &Uploads{
+		inFlight:  r.Gauge("hivemoji_uploads_in_flight", "Chunk uploads that have not completed yet."),
+		oldestAge: r.Gauge("hivemoji_oldest_incomplete_upload_seconds", "Seconds since the stalest incomplete upload last received a chunk."),
+		chunks:    r.Gauge("hivemoji_chunks_stored", "Chunks currently stored, complete or not."),
+	}
+}
+
+// ObserveUploads records the latest upload aggregate; oldestAge is zero when nothing is in flight.
+func (m *Uploads) ObserveUploads(inFlight, chunks int64, oldestAge time.Duration) {
+	m.inFlight.Set(float64(inFlight))
+	m.chunks.Set(float64(chunks))
+	m.oldestAge.Set(oldestAge.Seconds())
+}
@@ -0,0 +1,106 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Moderation statuses stored alongside each asset. Only approved assets are publicly served.
+const (
+	StatusApproved = "approved"
+	StatusPending  = "pending"
+	StatusBlocked  = "blocked"
+)
+
+// ValidStatus reports whether status is a known moderation status.
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusApproved, StatusPending, StatusBlocked:
+		return true
+	}
+	return false
+}
+
+// Request describes an image submitted for scanning.
+type Request struct {
+	Author string
+	Name   string
+	Mime   string
+	Data   []byte
+}
+
+// Client posts decoded images to an external content-scanning webhook.
+type Client struct {
+	url     string
+	retries int
+	backoff time.Duration
+	http    *http.Client
+}
+
+// NewClient builds a webhook client. Each attempt is bounded by timeout and failed
+// attempts are retried up to retries additional times.
+func NewClient(url string, timeout time.Duration, retries int) *Client {
+	return &Client{
+		url:     url,
+		retries: retries,
+		backoff: 500 * time.Millisecond,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Scan submits the image and returns the verdict reported by the webhook.
+func (c *Client) Scan(ctx context.Context, req Request) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(c.backoff * time.Duration(attempt)):
+			}
+		}
+
+		status, err := c.scanOnce(ctx, req)
+		if err == nil {
+			return status, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("moderation webhook failed after %d attempts: %w", c.retries+1, lastErr)
+}
+
+func (c *Client) scanOnce(ctx context.Context, req Request) (string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(req.Data))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", req.Mime)
+	httpReq.Header.Set("X-Hivemoji-Author", req.Author)
+	httpReq.Header.Set("X-Hivemoji-Name", req.Name)
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var verdict struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&verdict); err != nil {
+		return "", fmt.Errorf("decode verdict: %w", err)
+	}
+	if !ValidStatus(verdict.Status) {
+		return "", fmt.Errorf("unknown verdict status %q", verdict.Status)
+	}
+	return verdict.Status, nil
+}
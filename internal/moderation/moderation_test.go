@@ -0,0 +1,68 @@
+package moderation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScan_RetriesThenSucceeds(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "png" || r.Header.Get("Content-Type") != "image/png" || r.Header.Get("X-Hivemoji-Author") != "mrtats" {
+			t.Errorf("unexpected request: body=%q headers=%v", body, r.Header)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"blocked"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, time.Second, 2)
+	c.backoff = time.Millisecond
+
+	status, err := c.Scan(context.Background(), Request{Author: "mrtats", Name: "wave", Mime: "image/png", Data: []byte("png")})
+	if err != nil {
+		t.Fatalf("Scan error: %v", err)
+	}
+	if status != StatusBlocked {
+		t.Fatalf("expected blocked, got %q", status)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls.Load())
+	}
+}
+
+func TestScan_TimeoutExhaustsRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"status":"approved"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, 5*time.Millisecond, 1)
+	c.backoff = time.Millisecond
+
+	if _, err := c.Scan(context.Background(), Request{Mime: "image/png", Data: []byte("png")}); err == nil {
+		t.Fatalf("expected timeout error")
+	}
+}
+
+func TestScan_RejectsUnknownVerdict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"maybe"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, time.Second, 0)
+	if _, err := c.Scan(context.Background(), Request{Mime: "image/png"}); err == nil {
+		t.Fatalf("expected error for unknown verdict")
+	}
+}
@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"hivemoji/internal/storage"
+)
+
+// opResume lets a retried block skip the ops an earlier, failed attempt already applied. Each
+// applied op is checkpointed by its position in the block; ops are applied at least once, as
+// an op whose checkpoint couldn't be written is applied again on retry.
+type opResume struct {
+	block      int64
+	loaded     bool
+	checkpoint storage.OpCheckpoint
+	// dirty is set once the stored checkpoint refers to this block and must be cleared when
+	// the block completes, so a deliberate replay later applies every op again.
+	dirty bool
+}
+
+// applied reports whether the op at (tx, op) was applied by an earlier attempt at the block.
+// The checkpoint is read on the first call only, so blocks without hivemoji ops cost nothing.
+func (r *opResume) applied(ctx context.Context, p *Processor, tx, op int) (bool, error) {
+	if !r.loaded {
+		checkpoint, err := p.store.GetOpCheckpoint(ctx)
+		if err != nil {
+			return false, fmt.Errorf("block %d: read op checkpoint: %w", r.block, err)
+		}
+		r.loaded, r.checkpoint = true, checkpoint
+		r.dirty = checkpoint.Block == r.block
+	}
+	if !r.checkpoint.Covers(r.block, tx, op) {
+		return false, nil
+	}
+	log.Printf("block %d: skip op %d.%d already applied", r.block, tx, op)
+	return true, nil
+}
+
+// done checkpoints the op at (tx, op) as applied.
+func (r *opResume) done(ctx context.Context, p *Processor, tx, op int) error {
+	r.dirty = true
+	if err := p.store.SetOpCheckpoint(ctx, storage.OpCheckpoint{Block: r.block, Tx: tx, Op: op}); err != nil {
+		return fmt.Errorf("block %d: save op checkpoint: %w", r.block, err)
+	}
+	return nil
+}
+
+// finish clears the checkpoint of a completed block.
+func (r *opResume) finish(ctx context.Context, p *Processor) error {
+	if !r.dirty {
+		return nil
+	}
+	if err := p.store.SetOpCheckpoint(ctx, storage.OpCheckpoint{}); err != nil {
+		return fmt.Errorf("block %d: clear op checkpoint: %w", r.block, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxLicenseLen and maxAttributionLen bound the free-text license and attribution fields, in characters.
+	maxLicenseLen     = 64
+	maxAttributionLen = 256
+)
+
+// spdxLicenses maps the lowercased SPDX identifiers of licenses commonly used for artwork to
+// their canonical spelling.
+var spdxLicenses = func() map[string]string {
+	ids := []string{
+		"CC0-1.0",
+		"CC-BY-3.0", "CC-BY-4.0",
+		"CC-BY-SA-3.0", "CC-BY-SA-4.0",
+		"CC-BY-ND-4.0", "CC-BY-NC-4.0", "CC-BY-NC-SA-4.0", "CC-BY-NC-ND-4.0",
+		"MIT", "Apache-2.0", "OFL-1.1", "Unlicense",
+	}
+	m := make(map[string]string, len(ids))
+	for _, id := range ids {
+		m[strings.ToLower(id)] = id
+	}
+	return m
+}()
+
+// normalizeLicense trims a declared license and spells known SPDX identifiers canonically
+// (e.g. "cc-by-4.0" becomes "CC-BY-4.0"). Anything else is kept as free text, which must be
+// at most maxLicenseLen characters without control characters.
+func normalizeLicense(raw string) (string, bool) {
+	license := strings.TrimSpace(raw)
+	if id, ok := spdxLicenses[strings.ToLower(license)]; ok {
+		return id, true
+	}
+	return license, validFreeText(license, maxLicenseLen)
+}
+
+// normalizeAttribution trims a declared attribution, which must be at most maxAttributionLen
+// characters without control characters.
+func normalizeAttribution(raw string) (string, bool) {
+	attribution := strings.TrimSpace(raw)
+	return attribution, validFreeText(attribution, maxAttributionLen)
+}
+
+func validFreeText(s string, max int) bool {
+	if !utf8.ValidString(s) || utf8.RuneCountInString(s) > max {
+		return false
+	}
+	return strings.IndexFunc(s, unicode.IsControl) < 0
+}
@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"hivemoji/internal/imagemeta"
+	"hivemoji/internal/storage"
+)
+
+// MimeRule denies images of Mime that meet all of its other conditions; a rule with none
+// denies the mime outright. Rules narrow the allowed mimes further, e.g. to keep WebP images
+// over a size known to crash some clients out of storage.
+type MimeRule struct {
+	Mime string
+	// MaxBytes, when positive, limits the rule to images larger than this many bytes.
+	MaxBytes int
+	// Animated, when set, limits the rule to animated (true) or still (false) images.
+	Animated *bool
+}
+
+func (r MimeRule) String() string {
+	s := r.Mime
+	if r.MaxBytes > 0 {
+		s += fmt.Sprintf(",max_bytes=%d", r.MaxBytes)
+	}
+	if r.Animated != nil {
+		s += fmt.Sprintf(",animated=%t", *r.Animated)
+	}
+	return s
+}
+
+func (r MimeRule) matches(mime string, size int, animated bool) bool {
+	if mime != r.Mime {
+		return false
+	}
+	if r.MaxBytes > 0 && size <= r.MaxBytes {
+		return false
+	}
+	return r.Animated == nil || *r.Animated == animated
+}
+
+// ParseMimeRules parses a rule list such as "image/webp,max_bytes=1048576;image/gif,animated=true":
+// rules are separated by semicolons, and each is a mime followed by optional comma-separated
+// max_bytes and animated conditions.
+func ParseMimeRules(spec string) ([]MimeRule, error) {
+	var rules []MimeRule
+	for _, raw := range strings.Split(spec, ";") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		fields := strings.Split(raw, ",")
+		mime, ok := storage.NormalizeEmojiMime(fields[0])
+		if !ok {
+			return nil, fmt.Errorf("rule %q: %q is not a supported emoji mime", raw, strings.TrimSpace(fields[0]))
+		}
+		rule := MimeRule{Mime: mime}
+		seen := map[string]bool{}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+			if !ok {
+				return nil, fmt.Errorf("rule %q: condition %q must be key=value", raw, strings.TrimSpace(field))
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("rule %q: %s given twice", raw, key)
+			}
+			seen[key] = true
+			switch key {
+			case "max_bytes":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("rule %q: max_bytes must be a positive integer", raw)
+				}
+				rule.MaxBytes = n
+			case "animated":
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("rule %q: animated must be true or false", raw)
+				}
+				rule.Animated = &b
+			default:
+				return nil, fmt.Errorf("rule %q: unknown condition %q", raw, key)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchMimeRule returns the first rule that denies an image. Whether the image is animated is
+// read from data where the format allows, falling back to what its author declared.
+func matchMimeRule(rules []MimeRule, mime string, data []byte, declaredAnimated bool) (MimeRule, bool) {
+	if len(rules) == 0 {
+		return MimeRule{}, false
+	}
+	animated := declaredAnimated
+	if info, err := imagemeta.Inspect(data); err == nil {
+		animated = info.Animated
+	}
+	for _, rule := range rules {
+		if rule.matches(mime, len(data), animated) {
+			return rule, true
+		}
+	}
+	return MimeRule{}, false
+}
+
+// deniedImage reports whether a main image matches one of the MimeRules, in which case the op
+// is skipped.
+func (p *Processor) deniedImage(blockNum int64, author, name string, img imageCheck) bool {
+	rule, denied := matchMimeRule(p.opts.MimeRules, img.Mime, img.Data, img.Animated)
+	if denied {
+		log.Printf("block %d: skip name=%s author=%s bytes=%d matches mime rule %s", blockNum, name, safeAuthor(author), len(img.Data), rule)
+	}
+	return denied
+}
+
+// deniedFallback reports whether a fallback image matches one of the MimeRules, in which case
+// it is dropped and the main image stored alone.
+func (p *Processor) deniedFallback(blockNum int64, author, name, mime string, data []byte, animated bool, notes *ingestNotes) bool {
+	rule, denied := matchMimeRule(p.opts.MimeRules, mime, data, animated)
+	if denied {
+		notes.addf("fallback dropped: matches mime rule %s", rule)
+		log.Printf("block %d: drop fallback name=%s author=%s bytes=%d matches mime rule %s", blockNum, name, safeAuthor(author), len(data), rule)
+	}
+	return denied
+}
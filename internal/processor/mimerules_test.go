@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+func TestParseMimeRules(t *testing.T) {
+	rules, err := ParseMimeRules(" image/webp , max_bytes=1024 ; IMAGE/GIF,animated=true;image/png;")
+	if err != nil {
+		t.Fatalf("ParseMimeRules: %v", err)
+	}
+	var got []string
+	for _, r := range rules {
+		got = append(got, r.String())
+	}
+	if want := "image/webp,max_bytes=1024 image/gif,animated=true image/png"; strings.Join(got, " ") != want {
+		t.Fatalf("parsed %q, want %q", strings.Join(got, " "), want)
+	}
+
+	if rules, err := ParseMimeRules(""); err != nil || len(rules) != 0 {
+		t.Fatalf("expected no rules from an empty spec, got %v, %v", rules, err)
+	}
+
+	for _, spec := range []string{
+		"image/svg+xml",
+		"text/plain,max_bytes=1",
+		"image/webp,max_bytes=0",
+		"image/webp,max_bytes=big",
+		"image/webp,animated=maybe",
+		"image/webp,max_bytes",
+		"image/webp,width=10",
+		"image/webp,max_bytes=1,max_bytes=2",
+	} {
+		if _, err := ParseMimeRules(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestMatchMimeRule(t *testing.T) {
+	rules, err := ParseMimeRules("image/webp,max_bytes=4;image/gif,animated=true")
+	if err != nil {
+		t.Fatalf("ParseMimeRules: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		mime     string
+		data     []byte
+		animated bool
+		want     bool
+	}{
+		{"webp over the limit", "image/webp", []byte("12345"), false, true},
+		{"webp at the limit", "image/webp", []byte("1234"), false, false},
+		{"animated gif", "image/gif", []byte("not a gif"), true, true},
+		{"still gif", "image/gif", []byte("not a gif"), false, false},
+		{"other mime", "image/png", []byte("12345"), true, false},
+	}
+	for _, tc := range cases {
+		if _, got := matchMimeRule(rules, tc.mime, tc.data, tc.animated); got != tc.want {
+			t.Errorf("%s: denied=%t, want %t", tc.name, got, tc.want)
+		}
+	}
+
+	// Animation read from the image wins over the declaration.
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9), nil); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	if _, got := matchMimeRule(rules, "image/gif", buf.Bytes(), true); got {
+		t.Errorf("expected a single-frame GIF declared animated not to match")
+	}
+}
@@ -0,0 +1,22 @@
+package processor
+
+import "fmt"
+
+// ingestNotes collects what the processor changed or dropped while storing a payload, so
+// authors can see why the stored emoji differs from what they broadcast. A nil collector
+// discards notes.
+type ingestNotes []string
+
+func (n *ingestNotes) addf(format string, args ...any) {
+	if n == nil {
+		return
+	}
+	*n = append(*n, fmt.Sprintf(format, args...))
+}
+
+// mimeNormalized notes a declared mime that was rewritten to its canonical form.
+func (n *ingestNotes) mimeNormalized(declared, normalized string) {
+	if declared != normalized {
+		n.addf("mime %q normalized to %q", declared, normalized)
+	}
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"hivemoji/internal/storage"
+	"hivemoji/internal/webhook"
+)
+
+// notifyV1 reports a stored v1 register to the Notifier, if any.
+func (p *Processor) notifyV1(blockNum int64, reg storage.RegisterV1) {
+	if p.opts.Notifier == nil {
+		return
+	}
+	p.opts.Notifier.Notify(webhook.Event{
+		Author:              reg.Author,
+		Name:                reg.Name,
+		Variant:             reg.Variant,
+		Version:             1,
+		Mime:                reg.Mime,
+		Width:               reg.Width,
+		Height:              reg.Height,
+		Animated:            reg.Animated,
+		FallbackMime:        reg.FallbackMime,
+		ModerationStatus:    reg.ModerationStatus,
+		PendingConfirmation: reg.PendingConfirmation,
+		Block:               blockNum,
+		ExpiresAt:           reg.ExpiresAt,
+	})
+}
+
+// notifySet reports an assembled v2 upload stored from chunks to the Notifier, if any.
+func (p *Processor) notifySet(blockNum int64, main, fallback *storage.AssembledSet) {
+	if p.opts.Notifier == nil {
+		return
+	}
+	ev := webhook.Event{
+		Author:              main.Author,
+		Name:                main.Name,
+		Variant:             main.Variant,
+		Version:             main.Version,
+		Mime:                main.Mime,
+		Width:               main.Width,
+		Height:              main.Height,
+		Animated:            main.Animated,
+		Checksum:            main.Checksum,
+		UploadID:            main.UploadID,
+		ModerationStatus:    main.ModerationStatus,
+		PendingConfirmation: main.PendingConfirmation,
+		Block:               blockNum,
+		ExpiresAt:           main.ExpiresAt,
+	}
+	if fallback != nil {
+		ev.FallbackMime = fallback.Mime
+	}
+	p.opts.Notifier.Notify(ev)
+}
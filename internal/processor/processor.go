@@ -2,42 +2,156 @@ package processor
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"hivemoji/internal/encoding"
 	"hivemoji/internal/hive"
+	"hivemoji/internal/imagemeta"
+	"hivemoji/internal/moderation"
 	"hivemoji/internal/storage"
+	"hivemoji/internal/transcode"
+	"hivemoji/internal/webhook"
 )
 
 // Processor orchestrates Hive block processing into storage.
 type Processor struct {
 	store  store
 	client *hive.Client
+	opts   Options
+}
+
+// DefaultCustomJSONID is the custom_json id hivemoji ops are broadcast under.
+const DefaultCustomJSONID = "hivemoji"
+
+// Options tunes optional Processor behaviour.
+type Options struct {
+	// CustomJSONID selects which custom_json id is ingested; empty means DefaultCustomJSONID.
+	CustomJSONID string
+	// Scanner, when set, classifies images before they are stored.
+	Scanner Scanner
+	// ModerationFailClosed holds assets as pending when the scanner fails instead of approving them.
+	ModerationFailClosed bool
+	// StrictAuthors rejects ops whose author is not already lowercase instead of lowercasing it.
+	StrictAuthors bool
+	// StripMetadata removes ICC/EXIF/XMP and text chunks from PNG and WebP images before storage.
+	StripMetadata bool
+	// OptimizeGIF re-encodes GIFs with frame diffing and per-frame palettes, keeping the
+	// result only when it is smaller.
+	OptimizeGIF bool
+	// Metrics receives ingest instrumentation; nil disables it.
+	Metrics Metrics
+	// SlowBlockThreshold logs a warning for blocks that take longer to process; zero disables it.
+	SlowBlockThreshold time.Duration
+	// Keys resolves author posting keys for register signatures; nil skips verification.
+	Keys KeySource
+	// RequireSignature rejects registers that carry no valid author signature.
+	RequireSignature bool
+	// Accounts, when set, looks up authors for MinReputation; nil disables the reputation check.
+	Accounts AccountSource
+	// MinReputation skips registers and uploads from authors whose displayed reputation score
+	// is below it. It only applies when Accounts is set.
+	MinReputation float64
+	// MaxChunks rejects v2 chunks of uploads declaring more than this many chunks; zero means no limit.
+	MaxChunks int
+	// NamePrefix namespaces a deployment: only ops naming emojis that start with it are
+	// ingested, so instances with different prefixes can share one custom_json id.
+	NamePrefix string
+	// AuthorAllowlist, when non-empty, limits registers and uploads to these (lowercase)
+	// authors; deletes are still honoured so authors can clean up after being removed.
+	AuthorAllowlist []string
+	// Notifier, when set, is told about every emoji registration after it is stored.
+	Notifier Notifier
+	// Validation is the image validation level: ValidationOff, ValidationLenient (the
+	// default when empty) or ValidationStrict.
+	Validation string
+	// MaxImageBytes is the size gate for main images; zero disables it.
+	MaxImageBytes int
+	// ConfirmationDepth withholds newly stored emojis from public endpoints until this many
+	// further blocks have been processed, so a reorg can't leave them served; zero disables it.
+	ConfirmationDepth int64
+	// MimeRules skips registers and uploads whose main image matches one of the rules, and
+	// drops matching fallbacks; see ParseMimeRules.
+	MimeRules []MimeRule
+}
+
+// KeySource resolves the posting public keys of a Hive account.
+type KeySource interface {
+	PostingKeys(ctx context.Context, account string) ([]string, error)
+}
+
+// AccountSource resolves the state of a Hive account.
+type AccountSource interface {
+	Account(ctx context.Context, account string) (hive.Account, error)
+}
+
+// Metrics receives ingest instrumentation from the Processor.
+type Metrics interface {
+	ObserveBlock(number int64, d time.Duration, ops, bytes int)
+	SkippedOp(reason string)
+	ChunkSetAssembled()
+	DecodeFailure()
+}
+
+// Reasons passed to Metrics.SkippedOp.
+const (
+	skipInvalidAuthor      = "invalid_author"
+	skipInvalidPayload     = "invalid_payload"
+	skipBadMime            = "bad_mime"
+	skipBadBase64          = "bad_base64"
+	skipBadImage           = "bad_image"
+	skipUnsupportedVersion = "unsupported_version"
+)
+
+// Scanner classifies image content prior to storage.
+type Scanner interface {
+	Scan(ctx context.Context, req moderation.Request) (string, error)
+}
+
+// Notifier receives stored registrations, e.g. webhook.Notifier. Notify must not block.
+type Notifier interface {
+	Notify(ev webhook.Event) bool
 }
 
 // store defines the methods Processor needs from storage.Store.
 type store interface {
 	UpsertV1(ctx context.Context, payload storage.RegisterV1) error
 	DeleteEmoji(ctx context.Context, author, name string) error
+	DeleteVariant(ctx context.Context, author, name, variant string) error
+	UpsertManifest(ctx context.Context, m storage.Manifest) error
 	SaveChunk(ctx context.Context, chunk storage.ChunkPayload) (*storage.AssembledSet, error)
 	GetChunkSet(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error)
 	UpsertFromChunks(ctx context.Context, main *storage.AssembledSet, fallback *storage.AssembledSet) error
+	AssembleUpload(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error)
+	ConfirmAssets(ctx context.Context, block int64) (int64, error)
 	SetLastBlock(ctx context.Context, number int64) error
+	FindBannedChecksum(ctx context.Context, checksums []string) (string, error)
+	ReserveName(ctx context.Context, name, author string, block int64) (string, error)
+	TransferEmoji(ctx context.Context, name, from, to string, block int64) error
+	GetOpCheckpoint(ctx context.Context) (storage.OpCheckpoint, error)
+	SetOpCheckpoint(ctx context.Context, c storage.OpCheckpoint) error
 }
 
 // New builds a Processor.
-func New(store *storage.Store, client *hive.Client) *Processor {
-	return &Processor{store: store, client: client}
+func New(store *storage.Store, client *hive.Client, opts Options) *Processor {
+	return &Processor{store: store, client: client, opts: opts}
 }
 
-// ProcessBlock scans a block for hivemoji custom_json entries.
+// ProcessBlock scans a block for hivemoji custom_json entries. When it fails partway, applied
+// ops stay checkpointed and retrying the block resumes after them; see opResume.
 func (p *Processor) ProcessBlock(ctx context.Context, block *hive.Block) error {
-	for _, tx := range block.Transactions {
-		for _, op := range tx.Operations {
+	start := time.Now()
+	var ops, volume int
+	resume := opResume{block: block.Number}
+
+	for ti, tx := range block.Transactions {
+		for oi, op := range tx.Operations {
 			if op.Type != "custom_json" {
 				continue
 			}
@@ -47,7 +161,7 @@ func (p *Processor) ProcessBlock(ctx context.Context, block *hive.Block) error {
 				log.Printf("skip custom_json decode error: %v", err)
 				continue
 			}
-			if custom.ID != "hivemoji" {
+			if custom.ID != p.customJSONID() {
 				continue
 			}
 
@@ -57,165 +171,349 @@ func (p *Processor) ProcessBlock(ctx context.Context, block *hive.Block) error {
 				continue
 			}
 
-			author := firstNonEmpty(custom.RequiredPostingAuths, custom.RequiredAuths)
+			ops++
+			volume += len(payloadBytes)
 
+			author, err := p.normalizeAuthor(firstNonEmpty(custom.RequiredPostingAuths, custom.RequiredAuths))
+			if err != nil {
+				log.Printf("block %d: skip hivemoji op: %v", block.Number, err)
+				p.skippedOp(skipInvalidAuthor)
+				continue
+			}
+
+			applied, err := resume.applied(ctx, p, ti, oi)
+			if err != nil {
+				return err
+			}
+			if applied {
+				continue
+			}
 			if err := p.handlePayload(ctx, block.Number, payloadBytes, author); err != nil {
+				var invalid *ValidationError
+				if errors.As(err, &invalid) {
+					log.Printf("block %d: skip invalid hivemoji payload author=%s: %v", block.Number, safeAuthor(author), invalid)
+					p.skippedOp(invalid.skipReason())
+					continue
+				}
 				return fmt.Errorf("block %d: %w", block.Number, err)
 			}
+			if err := resume.done(ctx, p, ti, oi); err != nil {
+				return err
+			}
 		}
 	}
 
+	if err := p.confirmAssets(ctx, block.Number); err != nil {
+		return err
+	}
 	if err := p.store.SetLastBlock(ctx, block.Number); err != nil {
 		return err
 	}
+	if err := resume.finish(ctx, p); err != nil {
+		return err
+	}
+
+	p.observeBlock(block.Number, time.Since(start), ops, volume)
+	return nil
+}
+
+// inNamespace reports whether name belongs to this deployment's NamePrefix, logging ops that
+// belong to another namespace. Without a prefix every name does.
+func (p *Processor) inNamespace(blockNum int64, name, author string) bool {
+	prefix := p.opts.NamePrefix
+	if prefix == "" || (strings.HasPrefix(name, prefix) && len(name) > len(prefix)) {
+		return true
+	}
+	log.Printf("block %d: skip name=%s author=%s outside namespace %q", blockNum, name, safeAuthor(author), prefix)
+	return false
+}
+
+// authorAllowed reports whether author may register emojis under AuthorAllowlist, logging
+// skipped ops. Without an allowlist every author may.
+func (p *Processor) authorAllowed(blockNum int64, name, author string) bool {
+	if len(p.opts.AuthorAllowlist) == 0 {
+		return true
+	}
+	for _, allowed := range p.opts.AuthorAllowlist {
+		if author == allowed {
+			return true
+		}
+	}
+	log.Printf("block %d: skip name=%s author=%s not on author allowlist", blockNum, name, safeAuthor(author))
+	return false
+}
+
+// reputable reports whether author's reputation meets MinReputation, logging skipped ops.
+// Without an account source every author does. It returns an error only when the account
+// could not be fetched, so the block is retried.
+func (p *Processor) reputable(ctx context.Context, blockNum int64, name, author string) (bool, error) {
+	if p.opts.Accounts == nil {
+		return true, nil
+	}
+	acct, err := p.opts.Accounts.Account(ctx, author)
+	if errors.Is(err, hive.ErrAccountNotFound) {
+		log.Printf("block %d: skip name=%s author=%s: %v", blockNum, name, safeAuthor(author), err)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("account %s: %w", author, err)
+	}
+	if acct.Reputation < p.opts.MinReputation {
+		log.Printf("block %d: skip name=%s author=%s: reputation %.1f below %.1f", blockNum, name, safeAuthor(author), acct.Reputation, p.opts.MinReputation)
+		return false, nil
+	}
+	return true, nil
+}
+
+// confirmAssets promotes emojis whose register block is now ConfirmationDepth blocks deep.
+func (p *Processor) confirmAssets(ctx context.Context, blockNum int64) error {
+	depth := p.opts.ConfirmationDepth
+	if depth <= 0 || blockNum <= depth {
+		return nil
+	}
+	n, err := p.store.ConfirmAssets(ctx, blockNum-depth)
+	if err != nil {
+		return fmt.Errorf("block %d: confirm assets: %w", blockNum, err)
+	}
+	if n > 0 {
+		log.Printf("block %d: confirmed %d emojis registered at or before block %d", blockNum, n, blockNum-depth)
+	}
 	return nil
 }
 
+// pendingConfirmation reports whether an emoji stored from blockNum must wait for confirmation.
+// Block 0 marks admin re-assembly of chunks that are already on chain.
+func (p *Processor) pendingConfirmation(blockNum int64) bool {
+	return p.opts.ConfirmationDepth > 0 && blockNum > 0
+}
+
+func (p *Processor) customJSONID() string {
+	if p.opts.CustomJSONID != "" {
+		return p.opts.CustomJSONID
+	}
+	return DefaultCustomJSONID
+}
+
+func (p *Processor) skippedOp(reason string) {
+	if p.opts.Metrics != nil {
+		p.opts.Metrics.SkippedOp(reason)
+	}
+}
+
+// observeBlock reports block timing and warns when a block exceeds the slow threshold.
+func (p *Processor) observeBlock(number int64, elapsed time.Duration, ops, volume int) {
+	if p.opts.Metrics != nil {
+		p.opts.Metrics.ObserveBlock(number, elapsed, ops, volume)
+	}
+	if p.opts.SlowBlockThreshold > 0 && elapsed > p.opts.SlowBlockThreshold {
+		log.Printf("warn: slow block %d took %s (hivemoji ops=%d bytes=%d)", number, elapsed.Round(time.Millisecond), ops, volume)
+	}
+}
+
 func (p *Processor) handlePayload(ctx context.Context, blockNum int64, payload []byte, author string) error {
 	var env struct {
 		Version int    `json:"version"`
 		Op      string `json:"op"`
 	}
-	if err := json.Unmarshal(payload, &env); err != nil {
-		return fmt.Errorf("payload envelope: %w", err)
+	if err := decodePayload(payload, 0, &env); err != nil {
+		return err
 	}
 
 	log.Printf("block %d: hivemoji v%d op=%s author=%s", blockNum, env.Version, env.Op, safeAuthor(author))
 
-	switch env.Version {
-	case 1:
-		return p.handleV1(ctx, blockNum, payload, author)
-	case 2:
-		return p.handleV2(ctx, blockNum, payload, author)
-	default:
-		return fmt.Errorf("unsupported version %d", env.Version)
+	op := env.Op
+	if op == "" {
+		op = defaultOps[env.Version]
 	}
+	handle, err := lookupOp(env.Version, op)
+	if err != nil {
+		return err
+	}
+	return handle(p, ctx, blockNum, payload, author)
 }
 
-func (p *Processor) handleV1(ctx context.Context, blockNum int64, payload []byte, author string) error {
-	var msg struct {
-		Version  int             `json:"version"`
-		Op       string          `json:"op"`
-		Name     string          `json:"name"`
-		Mime     string          `json:"mime"`
-		Width    int             `json:"width"`
-		Height   int             `json:"height"`
-		Data     string          `json:"data"`
-		Animated bool            `json:"animated"`
-		Loop     json.RawMessage `json:"loop"`
-		Fallback *struct {
-			Mime string `json:"mime"`
-			Data string `json:"data"`
-		} `json:"fallback"`
+func (p *Processor) applyV1Register(ctx context.Context, blockNum int64, msg *v1Register, author string) error {
+	if !p.authorAllowed(blockNum, msg.Name, author) {
+		return nil
 	}
-
-	if err := json.Unmarshal(payload, &msg); err != nil {
-		return fmt.Errorf("decode v1: %w", err)
+	if ok, err := p.reputable(ctx, blockNum, msg.Name, author); !ok {
+		return err
+	}
+	mime, ok := storage.NormalizeEmojiMime(msg.Mime)
+	if !ok {
+		log.Printf(
+			"block %d: skip v1 register name=%s author=%s invalid mime=%q",
+			blockNum,
+			msg.Name,
+			safeAuthor(author),
+			msg.Mime,
+		)
+		p.skippedOp(skipBadMime)
+		return nil
 	}
 
-	switch msg.Op {
-	case "register":
-		mime, ok := storage.NormalizeEmojiMime(msg.Mime)
+	var notes ingestNotes
+	notes.mimeNormalized(msg.Mime, mime)
+
+	loop, err := parseLoop(msg.Loop)
+	if err != nil {
+		return &ValidationError{Version: 1, Op: "register", Field: "loop", Reason: err.Error()}
+	}
+	expiresAt, _ := parseExpiresAt(msg.ExpiresAt) // validated above
+	license, _ := normalizeLicense(msg.License)
+	attribution, _ := normalizeAttribution(msg.Attribution)
+	raw, _, err := encoding.DecodeImage(msg.Data)
+	if err != nil {
+		return &ValidationError{Version: 1, Op: "register", Field: "data", Reason: reasonNotBase64}
+	}
+	if msg.Bytes != nil && *msg.Bytes != len(raw) {
+		return &ValidationError{Version: 1, Op: "register", Field: "bytes", Reason: fmt.Sprintf("is %d but data decodes to %d bytes", *msg.Bytes, len(raw))}
+	}
+	img := imageCheck{Mime: mime, Data: raw, Width: msg.Width, Height: msg.Height, Animated: msg.Animated}
+	if !p.checkImage(blockNum, author, msg.Name, img, &notes) || p.deniedImage(blockNum, author, msg.Name, img) {
+		return nil
+	}
+	img = p.measured(blockNum, author, msg.Name, img)
+	sum := sha256.Sum256(raw)
+	ok, err = p.verifySignature(ctx, blockNum, author, msg.Name, hex.EncodeToString(sum[:]), msg.Signature)
+	if err != nil || !ok {
+		return err
+	}
+	var fallbackData []byte
+	var fallbackMime string
+	if msg.Fallback != nil {
+		normalizedFallback, ok := storage.NormalizeEmojiMime(msg.Fallback.Mime)
 		if !ok {
 			log.Printf(
-				"block %d: skip v1 register name=%s author=%s invalid mime=%q",
+				"block %d: skip v1 fallback name=%s author=%s invalid mime=%q",
 				blockNum,
 				msg.Name,
 				safeAuthor(author),
-				msg.Mime,
+				msg.Fallback.Mime,
 			)
-			return nil
-		}
-
-		loop, err := parseLoop(msg.Loop)
-		if err != nil {
-			return fmt.Errorf("loop: %w", err)
-		}
-		raw, err := base64.StdEncoding.DecodeString(msg.Data)
-		if err != nil {
-			return fmt.Errorf("decode v1 data: %w", err)
-		}
-		var fallbackData []byte
-		var fallbackMime string
-		if msg.Fallback != nil {
-			normalizedFallback, ok := storage.NormalizeEmojiMime(msg.Fallback.Mime)
-			if !ok {
-				log.Printf(
-					"block %d: skip v1 fallback name=%s author=%s invalid mime=%q",
-					blockNum,
-					msg.Name,
-					safeAuthor(author),
-					msg.Fallback.Mime,
-				)
-			} else {
-				fb, err := base64.StdEncoding.DecodeString(msg.Fallback.Data)
-				if err != nil {
-					return fmt.Errorf("decode fallback: %w", err)
-				}
+			notes.addf("fallback dropped: unsupported mime %q", msg.Fallback.Mime)
+		} else if !p.redundantFallback(blockNum, msg.Name, author, mime, normalizedFallback, &notes) {
+			fb, _, err := encoding.DecodeImage(msg.Fallback.Data)
+			if err != nil {
+				return &ValidationError{Version: 1, Op: "register", Field: "fallback.data", Reason: reasonNotBase64}
+			}
+			if !p.deniedFallback(blockNum, author, msg.Name, normalizedFallback, fb, false, &notes) {
 				fallbackData = fb
 				fallbackMime = normalizedFallback
 			}
 		}
+	}
 
-		log.Printf(
-			"block %d: v1 register name=%s author=%s animated=%t loop=%v bytes=%d fallback_bytes=%d",
-			blockNum,
-			msg.Name,
-			safeAuthor(author),
-			msg.Animated,
-			loop,
-			len(raw),
-			len(fallbackData),
-		)
+	log.Printf(
+		"block %d: v1 register name=%s author=%s animated=%t loop=%v bytes=%d fallback_bytes=%d",
+		blockNum,
+		msg.Name,
+		safeAuthor(author),
+		img.Animated,
+		loop,
+		len(raw),
+		len(fallbackData),
+	)
 
-		return p.store.UpsertV1(ctx, storage.RegisterV1{
-			Name:         msg.Name,
-			Author:       author,
-			Mime:         mime,
-			Width:        msg.Width,
-			Height:       msg.Height,
-			Data:         raw,
-			Animated:     msg.Animated,
-			Loop:         loop,
-			FallbackMime: fallbackMime,
-			FallbackData: fallbackData,
-		})
-
-	case "delete":
-		return p.store.DeleteEmoji(ctx, author, msg.Name)
-	default:
-		return fmt.Errorf("unknown v1 op %q", msg.Op)
+	uploaded := [][]byte{raw, fallbackData}
+	raw = p.optimizeGIF(blockNum, msg.Name, mime, p.stripMetadata(blockNum, msg.Name, mime, raw, &notes), &notes)
+	fallbackData = p.optimizeGIF(blockNum, msg.Name, fallbackMime, p.stripMetadata(blockNum, msg.Name, fallbackMime, fallbackData, &notes), &notes)
+	if banned, err := p.bannedImage(ctx, blockNum, author, msg.Name, append(uploaded, raw, fallbackData)...); err != nil || banned {
+		return err
 	}
+
+	status := p.moderate(ctx, blockNum, author, msg.Name, mime, raw, fallbackMime, fallbackData)
+
+	reg := storage.RegisterV1{
+		Name:                msg.Name,
+		Author:              author,
+		Mime:                mime,
+		Width:               img.Width,
+		Height:              img.Height,
+		Data:                raw,
+		Animated:            img.Animated,
+		Loop:                loop,
+		FallbackMime:        fallbackMime,
+		FallbackData:        fallbackData,
+		ExpiresAt:           expiresAt,
+		Cover:               msg.Cover,
+		License:             license,
+		Attribution:         attribution,
+		Variant:             msg.Variant,
+		ModerationStatus:    status,
+		IngestNotes:         notes,
+		RegisteredBlock:     blockNum,
+		PendingConfirmation: p.pendingConfirmation(blockNum),
+	}
+	if err := p.store.UpsertV1(ctx, reg); err != nil {
+		return err
+	}
+	p.notifyV1(blockNum, reg)
+	return nil
+}
+
+func (p *Processor) applyV1Delete(ctx context.Context, blockNum int64, msg *v1Delete, author string) error {
+	if msg.Variant != "" {
+		return p.store.DeleteVariant(ctx, author, msg.Name, msg.Variant)
+	}
+	return p.store.DeleteEmoji(ctx, author, msg.Name)
 }
 
-func (p *Processor) handleV2(ctx context.Context, blockNum int64, payload []byte, author string) error {
-	var msg struct {
-		Version  int             `json:"version"`
-		Op       string          `json:"op"`
-		ID       string          `json:"id"`
-		Name     string          `json:"name"`
-		Mime     string          `json:"mime"`
-		Width    int             `json:"width"`
-		Height   int             `json:"height"`
-		Animated bool            `json:"animated"`
-		Loop     json.RawMessage `json:"loop"`
-		Checksum string          `json:"checksum"`
-		Kind     string          `json:"kind"`
-		Seq      int             `json:"seq"`
-		Total    int             `json:"total"`
-		Data     string          `json:"data"`
+func (p *Processor) applyV1Reserve(ctx context.Context, blockNum int64, msg *v1Reserve, author string) error {
+	if !p.authorAllowed(blockNum, msg.Name, author) {
+		return nil
+	}
+	owner, err := p.store.ReserveName(ctx, msg.Name, author, blockNum)
+	if err != nil {
+		return err
 	}
+	switch owner {
+	case author:
+		log.Printf("block %d: v1 reserve name=%s author=%s", blockNum, msg.Name, safeAuthor(author))
+	case "":
+		log.Printf("block %d: skip v1 reserve name=%s author=%s not its first registrant", blockNum, msg.Name, safeAuthor(author))
+	default:
+		log.Printf("block %d: skip v1 reserve name=%s author=%s already reserved by %s", blockNum, msg.Name, safeAuthor(author), safeAuthor(owner))
+	}
+	return nil
+}
 
-	if err := json.Unmarshal(payload, &msg); err != nil {
-		return fmt.Errorf("decode v2: %w", err)
+func (p *Processor) applyV1Transfer(ctx context.Context, blockNum int64, msg *v1Transfer, author string) error {
+	// The op is signed by its broadcaster, so only the current owner can give an emoji away.
+	if msg.To == author {
+		log.Printf("block %d: skip v1 transfer name=%s author=%s to itself", blockNum, msg.Name, safeAuthor(author))
+		return nil
+	}
+	if !p.authorAllowed(blockNum, msg.Name, msg.To) {
+		return nil
 	}
+	err := p.store.TransferEmoji(ctx, msg.Name, author, msg.To, blockNum)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		log.Printf("block %d: skip v1 transfer name=%s author=%s no such emoji", blockNum, msg.Name, safeAuthor(author))
+	case errors.Is(err, storage.ErrNameTaken):
+		log.Printf("block %d: skip v1 transfer name=%s author=%s to=%s already has an emoji with that name", blockNum, msg.Name, safeAuthor(author), msg.To)
+	case err != nil:
+		return err
+	default:
+		log.Printf("block %d: v1 transfer name=%s author=%s to=%s", blockNum, msg.Name, safeAuthor(author), msg.To)
+	}
+	return nil
+}
 
-	if msg.Op != "chunk" && msg.Op != "register" && msg.Op != "" {
-		return fmt.Errorf("unsupported v2 op %q", msg.Op)
+func (p *Processor) applyV2(ctx context.Context, blockNum int64, msg *v2Message, author string) error {
+	if !p.authorAllowed(blockNum, msg.Name, author) {
+		return nil
+	}
+	if ok, err := p.reputable(ctx, blockNum, msg.Name, author); !ok {
+		return err
+	}
+	if max := p.opts.MaxChunks; max > 0 && msg.Total > max && !msg.isManifest() {
+		return &ValidationError{Version: 2, Op: "chunk", Field: "total", Reason: fmt.Sprintf("must be <= %d", max)}
 	}
 
-	if msg.Op == "register" && msg.Data == "" {
-		// Manifest-only entry for discovery; nothing to persist.
+	if msg.isManifest() {
+		// Manifest-only entry for discovery, listed as pending until the upload is stored.
 		log.Printf(
 			"block %d: v2 register manifest name=%s author=%s upload=%s animated=%t loop=%s",
 			blockNum,
@@ -225,7 +523,7 @@ func (p *Processor) handleV2(ctx context.Context, blockNum int64, payload []byte
 			msg.Animated,
 			string(msg.Loop),
 		)
-		return nil
+		return p.store.UpsertManifest(ctx, manifestOf(blockNum, msg, author))
 	}
 
 	kind := msg.Kind
@@ -243,51 +541,56 @@ func (p *Processor) handleV2(ctx context.Context, blockNum int64, payload []byte
 			kind,
 			msg.Mime,
 		)
-		return nil
-	}
-
-	if msg.Total <= 0 {
-		return errors.New("total must be > 0")
-	}
-
-	if msg.Seq <= 0 {
-		log.Printf(
-			"block %d: skip v2 chunk upload=%s kind=%s name=%s seq=%d (must be > 0)",
-			blockNum,
-			msg.ID,
-			msg.Kind,
-			msg.Name,
-			msg.Seq,
-		)
+		p.skippedOp(skipBadMime)
 		return nil
 	}
 
 	loop, err := parseLoop(msg.Loop)
 	if err != nil {
-		return fmt.Errorf("loop: %w", err)
+		return &ValidationError{Version: 2, Op: "chunk", Field: "loop", Reason: err.Error()}
+	}
+
+	if kind == "main" && msg.Seq == 1 {
+		// The first main chunk vouches for the whole upload: assembly rejects data that
+		// doesn't match the declared checksum, so signing the checksum covers every chunk.
+		ok, err := p.verifySignature(ctx, blockNum, author, msg.Name, msg.Checksum, msg.Signature)
+		if err != nil || !ok {
+			return err
+		}
 	}
 
-	data, err := base64.StdEncoding.DecodeString(msg.Data)
+	data, _, err := encoding.DecodeImage(msg.Data)
 	if err != nil {
-		return fmt.Errorf("decode v2 chunk: %w", err)
+		return &ValidationError{Version: 2, Op: "chunk", Field: "data", Reason: reasonNotBase64}
 	}
+	expiresAt, _ := parseExpiresAt(msg.ExpiresAt) // validated above
+	license, _ := normalizeLicense(msg.License)
+	attribution, _ := normalizeAttribution(msg.Attribution)
 
 	assembled, err := p.store.SaveChunk(ctx, storage.ChunkPayload{
-		ID:       msg.ID,
-		Author:   author,
-		Name:     msg.Name,
-		Version:  msg.Version,
-		Mime:     mime,
-		Width:    msg.Width,
-		Height:   msg.Height,
-		Animated: msg.Animated,
-		Loop:     loop,
-		Checksum: msg.Checksum,
-		Kind:     kind,
-		Seq:      msg.Seq,
-		Total:    msg.Total,
-		Data:     data,
+		ID:          msg.ID,
+		Author:      author,
+		Name:        msg.Name,
+		Version:     msg.Version,
+		Mime:        mime,
+		Width:       msg.Width,
+		Height:      msg.Height,
+		Animated:    msg.Animated,
+		Loop:        loop,
+		Checksum:    msg.Checksum,
+		Kind:        kind,
+		Seq:         msg.Seq,
+		Total:       msg.Total,
+		Data:        data,
+		ExpiresAt:   expiresAt,
+		Cover:       msg.Cover,
+		Variant:     msg.Variant,
+		License:     license,
+		Attribution: attribution,
 	})
+	if errors.Is(err, storage.ErrChunkTotal) {
+		return &ValidationError{Version: 2, Op: "chunk", Field: "total", Reason: err.Error()}
+	}
 	if err != nil {
 		return err
 	}
@@ -295,6 +598,9 @@ func (p *Processor) handleV2(ctx context.Context, blockNum int64, payload []byte
 	if assembled == nil {
 		return nil
 	}
+	if p.opts.Metrics != nil {
+		p.opts.Metrics.ChunkSetAssembled()
+	}
 
 	log.Printf(
 		"block %d: v2 assembled upload=%s kind=%s name=%s author=%s animated=%t loop=%v bytes=%d",
@@ -308,42 +614,321 @@ func (p *Processor) handleV2(ctx context.Context, blockNum int64, payload []byte
 		len(assembled.Data),
 	)
 
-	return p.handleCompletedSet(ctx, assembled)
+	return p.handleCompletedSet(ctx, blockNum, assembled)
+}
+
+// manifestOf describes a v2 register manifest for storage. A mime that doesn't normalize and
+// zero dimensions or totals are left unset.
+func manifestOf(blockNum int64, msg *v2Message, author string) storage.Manifest {
+	m := storage.Manifest{UploadID: msg.ID, Name: msg.Name, Author: author, Animated: msg.Animated, FirstBlock: &blockNum}
+	if mime, ok := storage.NormalizeEmojiMime(msg.Mime); ok {
+		m.Mime = &mime
+	}
+	if msg.Width > 0 && msg.Height > 0 {
+		m.Width, m.Height = &msg.Width, &msg.Height
+	}
+	if msg.Total > 0 {
+		m.Total = &msg.Total
+	}
+	return m
+}
+
+// CompleteUpload re-assembles a buffered chunk set and stores the result exactly as if its
+// last chunk had just been ingested, including stripping and moderation.
+func (p *Processor) CompleteUpload(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error) {
+	set, err := p.store.AssembleUpload(ctx, uploadID, kind)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("upload %s: re-assembled kind=%s name=%s author=%s bytes=%d", uploadID, kind, set.Name, safeAuthor(set.Author), len(set.Data))
+	// Block 0 marks log lines that come from an admin request rather than ingest.
+	if err := p.handleCompletedSet(ctx, 0, set); err != nil {
+		return nil, err
+	}
+	return set, nil
 }
 
-func (p *Processor) handleCompletedSet(ctx context.Context, set *storage.AssembledSet) error {
+func (p *Processor) handleCompletedSet(ctx context.Context, blockNum int64, set *storage.AssembledSet) error {
 	switch set.Kind {
 	case "main":
 		fallback, err := p.store.GetChunkSet(ctx, set.UploadID, "fallback")
-		if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			fallback = nil
+		} else if err != nil {
 			return err
 		}
-		return p.store.UpsertFromChunks(ctx, set, fallback)
+		var notes ingestNotes
+		if !p.checkImage(blockNum, set.Author, set.Name, setCheck(set), &notes) || p.deniedImage(blockNum, set.Author, set.Name, setCheck(set)) {
+			return nil
+		}
+		p.measureSet(blockNum, set)
+		if fallback != nil && (p.redundantFallback(blockNum, set.Name, set.Author, set.Mime, fallback.Mime, &notes) ||
+			p.deniedFallback(blockNum, set.Author, set.Name, fallback.Mime, fallback.Data, fallback.Animated, &notes)) {
+			fallback = nil
+		}
+		uploaded := [][]byte{set.Data, setData(fallback)}
+		p.stripSet(blockNum, set, &notes)
+		p.stripSet(blockNum, fallback, &notes)
+		if banned, err := p.bannedImage(ctx, blockNum, set.Author, set.Name, append(uploaded, set.Data, setData(fallback))...); err != nil || banned {
+			return err
+		}
+		set.ModerationStatus = p.moderateSets(ctx, blockNum, set, fallback)
+		set.IngestNotes = notes
+		set.RegisteredBlock, set.PendingConfirmation = blockNum, p.pendingConfirmation(blockNum)
+		if err := p.store.UpsertFromChunks(ctx, set, fallback); err != nil {
+			return err
+		}
+		p.notifySet(blockNum, set, fallback)
+		return nil
 	case "fallback":
 		mainSet, err := p.store.GetChunkSet(ctx, set.UploadID, "main")
+		if errors.Is(err, storage.ErrNotFound) {
+			// Fallback arrived before main; do nothing until main completes.
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		if mainSet == nil {
-			// Fallback arrived before main; do nothing until main completes.
+		var notes ingestNotes
+		if !p.checkImage(blockNum, mainSet.Author, mainSet.Name, setCheck(mainSet), &notes) || p.deniedImage(blockNum, mainSet.Author, mainSet.Name, setCheck(mainSet)) {
+			return nil
+		}
+		p.measureSet(blockNum, mainSet)
+		if p.redundantFallback(blockNum, set.Name, set.Author, mainSet.Mime, set.Mime, nil) ||
+			p.deniedFallback(blockNum, set.Author, set.Name, set.Mime, set.Data, set.Animated, nil) {
+			// Main was already stored without a fallback when it completed.
 			return nil
 		}
-		return p.store.UpsertFromChunks(ctx, mainSet, set)
+		uploaded := [][]byte{mainSet.Data, set.Data}
+		p.stripSet(blockNum, mainSet, &notes)
+		p.stripSet(blockNum, set, &notes)
+		if banned, err := p.bannedImage(ctx, blockNum, set.Author, set.Name, append(uploaded, mainSet.Data, set.Data)...); err != nil || banned {
+			return err
+		}
+		mainSet.ModerationStatus = p.moderateSets(ctx, blockNum, mainSet, set)
+		mainSet.IngestNotes = notes
+		mainSet.RegisteredBlock, mainSet.PendingConfirmation = blockNum, p.pendingConfirmation(blockNum)
+		if err := p.store.UpsertFromChunks(ctx, mainSet, set); err != nil {
+			return err
+		}
+		p.notifySet(blockNum, mainSet, set)
+		return nil
 	default:
 		return fmt.Errorf("unknown chunk kind %q", set.Kind)
 	}
 }
 
+// redundantFallback reports whether a fallback repeats the main image's mime and should be dropped.
+// A fallback only helps clients that can't decode the main format, so the same mime is wasted space.
+func (p *Processor) redundantFallback(blockNum int64, name, author, mainMime, fallbackMime string, notes *ingestNotes) bool {
+	if fallbackMime != mainMime {
+		return false
+	}
+	notes.addf("fallback dropped: same mime as main (%s)", fallbackMime)
+	log.Printf(
+		"block %d: drop redundant fallback name=%s author=%s mime=%s (same as main)",
+		blockNum,
+		name,
+		safeAuthor(author),
+		fallbackMime,
+	)
+	return true
+}
+
+// verifySignature checks an optional register signature against the author's posting keys.
+// It reports false (after logging) when the register must be skipped, and an error only when
+// the keys could not be fetched so the block is retried.
+func (p *Processor) verifySignature(ctx context.Context, blockNum int64, author, name, checksum, signature string) (bool, error) {
+	if signature == "" {
+		if p.opts.RequireSignature {
+			log.Printf("block %d: skip register name=%s author=%s: signature required", blockNum, name, safeAuthor(author))
+			return false, nil
+		}
+		return true, nil
+	}
+	if p.opts.Keys == nil {
+		if p.opts.RequireSignature {
+			return false, errors.New("signature verification required but no key source configured")
+		}
+		return true, nil
+	}
+	if checksum == "" {
+		log.Printf("block %d: skip register name=%s author=%s: signature without checksum", blockNum, name, safeAuthor(author))
+		return false, nil
+	}
+
+	keys, err := p.opts.Keys.PostingKeys(ctx, author)
+	if errors.Is(err, hive.ErrAccountNotFound) {
+		log.Printf("block %d: skip register name=%s author=%s: %v", blockNum, name, safeAuthor(author), err)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("posting keys for %s: %w", author, err)
+	}
+
+	if err := hive.VerifySignature(hive.RegisterMessage(author, name, strings.ToLower(checksum)), signature, keys); err != nil {
+		log.Printf("block %d: skip register name=%s author=%s: invalid signature: %v", blockNum, name, safeAuthor(author), err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// stripMetadata removes non-essential image metadata when enabled, keeping the original bytes if the container cannot be parsed.
+func (p *Processor) stripMetadata(blockNum int64, name, mime string, data []byte, notes *ingestNotes) []byte {
+	if !p.opts.StripMetadata || len(data) == 0 {
+		return data
+	}
+	stripped, err := imagemeta.Strip(data, mime)
+	if err != nil {
+		if p.opts.Metrics != nil {
+			p.opts.Metrics.DecodeFailure()
+		}
+		log.Printf("block %d: strip metadata name=%s mime=%s: %v; storing original bytes", blockNum, name, mime, err)
+		return data
+	}
+	if len(stripped) < len(data) {
+		log.Printf("block %d: stripped metadata name=%s mime=%s bytes=%d->%d", blockNum, name, mime, len(data), len(stripped))
+		notes.addf("metadata stripped from %s data (%d to %d bytes)", mime, len(data), len(stripped))
+	}
+	return stripped
+}
+
+// optimizeGIF re-encodes a GIF with transcode.OptimizeGIF when enabled, keeping the original
+// bytes unless the result is smaller.
+func (p *Processor) optimizeGIF(blockNum int64, name, mime string, data []byte, notes *ingestNotes) []byte {
+	if !p.opts.OptimizeGIF || mime != "image/gif" || len(data) == 0 {
+		return data
+	}
+	optimized, err := transcode.OptimizeGIF(data)
+	if err != nil {
+		log.Printf("block %d: optimize gif name=%s: %v; storing original bytes", blockNum, name, err)
+		return data
+	}
+	if len(optimized) >= len(data) {
+		return data
+	}
+	log.Printf("block %d: optimized gif name=%s bytes=%d->%d", blockNum, name, len(data), len(optimized))
+	notes.addf("gif optimized (%d to %d bytes, %d%% smaller)", len(data), len(optimized), 100*(len(data)-len(optimized))/len(data))
+	return optimized
+}
+
+// stripSet strips metadata from (and optimizes GIFs in) an assembled set, recomputing its
+// checksum so it matches the stored bytes.
+func (p *Processor) stripSet(blockNum int64, set *storage.AssembledSet, notes *ingestNotes) {
+	if set == nil {
+		return
+	}
+	stripped := p.stripMetadata(blockNum, set.Name, set.Mime, set.Data, notes)
+	stripped = p.optimizeGIF(blockNum, set.Name, set.Mime, stripped, notes)
+	if len(stripped) == len(set.Data) {
+		return
+	}
+	set.Data = stripped
+	if set.Checksum != "" {
+		sum := sha256.Sum256(stripped)
+		set.Checksum = hex.EncodeToString(sum[:])
+	}
+}
+
+// bannedImage reports whether any of images hashes to a banned checksum. Callers pass the
+// images both as uploaded and as stored, so a ban matches either form.
+func (p *Processor) bannedImage(ctx context.Context, blockNum int64, author, name string, images ...[]byte) (bool, error) {
+	var checksums []string
+	for _, img := range images {
+		if len(img) == 0 {
+			continue
+		}
+		sum := sha256.Sum256(img)
+		checksums = append(checksums, hex.EncodeToString(sum[:]))
+	}
+	banned, err := p.store.FindBannedChecksum(ctx, checksums)
+	if err != nil || banned == "" {
+		return false, err
+	}
+	log.Printf("block %d: skip name=%s author=%s banned checksum=%s", blockNum, name, safeAuthor(author), banned)
+	return true, nil
+}
+
+// setData returns the assembled bytes of set, or nil when there is no set.
+func setData(set *storage.AssembledSet) []byte {
+	if set == nil {
+		return nil
+	}
+	return set.Data
+}
+
+// moderateSets scans an assembled main set and optional fallback.
+func (p *Processor) moderateSets(ctx context.Context, blockNum int64, main, fallback *storage.AssembledSet) string {
+	var fbMime string
+	var fbData []byte
+	if fallback != nil {
+		fbMime, fbData = fallback.Mime, fallback.Data
+	}
+	return p.moderate(ctx, blockNum, main.Author, main.Name, main.Mime, main.Data, fbMime, fbData)
+}
+
+// moderate runs the configured scanner over the main image and fallback (if any) and
+// returns the most restrictive verdict. It returns "" when no scanner is configured,
+// leaving the stored status untouched.
+func (p *Processor) moderate(ctx context.Context, blockNum int64, author, name, mime string, data []byte, fallbackMime string, fallbackData []byte) string {
+	if p.opts.Scanner == nil {
+		return ""
+	}
+
+	images := []moderation.Request{{Author: author, Name: name, Mime: mime, Data: data}}
+	if len(fallbackData) > 0 {
+		images = append(images, moderation.Request{Author: author, Name: name, Mime: fallbackMime, Data: fallbackData})
+	}
+
+	verdict := moderation.StatusApproved
+	for _, img := range images {
+		status, err := p.opts.Scanner.Scan(ctx, img)
+		if err != nil {
+			status = moderation.StatusApproved
+			if p.opts.ModerationFailClosed {
+				status = moderation.StatusPending
+			}
+			log.Printf("block %d: moderation scan name=%s author=%s failed, marking %s: %v", blockNum, name, safeAuthor(author), status, err)
+		}
+		verdict = stricterStatus(verdict, status)
+	}
+
+	if verdict != moderation.StatusApproved {
+		log.Printf("block %d: moderation name=%s author=%s status=%s", blockNum, name, safeAuthor(author), verdict)
+	}
+	return verdict
+}
+
+// stricterStatus returns the more restrictive of two moderation statuses.
+func stricterStatus(a, b string) string {
+	rank := map[string]int{moderation.StatusApproved: 0, moderation.StatusPending: 1, moderation.StatusBlocked: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
 // FetchBlock wraps the Hive client to retrieve a block.
 func (p *Processor) FetchBlock(ctx context.Context, number int64) (*hive.Block, error) {
 	return p.client.GetBlock(ctx, number)
 }
 
+// FetchBlocks retrieves the chain head and up to count consecutive blocks from number, in a
+// single round-trip when the node supports batching.
+func (p *Processor) FetchBlocks(ctx context.Context, number int64, count int) (int64, []*hive.Block, error) {
+	return p.client.HeadAndBlocks(ctx, number, count)
+}
+
 // HeadBlockNumber returns the chain head block number from the Hive node.
 func (p *Processor) HeadBlockNumber(ctx context.Context) (int64, error) {
 	return p.client.HeadBlockNumber(ctx)
 }
 
+// BlockNumberAtTime returns the first block produced at or after t.
+func (p *Processor) BlockNumberAtTime(ctx context.Context, t time.Time) (int64, error) {
+	return p.client.BlockNumberAtTime(ctx, t)
+}
+
 func firstNonEmpty(primary []string, fallback []string) string {
 	if len(primary) > 0 && primary[0] != "" {
 		return primary[0]
@@ -354,6 +939,23 @@ func firstNonEmpty(primary []string, fallback []string) string {
 	return ""
 }
 
+// normalizeAuthor lowercases the signing account (or rejects mixed case when strict)
+// and validates it against Hive's account naming rules.
+func (p *Processor) normalizeAuthor(raw string) (string, error) {
+	author := strings.TrimSpace(raw)
+	if author == "" {
+		return "", errors.New("missing author")
+	}
+	lower := strings.ToLower(author)
+	if lower != author && p.opts.StrictAuthors {
+		return "", fmt.Errorf("author %q is not lowercase", raw)
+	}
+	if !hive.ValidAccountName(lower) {
+		return "", fmt.Errorf("invalid author %q", raw)
+	}
+	return lower, nil
+}
+
 func safeAuthor(author string) string {
 	if strings.TrimSpace(author) == "" {
 		return "<unknown>"
@@ -1,12 +1,28 @@
 package processor
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	hivego "github.com/deathwingtheboss/hivego"
 
 	"hivemoji/internal/hive"
+	"hivemoji/internal/moderation"
 	"hivemoji/internal/storage"
+	"hivemoji/internal/webhook"
 )
 
 // recordingStore captures calls from Processor for assertions.
@@ -14,28 +30,144 @@ type recordingStore struct {
 	lastV1    storage.RegisterV1
 	lastBlock int64
 	v1Calls   int
+
+	manifests       []storage.Manifest
+	lastChunk       storage.ChunkPayload
+	assembled       *storage.AssembledSet
+	chunkLimit      int // SaveChunk rejects totals above it with ErrChunkTotal when set
+	chunkSets       map[string]*storage.AssembledSet
+	lastMain        *storage.AssembledSet
+	lastFallback    *storage.AssembledSet
+	fromChunksCalls int
+
+	confirmedThrough []int64
+
+	deletedVariant string
+
+	banned map[string]bool
+
+	// registered maps names to authors in registration order; reservations to their owner.
+	registered   map[string][]string
+	reservations map[string]string
+	transfers    []string
+
+	// v1Names lists registered names in order; failV1 makes the register of that name fail once.
+	v1Names    []string
+	failV1     string
+	checkpoint storage.OpCheckpoint
 }
 
 func (r *recordingStore) UpsertV1(ctx context.Context, payload storage.RegisterV1) error {
+	if payload.Name == r.failV1 {
+		r.failV1 = ""
+		return errors.New("connection reset")
+	}
 	r.lastV1 = payload
 	r.v1Calls++
+	r.v1Names = append(r.v1Names, payload.Name)
+	return nil
+}
+
+func (r *recordingStore) GetOpCheckpoint(ctx context.Context) (storage.OpCheckpoint, error) {
+	return r.checkpoint, nil
+}
+
+func (r *recordingStore) SetOpCheckpoint(ctx context.Context, c storage.OpCheckpoint) error {
+	r.checkpoint = c
 	return nil
 }
 
 func (r *recordingStore) DeleteEmoji(ctx context.Context, author, name string) error { return nil }
 
+func (r *recordingStore) FindBannedChecksum(ctx context.Context, checksums []string) (string, error) {
+	for _, c := range checksums {
+		if r.banned[c] {
+			return c, nil
+		}
+	}
+	return "", nil
+}
+
+func (r *recordingStore) ReserveName(ctx context.Context, name, author string, block int64) (string, error) {
+	if _, ok := r.reservations[name]; !ok {
+		if authors := r.registered[name]; len(authors) > 0 && authors[0] == author {
+			if r.reservations == nil {
+				r.reservations = make(map[string]string)
+			}
+			r.reservations[name] = author
+		}
+	}
+	return r.reservations[name], nil
+}
+
+func (r *recordingStore) TransferEmoji(ctx context.Context, name, from, to string, block int64) error {
+	authors := r.registered[name]
+	owned := -1
+	for i, a := range authors {
+		if a == to {
+			return storage.ErrNameTaken
+		}
+		if a == from {
+			owned = i
+		}
+	}
+	if owned < 0 {
+		return storage.ErrNotFound
+	}
+	authors[owned] = to
+	r.transfers = append(r.transfers, fmt.Sprintf("%s:%s->%s", name, from, to))
+	return nil
+}
+
+func (r *recordingStore) DeleteVariant(ctx context.Context, author, name, variant string) error {
+	r.deletedVariant = variant
+	return nil
+}
+
+func (r *recordingStore) UpsertManifest(ctx context.Context, m storage.Manifest) error {
+	r.manifests = append(r.manifests, m)
+	return nil
+}
+
 func (r *recordingStore) SaveChunk(ctx context.Context, chunk storage.ChunkPayload) (*storage.AssembledSet, error) {
-	return nil, nil
+	if r.chunkLimit > 0 && chunk.Total > r.chunkLimit {
+		return nil, fmt.Errorf("%w: %d chunks", storage.ErrChunkTotal, chunk.Total)
+	}
+	r.lastChunk = chunk
+	return r.assembled, nil
 }
 
 func (r *recordingStore) GetChunkSet(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error) {
-	return nil, nil
+	if set, ok := r.chunkSets[uploadID+"/"+kind]; ok {
+		return set, nil
+	}
+	return nil, storage.ErrNotFound
 }
 
 func (r *recordingStore) UpsertFromChunks(ctx context.Context, main *storage.AssembledSet, fallback *storage.AssembledSet) error {
+	r.lastMain = main
+	r.lastFallback = fallback
+	r.fromChunksCalls++
 	return nil
 }
 
+func (r *recordingStore) AssembleUpload(ctx context.Context, uploadID, kind string) (*storage.AssembledSet, error) {
+	if r.assembled == nil {
+		return nil, storage.ErrNotFound
+	}
+	return r.assembled, nil
+}
+
+// ConfirmAssets promotes the last v1 register when its block is covered.
+func (r *recordingStore) ConfirmAssets(ctx context.Context, block int64) (int64, error) {
+	r.confirmedThrough = append(r.confirmedThrough, block)
+	if r.lastV1.PendingConfirmation && r.lastV1.RegisteredBlock <= block {
+		r.lastV1.PendingConfirmation = false
+		return 1, nil
+	}
+	return 0, nil
+}
+
 func (r *recordingStore) SetLastBlock(ctx context.Context, number int64) error {
 	r.lastBlock = number
 	return nil
@@ -124,3 +256,1360 @@ func TestProcessBlock_V1RegisterRejectsMime(t *testing.T) {
 		t.Fatalf("expected last block 101482213, got %d", store.lastBlock)
 	}
 }
+
+// hivemojiBlock wraps a single hivemoji payload posted by author into a block.
+func hivemojiBlock(t *testing.T, number int64, payload, author string) *hive.Block {
+	t.Helper()
+	return customJSONBlock(t, number, "hivemoji", payload, author)
+}
+
+// customJSONBlock wraps a single custom_json payload with the given id into a block.
+func customJSONBlock(t *testing.T, number int64, id, payload, author string) *hive.Block {
+	t.Helper()
+
+	opEnvelope := map[string]interface{}{
+		"id":                     id,
+		"json":                   payload,
+		"required_auths":         []string{},
+		"required_posting_auths": []string{author},
+	}
+	rawOp, err := json.Marshal(opEnvelope)
+	if err != nil {
+		t.Fatalf("marshal op envelope: %v", err)
+	}
+
+	return &hive.Block{
+		Number: number,
+		Transactions: []hive.Transaction{
+			{Operations: []hive.Operation{{Type: "custom_json", Value: rawOp}}},
+		},
+	}
+}
+
+func TestProcessBlock_ObjectJSON(t *testing.T) {
+	// Some broadcasters put the payload in custom_json's json field as an object instead of a
+	// JSON-encoded string; both forms must be ingested alike.
+	str := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+	obj := `{ "op": "register", "version": 1, "name": "smile", "mime": "image/png", "width": 1, "height": 1, "data": "` + tinyPNG + `" }`
+
+	rawOp, err := json.Marshal(map[string]interface{}{
+		"id":                     "hivemoji",
+		"json":                   json.RawMessage(obj),
+		"required_auths":         []string{},
+		"required_posting_auths": []string{"mrtats"},
+	})
+	if err != nil {
+		t.Fatalf("marshal op envelope: %v", err)
+	}
+	block := hivemojiBlock(t, 30, str, "mrtats")
+	block.Transactions = append(block.Transactions, hive.Transaction{
+		Operations: []hive.Operation{{Type: "custom_json", Value: rawOp}},
+	})
+
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	if err := proc.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 2 {
+		t.Fatalf("expected both payload forms to be upserted, got %d upserts", store.v1Calls)
+	}
+	if store.lastV1.Name != "smile" || store.lastV1.Author != "mrtats" || !bytes.Equal(store.lastV1.Data, tinyPNGData) {
+		t.Fatalf("object-form payload stored wrongly: %+v", store.lastV1)
+	}
+	if store.lastBlock != 30 {
+		t.Fatalf("expected last block 30, got %d", store.lastBlock)
+	}
+}
+
+func TestProcessBlock_ResumesAfterAppliedOps(t *testing.T) {
+	// Four registers: two in the first transaction, two more in transactions of their own.
+	var txs []hive.Transaction
+	for i, name := range []string{"one", "two", "three", "four"} {
+		payload := fmt.Sprintf(`{"version":1,"op":"register","name":%q,"mime":"image/png","data":"`+tinyPNG+`"}`, name)
+		tx := hivemojiBlock(t, 50, payload, "mrtats").Transactions[0]
+		if i == 1 {
+			txs[0].Operations = append(txs[0].Operations, tx.Operations...)
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	block := &hive.Block{Number: 50, Transactions: txs}
+
+	store := &recordingStore{failV1: "three"}
+	proc := &Processor{store: store}
+	if err := proc.ProcessBlock(context.Background(), block); err == nil {
+		t.Fatalf("expected the failing third op to fail the block")
+	}
+	if want := (storage.OpCheckpoint{Block: 50, Tx: 0, Op: 1}); store.checkpoint != want {
+		t.Fatalf("expected checkpoint %+v, got %+v", want, store.checkpoint)
+	}
+	if store.lastBlock == 50 {
+		t.Fatalf("expected the failed block not to be marked processed")
+	}
+
+	if err := proc.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if want := []string{"one", "two", "three", "four"}; !reflect.DeepEqual(store.v1Names, want) {
+		t.Fatalf("expected each op applied once, got %v", store.v1Names)
+	}
+	if store.lastBlock != 50 || store.checkpoint != (storage.OpCheckpoint{}) {
+		t.Fatalf("expected the block completed and its checkpoint cleared, got last=%d checkpoint=%+v", store.lastBlock, store.checkpoint)
+	}
+
+	// A later replay of the block, e.g. HIVE_REPROCESS_FROM, applies every op again.
+	if err := proc.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(store.v1Names) != 8 {
+		t.Fatalf("expected a replay to apply all four ops, got %v", store.v1Names)
+	}
+}
+
+func TestProcessBlock_V2MainWithoutFallback(t *testing.T) {
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyPNGData},
+	}
+	proc := &Processor{store: store}
+
+	payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":1,"data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 10, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 1 {
+		t.Fatalf("expected 1 upsert from chunks, got %d", store.fromChunksCalls)
+	}
+	if store.lastFallback != nil {
+		t.Fatalf("expected nil fallback when fallback set is not found")
+	}
+}
+
+func TestProcessBlock_V2FallbackBeforeMain(t *testing.T) {
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "fallback", Name: "wave", Author: "mrtats", Mime: "image/gif", Data: []byte("gif")},
+	}
+	proc := &Processor{store: store}
+
+	payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/gif","kind":"fallback","seq":1,"total":1,"data":"Z2lm"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 11, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 0 {
+		t.Fatalf("expected no upsert until main completes, got %d", store.fromChunksCalls)
+	}
+	if store.lastBlock != 11 {
+		t.Fatalf("expected last block 11, got %d", store.lastBlock)
+	}
+}
+
+// stubScanner returns a fixed verdict or error for every scan.
+type stubScanner struct {
+	status string
+	err    error
+	calls  int
+}
+
+func (s *stubScanner) Scan(ctx context.Context, req moderation.Request) (string, error) {
+	s.calls++
+	return s.status, s.err
+}
+
+func TestProcessBlock_V1RegisterModeration(t *testing.T) {
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+
+	cases := []struct {
+		name       string
+		scanner    *stubScanner
+		failClosed bool
+		want       string
+	}{
+		{"blocked verdict", &stubScanner{status: moderation.StatusBlocked}, false, moderation.StatusBlocked},
+		{"approved verdict", &stubScanner{status: moderation.StatusApproved}, false, moderation.StatusApproved},
+		{"error fail-open", &stubScanner{err: errors.New("timeout")}, false, moderation.StatusApproved},
+		{"error fail-closed", &stubScanner{err: errors.New("timeout")}, true, moderation.StatusPending},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &recordingStore{}
+			proc := &Processor{store: store, opts: Options{Scanner: tc.scanner, ModerationFailClosed: tc.failClosed}}
+
+			if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 12, payload, "mrtats")); err != nil {
+				t.Fatalf("ProcessBlock error: %v", err)
+			}
+			if tc.scanner.calls != 1 {
+				t.Fatalf("expected 1 scan, got %d", tc.scanner.calls)
+			}
+			if store.lastV1.ModerationStatus != tc.want {
+				t.Fatalf("expected status %q, got %q", tc.want, store.lastV1.ModerationStatus)
+			}
+		})
+	}
+}
+
+func TestProcessBlock_V1RegisterWithoutScanner(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 13, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.ModerationStatus != "" {
+		t.Fatalf("expected status left unset without scanner, got %q", store.lastV1.ModerationStatus)
+	}
+}
+
+func TestProcessBlock_AuthorNormalization(t *testing.T) {
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+
+	cases := []struct {
+		name    string
+		author  string
+		strict  bool
+		want    string
+		upserts int
+	}{
+		{"mixed case lowercased", "MrTats", false, "mrtats", 1},
+		{"mixed case rejected when strict", "MrTats", true, "", 0},
+		{"lowercase accepted when strict", "mrtats", true, "mrtats", 1},
+		{"invalid charset", "mr_tats", false, "", 0},
+		{"too long", "averyveryverylongname", false, "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &recordingStore{}
+			proc := &Processor{store: store, opts: Options{StrictAuthors: tc.strict}}
+
+			if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 14, payload, tc.author)); err != nil {
+				t.Fatalf("ProcessBlock error: %v", err)
+			}
+			if store.v1Calls != tc.upserts {
+				t.Fatalf("expected %d upserts, got %d", tc.upserts, store.v1Calls)
+			}
+			if store.lastV1.Author != tc.want {
+				t.Fatalf("expected author %q, got %q", tc.want, store.lastV1.Author)
+			}
+			if store.lastBlock != 14 {
+				t.Fatalf("expected block to advance even when op is skipped, got %d", store.lastBlock)
+			}
+		})
+	}
+}
+
+// recordingMetrics captures ingest instrumentation.
+type recordingMetrics struct {
+	blocks         int
+	lastBlock      int64
+	ops            int
+	bytes          int
+	skipped        int
+	skipReasons    []string
+	assembled      int
+	decodeFailures int
+}
+
+func (m *recordingMetrics) ChunkSetAssembled() { m.assembled++ }
+func (m *recordingMetrics) DecodeFailure()     { m.decodeFailures++ }
+
+func (m *recordingMetrics) SkippedOp(reason string) {
+	m.skipped++
+	m.skipReasons = append(m.skipReasons, reason)
+}
+
+func (m *recordingMetrics) ObserveBlock(number int64, d time.Duration, ops, bytes int) {
+	m.blocks++
+	m.lastBlock = number
+	m.ops += ops
+	m.bytes += bytes
+}
+
+func TestProcessBlock_ObservesTiming(t *testing.T) {
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+	m := &recordingMetrics{}
+	proc := &Processor{store: &recordingStore{}, opts: Options{Metrics: m}}
+
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 15, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if err := proc.ProcessBlock(context.Background(), &hive.Block{Number: 16}); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if m.blocks != 2 {
+		t.Fatalf("expected 2 observed blocks, got %d", m.blocks)
+	}
+	if m.ops != 1 || m.bytes != len(payload) {
+		t.Fatalf("expected 1 op of %d bytes, got %d ops %d bytes", len(payload), m.ops, m.bytes)
+	}
+}
+
+func TestProcessBlock_CountsSkipReasonsAndAssembledSets(t *testing.T) {
+	cases := []struct {
+		payload string
+		want    string
+	}{
+		{`{"op":"register","version":1,"name":"wave","mime":"image/bmp","data":"cG5n"}`, skipBadMime},
+		{`{"op":"register","version":1,"name":"wave","mime":"image/png","data":"not base64!"}`, skipBadBase64},
+		{`{"op":"register","version":9,"name":"wave"}`, skipUnsupportedVersion},
+		{`{"op":"register","version":1,"mime":"image/png","data":"` + tinyPNG + `"}`, skipInvalidPayload},
+	}
+	for i, tc := range cases {
+		m := &recordingMetrics{}
+		proc := &Processor{store: &recordingStore{}, opts: Options{Metrics: m}}
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, int64(30+i), tc.payload, "mrtats")); err != nil {
+			t.Fatalf("%s: ProcessBlock error: %v", tc.payload, err)
+		}
+		if !reflect.DeepEqual(m.skipReasons, []string{tc.want}) {
+			t.Fatalf("%s: expected skip reason %q, got %v", tc.payload, tc.want, m.skipReasons)
+		}
+	}
+
+	m := &recordingMetrics{}
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyPNGData},
+	}
+	proc := &Processor{store: store, opts: Options{Metrics: m}}
+	chunk := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":1,"total":1,"data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 40, chunk, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if m.assembled != 1 || m.skipped != 0 {
+		t.Fatalf("expected 1 assembled set and no skips, got %d assembled %d skipped", m.assembled, m.skipped)
+	}
+}
+
+func TestProcessBlock_EmptyBlockOnlyAdvancesLastBlock(t *testing.T) {
+	store := &recordingStore{}
+	m := &recordingMetrics{}
+	proc := &Processor{store: store, opts: Options{Metrics: m}}
+
+	other := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+	if err := proc.ProcessBlock(context.Background(), customJSONBlock(t, 21, "someapp", other, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastBlock != 21 {
+		t.Fatalf("expected last block 21, got %d", store.lastBlock)
+	}
+	if store.v1Calls != 0 || store.lastChunk.ID != "" || len(store.confirmedThrough) != 0 {
+		t.Fatalf("expected a block without hivemoji ops to touch nothing but last_block")
+	}
+	if m.blocks != 1 || m.ops != 0 {
+		t.Fatalf("expected the block to be observed with no ops, got %d blocks %d ops", m.blocks, m.ops)
+	}
+}
+
+func TestProcessBlock_CustomJSONID(t *testing.T) {
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `"}`
+
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{CustomJSONID: "hivemoji-test"}}
+
+	if err := proc.ProcessBlock(context.Background(), customJSONBlock(t, 20, "hivemoji", payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected default id to be ignored when overridden, got %d upserts", store.v1Calls)
+	}
+
+	if err := proc.ProcessBlock(context.Background(), customJSONBlock(t, 21, "hivemoji-test", payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 {
+		t.Fatalf("expected overridden id to be processed, got %d upserts", store.v1Calls)
+	}
+
+	store = &recordingStore{}
+	proc = &Processor{store: store}
+	if err := proc.ProcessBlock(context.Background(), customJSONBlock(t, 22, "hivemoji-test", payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected other ids to be ignored by default, got %d upserts", store.v1Calls)
+	}
+}
+
+// staticKeys serves a fixed posting key set for every account.
+type staticKeys []string
+
+func (k staticKeys) PostingKeys(ctx context.Context, account string) ([]string, error) {
+	return k, nil
+}
+
+func TestProcessBlock_V1RegisterSignature(t *testing.T) {
+	wif := "5JuMt237G3m3BaT7zH4YdoycUtbw4AEPy6DLdCrKAnFGAtXyQ1W"
+	kp, err := hivego.KeyPairFromWif(wif)
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	keys := staticKeys{*kp.GetPublicKeyString()}
+
+	sum := sha256.Sum256(tinyPNGData)
+	digest := sha256.Sum256([]byte(hive.RegisterMessage("mrtats", "wave", hex.EncodeToString(sum[:]))))
+	sig, err := hivego.SignDigest(digest[:], &wif)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	register := func(name, signature string) string {
+		return fmt.Sprintf(`{"op":"register","version":1,"name":%q,"mime":"image/png","width":1,"height":1,"data":"`+tinyPNG+`","signature":%q}`, name, signature)
+	}
+
+	cases := []struct {
+		name    string
+		opts    Options
+		payload string
+		want    int
+	}{
+		{"valid signature", Options{Keys: keys, RequireSignature: true}, register("wave", hex.EncodeToString(sig)), 1},
+		{"signature for other name", Options{Keys: keys}, register("other", hex.EncodeToString(sig)), 0},
+		{"signature from other key", Options{Keys: staticKeys{"STMother"}}, register("wave", hex.EncodeToString(sig)), 0},
+		{"unsigned allowed", Options{Keys: keys}, register("wave", ""), 1},
+		{"unsigned required", Options{Keys: keys, RequireSignature: true}, register("wave", ""), 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &recordingStore{}
+			proc := &Processor{store: store, opts: tc.opts}
+			if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 30, tc.payload, "mrtats")); err != nil {
+				t.Fatalf("ProcessBlock error: %v", err)
+			}
+			if store.v1Calls != tc.want {
+				t.Fatalf("expected %d upserts, got %d", tc.want, store.v1Calls)
+			}
+		})
+	}
+}
+
+func TestProcessBlock_MalformedImageKeepsIngesting(t *testing.T) {
+	// "cG5n" decodes to "png", which is not a parseable PNG container. Validation would skip
+	// it before stripping, so it is off here.
+	payload := `{"op":"register","version":1,"name":"wave","mime":"image/png","width":1,"height":1,"data":"cG5n"}`
+	m := &recordingMetrics{}
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{StripMetadata: true, Metrics: m, Validation: ValidationOff}}
+
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 40, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if m.decodeFailures != 1 {
+		t.Fatalf("expected 1 decode failure, got %d", m.decodeFailures)
+	}
+	if store.v1Calls != 1 || string(store.lastV1.Data) != "png" {
+		t.Fatalf("expected original bytes to be stored, got %d calls data=%q", store.v1Calls, store.lastV1.Data)
+	}
+	if store.lastBlock != 40 {
+		t.Fatalf("expected block to advance, got %d", store.lastBlock)
+	}
+}
+
+func TestProcessBlock_V1RegisterExpiry(t *testing.T) {
+	payload := `{"op":"register","version":1,"name":"xmas","mime":"image/png","width":1,"height":1,"data":"` + tinyPNG + `","expires_at":"2024-12-26T02:00:00+02:00"}`
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 60, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	want := time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)
+	if store.lastV1.ExpiresAt == nil || !store.lastV1.ExpiresAt.Equal(want) {
+		t.Fatalf("expected expiry %v, got %v", want, store.lastV1.ExpiresAt)
+	}
+}
+
+func TestProcessBlock_DropsRedundantFallback(t *testing.T) {
+	t.Run("v1 same mime", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/webp","data":"` + tinyWebP + `","fallback":{"mime":"image/webp","data":"d2VicA=="}}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 20, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 1 {
+			t.Fatalf("expected register to be stored, got %d upserts", store.v1Calls)
+		}
+		if store.lastV1.FallbackData != nil || store.lastV1.FallbackMime != "" {
+			t.Fatalf("expected redundant fallback to be dropped, got mime %q", store.lastV1.FallbackMime)
+		}
+	})
+
+	t.Run("v1 different mime", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/webp","data":"` + tinyWebP + `","fallback":{"mime":"image/png","data":"` + tinyPNG + `"}}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 21, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.lastV1.FallbackMime != "image/png" || len(store.lastV1.FallbackData) == 0 {
+			t.Fatalf("expected png fallback to be kept, got mime %q", store.lastV1.FallbackMime)
+		}
+	})
+
+	t.Run("v2 main completes after same-mime fallback", func(t *testing.T) {
+		store := &recordingStore{
+			assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/webp", Data: tinyWebPData},
+			chunkSets: map[string]*storage.AssembledSet{
+				"up1/fallback": {UploadID: "up1", Kind: "fallback", Name: "wave", Author: "mrtats", Mime: "image/webp", Data: []byte("webp")},
+			},
+		}
+		proc := &Processor{store: store}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/webp","kind":"main","seq":1,"total":1,"data":"d2VicA=="}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 22, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.fromChunksCalls != 1 || store.lastFallback != nil {
+			t.Fatalf("expected main stored without fallback, got %d upserts fallback=%v", store.fromChunksCalls, store.lastFallback)
+		}
+	})
+
+	t.Run("v2 same-mime fallback completes after main", func(t *testing.T) {
+		store := &recordingStore{
+			assembled: &storage.AssembledSet{UploadID: "up1", Kind: "fallback", Name: "wave", Author: "mrtats", Mime: "image/webp", Data: []byte("webp")},
+			chunkSets: map[string]*storage.AssembledSet{
+				"up1/main": {UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/webp", Data: tinyWebPData},
+			},
+		}
+		proc := &Processor{store: store}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/webp","kind":"fallback","seq":1,"total":1,"data":"d2VicA=="}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 23, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.fromChunksCalls != 0 {
+			t.Fatalf("expected redundant fallback to leave the stored main alone, got %d upserts", store.fromChunksCalls)
+		}
+	})
+}
+
+func TestProcessBlock_MimeRules(t *testing.T) {
+	rules, err := ParseMimeRules("image/webp,max_bytes=3")
+	if err != nil {
+		t.Fatalf("ParseMimeRules: %v", err)
+	}
+
+	t.Run("v1 main skipped", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store, opts: Options{MimeRules: rules}}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/webp","data":"` + tinyWebP + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 30, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 0 {
+			t.Fatalf("expected a matching register to be skipped, got %d upserts", store.v1Calls)
+		}
+	})
+
+	t.Run("v1 fallback dropped", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store, opts: Options{MimeRules: rules}}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"` + tinyPNG + `","fallback":{"mime":"image/webp","data":"` + tinyWebP + `"}}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 31, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 1 || store.lastV1.FallbackData != nil {
+			t.Fatalf("expected the main image stored without its fallback, got %d upserts fallback=%q", store.v1Calls, store.lastV1.FallbackData)
+		}
+		if notes := store.lastV1.IngestNotes; len(notes) != 1 || notes[0] != "fallback dropped: matches mime rule image/webp,max_bytes=3" {
+			t.Fatalf("unexpected notes %q", notes)
+		}
+	})
+
+	t.Run("v2 main skipped", func(t *testing.T) {
+		store := &recordingStore{
+			assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/webp", Data: tinyWebPData},
+		}
+		proc := &Processor{store: store, opts: Options{MimeRules: rules}}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/webp","kind":"main","seq":1,"total":1,"data":"d2VicA=="}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 32, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.fromChunksCalls != 0 {
+			t.Fatalf("expected a matching upload to be skipped, got %d upserts", store.fromChunksCalls)
+		}
+	})
+
+	t.Run("small images pass", func(t *testing.T) {
+		store := &recordingStore{}
+		larger, err := ParseMimeRules("image/webp,max_bytes=64")
+		if err != nil {
+			t.Fatalf("ParseMimeRules: %v", err)
+		}
+		proc := &Processor{store: store, opts: Options{MimeRules: larger}}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/webp","data":"` + tinyWebP + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 33, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 1 {
+			t.Fatalf("expected an image under the rule's size to be stored, got %d upserts", store.v1Calls)
+		}
+	})
+}
+
+func TestProcessBlock_MaxChunks(t *testing.T) {
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyPNGData},
+	}
+	m := &recordingMetrics{}
+	proc := &Processor{store: store, opts: Options{MaxChunks: 4, Metrics: m}}
+
+	tooMany := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":5,"data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 30, tooMany, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 0 || m.skipped != 1 {
+		t.Fatalf("expected oversized upload to be skipped, got %d upserts and %d skips", store.fromChunksCalls, m.skipped)
+	}
+
+	withinLimit := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":4,"data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 31, withinLimit, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 1 {
+		t.Fatalf("expected upload within the limit to be stored, got %d upserts", store.fromChunksCalls)
+	}
+}
+
+func TestProcessBlock_AbsurdChunkTotal(t *testing.T) {
+	store := &recordingStore{
+		chunkLimit: storage.DefaultMaxChunks,
+		assembled:  &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyPNGData},
+	}
+	m := &recordingMetrics{}
+	proc := &Processor{store: store, opts: Options{Metrics: m}}
+
+	absurd := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":2147483647,"data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 30, absurd, "mrtats")); err != nil {
+		t.Fatalf("expected the chunk to be skipped without failing the block, got %v", err)
+	}
+	if store.lastChunk.ID != "" || store.fromChunksCalls != 0 || m.skipped != 1 {
+		t.Fatalf("expected the chunk to be rejected, got chunk %q, %d upserts and %d skips", store.lastChunk.ID, store.fromChunksCalls, m.skipped)
+	}
+}
+
+func TestCompleteUpload(t *testing.T) {
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyPNGData},
+	}
+	proc := &Processor{store: store}
+
+	set, err := proc.CompleteUpload(context.Background(), "up1", "main")
+	if err != nil {
+		t.Fatalf("CompleteUpload error: %v", err)
+	}
+	if set.Name != "wave" || store.fromChunksCalls != 1 || store.lastMain != set {
+		t.Fatalf("expected re-assembled set to be stored, got %d upserts", store.fromChunksCalls)
+	}
+
+	if _, err := (&Processor{store: &recordingStore{}}).CompleteUpload(context.Background(), "missing", "main"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestProcessBlock_Cover(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"` + tinyPNG + `","cover":true}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 40, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if !store.lastV1.Cover {
+		t.Fatalf("expected v1 cover flag to be stored")
+	}
+
+	store = &recordingStore{}
+	proc = &Processor{store: store}
+	payload = `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":2,"data":"cG5n","cover":true}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 41, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if !store.lastChunk.Cover {
+		t.Fatalf("expected v2 cover flag to reach the chunk set")
+	}
+}
+
+func TestProcessBlock_NormalizesMime(t *testing.T) {
+	t.Run("v1 svg rejected", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/svg+xml","data":"PHN2Zz4="}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 50, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 0 {
+			t.Fatalf("expected svg register to be skipped, got %d upserts", store.v1Calls)
+		}
+	})
+
+	t.Run("v1 odd casing stored normalized", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"IMAGE/WEBP","data":"` + tinyWebP + `","fallback":{"mime":"image/svg+xml","data":"PHN2Zz4="}}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 51, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.v1Calls != 1 || store.lastV1.Mime != "image/webp" {
+			t.Fatalf("expected one upsert with mime image/webp, got %d with %q", store.v1Calls, store.lastV1.Mime)
+		}
+		if store.lastV1.FallbackMime != "" || len(store.lastV1.FallbackData) != 0 {
+			t.Fatalf("expected the svg fallback to be dropped, got %q", store.lastV1.FallbackMime)
+		}
+	})
+
+	t.Run("v2 svg rejected", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/svg+xml","kind":"main","seq":1,"total":1,"data":"PHN2Zz4="}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 52, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.lastChunk.ID != "" {
+			t.Fatalf("expected svg chunk not to be saved, got %+v", store.lastChunk)
+		}
+	})
+
+	t.Run("v2 odd casing stored normalized", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"IMAGE/WEBP","kind":"main","seq":1,"total":2,"data":"cG5n"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 53, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if store.lastChunk.Mime != "image/webp" {
+			t.Fatalf("expected chunk mime image/webp, got %q", store.lastChunk.Mime)
+		}
+	})
+}
+
+func TestProcessBlock_LicenseAndAttribution(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"` + tinyPNG + `","license":" cc-by-4.0 ","attribution":"drawn by @alice"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 42, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.License != "CC-BY-4.0" || store.lastV1.Attribution != "drawn by @alice" {
+		t.Fatalf("unexpected v1 credit: license %q, attribution %q", store.lastV1.License, store.lastV1.Attribution)
+	}
+
+	store = &recordingStore{}
+	proc = &Processor{store: store}
+	payload = `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":2,"data":"cG5n","license":"free for Hive posts"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 43, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastChunk.License != "free for Hive posts" {
+		t.Fatalf("expected free-text license to reach the chunk set, got %q", store.lastChunk.License)
+	}
+}
+
+// tinyGIF is a valid 1x1 still GIF, base64-encoded.
+const tinyGIF = "R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+
+// tinyGIFData is tinyGIF decoded.
+var tinyGIFData, _ = base64.StdEncoding.DecodeString(tinyGIF)
+
+// tinyPNG is a valid 1x1 transparent PNG, base64-encoded.
+const tinyPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAAC0lEQVR4nGNgAAIAAAUAAXpeqz8AAAAASUVORK5CYII="
+
+// tinyPNGData is tinyPNG decoded.
+var tinyPNGData, _ = base64.StdEncoding.DecodeString(tinyPNG)
+
+// tinyWebP is a valid 1x1 transparent lossless WebP, base64-encoded.
+const tinyWebP = "UklGRhQAAABXRUJQVlA4TAgAAAAvAAAAEIiICA=="
+
+// tinyWebPData is tinyWebP decoded.
+var tinyWebPData, _ = base64.StdEncoding.DecodeString(tinyWebP)
+
+// otherGIF is tinyGIF with a different palette, so a different checksum.
+const otherGIF = "R0lGODlhAQABAIAAABAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+
+func TestProcessBlock_StoresMeasuredDimensions(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `","width":64,"height":64,"animated":true}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 33, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if got := store.lastV1; got.Width != 1 || got.Height != 1 || got.Animated {
+		t.Fatalf("expected measured 1x1 still image, got %dx%d animated=%t", got.Width, got.Height, got.Animated)
+	}
+
+	store = &recordingStore{}
+	proc = &Processor{store: store, opts: Options{Validation: ValidationStrict}}
+	payload = `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"R0lGODlhAQABAIAAAA=="}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 34, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected a truncated gif to be skipped in strict mode")
+	}
+
+	store = &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/gif", Width: 9, Height: 9},
+	}
+	store.assembled.Data, _ = base64.StdEncoding.DecodeString(tinyGIF)
+	proc = &Processor{store: store}
+	payload = `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/gif","kind":"main","seq":1,"total":1,"data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 35, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastMain == nil || store.lastMain.Width != 1 || store.lastMain.Height != 1 {
+		t.Fatalf("expected measured dimensions on the stored v2 main, got %+v", store.lastMain)
+	}
+}
+
+func TestProcessBlock_V2Manifest(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	payload := `{"version":2,"op":"register","id":"up1","name":"wave","mime":"IMAGE/WEBP","width":32,"height":32,"animated":true,"total":3}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 60, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if len(store.manifests) != 1 {
+		t.Fatalf("expected one manifest, got %d", len(store.manifests))
+	}
+	m := store.manifests[0]
+	if m.UploadID != "up1" || m.Name != "wave" || m.Author != "mrtats" || !m.Animated {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if m.Mime == nil || *m.Mime != "image/webp" || m.Width == nil || *m.Width != 32 || m.Total == nil || *m.Total != 3 || m.FirstBlock == nil || *m.FirstBlock != 60 {
+		t.Fatalf("unexpected manifest details %+v", m)
+	}
+
+	payload = `{"version":2,"op":"register","id":"up2","name":"wave","mime":"text/html"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 61, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if m := store.manifests[1]; m.Mime != nil || m.Width != nil || m.Total != nil {
+		t.Fatalf("expected unknown mime and missing details to stay unset, got %+v", m)
+	}
+	if store.lastChunk.ID != "" {
+		t.Fatalf("expected manifests not to be saved as chunks")
+	}
+}
+
+func TestProcessBlock_SkipsUnreadableImages(t *testing.T) {
+	unreadable := []struct{ mime, data string }{
+		{"image/png", "iVBORw0KGgoAAAANSUhEUgAA"},  // signature and a cut-off IHDR
+		{"image/gif", "R0lGODlhAQA="},              // header without the logical screen descriptor
+		{"image/webp", "UklGRhAAAABXRUJQVlA4IA=="}, // RIFF header and a bare VP8 chunk tag
+		{"image/png", "cG5n"},                      // garbage without any image signature
+		{"image/webp", "PHN2Zz4="},                 // an SVG declared as WebP
+	}
+	for _, tc := range unreadable {
+		m := &recordingMetrics{}
+		store := &recordingStore{}
+		proc := &Processor{store: store, opts: Options{Metrics: m}}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"` + tc.mime + `","data":"` + tc.data + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 35, payload, "mrtats")); err != nil {
+			t.Fatalf("%s: ProcessBlock error: %v", tc.mime, err)
+		}
+		if store.v1Calls != 0 {
+			t.Fatalf("%s %s: expected an unreadable image to be skipped at the default level", tc.mime, tc.data)
+		}
+		if !reflect.DeepEqual(m.skipReasons, []string{skipBadImage}) {
+			t.Fatalf("%s: expected a bad_image skip, got %v", tc.mime, m.skipReasons)
+		}
+
+		data, _ := base64.StdEncoding.DecodeString(tc.data)
+		store = &recordingStore{
+			assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: tc.mime, Data: data},
+		}
+		proc = &Processor{store: store, opts: Options{Metrics: m}}
+		chunk := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"` + tc.mime + `","kind":"main","seq":1,"total":1,"data":"` + tc.data + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 36, chunk, "mrtats")); err != nil {
+			t.Fatalf("%s: ProcessBlock error: %v", tc.mime, err)
+		}
+		if store.fromChunksCalls != 0 {
+			t.Fatalf("%s %s: expected an unreadable upload to be skipped at the default level", tc.mime, tc.data)
+		}
+	}
+
+	// Off stores what was broadcast without looking at it.
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{Validation: ValidationOff}}
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"iVBORw0KGgoAAAANSUhEUgAA"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 37, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 {
+		t.Fatalf("expected validation off to store the image, got %d calls", store.v1Calls)
+	}
+}
+
+func TestProcessBlock_RecordsIngestNotes(t *testing.T) {
+	t.Run("v1", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"Image/WEBP","data":"` + tinyGIF + `","fallback":{"mime":"image/webp","data":"` + tinyWebP + `"}}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 30, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		want := []string{
+			`mime "Image/WEBP" normalized to "image/webp"`,
+			"data looks like image/gif, not the declared image/webp",
+			"fallback dropped: same mime as main (image/webp)",
+		}
+		if !reflect.DeepEqual(store.lastV1.IngestNotes, want) {
+			t.Fatalf("unexpected notes:\n got %q\nwant %q", store.lastV1.IngestNotes, want)
+		}
+	})
+
+	t.Run("v1 clean payload", func(t *testing.T) {
+		store := &recordingStore{}
+		proc := &Processor{store: store}
+		payload := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 31, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if len(store.lastV1.IngestNotes) != 0 {
+			t.Fatalf("expected no notes, got %q", store.lastV1.IngestNotes)
+		}
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		store := &recordingStore{
+			assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Data: tinyGIFData},
+		}
+		proc := &Processor{store: store}
+		payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":1,"data":"` + tinyGIF + `"}`
+		if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 32, payload, "mrtats")); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		want := []string{"data looks like image/gif, not the declared image/png"}
+		if store.lastMain == nil || !reflect.DeepEqual(store.lastMain.IngestNotes, want) {
+			t.Fatalf("unexpected notes on stored main: %+v", store.lastMain)
+		}
+	})
+}
+
+func TestProcessBlock_ConfirmationDepth(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{ConfirmationDepth: 3}}
+	ctx := context.Background()
+
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if !store.lastV1.PendingConfirmation || store.lastV1.RegisteredBlock != 100 {
+		t.Fatalf("expected register to be pending at block 100, got pending=%t block=%d", store.lastV1.PendingConfirmation, store.lastV1.RegisteredBlock)
+	}
+
+	for n := int64(101); n <= 102; n++ {
+		if err := proc.ProcessBlock(ctx, &hive.Block{Number: n}); err != nil {
+			t.Fatalf("ProcessBlock %d error: %v", n, err)
+		}
+		if !store.lastV1.PendingConfirmation {
+			t.Fatalf("expected emoji to stay pending at block %d", n)
+		}
+	}
+
+	if err := proc.ProcessBlock(ctx, &hive.Block{Number: 103}); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.PendingConfirmation {
+		t.Fatalf("expected emoji to be confirmed once block 103 is processed")
+	}
+	if want := []int64{97, 98, 99, 100}; !reflect.DeepEqual(store.confirmedThrough, want) {
+		t.Fatalf("expected confirmations through %v, got %v", want, store.confirmedThrough)
+	}
+}
+
+func TestProcessBlock_NoConfirmationDepth(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 100, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.PendingConfirmation || len(store.confirmedThrough) != 0 {
+		t.Fatalf("expected emoji to be visible immediately without a confirmation depth")
+	}
+}
+
+func TestProcessBlock_NamePrefix(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{NamePrefix: "acme_"}}
+	ctx := context.Background()
+
+	outside := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, outside, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.Name != "" {
+		t.Fatalf("expected name outside the namespace to be skipped, stored %q", store.lastV1.Name)
+	}
+
+	inside := `{"version":1,"op":"register","name":"acme_wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, inside, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.Name != "acme_wave" {
+		t.Fatalf("expected prefixed name to be stored in full, got %q", store.lastV1.Name)
+	}
+}
+
+func TestProcessBlock_ValidationLevels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9), nil); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	// The GIF is 4x4 but declared 8x8.
+	mismatched := fmt.Sprintf(`{"version":1,"op":"register","name":"wave","mime":"image/gif","width":8,"height":8,"data":%q}`, data)
+	accurate := fmt.Sprintf(`{"version":1,"op":"register","name":"wave","mime":"image/gif","width":4,"height":4,"data":%q}`, data)
+
+	cases := []struct {
+		name      string
+		opts      Options
+		payload   string
+		wantStore bool
+		wantNote  string
+	}{
+		{"off stores anything", Options{Validation: ValidationOff}, mismatched, true, ""},
+		{"lenient notes and stores", Options{Validation: ValidationLenient}, mismatched, true, "image is 4x4, not the declared 8x8"},
+		{"empty means lenient", Options{}, mismatched, true, "image is 4x4, not the declared 8x8"},
+		{"strict rejects", Options{Validation: ValidationStrict}, mismatched, false, ""},
+		{"strict accepts valid images", Options{Validation: ValidationStrict}, accurate, true, ""},
+		{"strict enforces size limit", Options{Validation: ValidationStrict, MaxImageBytes: 8}, accurate, false, ""},
+		{"off ignores size limit", Options{Validation: ValidationOff, MaxImageBytes: 8}, accurate, true, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &recordingStore{}
+			proc := &Processor{store: store, opts: tc.opts}
+			if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 100, tc.payload, "mrtats")); err != nil {
+				t.Fatalf("ProcessBlock error: %v", err)
+			}
+			if stored := store.v1Calls == 1; stored != tc.wantStore {
+				t.Fatalf("stored=%t, want %t", stored, tc.wantStore)
+			}
+			if tc.wantStore && tc.wantNote == "" && len(store.lastV1.IngestNotes) != 0 {
+				t.Fatalf("expected no notes, got %q", store.lastV1.IngestNotes)
+			}
+			if tc.wantNote != "" && (len(store.lastV1.IngestNotes) != 1 || store.lastV1.IngestNotes[0] != tc.wantNote) {
+				t.Fatalf("expected note %q, got %q", tc.wantNote, store.lastV1.IngestNotes)
+			}
+		})
+	}
+}
+
+func TestProcessBlock_ReserveName(t *testing.T) {
+	store := &recordingStore{registered: map[string][]string{"wave": {"mrtats", "squatter"}}}
+	proc := &Processor{store: store}
+	ctx := context.Background()
+
+	reserve := `{"version":1,"op":"reserve","name":"wave"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, reserve, "squatter")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if owner := store.reservations["wave"]; owner != "" {
+		t.Fatalf("expected a later registrant not to reserve the name, got owner %q", owner)
+	}
+
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, reserve, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if owner := store.reservations["wave"]; owner != "mrtats" {
+		t.Fatalf("expected first registrant to reserve the name, got owner %q", owner)
+	}
+
+	// Other authors can still register the name; the API flags their emoji as unofficial.
+	register := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 102, register, "squatter")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 || store.lastV1.Author != "squatter" {
+		t.Fatalf("expected conflicting register to still be stored, got %d calls", store.v1Calls)
+	}
+}
+
+func TestProcessBlock_Transfer(t *testing.T) {
+	store := &recordingStore{registered: map[string][]string{"wave": {"mrtats", "taken"}}}
+	proc := &Processor{store: store}
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		payload string
+		author  string
+	}{
+		{"unknown emoji", `{"version":1,"op":"transfer","name":"nope","to":"newtats"}`, "mrtats"},
+		{"not the owner", `{"version":1,"op":"transfer","name":"wave","to":"thief"}`, "thief"},
+		{"target has the name", `{"version":1,"op":"transfer","name":"wave","to":"taken"}`, "mrtats"},
+		{"to itself", `{"version":1,"op":"transfer","name":"wave","to":"mrtats"}`, "mrtats"},
+		{"invalid account", `{"version":1,"op":"transfer","name":"wave","to":"No"}`, "mrtats"},
+	}
+	for i, tc := range cases {
+		if err := proc.ProcessBlock(ctx, hivemojiBlock(t, int64(100+i), tc.payload, tc.author)); err != nil {
+			t.Fatalf("%s: ProcessBlock error: %v", tc.name, err)
+		}
+		if len(store.transfers) != 0 {
+			t.Fatalf("%s: expected no transfer, got %v", tc.name, store.transfers)
+		}
+	}
+
+	payload := `{"version":1,"op":"transfer","name":"wave","to":"newtats"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 200, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if len(store.transfers) != 1 || store.transfers[0] != "wave:mrtats->newtats" {
+		t.Fatalf("expected wave to move to newtats, got %v", store.transfers)
+	}
+
+	// The old owner no longer has the emoji to give away.
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 201, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if len(store.transfers) != 1 {
+		t.Fatalf("expected a repeated transfer to be skipped, got %v", store.transfers)
+	}
+}
+
+func TestProcessBlock_AuthorAllowlist(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{AuthorAllowlist: []string{"mrtats"}}}
+	ctx := context.Background()
+
+	payload := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, payload, "stranger")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected register from an author off the allowlist to be skipped")
+	}
+
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 || store.lastV1.Author != "mrtats" {
+		t.Fatalf("expected allowlisted author to be stored, got %d calls", store.v1Calls)
+	}
+}
+
+// fakeAccounts serves accounts from a map; unknown accounts are ErrAccountNotFound.
+type fakeAccounts struct {
+	accounts map[string]hive.Account
+	err      error
+}
+
+func (f fakeAccounts) Account(ctx context.Context, account string) (hive.Account, error) {
+	if f.err != nil {
+		return hive.Account{}, f.err
+	}
+	acct, ok := f.accounts[account]
+	if !ok {
+		return hive.Account{}, fmt.Errorf("%w: %s", hive.ErrAccountNotFound, account)
+	}
+	return acct, nil
+}
+
+func TestProcessBlock_MinReputation(t *testing.T) {
+	accounts := fakeAccounts{accounts: map[string]hive.Account{
+		"spammer": {Name: "spammer", Reputation: 12},
+		"mrtats":  {Name: "mrtats", Reputation: 61.5},
+	}}
+	store := &recordingStore{}
+	proc := &Processor{store: store, opts: Options{Accounts: accounts, MinReputation: 25}}
+	ctx := context.Background()
+
+	register := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	chunk := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"main","seq":1,"total":1,"data":"cG5n"}`
+	for i, author := range []string{"spammer", "nobody"} {
+		if err := proc.ProcessBlock(ctx, hivemojiBlock(t, int64(100+2*i), register, author)); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+		if err := proc.ProcessBlock(ctx, hivemojiBlock(t, int64(101+2*i), chunk, author)); err != nil {
+			t.Fatalf("ProcessBlock error: %v", err)
+		}
+	}
+	if store.v1Calls != 0 || store.lastChunk.ID != "" {
+		t.Fatalf("expected ops from low-reputation and unknown accounts to be skipped")
+	}
+
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 110, register, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 || store.lastV1.Author != "mrtats" {
+		t.Fatalf("expected register from a reputable author to be stored, got %d calls", store.v1Calls)
+	}
+
+	proc.opts.Accounts = fakeAccounts{err: errors.New("node down")}
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 111, register, "mrtats")); err == nil {
+		t.Fatal("expected an account lookup failure to fail the block for a retry")
+	}
+	if store.lastBlock != 110 {
+		t.Fatalf("expected the failed block not to be marked processed, last block %d", store.lastBlock)
+	}
+}
+
+func TestProcessBlock_V1DeclaredBytes(t *testing.T) {
+	store := &recordingStore{}
+	m := &recordingMetrics{}
+	proc := &Processor{store: store, opts: Options{Metrics: m}}
+	ctx := context.Background()
+
+	truncated := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `","bytes":1024}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, truncated, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected register with mismatched byte length to be skipped")
+	}
+	if !reflect.DeepEqual(m.skipReasons, []string{skipInvalidPayload}) {
+		t.Fatalf("expected the mismatch to count as a rejected op, got %v", m.skipReasons)
+	}
+
+	exact := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `","bytes":42}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, exact, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 || store.lastV1.Name != "wave" {
+		t.Fatalf("expected register with matching byte length to be stored, got %d calls", store.v1Calls)
+	}
+}
+
+func TestProcessBlock_BannedChecksum(t *testing.T) {
+	sum := sha256.Sum256(tinyGIFData)
+	store := &recordingStore{banned: map[string]bool{hex.EncodeToString(sum[:]): true}}
+	proc := &Processor{store: store}
+	ctx := context.Background()
+
+	v1 := `{"version":1,"op":"register","name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, v1, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 0 {
+		t.Fatalf("expected banned v1 register to be skipped")
+	}
+
+	store.assembled = &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/gif", Data: tinyGIFData}
+	chunk := `{"version":2,"op":"chunk","id":"up1","kind":"main","seq":1,"total":1,"name":"wave","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, chunk, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 0 {
+		t.Fatalf("expected banned v2 upload to be skipped")
+	}
+	store.assembled.Data, _ = base64.StdEncoding.DecodeString(otherGIF)
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, chunk, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.fromChunksCalls != 1 {
+		t.Fatalf("expected unbanned v2 upload to be stored, got %d calls", store.fromChunksCalls)
+	}
+
+	allowed := `{"version":1,"op":"register","name":"smile","mime":"image/gif","data":"` + otherGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 102, allowed, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.v1Calls != 1 {
+		t.Fatalf("expected other images to be stored, got %d calls", store.v1Calls)
+	}
+}
+
+func TestProcessBlock_Variants(t *testing.T) {
+	store := &recordingStore{}
+	proc := &Processor{store: store}
+	ctx := context.Background()
+
+	register := `{"version":1,"op":"register","name":"wave","variant":"dark","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 100, register, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.Name != "wave" || store.lastV1.Variant != "dark" {
+		t.Fatalf("expected wave~dark to be stored, got %q~%q", store.lastV1.Name, store.lastV1.Variant)
+	}
+
+	del := `{"version":1,"op":"delete","name":"wave","variant":"dark"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 101, del, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.deletedVariant != "dark" {
+		t.Fatalf("expected variant delete, got %q", store.deletedVariant)
+	}
+
+	store.lastV1 = storage.RegisterV1{}
+	bad := `{"version":1,"op":"register","name":"wave","variant":"Dark Mode","mime":"image/gif","data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 102, bad, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	if store.lastV1.Name != "" {
+		t.Fatalf("expected invalid variant to be rejected, stored %q", store.lastV1.Name)
+	}
+}
+
+func TestProcessBlock_OptimizeGIF(t *testing.T) {
+	g := &gif.GIF{}
+	for i := 0; i < 6; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 24, 24), palette.Plan9)
+		frame.SetColorIndex(i, i, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	original := buf.Bytes()
+	sum := sha256.Sum256(original)
+
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/gif", Animated: true, Data: original, Checksum: hex.EncodeToString(sum[:])},
+	}
+	proc := &Processor{store: store, opts: Options{OptimizeGIF: true}}
+	payload := `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/gif","kind":"main","seq":1,"total":1,"data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 40, payload, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+
+	stored := store.lastMain
+	if stored == nil || len(stored.Data) >= len(original) {
+		t.Fatalf("expected a smaller optimized gif to be stored")
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(stored.Data))
+	if err != nil || len(decoded.Image) != len(g.Image) {
+		t.Fatalf("expected %d frames after optimization, got err=%v", len(g.Image), err)
+	}
+	if newSum := sha256.Sum256(stored.Data); stored.Checksum != hex.EncodeToString(newSum[:]) {
+		t.Fatalf("expected checksum to match the optimized bytes")
+	}
+	if len(stored.IngestNotes) != 1 || !strings.HasPrefix(stored.IngestNotes[0], "gif optimized (") {
+		t.Fatalf("expected an optimization note, got %q", stored.IngestNotes)
+	}
+}
+
+type recordingNotifier struct {
+	events []webhook.Event
+}
+
+func (n *recordingNotifier) Notify(ev webhook.Event) bool {
+	n.events = append(n.events, ev)
+	return true
+}
+
+func TestProcessBlock_NotifiesRegistrations(t *testing.T) {
+	notifier := &recordingNotifier{}
+	store := &recordingStore{
+		assembled: &storage.AssembledSet{UploadID: "up1", Kind: "main", Name: "party", Author: "mrtats", Version: 2, Mime: "image/png", Data: tinyPNGData, Checksum: "abc"},
+	}
+	proc := &Processor{store: store, opts: Options{Notifier: notifier}}
+	ctx := context.Background()
+
+	v1 := `{"version":1,"op":"register","name":"wave","mime":"image/gif","width":1,"height":1,"data":"` + tinyGIF + `"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 50, v1, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+	v2 := `{"version":2,"op":"chunk","id":"up1","name":"party","mime":"image/png","kind":"main","seq":1,"total":1,"data":"cG5n"}`
+	if err := proc.ProcessBlock(ctx, hivemojiBlock(t, 51, v2, "mrtats")); err != nil {
+		t.Fatalf("ProcessBlock error: %v", err)
+	}
+
+	want := []webhook.Event{
+		{Author: "mrtats", Name: "wave", Version: 1, Mime: "image/gif", Width: 1, Height: 1, Block: 50},
+		{Author: "mrtats", Name: "party", Version: 2, Mime: "image/png", Width: 1, Height: 1, Checksum: "abc", UploadID: "up1", Block: 51},
+	}
+	if !reflect.DeepEqual(notifier.events, want) {
+		t.Fatalf("unexpected events:\n got %+v\nwant %+v", notifier.events, want)
+	}
+}
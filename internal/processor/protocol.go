@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Protocol describes a hivemoji payload version and the ops it accepts.
+type Protocol struct {
+	Version int      `json:"version"`
+	Ops     []string `json:"ops"`
+}
+
+// opMessage is the decoded payload of one op. Every op acts on a named emoji.
+type opMessage interface {
+	emojiName() string
+	validate() error
+}
+
+// opHandler decodes, validates and applies one payload of a registered (version, op).
+type opHandler func(p *Processor, ctx context.Context, blockNum int64, payload []byte, author string) error
+
+// handleOp builds the handler of an op whose payload decodes into M. The payload is decoded
+// and validated, ops on names outside the deployment's namespace are skipped, and apply gets
+// the typed message.
+func handleOp[M any, PM interface {
+	*M
+	opMessage
+}](version int, apply func(p *Processor, ctx context.Context, blockNum int64, msg PM, author string) error) opHandler {
+	return func(p *Processor, ctx context.Context, blockNum int64, payload []byte, author string) error {
+		msg := PM(new(M))
+		if err := decodePayload(payload, version, msg); err != nil {
+			return err
+		}
+		if err := msg.validate(); err != nil {
+			return err
+		}
+		if !p.inNamespace(blockNum, msg.emojiName(), author) {
+			return nil
+		}
+		return apply(p, ctx, blockNum, msg, author)
+	}
+}
+
+// opRegistry is the capability table: handlePayload dispatches on it and Protocols reports it,
+// so an op is advertised exactly when it is ingested. Protocols lists versions and their ops
+// in the order they appear here.
+var opRegistry = []struct {
+	version int
+	op      string
+	handle  opHandler
+}{
+	{1, "register", handleOp(1, (*Processor).applyV1Register)},
+	{1, "delete", handleOp(1, (*Processor).applyV1Delete)},
+	{1, "reserve", handleOp(1, (*Processor).applyV1Reserve)},
+	{1, "transfer", handleOp(1, (*Processor).applyV1Transfer)},
+	{2, "chunk", handleOp(2, (*Processor).applyV2)},
+	{2, "register", handleOp(2, (*Processor).applyV2)},
+}
+
+// defaultOps names the op assumed when a payload omits it. v2 chunks predate the op field.
+var defaultOps = map[int]string{2: "chunk"}
+
+// Protocols lists the payload versions and ops the processor supports, in version order.
+func Protocols() []Protocol {
+	var out []Protocol
+	for _, entry := range opRegistry {
+		if n := len(out); n > 0 && out[n-1].Version == entry.version {
+			out[n-1].Ops = append(out[n-1].Ops, entry.op)
+			continue
+		}
+		out = append(out, Protocol{Version: entry.version, Ops: []string{entry.op}})
+	}
+	return out
+}
+
+// lookupOp returns the handler registered for (version, op), or the ValidationError explaining
+// why there is none.
+func lookupOp(version int, op string) (opHandler, error) {
+	known := false
+	for _, entry := range opRegistry {
+		if entry.version != version {
+			continue
+		}
+		known = true
+		if entry.op == op {
+			return entry.handle, nil
+		}
+	}
+	switch {
+	case !known:
+		return nil, &ValidationError{Version: version, Field: "version", Reason: "is not supported"}
+	case op == "":
+		return nil, &ValidationError{Version: version, Field: "op", Reason: "is required"}
+	}
+	return nil, &ValidationError{Version: version, Op: op, Field: "op", Reason: fmt.Sprintf("%q is not a v%d op", op, version)}
+}
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"hivemoji/internal/encoding"
+	"hivemoji/internal/imagemeta"
+	"hivemoji/internal/storage"
+)
+
+// Validation levels for Options.Validation.
+const (
+	// ValidationOff stores images without running the image checks.
+	ValidationOff = "off"
+	// ValidationLenient records failed checks as ingest notes and logs them, but stores the image.
+	ValidationLenient = "lenient"
+	// ValidationStrict skips registers and uploads whose main image fails any check.
+	ValidationStrict = "strict"
+)
+
+// imageCheck is a main image as broadcast, with the metadata its author declared.
+type imageCheck struct {
+	Mime     string
+	Data     []byte
+	Width    int
+	Height   int
+	Animated bool
+}
+
+// setCheck describes an assembled v2 main set for checkImage.
+func setCheck(set *storage.AssembledSet) imageCheck {
+	return imageCheck{Mime: set.Mime, Data: set.Data, Width: set.Width, Height: set.Height, Animated: set.Animated}
+}
+
+// checkImage runs the image gates (sniffed mime, declared dimensions, declared animation and
+// MaxImageBytes) at the configured Validation level. It returns false when the op must be
+// skipped: in strict mode for any failed gate, and at every level but off when data declared
+// as PNG, GIF or WebP isn't a readable image of any of them, as it can't be served as one.
+func (p *Processor) checkImage(blockNum int64, author, name string, img imageCheck, notes *ingestNotes) bool {
+	level := p.opts.Validation
+	if level == ValidationOff {
+		return true
+	}
+	if _, err := imagemeta.Inspect(img.Data); err != nil && (sniffable(img.Mime) || !errors.Is(err, imagemeta.ErrUnknownFormat)) {
+		log.Printf("block %d: skip name=%s author=%s unreadable image header: %v", blockNum, name, safeAuthor(author), err)
+		p.skippedOp(skipBadImage)
+		return false
+	}
+
+	problems := imageProblems(img, p.opts.MaxImageBytes)
+	if len(problems) == 0 {
+		return true
+	}
+	if level == ValidationStrict {
+		log.Printf("block %d: skip name=%s author=%s failed validation: %s", blockNum, name, safeAuthor(author), strings.Join(problems, "; "))
+		return false
+	}
+	for _, problem := range problems {
+		notes.addf("%s", problem)
+	}
+	log.Printf("block %d: name=%s author=%s stored despite validation warnings: %s", blockNum, name, safeAuthor(author), strings.Join(problems, "; "))
+	return true
+}
+
+// imageProblems lists every check img fails. Dimensions and animation are read with
+// imagemeta.Inspect, which recovers from parser panics; checks it can't evaluate are passed.
+func imageProblems(img imageCheck, maxBytes int) []string {
+	var problems []string
+	if sniffed := encoding.SniffMime(img.Data); strings.HasPrefix(sniffed, "image/") && sniffed != img.Mime {
+		problems = append(problems, fmt.Sprintf("data looks like %s, not the declared %s", sniffed, img.Mime))
+	}
+	if maxBytes > 0 && len(img.Data) > maxBytes {
+		problems = append(problems, fmt.Sprintf("image is %d bytes, over the %d byte limit", len(img.Data), maxBytes))
+	}
+	info, err := imagemeta.Inspect(img.Data)
+	if err == nil && img.Width > 0 && img.Height > 0 && (info.Width != img.Width || info.Height != img.Height) {
+		problems = append(problems, fmt.Sprintf("image is %dx%d, not the declared %dx%d", info.Width, info.Height, img.Width, img.Height))
+	}
+	if err == nil && info.Animated != img.Animated {
+		problems = append(problems, fmt.Sprintf("image animated=%t, declared animated=%t", info.Animated, img.Animated))
+	}
+	return problems
+}
+
+// sniffable reports whether mime is a format imagemeta.Inspect reads, so data declared as it
+// must carry that format's header.
+func sniffable(mime string) bool {
+	switch mime {
+	case "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// measured returns img with the dimensions and animation flag read from its headers, so what
+// is stored matches the pixels rather than what the author declared. Each declared value that
+// differs is logged. Every image is returned as declared when Validation is off; otherwise
+// checkImage has already skipped images whose headers can't be read.
+func (p *Processor) measured(blockNum int64, author, name string, img imageCheck) imageCheck {
+	if p.opts.Validation == ValidationOff {
+		return img
+	}
+	info, err := imagemeta.Inspect(img.Data)
+	if err != nil {
+		return img
+	}
+	if (img.Width != 0 || img.Height != 0) && (img.Width != info.Width || img.Height != info.Height) {
+		log.Printf("block %d: name=%s author=%s declared %dx%d, storing measured %dx%d", blockNum, name, safeAuthor(author), img.Width, img.Height, info.Width, info.Height)
+	}
+	if img.Animated != info.Animated {
+		log.Printf("block %d: name=%s author=%s declared animated=%t, storing measured animated=%t", blockNum, name, safeAuthor(author), img.Animated, info.Animated)
+	}
+	img.Width, img.Height, img.Animated = info.Width, info.Height, info.Animated
+	return img
+}
+
+// measureSet applies measured to an assembled v2 main set.
+func (p *Processor) measureSet(blockNum int64, set *storage.AssembledSet) {
+	img := p.measured(blockNum, set.Author, set.Name, setCheck(set))
+	set.Width, set.Height, set.Animated = img.Width, img.Height, img.Animated
+}
@@ -0,0 +1,321 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"hivemoji/internal/hive"
+)
+
+// ValidationError explains why a hivemoji payload was rejected. Payloads that fail
+// validation are logged and skipped without touching storage.
+type ValidationError struct {
+	Version int
+	Op      string
+	Field   string
+	Reason  string
+}
+
+func (e *ValidationError) Error() string {
+	prefix := fmt.Sprintf("v%d", e.Version)
+	if e.Op != "" {
+		prefix += " " + e.Op
+	}
+	if e.Field == "" {
+		return prefix + ": " + e.Reason
+	}
+	return fmt.Sprintf("%s: %s %s", prefix, e.Field, e.Reason)
+}
+
+// reasonNotBase64 is the Reason of a ValidationError for image data that isn't valid base64.
+const reasonNotBase64 = "must be base64"
+
+// skipReason classifies e for Metrics.SkippedOp.
+func (e *ValidationError) skipReason() string {
+	switch {
+	case e.Field == "version":
+		return skipUnsupportedVersion
+	case e.Reason == reasonNotBase64:
+		return skipBadBase64
+	case e.Field == "mime":
+		return skipBadMime
+	}
+	return skipInvalidPayload
+}
+
+// invalidOp returns a constructor for ValidationErrors of one (version, op).
+func invalidOp(version int, op string) func(field, reason string) error {
+	return func(field, reason string) error {
+		return &ValidationError{Version: version, Op: op, Field: field, Reason: reason}
+	}
+}
+
+// v1Register is the payload of a version 1 register op.
+type v1Register struct {
+	Name      string          `json:"name"`
+	Mime      string          `json:"mime"`
+	Width     int             `json:"width"`
+	Height    int             `json:"height"`
+	Data      string          `json:"data"`
+	Bytes     *int            `json:"bytes"`
+	Animated  bool            `json:"animated"`
+	Loop      json.RawMessage `json:"loop"`
+	Signature string          `json:"signature"`
+	ExpiresAt string          `json:"expires_at"`
+	Cover     bool            `json:"cover"`
+	Variant   string          `json:"variant"`
+	// License and Attribution credit the emoji for reuse; see normalizeLicense.
+	License     string `json:"license"`
+	Attribution string `json:"attribution"`
+	Fallback    *struct {
+		Mime string `json:"mime"`
+		Data string `json:"data"`
+	} `json:"fallback"`
+}
+
+func (m *v1Register) emojiName() string { return m.Name }
+
+func (m *v1Register) validate() error {
+	invalid := invalidOp(1, "register")
+	if m.Name == "" {
+		return invalid("name", "is required")
+	}
+	if m.Mime == "" {
+		return invalid("mime", "is required")
+	}
+	if m.Data == "" {
+		return invalid("data", "is required")
+	}
+	if m.Width < 0 || m.Height < 0 {
+		return invalid("width/height", "must not be negative")
+	}
+	if m.Bytes != nil && *m.Bytes <= 0 {
+		return invalid("bytes", "must be positive")
+	}
+	if m.Fallback != nil && m.Fallback.Data == "" {
+		return invalid("fallback.data", "is required when fallback is present")
+	}
+	if _, err := parseExpiresAt(m.ExpiresAt); err != nil {
+		return invalid("expires_at", "must be an RFC 3339 timestamp")
+	}
+	if !validVariant(m.Variant) {
+		return invalid("variant", variantRule)
+	}
+	return validCredit(invalid, m.License, m.Attribution)
+}
+
+// v1Delete is the payload of a version 1 delete op. A variant deletes only that variant.
+type v1Delete struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+}
+
+func (m *v1Delete) emojiName() string { return m.Name }
+
+func (m *v1Delete) validate() error {
+	invalid := invalidOp(1, "delete")
+	if m.Name == "" {
+		return invalid("name", "is required")
+	}
+	if !validVariant(m.Variant) {
+		return invalid("variant", variantRule)
+	}
+	return nil
+}
+
+// v1Reserve is the payload of a version 1 reserve op.
+type v1Reserve struct {
+	Name string `json:"name"`
+}
+
+func (m *v1Reserve) emojiName() string { return m.Name }
+
+func (m *v1Reserve) validate() error {
+	if m.Name == "" {
+		return invalidOp(1, "reserve")("name", "is required")
+	}
+	return nil
+}
+
+// v1Transfer is the payload of a version 1 transfer op.
+type v1Transfer struct {
+	Name string `json:"name"`
+	To   string `json:"to"`
+}
+
+func (m *v1Transfer) emojiName() string { return m.Name }
+
+func (m *v1Transfer) validate() error {
+	invalid := invalidOp(1, "transfer")
+	if m.Name == "" {
+		return invalid("name", "is required")
+	}
+	if m.To == "" {
+		return invalid("to", "is required")
+	}
+	if !hive.ValidAccountName(m.To) {
+		return invalid("to", "must be a valid Hive account name")
+	}
+	return nil
+}
+
+// v2Message is the payload of a version 2 chunk or register op. A register without data is a
+// manifest announcing an upload; with data it is a chunk.
+type v2Message struct {
+	Version   int             `json:"version"`
+	Op        string          `json:"op"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Mime      string          `json:"mime"`
+	Width     int             `json:"width"`
+	Height    int             `json:"height"`
+	Animated  bool            `json:"animated"`
+	Loop      json.RawMessage `json:"loop"`
+	Checksum  string          `json:"checksum"`
+	Kind      string          `json:"kind"`
+	Seq       int             `json:"seq"`
+	Total     int             `json:"total"`
+	Data      string          `json:"data"`
+	Signature string          `json:"signature"`
+	ExpiresAt string          `json:"expires_at"`
+	Cover     bool            `json:"cover"`
+	Variant   string          `json:"variant"`
+	// License and Attribution may be sent on any chunk of the main upload.
+	License     string `json:"license"`
+	Attribution string `json:"attribution"`
+}
+
+// isManifest reports whether the message is a data-less register entry used for discovery.
+func (m *v2Message) isManifest() bool {
+	return m.Op == "register" && m.Data == ""
+}
+
+func (m *v2Message) emojiName() string { return m.Name }
+
+func (m *v2Message) validate() error {
+	op := m.Op
+	if op == "" {
+		op = "chunk"
+	}
+	invalid := invalidOp(2, op)
+
+	if m.ID == "" {
+		return invalid("id", "is required")
+	}
+	if m.Name == "" {
+		return invalid("name", "is required")
+	}
+	if m.isManifest() {
+		if m.Width < 0 || m.Height < 0 {
+			return invalid("width/height", "must not be negative")
+		}
+		if m.Total < 0 {
+			return invalid("total", "must not be negative")
+		}
+		return nil
+	}
+
+	if m.Mime == "" {
+		return invalid("mime", "is required")
+	}
+	if m.Data == "" {
+		return invalid("data", "is required")
+	}
+	if m.Kind != "" && m.Kind != "main" && m.Kind != "fallback" {
+		return invalid("kind", `must be "main" or "fallback"`)
+	}
+	if m.Total <= 0 {
+		return invalid("total", "must be > 0")
+	}
+	if m.Seq <= 0 || m.Seq > m.Total {
+		return invalid("seq", fmt.Sprintf("must be between 1 and total (%d)", m.Total))
+	}
+	if m.Width < 0 || m.Height < 0 {
+		return invalid("width/height", "must not be negative")
+	}
+	if _, err := parseExpiresAt(m.ExpiresAt); err != nil {
+		return invalid("expires_at", "must be an RFC 3339 timestamp")
+	}
+	if !validVariant(m.Variant) {
+		return invalid("variant", variantRule)
+	}
+	return validCredit(invalid, m.License, m.Attribution)
+}
+
+// validCredit checks the optional license and attribution fields of a register or chunk.
+func validCredit(invalid func(field, reason string) error, license, attribution string) error {
+	if _, ok := normalizeLicense(license); !ok {
+		return invalid("license", fmt.Sprintf("must be an SPDX identifier or at most %d characters of text", maxLicenseLen))
+	}
+	if _, ok := normalizeAttribution(attribution); !ok {
+		return invalid("attribution", fmt.Sprintf("must be at most %d characters of text", maxAttributionLen))
+	}
+	return nil
+}
+
+// variantRule describes the accepted variant names for validation errors.
+const variantRule = "must be 1-32 lowercase letters, digits, '-' or '_'"
+
+// validVariant reports whether variant is empty (the base emoji) or a valid variant name.
+func validVariant(variant string) bool {
+	if len(variant) > 32 {
+		return false
+	}
+	for _, r := range variant {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseExpiresAt parses the optional expires_at field; empty means the emoji never expires.
+// Expiries already in the past are accepted so replayed history is stored faithfully.
+func parseExpiresAt(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+// decodePayload unmarshals payload into msg, reporting malformed JSON and wrongly typed
+// fields as a ValidationError.
+func decodePayload(payload []byte, version int, msg any) error {
+	err := json.Unmarshal(payload, msg)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ValidationError{Version: version, Field: typeErr.Field, Reason: "must be " + jsonTypeName(typeErr.Type)}
+	}
+	return &ValidationError{Version: version, Reason: "invalid JSON: " + err.Error()}
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Struct, reflect.Map, reflect.Pointer:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return t.String()
+}
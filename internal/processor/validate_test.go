@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidatePayloads(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{"v1 register missing name", `{"version":1,"op":"register","mime":"image/png","data":"cG5n"}`, "v1 register: name is required"},
+		{"v1 register missing mime", `{"version":1,"op":"register","name":"wave","data":"cG5n"}`, "v1 register: mime is required"},
+		{"v1 register missing data", `{"version":1,"op":"register","name":"wave","mime":"image/png"}`, "v1 register: data is required"},
+		{"v1 register negative size", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"cG5n","width":-1}`, "v1 register: width/height must not be negative"},
+		{"v1 register empty fallback", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"cG5n","fallback":{"mime":"image/gif"}}`, "v1 register: fallback.data is required when fallback is present"},
+		{"v1 register bad base64", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"!!"}`, "v1 register: data must be base64"},
+		{"v1 delete missing name", `{"version":1,"op":"delete"}`, "v1 delete: name is required"},
+		{"v1 missing op", `{"version":1,"name":"wave"}`, "v1: op is required"},
+		{"v1 unknown op", `{"version":1,"op":"rename","name":"wave"}`, `v1 rename: op "rename" is not a v1 op`},
+		{"v1 wrong type", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"cG5n","width":"96"}`, "v1: width must be an integer"},
+		{"v1 register bad expiry", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"cG5n","expires_at":"next week"}`, "v1 register: expires_at must be an RFC 3339 timestamp"},
+		{"v2 bad expiry", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":1,"total":1,"data":"cG5n","expires_at":"2024-13-01"}`, "v2 chunk: expires_at must be an RFC 3339 timestamp"},
+		{"v1 register long license", `{"version":1,"op":"register","name":"wave","mime":"image/png","data":"cG5n","license":"` + strings.Repeat("x", 65) + `"}`, "v1 register: license must be an SPDX identifier or at most 64 characters of text"},
+		{"v2 control char attribution", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":1,"total":1,"data":"cG5n","attribution":"by\nme"}`, "v2 chunk: attribution must be at most 256 characters of text"},
+		{"v2 missing id", `{"version":2,"op":"chunk","name":"wave","mime":"image/png","seq":1,"total":1,"data":"cG5n"}`, "v2 chunk: id is required"},
+		{"v2 missing name", `{"version":2,"op":"chunk","id":"up1","mime":"image/png","seq":1,"total":1,"data":"cG5n"}`, "v2 chunk: name is required"},
+		{"v2 missing mime", `{"version":2,"op":"chunk","id":"up1","name":"wave","seq":1,"total":1,"data":"cG5n"}`, "v2 chunk: mime is required"},
+		{"v2 missing data", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":1,"total":1}`, "v2 chunk: data is required"},
+		{"v2 missing total", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":1,"data":"cG5n"}`, "v2 chunk: total must be > 0"},
+		{"v2 seq beyond total", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","seq":3,"total":2,"data":"cG5n"}`, "v2 chunk: seq must be between 1 and total (2)"},
+		{"v2 bad kind", `{"version":2,"op":"chunk","id":"up1","name":"wave","mime":"image/png","kind":"thumb","seq":1,"total":1,"data":"cG5n"}`, `v2 chunk: kind must be "main" or "fallback"`},
+		{"v2 unknown op", `{"version":2,"op":"delete","id":"up1","name":"wave"}`, `v2 delete: op "delete" is not a v2 op`},
+		{"unsupported version", `{"version":3,"op":"register"}`, "v3: version is not supported"},
+		{"not json", `not json`, "v0: invalid JSON: "},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &recordingStore{}
+			proc := &Processor{store: store}
+
+			err := proc.handlePayload(context.Background(), 1, []byte(tc.payload), "mrtats")
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if !strings.HasPrefix(invalid.Error(), tc.want) {
+				t.Fatalf("unexpected message:\n got %q\nwant %q", invalid.Error(), tc.want)
+			}
+			if store.v1Calls != 0 {
+				t.Fatalf("expected invalid payload not to be stored")
+			}
+		})
+	}
+}
+
+func TestProcessBlock_SkipsInvalidPayload(t *testing.T) {
+	store := &recordingStore{}
+	m := &recordingMetrics{}
+	proc := &Processor{store: store, opts: Options{Metrics: m}}
+
+	payload := `{"version":1,"op":"register","mime":"image/png","data":"cG5n"}`
+	if err := proc.ProcessBlock(context.Background(), hivemojiBlock(t, 50, payload, "mrtats")); err != nil {
+		t.Fatalf("expected invalid payload to be skipped, got %v", err)
+	}
+	if store.lastBlock != 50 {
+		t.Fatalf("expected block to advance past invalid payload, got %d", store.lastBlock)
+	}
+	if m.skipped != 1 || m.lastBlock != 50 {
+		t.Fatalf("expected 1 skipped op at block 50, got %d at %d", m.skipped, m.lastBlock)
+	}
+}
+
+// TestProtocols_MatchRegistry keeps the advertised capability table in sync with what
+// handlePayload dispatches: every advertised op reaches its handler, anything else is rejected
+// as an unknown op.
+func TestProtocols_MatchRegistry(t *testing.T) {
+	protos := Protocols()
+	if len(protos) != 2 {
+		t.Fatalf("expected 2 protocols, got %d", len(protos))
+	}
+	for _, proto := range protos {
+		for _, op := range append(proto.Ops, "rename") {
+			payload := fmt.Sprintf(`{"version":%d,"op":%q}`, proto.Version, op)
+			err := (&Processor{store: &recordingStore{}}).handlePayload(context.Background(), 1, []byte(payload), "mrtats")
+			var invalid *ValidationError
+			rejected := errors.As(err, &invalid) && invalid.Field == "op"
+			if advertised := op != "rename"; rejected == advertised {
+				t.Fatalf("v%d op %q: advertised=%t but dispatch rejected=%t (%v)", proto.Version, op, advertised, rejected, err)
+			}
+		}
+	}
+}
+
+// TestHandlePayload_DispatchesEachOp checks each registered (version, op) decodes into its own
+// message: a payload missing its name fails that op's validation.
+func TestHandlePayload_DispatchesEachOp(t *testing.T) {
+	cases := []struct {
+		payload string
+		want    string
+	}{
+		{`{"version":1,"op":"register"}`, "v1 register: name is required"},
+		{`{"version":1,"op":"delete"}`, "v1 delete: name is required"},
+		{`{"version":1,"op":"reserve"}`, "v1 reserve: name is required"},
+		{`{"version":1,"op":"transfer","name":"wave"}`, "v1 transfer: to is required"},
+		{`{"version":2,"op":"chunk","id":"up1"}`, "v2 chunk: name is required"},
+		{`{"version":2,"id":"up1"}`, "v2 chunk: name is required"},
+		{`{"version":2,"op":"register","id":"up1"}`, "v2 register: name is required"},
+		{`{"version":2,"op":"register","id":"up1","name":"wave","width":-1}`, "v2 register: width/height must not be negative"},
+		{`{"version":2,"op":"register","id":"up1","name":"wave","total":-2}`, "v2 register: total must not be negative"},
+	}
+	for _, tc := range cases {
+		err := (&Processor{store: &recordingStore{}}).handlePayload(context.Background(), 1, []byte(tc.payload), "mrtats")
+		if err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.payload, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package sprite
+
+import "sync"
+
+// Cache keeps recently built sheets so the PNG and CSS endpoints share one build. It holds at
+// most max entries and evicts the oldest first. A nil Cache builds on every call.
+type Cache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*Sheet
+	order   []string
+}
+
+// NewCache returns a cache holding up to max sheets.
+func NewCache(max int) *Cache {
+	return &Cache{max: max, entries: make(map[string]*Sheet)}
+}
+
+// Sheet returns the cached sheet for key, calling build on a miss. Keys must change whenever
+// the sources do; errors are not cached.
+func (c *Cache) Sheet(key string, build func() (*Sheet, error)) (*Sheet, error) {
+	if c == nil {
+		return build()
+	}
+
+	c.mu.Lock()
+	sheet, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return sheet, nil
+	}
+
+	sheet, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		for len(c.order) >= c.max && len(c.order) > 0 {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = sheet
+	return sheet, nil
+}
@@ -0,0 +1,66 @@
+package sprite
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// placeholder fills cells whose emoji is missing or can't be decoded.
+var placeholder = color.NRGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}
+
+// Row draws sources side by side, left to right, each scaled to fit a cell-pixel square and
+// centred in it, and returns the encoded PNG. Sources without data or that can't be decoded
+// (e.g. WebP) get a grey placeholder cell, so the row always has one cell per source.
+func Row(sources []Source, cell int) ([]byte, error) {
+	if len(sources) == 0 || cell < 1 {
+		return nil, ErrEmpty
+	}
+	if cell*len(sources) > MaxSide {
+		return nil, fmt.Errorf("row of %d %dpx cells is wider than %dpx", len(sources), cell, MaxSide)
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, cell*len(sources), cell))
+	for i, src := range sources {
+		rect := image.Rect(i*cell, 0, (i+1)*cell, cell)
+		var img image.Image
+		if len(src.Data) > 0 {
+			img, _ = decode(src)
+		}
+		if img == nil || img.Bounds().Empty() {
+			draw.Draw(canvas, rect.Inset(cell/8), image.NewUniform(placeholder), image.Point{}, draw.Src)
+			continue
+		}
+		drawFitted(canvas, rect, img)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("encode preview: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawFitted scales img, nearest neighbour, to the largest size that fits in rect without
+// changing its aspect ratio, and draws it centred there.
+func drawFitted(dst *image.NRGBA, rect image.Rectangle, img image.Image) {
+	b := img.Bounds()
+	w, h := rect.Dx(), rect.Dy()
+	if b.Dx()*h > b.Dy()*w {
+		h = max(1, b.Dy()*w/b.Dx())
+	} else {
+		w = max(1, b.Dx()*h/b.Dy())
+	}
+	x0 := rect.Min.X + (rect.Dx()-w)/2
+	y0 := rect.Min.Y + (rect.Dy()-h)/2
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + y*b.Dy()/h
+		for x := 0; x < w; x++ {
+			sx := b.Min.X + x*b.Dx()/w
+			dst.Set(x0+x, y0+y, img.At(sx, sy))
+		}
+	}
+}
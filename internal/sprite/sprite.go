@@ -0,0 +1,186 @@
+// Package sprite packs an author's emojis into a single PNG atlas with matching CSS rules.
+package sprite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxSide bounds the atlas width so sheets stay within what browsers will decode.
+const MaxSide = 4096
+
+// ErrEmpty is returned when none of the sources could be placed on a sheet.
+var ErrEmpty = errors.New("no images to pack")
+
+// Source is an emoji to place on the sheet.
+type Source struct {
+	Name string
+	Mime string
+	Data []byte
+}
+
+// Cell is where an emoji sits on the sheet, in pixels.
+type Cell struct {
+	Name   string
+	X, Y   int
+	Width  int
+	Height int
+}
+
+// Sheet is a packed atlas. Skipped lists sources that could not be decoded (e.g. WebP, which
+// the standard library cannot read); animated GIFs contribute their first frame.
+type Sheet struct {
+	PNG     []byte
+	Cells   []Cell
+	Skipped []string
+}
+
+// Build decodes sources and packs them onto shelves, tallest first, returning the encoded
+// sheet. It returns ErrEmpty when nothing could be decoded.
+func Build(sources []Source) (*Sheet, error) {
+	type decoded struct {
+		name string
+		img  image.Image
+	}
+	var sheet Sheet
+	var imgs []decoded
+	for _, src := range sources {
+		img, err := decode(src)
+		if err != nil || img.Bounds().Empty() {
+			sheet.Skipped = append(sheet.Skipped, src.Name)
+			continue
+		}
+		imgs = append(imgs, decoded{name: src.Name, img: img})
+	}
+	if len(imgs) == 0 {
+		return nil, ErrEmpty
+	}
+
+	sort.SliceStable(imgs, func(i, j int) bool {
+		hi, hj := imgs[i].img.Bounds().Dy(), imgs[j].img.Bounds().Dy()
+		if hi != hj {
+			return hi > hj
+		}
+		return imgs[i].name < imgs[j].name
+	})
+
+	// Aim for a roughly square sheet, but never narrower than the widest image.
+	area, widest := 0, 0
+	for _, d := range imgs {
+		b := d.img.Bounds()
+		area += b.Dx() * b.Dy()
+		if b.Dx() > widest {
+			widest = b.Dx()
+		}
+	}
+	width := int(math.Ceil(math.Sqrt(float64(area))))
+	if width < widest {
+		width = widest
+	}
+	if width > MaxSide {
+		width = MaxSide
+	}
+
+	x, y, shelf, used := 0, 0, 0, 0
+	for _, d := range imgs {
+		b := d.img.Bounds()
+		if b.Dx() > width {
+			sheet.Skipped = append(sheet.Skipped, d.name)
+			continue
+		}
+		if x+b.Dx() > width {
+			x, y, shelf = 0, y+shelf, 0
+		}
+		sheet.Cells = append(sheet.Cells, Cell{Name: d.name, X: x, Y: y, Width: b.Dx(), Height: b.Dy()})
+		x += b.Dx()
+		if b.Dy() > shelf {
+			shelf = b.Dy()
+		}
+		if x > used {
+			used = x
+		}
+	}
+	if len(sheet.Cells) == 0 {
+		return nil, ErrEmpty
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, used, y+shelf))
+	byName := make(map[string]image.Image, len(imgs))
+	for _, d := range imgs {
+		byName[d.name] = d.img
+	}
+	for _, cell := range sheet.Cells {
+		img := byName[cell.Name]
+		draw.Draw(canvas, image.Rect(cell.X, cell.Y, cell.X+cell.Width, cell.Y+cell.Height), img, img.Bounds().Min, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("encode sheet: %w", err)
+	}
+	sheet.PNG = buf.Bytes()
+	sort.Strings(sheet.Skipped)
+	return &sheet, nil
+}
+
+func decode(src Source) (img image.Image, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, fmt.Errorf("%s: decoder panic: %v", src.Mime, r)
+		}
+	}()
+
+	r := bytes.NewReader(src.Data)
+	switch src.Mime {
+	case "image/png":
+		return png.Decode(r)
+	case "image/gif":
+		return gif.Decode(r)
+	case "image/jpeg":
+		return jpeg.Decode(r)
+	default:
+		return nil, fmt.Errorf("unsupported mime %s", src.Mime)
+	}
+}
+
+// CSS renders one `.emoji-<name>` rule per cell, positioning spriteURL so the element shows
+// just that emoji. Names are escaped into valid CSS identifiers.
+func (s *Sheet) CSS(spriteURL string) []byte {
+	var buf bytes.Buffer
+	for _, cell := range s.Cells {
+		fmt.Fprintf(&buf, ".emoji-%s { background: url(\"%s\") %s %s no-repeat; width: %dpx; height: %dpx; display: inline-block; }\n",
+			ClassName(cell.Name), spriteURL, offset(cell.X), offset(cell.Y), cell.Width, cell.Height)
+	}
+	return buf.Bytes()
+}
+
+func offset(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("-%dpx", n)
+}
+
+// ClassName escapes an emoji name for use after the `emoji-` class prefix. Letters, digits,
+// `-`, `_` and non-ASCII characters are kept; anything else becomes a CSS hex escape.
+func ClassName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r >= 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, `\%x `, r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package sprite
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func solidPNG(t *testing.T, w, h int, c color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+var cssRule = regexp.MustCompile(`^\.emoji-(\S+) \{ background: url\("sprite\.png"\) (0|-\d+px) (0|-\d+px) no-repeat; width: (\d+)px; height: (\d+)px; display: inline-block; \}$`)
+
+func px(v string) int {
+	if v == "0" {
+		return 0
+	}
+	n, _ := strconv.Atoi(v[1 : len(v)-2])
+	return n
+}
+
+func TestBuild_CSSMatchesAtlas(t *testing.T) {
+	colours := map[string]color.NRGBA{
+		"wave":  {R: 0xff, A: 0xff},
+		"smile": {G: 0xff, A: 0xff},
+		"party": {B: 0xff, A: 0xff},
+	}
+	sizes := map[string][2]int{"wave": {32, 32}, "smile": {16, 24}, "party": {48, 16}}
+	var sources []Source
+	for _, name := range []string{"wave", "smile", "party"} {
+		sources = append(sources, Source{Name: name, Mime: "image/png", Data: solidPNG(t, sizes[name][0], sizes[name][1], colours[name])})
+	}
+	sources = append(sources, Source{Name: "webp", Mime: "image/webp", Data: []byte("RIFF")})
+
+	sheet, err := Build(sources)
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	if len(sheet.Skipped) != 1 || sheet.Skipped[0] != "webp" {
+		t.Fatalf("expected webp to be skipped, got %v", sheet.Skipped)
+	}
+	atlas, err := png.Decode(bytes.NewReader(sheet.PNG))
+	if err != nil {
+		t.Fatalf("decode sheet: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(sheet.CSS("sprite.png")), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 rules, got %d:\n%s", len(lines), sheet.CSS("sprite.png"))
+	}
+	for _, line := range lines {
+		m := cssRule.FindStringSubmatch(string(line))
+		if m == nil {
+			t.Fatalf("unexpected rule %q", line)
+		}
+		name := m[1]
+		x, y := px(m[2]), px(m[3])
+		w, _ := strconv.Atoi(m[4])
+		h, _ := strconv.Atoi(m[5])
+		if [2]int{w, h} != sizes[name] {
+			t.Fatalf("%s: expected size %v, got %dx%d", name, sizes[name], w, h)
+		}
+		// Every pixel the rule exposes must belong to that emoji.
+		for _, p := range []image.Point{{x, y}, {x + w - 1, y + h - 1}} {
+			if got := color.NRGBAModel.Convert(atlas.At(p.X, p.Y)).(color.NRGBA); got != colours[name] {
+				t.Fatalf("%s: pixel %v is %v, want %v", name, p, got, colours[name])
+			}
+		}
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	if _, err := Build([]Source{{Name: "webp", Mime: "image/webp"}}); err != ErrEmpty {
+		t.Fatalf("expected ErrEmpty, got %v", err)
+	}
+}
+
+func TestClassName(t *testing.T) {
+	for name, want := range map[string]string{
+		"wave":      "wave",
+		"thumbs-up": "thumbs-up",
+		"a.b":       `a\2e b`,
+		"café":      "café",
+	} {
+		if got := ClassName(name); got != want {
+			t.Errorf("ClassName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// sync_state keys tracking the checksum backfill: the key of the last asset scanned, and a
+// marker set once a scan found nothing left.
+const (
+	checksumBackfillCursorKey = "checksum_backfill_cursor"
+	checksumBackfillDoneKey   = "checksum_backfill_done"
+)
+
+// backfillKey identifies an asset row; the backfill cursor stores the last one scanned.
+type backfillKey struct {
+	Author  string `json:"author"`
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+}
+
+// BackfillStatus reports the progress of the checksum backfill.
+type BackfillStatus struct {
+	// Done is set once the backfill has scanned every asset.
+	Done bool
+	// Remaining counts assets still without a checksum. It can stay above zero after Done
+	// for assets whose data couldn't be read.
+	Remaining int64
+}
+
+// BackfillChecksums fills in the checksum of up to batch assets stored without one (v1 emojis
+// registered before checksums were recorded), resuming after the last asset an earlier call
+// scanned. Each batch commits on its own, so a restart loses at most one batch of work. It
+// returns how many checksums it filled and whether the backfill is now complete.
+func (s *Store) BackfillChecksums(ctx context.Context, batch int) (int, bool, error) {
+	var filled int
+	var done bool
+	err := retryTx(ctx, func() (err error) {
+		filled, done, err = s.backfillChecksums(ctx, batch)
+		return err
+	})
+	return filled, done, err
+}
+
+func (s *Store) backfillChecksums(ctx context.Context, batch int) (int, bool, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var done string
+	err = tx.QueryRow(ctx, `SELECT value FROM sync_state WHERE key = $1`, checksumBackfillDoneKey).Scan(&done)
+	if err == nil {
+		return 0, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, err
+	}
+
+	var cursor backfillKey
+	var raw string
+	err = tx.QueryRow(ctx, `SELECT value FROM sync_state WHERE key = $1 FOR UPDATE`, checksumBackfillCursorKey).Scan(&raw)
+	if err == nil {
+		if err := json.Unmarshal([]byte(raw), &cursor); err != nil {
+			return 0, false, fmt.Errorf("decode backfill cursor: %w", err)
+		}
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT author, name, variant, data, fallback_data, compression
+        FROM hivemoji_assets
+        WHERE checksum IS NULL AND (author, name, variant) > ($1, $2, $3)
+        ORDER BY author, name, variant
+        LIMIT $4
+    `, cursor.Author, cursor.Name, cursor.Variant, batch)
+	if err != nil {
+		return 0, false, err
+	}
+	type pending struct {
+		key      backfillKey
+		checksum string
+	}
+	var scanned []pending
+	for rows.Next() {
+		var p pending
+		var asset Asset
+		var compression string
+		if err := rows.Scan(&p.key.Author, &p.key.Name, &p.key.Variant, &asset.Data, &asset.FallbackData, &compression); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		asset.Name = p.key.Name
+		if err := asset.decompressImages(compression); err != nil {
+			log.Printf("checksum backfill: skip %s/%s: %v", p.key.Author, p.key.Name, err)
+		} else {
+			p.checksum = sha256Hex(asset.Data)
+		}
+		scanned = append(scanned, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	if len(scanned) == 0 {
+		if _, err := tx.Exec(ctx, `
+            INSERT INTO sync_state (key, value, updated_at) VALUES ($1, 'true', now())
+            ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+        `, checksumBackfillDoneKey); err != nil {
+			return 0, false, err
+		}
+		return 0, true, tx.Commit(ctx)
+	}
+
+	filled := 0
+	for _, p := range scanned {
+		if p.checksum == "" {
+			continue
+		}
+		// Leave updated_at alone: the image didn't change, so caches keyed on it stay valid.
+		tag, err := tx.Exec(ctx, `
+            UPDATE hivemoji_assets SET checksum = $4
+            WHERE author = $1 AND name = $2 AND variant = $3 AND checksum IS NULL
+        `, p.key.Author, p.key.Name, p.key.Variant, p.checksum)
+		if err != nil {
+			return 0, false, err
+		}
+		filled += int(tag.RowsAffected())
+	}
+
+	next, err := json.Marshal(scanned[len(scanned)-1].key)
+	if err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO sync_state (key, value, updated_at) VALUES ($1, $2, now())
+        ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+    `, checksumBackfillCursorKey, string(next)); err != nil {
+		return 0, false, err
+	}
+	return filled, false, tx.Commit(ctx)
+}
+
+// GetBackfillStatus reports whether the checksum backfill has finished and how many assets
+// still lack a checksum.
+func (s *Store) GetBackfillStatus(ctx context.Context) (BackfillStatus, error) {
+	var status BackfillStatus
+	err := s.pool.QueryRow(ctx, `
+        SELECT
+            EXISTS (SELECT 1 FROM sync_state WHERE key = $1),
+            (SELECT count(*) FROM hivemoji_assets WHERE checksum IS NULL)
+    `, checksumBackfillDoneKey).Scan(&status.Done, &status.Remaining)
+	return status, err
+}
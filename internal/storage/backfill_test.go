@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBackfillChecksums(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	author := fmt.Sprintf("backfill-%d", time.Now().UnixNano())
+	data := []byte("legacy-png")
+	if err := store.UpsertV1(ctx, RegisterV1{Name: "legacy", Author: author, Mime: "image/png", Data: data}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	// Simulate a row stored before v1 checksums were recorded.
+	if _, err := store.pool.Exec(ctx, `UPDATE hivemoji_assets SET checksum = NULL WHERE author = $1`, author); err != nil {
+		t.Fatalf("clear checksum: %v", err)
+	}
+	if _, err := store.pool.Exec(ctx, `DELETE FROM sync_state WHERE key IN ($1, $2)`, checksumBackfillCursorKey, checksumBackfillDoneKey); err != nil {
+		t.Fatalf("reset backfill: %v", err)
+	}
+
+	for {
+		_, done, err := store.BackfillChecksums(ctx, 10)
+		if err != nil {
+			t.Fatalf("backfill: %v", err)
+		}
+		if done {
+			break
+		}
+	}
+
+	var checksum string
+	if err := store.pool.QueryRow(ctx, `SELECT checksum FROM hivemoji_assets WHERE author = $1`, author).Scan(&checksum); err != nil {
+		t.Fatalf("read checksum: %v", err)
+	}
+	if checksum != sha256Hex(data) {
+		t.Fatalf("expected checksum %s, got %s", sha256Hex(data), checksum)
+	}
+	status, err := store.GetBackfillStatus(ctx)
+	if err != nil || !status.Done {
+		t.Fatalf("expected backfill to report done, got %+v (%v)", status, err)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BannedChecksum is an image hash the processor refuses to store, whoever uploads it.
+type BannedChecksum struct {
+	Checksum  string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// BanChecksum adds checksum (a hex sha256) to the banned list, updating the reason if it is
+// already banned. Already stored emojis are left alone.
+func (s *Store) BanChecksum(ctx context.Context, checksum, reason string) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_banned_checksums (checksum, reason)
+        VALUES ($1, $2)
+        ON CONFLICT (checksum) DO UPDATE SET reason = EXCLUDED.reason
+    `, strings.ToLower(checksum), reason)
+	return err
+}
+
+// UnbanChecksum removes checksum from the banned list, returning ErrNotFound if it wasn't banned.
+func (s *Store) UnbanChecksum(ctx context.Context, checksum string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_banned_checksums WHERE checksum = $1`, strings.ToLower(checksum))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListBannedChecksums returns every banned checksum, most recently banned first.
+func (s *Store) ListBannedChecksums(ctx context.Context) ([]BannedChecksum, error) {
+	rows, err := s.pool.Query(ctx, `SELECT checksum, reason, created_at FROM hivemoji_banned_checksums ORDER BY created_at DESC, checksum`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	banned := []BannedChecksum{}
+	for rows.Next() {
+		var b BannedChecksum
+		if err := rows.Scan(&b.Checksum, &b.Reason, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		banned = append(banned, b)
+	}
+	return banned, rows.Err()
+}
+
+// FindBannedChecksum returns the first of checksums that is banned, or "" when none are.
+func (s *Store) FindBannedChecksum(ctx context.Context, checksums []string) (string, error) {
+	if len(checksums) == 0 {
+		return "", nil
+	}
+	lower := make([]string, len(checksums))
+	for i, c := range checksums {
+		lower[i] = strings.ToLower(c)
+	}
+	var banned string
+	err := s.pool.QueryRow(ctx, `SELECT checksum FROM hivemoji_banned_checksums WHERE checksum = ANY($1) LIMIT 1`, lower).Scan(&banned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return banned, err
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BlobStore holds emoji image bytes outside hivemoji_assets, which then keeps only a key for
+// each image. Keys are opaque and written once; Get returns ErrNotFound for unknown keys and
+// Delete succeeds for them.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// SetBlobStore makes emojis written from now on keep their image bytes in blobs; nil, the
+// default, stores them inline in hivemoji_assets. Rows already written keep their bytes
+// where they are, and stay readable as long as the blob store holding them is configured.
+func (s *Store) SetBlobStore(blobs BlobStore) {
+	s.blobs = blobs
+}
+
+// storedImages is how an emoji's image bytes are written to hivemoji_assets: inline in data
+// and fallback_data, or as keys of blobs already put in the BlobStore.
+type storedImages struct {
+	data        []byte
+	fallback    []byte
+	compression string
+	dataRef     *string
+	fallbackRef *string
+	// blobBytes is the size of the blobs, which AuthorUsage counts in place of the empty
+	// inline columns.
+	blobBytes int64
+}
+
+// refs lists the blob keys the images were stored under.
+func (img storedImages) refs() []string {
+	var refs []string
+	for _, ref := range []*string{img.dataRef, img.fallbackRef} {
+		if ref != nil {
+			refs = append(refs, *ref)
+		}
+	}
+	return refs
+}
+
+// storeImages compresses data and fallback and, with a BlobStore configured, puts them there
+// under fresh keys. Blobs are put before the row that references them is written, so callers
+// must release img.refs() if that write fails.
+func (s *Store) storeImages(ctx context.Context, data, fallback []byte) (storedImages, error) {
+	var img storedImages
+	img.data, img.fallback, img.compression = s.compressImages(data, fallback)
+	if s.blobs == nil {
+		return img, nil
+	}
+
+	dataRef, err := s.putBlob(ctx, img.data)
+	if err != nil {
+		return storedImages{}, err
+	}
+	img.dataRef, img.blobBytes = &dataRef, int64(len(img.data))
+	img.data = []byte{}
+	if len(img.fallback) > 0 {
+		fallbackRef, err := s.putBlob(ctx, img.fallback)
+		if err != nil {
+			s.releaseBlobs(ctx, img.refs())
+			return storedImages{}, err
+		}
+		img.fallbackRef, img.blobBytes = &fallbackRef, img.blobBytes+int64(len(img.fallback))
+		img.fallback = nil
+	}
+	return img, nil
+}
+
+func (s *Store) putBlob(ctx context.Context, data []byte) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b[:])
+	if err := s.blobs.Put(ctx, key, data); err != nil {
+		return "", fmt.Errorf("put blob: %w", err)
+	}
+	return key, nil
+}
+
+// loadBlobs fills r's image bytes from the BlobStore when the row references blobs.
+func (s *Store) loadBlobs(ctx context.Context, r *assetRow) error {
+	if r.DataRef == nil && r.FallbackRef == nil {
+		return nil
+	}
+	if s.blobs == nil {
+		return fmt.Errorf("%s: images are in a blob store, but none is configured", r.Name)
+	}
+	if r.DataRef != nil {
+		data, err := s.blobs.Get(ctx, *r.DataRef)
+		if err != nil {
+			return fmt.Errorf("%s: get blob %s: %w", r.Name, *r.DataRef, err)
+		}
+		r.Data = data
+	}
+	if r.FallbackRef != nil {
+		fallback, err := s.blobs.Get(ctx, *r.FallbackRef)
+		if err != nil {
+			return fmt.Errorf("%s: get fallback blob %s: %w", r.Name, *r.FallbackRef, err)
+		}
+		r.FallbackData = fallback
+	}
+	return nil
+}
+
+// releaseBlobs deletes blobs no row references any more. Failures are only logged: a
+// leftover blob wastes space but is never read.
+func (s *Store) releaseBlobs(ctx context.Context, refs []string) {
+	if s.blobs == nil {
+		return
+	}
+	for _, ref := range refs {
+		if err := s.blobs.Delete(ctx, ref); err != nil {
+			log.Printf("release blob %s: %v", ref, err)
+		}
+	}
+}
+
+// replacedBlobs returns the blob keys of the emoji row a write is about to replace, locking
+// the row until tx ends. Without a BlobStore there is nothing to release.
+func (s *Store) replacedBlobs(ctx context.Context, tx pgx.Tx, author, name, variant string) ([]string, error) {
+	if s.blobs == nil {
+		return nil, nil
+	}
+	var dataRef, fallbackRef *string
+	err := tx.QueryRow(ctx, `
+        SELECT data_ref, fallback_ref FROM hivemoji_assets WHERE author = $1 AND name = $2 AND variant = $3 FOR UPDATE
+    `, author, name, variant).Scan(&dataRef, &fallbackRef)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storedImages{dataRef: dataRef, fallbackRef: fallbackRef}.refs(), nil
+}
+
+// deletedBlobs collects the blob keys from a DELETE ... RETURNING data_ref, fallback_ref.
+func deletedBlobs(rows pgx.Rows) ([]string, error) {
+	var refs []string
+	var dataRef, fallbackRef *string
+	_, err := pgx.ForEachRow(rows, []any{&dataRef, &fallbackRef}, func() error {
+		refs = append(refs, storedImages{dataRef: dataRef, fallbackRef: fallbackRef}.refs()...)
+		return nil
+	})
+	return refs, err
+}
+
+// PostgresBlobs keeps blobs in the hivemoji_blobs table, out of the rows listings scan.
+type PostgresBlobs struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresBlobs returns a BlobStore backed by pool. The table is created by EnsureSchema.
+func NewPostgresBlobs(pool *pgxpool.Pool) *PostgresBlobs {
+	return &PostgresBlobs{pool: pool}
+}
+
+func (b *PostgresBlobs) Put(ctx context.Context, key string, data []byte) error {
+	_, err := b.pool.Exec(ctx, `
+        INSERT INTO hivemoji_blobs (key, data) VALUES ($1, $2)
+        ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data
+    `, key, data)
+	return err
+}
+
+func (b *PostgresBlobs) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := b.pool.QueryRow(ctx, `SELECT data FROM hivemoji_blobs WHERE key = $1`, key).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (b *PostgresBlobs) Delete(ctx context.Context, key string) error {
+	_, err := b.pool.Exec(ctx, `DELETE FROM hivemoji_blobs WHERE key = $1`, key)
+	return err
+}
+
+// FileBlobs keeps each blob in its own file under a directory, fanned out into
+// subdirectories by the key's first two characters.
+type FileBlobs struct {
+	dir string
+}
+
+// NewFileBlobs returns a BlobStore rooted at dir, creating it if needed.
+func NewFileBlobs(dir string) (*FileBlobs, error) {
+	if dir == "" {
+		return nil, errors.New("blob directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileBlobs{dir: dir}, nil
+}
+
+func (b *FileBlobs) path(key string) (string, error) {
+	if len(key) < 3 || filepath.Base(key) != key || key[0] == '.' {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(b.dir, key[:2], key), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place, so readers never see a
+// partial blob.
+func (b *FileBlobs) Put(ctx context.Context, key string, data []byte) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *FileBlobs) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (b *FileBlobs) Delete(ctx context.Context, key string) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memBlobs is an in-memory BlobStore.
+type memBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memBlobs) Put(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = map[string][]byte{}
+	}
+	m.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memBlobs) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *memBlobs) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
+
+// testBlobContract checks the BlobStore behaviour every backend must share.
+func testBlobContract(t *testing.T, blobs BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+	key := fmt.Sprintf("%x", time.Now().UnixNano())
+
+	if _, err := blobs.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before put, got %v", err)
+	}
+	if err := blobs.Put(ctx, key, []byte("first")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := blobs.Put(ctx, key, []byte("second")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	got, err := blobs.Get(ctx, key)
+	if err != nil || string(got) != "second" {
+		t.Fatalf("expected the overwritten blob, got %q, %v", got, err)
+	}
+	if err := blobs.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := blobs.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := blobs.Delete(ctx, key); err != nil {
+		t.Fatalf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestFileBlobs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	blobs, err := NewFileBlobs(dir)
+	if err != nil {
+		t.Fatalf("NewFileBlobs: %v", err)
+	}
+	testBlobContract(t, blobs)
+
+	if err := blobs.Put(context.Background(), "abcdef", []byte("png")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "ab", "abcdef")); err != nil || string(data) != "png" {
+		t.Fatalf("expected the blob under its fan-out directory, got %q, %v", data, err)
+	}
+	for _, key := range []string{"../escape", "ab/cd", ".hidden", "x"} {
+		if err := blobs.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Fatalf("expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestPostgresBlobs(t *testing.T) {
+	store := testStore(t)
+	testBlobContract(t, NewPostgresBlobs(store.pool))
+}
+
+func TestStoreImages_BlobStore(t *testing.T) {
+	ctx := context.Background()
+	blobs := &memBlobs{}
+	store := &Store{compression: CompressionZstd, blobs: blobs}
+	main, fallback := bytes.Repeat([]byte("png"), 100), bytes.Repeat([]byte("gif"), 100)
+
+	img, err := store.storeImages(ctx, main, fallback)
+	if err != nil {
+		t.Fatalf("storeImages: %v", err)
+	}
+	if len(img.data) != 0 || img.fallback != nil || len(img.refs()) != 2 {
+		t.Fatalf("expected both images moved to blobs, got %d inline bytes and refs %v", len(img.data), img.refs())
+	}
+	if want := int64(len(blobs.blobs[*img.dataRef]) + len(blobs.blobs[*img.fallbackRef])); img.blobBytes != want {
+		t.Fatalf("expected blob_bytes %d, got %d", want, img.blobBytes)
+	}
+
+	asset, err := store.resolveImages(ctx, assetRow{Asset: Asset{Name: "wave"}, Compression: img.compression, DataRef: img.dataRef, FallbackRef: img.fallbackRef})
+	if err != nil {
+		t.Fatalf("resolveImages: %v", err)
+	}
+	if !bytes.Equal(asset.Data, main) || !bytes.Equal(asset.FallbackData, fallback) {
+		t.Fatalf("expected the original images back from the blob store")
+	}
+
+	store.releaseBlobs(ctx, img.refs())
+	if len(blobs.blobs) != 0 {
+		t.Fatalf("expected released blobs to be deleted, %d left", len(blobs.blobs))
+	}
+	if _, err := (&Store{}).resolveImages(ctx, assetRow{Asset: Asset{Name: "wave"}, DataRef: img.dataRef}); err == nil {
+		t.Fatalf("expected an error reading blob-backed images without a blob store")
+	}
+}
+
+func TestUpsertV1_FileBlobs(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	blobs, err := NewFileBlobs(dir)
+	if err != nil {
+		t.Fatalf("NewFileBlobs: %v", err)
+	}
+	store.SetBlobStore(blobs)
+
+	author := fmt.Sprintf("blobs-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author = $1`, author)
+	})
+	files := func() int {
+		n := 0
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+
+	for _, data := range []string{"first", "second"} {
+		if err := store.UpsertV1(ctx, RegisterV1{Name: "wave", Author: author, Mime: "image/png", Data: []byte(data), FallbackMime: "image/png", FallbackData: []byte("fallback")}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	if n := files(); n != 2 {
+		t.Fatalf("expected the replaced blobs to be released, found %d files", n)
+	}
+
+	asset, err := store.GetAsset(ctx, author, "wave")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(asset.Data) != "second" || string(asset.FallbackData) != "fallback" {
+		t.Fatalf("expected images from the blob store, got %q and %q", asset.Data, asset.FallbackData)
+	}
+	var inline int
+	if err := store.pool.QueryRow(ctx, `SELECT octet_length(data) FROM hivemoji_assets WHERE author = $1`, author).Scan(&inline); err != nil || inline != 0 {
+		t.Fatalf("expected no inline bytes, got %d, %v", inline, err)
+	}
+	if usage, err := store.AuthorUsage(ctx, author); err != nil || usage.Bytes != int64(len("second")+len("fallback")) {
+		t.Fatalf("expected usage to count blob bytes, got %+v, %v", usage, err)
+	}
+
+	if err := store.DeleteEmoji(ctx, author, "wave"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n := files(); n != 0 {
+		t.Fatalf("expected deleting the emoji to delete its blobs, found %d files", n)
+	}
+}
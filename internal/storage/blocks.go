@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordBlockID stores the id of a processed block so the block built on it can be checked
+// against it. A block number processed again after a fork gets its new id.
+func (s *Store) RecordBlockID(ctx context.Context, number int64, id string) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_block_ids (block_num, block_id)
+        VALUES ($1, $2)
+        ON CONFLICT (block_num) DO UPDATE SET block_id = EXCLUDED.block_id, recorded_at = now()
+    `, number, id)
+	return err
+}
+
+// GetBlockID returns the recorded id of a processed block, or ErrNotFound.
+func (s *Store) GetBlockID(ctx context.Context, number int64) (string, error) {
+	var id string
+	err := s.pool.QueryRow(ctx, `SELECT block_id FROM hivemoji_block_ids WHERE block_num = $1`, number).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+// RollbackToBlock rewinds ingestion to forkPoint after a micro-fork: last_block becomes
+// forkPoint and the ids recorded for later blocks are dropped, so the canonical blocks after
+// it are processed again. Emojis already stored from orphaned blocks are left as they are,
+// including earlier emojis re-registered there: revisions keep no image bytes to restore
+// them from, so they hold the orphaned data until the canonical blocks register them again.
+func (s *Store) RollbackToBlock(ctx context.Context, forkPoint int64) error {
+	return retryTx(ctx, func() error { return s.rollbackToBlock(ctx, forkPoint) })
+}
+
+func (s *Store) rollbackToBlock(ctx context.Context, forkPoint int64) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM hivemoji_block_ids WHERE block_num > $1`, forkPoint); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO sync_state (key, value, updated_at)
+        VALUES ('last_block', $1, now())
+        ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+    `, fmt.Sprintf("%d", forkPoint)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM sync_state WHERE key = $1`, opCheckpointKey); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// PruneBlockIDs deletes the ids recorded for blocks before below and returns how many were
+// deleted. Only recent blocks can still be forked away.
+func (s *Store) PruneBlockIDs(ctx context.Context, below int64) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_block_ids WHERE block_num < $1`, below)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
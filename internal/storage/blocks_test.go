@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRollbackToBlock(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	last, err := store.LastBlock(ctx)
+	if err != nil {
+		t.Fatalf("LastBlock: %v", err)
+	}
+	base := int64(1_000_000_000)
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_block_ids WHERE block_num >= $1`, base)
+		_ = store.SetLastBlock(ctx, last)
+	})
+
+	for n, id := range map[int64]string{base: "a0", base + 1: "a1", base + 2: "a2"} {
+		if err := store.RecordBlockID(ctx, n, id); err != nil {
+			t.Fatalf("RecordBlockID %d: %v", n, err)
+		}
+	}
+	if id, err := store.GetBlockID(ctx, base+1); err != nil || id != "a1" {
+		t.Fatalf("expected recorded id a1, got %q, %v", id, err)
+	}
+
+	if err := store.RollbackToBlock(ctx, base); err != nil {
+		t.Fatalf("RollbackToBlock: %v", err)
+	}
+	if n, err := store.LastBlock(ctx); err != nil || n != base {
+		t.Fatalf("expected last block %d, got %d, %v", base, n, err)
+	}
+	if _, err := store.GetBlockID(ctx, base+1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ids past the fork point to be dropped, got %v", err)
+	}
+	if id, err := store.GetBlockID(ctx, base); err != nil || id != "a0" {
+		t.Fatalf("expected the fork point's id to be kept, got %q, %v", id, err)
+	}
+}
+
+func TestRollbackToBlock_KeepsReregisteredEmoji(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	last, err := store.LastBlock(ctx)
+	if err != nil {
+		t.Fatalf("LastBlock: %v", err)
+	}
+	author := fmt.Sprintf("hivemoji-fork-%d", time.Now().UnixNano())
+	base := int64(1_000_000_100)
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author = $1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author = $1`, author)
+		_ = store.SetLastBlock(ctx, last)
+	})
+
+	// Registered before the fork point, then again in a block that gets orphaned.
+	for i, block := range []int64{base - 1, base + 2} {
+		err := store.UpsertV1(ctx, RegisterV1{Name: "wave", Author: author, Mime: "image/png", Data: []byte{byte(i)}, RegisteredBlock: block})
+		if err != nil {
+			t.Fatalf("UpsertV1 at %d: %v", block, err)
+		}
+	}
+
+	if err := store.RollbackToBlock(ctx, base); err != nil {
+		t.Fatalf("RollbackToBlock: %v", err)
+	}
+	asset, err := store.GetAsset(ctx, author, "wave")
+	if err != nil {
+		t.Fatalf("expected the emoji to survive the rollback, got %v", err)
+	}
+	if asset.RegisteredBlock == nil || *asset.RegisteredBlock != base+2 {
+		t.Fatalf("expected the emoji to keep its latest registration, got %v", asset.RegisteredBlock)
+	}
+	if versions, err := store.ListAssetVersions(ctx, author, "wave"); err != nil || len(versions) != 2 {
+		t.Fatalf("expected both revisions kept, got %d, %v", len(versions), err)
+	}
+	if n, err := store.LastBlock(ctx); err != nil || n != base {
+		t.Fatalf("expected last block %d, got %d, %v", base, n, err)
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const opCheckpointKey = "op_checkpoint"
+
+// OpCheckpoint is the position of the last op applied from a block whose processing hasn't
+// completed: Tx indexes the block's transactions and Op that transaction's operations. The
+// zero value means no block is partway through.
+type OpCheckpoint struct {
+	Block int64 `json:"block"`
+	Tx    int   `json:"tx"`
+	Op    int   `json:"op"`
+}
+
+// Covers reports whether the op at (tx, op) of block is at or before the checkpoint, i.e.
+// was already applied.
+func (c OpCheckpoint) Covers(block int64, tx, op int) bool {
+	if c.Block == 0 || c.Block != block {
+		return false
+	}
+	return tx < c.Tx || (tx == c.Tx && op <= c.Op)
+}
+
+// SetOpCheckpoint records c as the last applied op; the zero value clears it.
+func (s *Store) SetOpCheckpoint(ctx context.Context, c OpCheckpoint) error {
+	if c == (OpCheckpoint{}) {
+		_, err := s.pool.Exec(ctx, `DELETE FROM sync_state WHERE key = $1`, opCheckpointKey)
+		return err
+	}
+	value, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, `
+        INSERT INTO sync_state (key, value, updated_at)
+        VALUES ($1, $2, now())
+        ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+    `, opCheckpointKey, string(value))
+	return err
+}
+
+// GetOpCheckpoint returns the recorded op checkpoint, or the zero value if there is none.
+func (s *Store) GetOpCheckpoint(ctx context.Context) (OpCheckpoint, error) {
+	var raw string
+	err := s.pool.QueryRow(ctx, `SELECT value FROM sync_state WHERE key = $1`, opCheckpointKey).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return OpCheckpoint{}, nil
+	}
+	if err != nil {
+		return OpCheckpoint{}, err
+	}
+	var c OpCheckpoint
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		return OpCheckpoint{}, fmt.Errorf("decode op checkpoint: %w", err)
+	}
+	return c, nil
+}
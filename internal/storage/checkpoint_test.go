@@ -0,0 +1,27 @@
+package storage
+
+import "testing"
+
+func TestOpCheckpointCovers(t *testing.T) {
+	c := OpCheckpoint{Block: 50, Tx: 1, Op: 2}
+	cases := []struct {
+		block   int64
+		tx, op  int
+		applied bool
+	}{
+		{50, 0, 5, true},
+		{50, 1, 2, true},
+		{50, 1, 3, false},
+		{50, 2, 0, false},
+		{49, 0, 0, false},
+		{51, 0, 0, false},
+	}
+	for _, tc := range cases {
+		if got := c.Covers(tc.block, tc.tx, tc.op); got != tc.applied {
+			t.Errorf("Covers(%d, %d, %d) = %t, want %t", tc.block, tc.tx, tc.op, got, tc.applied)
+		}
+	}
+	if (OpCheckpoint{}).Covers(0, 0, 0) {
+		t.Errorf("expected the zero checkpoint to cover nothing")
+	}
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testStore connects to the database named by HIVEMOJI_TEST_POSTGRES_DSN, skipping the test
+// when it isn't set.
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	dsn := os.Getenv("HIVEMOJI_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("HIVEMOJI_TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	store := NewStore(pool)
+	if err := store.EnsureSchema(ctx); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+	return store
+}
+
+func TestSaveChunk_RejectsAbsurdTotal(t *testing.T) {
+	// No pool: the chunk must be rejected before anything touches the database.
+	store := &Store{}
+	chunk := ChunkPayload{ID: "huge", Kind: "main", Name: "wave", Author: "mrtats", Mime: "image/png", Seq: 1, Total: math.MaxInt32, Data: []byte("png")}
+	if _, err := store.SaveChunk(context.Background(), chunk); !errors.Is(err, ErrChunkTotal) {
+		t.Fatalf("expected ErrChunkTotal, got %v", err)
+	}
+
+	store.SetMaxChunks(4)
+	chunk.Total = 5
+	if _, err := store.SaveChunk(context.Background(), chunk); !errors.Is(err, ErrChunkTotal) {
+		t.Fatalf("expected ErrChunkTotal over the configured limit, got %v", err)
+	}
+}
+
+func TestSaveChunk_ConcurrentSameUpload(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	const total = 8
+	uploadID := fmt.Sprintf("concurrent-%d", time.Now().UnixNano())
+	author := "hivemoji-test"
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunks WHERE upload_id=$1`, uploadID)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunk_sets WHERE upload_id=$1`, uploadID)
+	})
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		completed []*AssembledSet
+	)
+	for seq := 0; seq < total; seq++ {
+		wg.Add(1)
+		go func(seq int) {
+			defer wg.Done()
+			set, err := store.SaveChunk(ctx, ChunkPayload{
+				ID:      uploadID,
+				Kind:    "main",
+				Seq:     seq,
+				Total:   total,
+				Name:    "wave",
+				Author:  author,
+				Version: 2,
+				Mime:    "image/png",
+				Data:    []byte{byte(seq)},
+			})
+			if err != nil {
+				t.Errorf("SaveChunk seq %d: %v", seq, err)
+				return
+			}
+			if set != nil {
+				mu.Lock()
+				completed = append(completed, set)
+				mu.Unlock()
+			}
+		}(seq)
+	}
+	wg.Wait()
+
+	if len(completed) != 1 {
+		t.Fatalf("expected exactly one call to complete the upload, got %d", len(completed))
+	}
+	if len(completed[0].Data) != total {
+		t.Fatalf("expected %d assembled bytes, got %d", total, len(completed[0].Data))
+	}
+	for i, b := range completed[0].Data {
+		if int(b) != i {
+			t.Fatalf("expected chunks in seq order, got %v", completed[0].Data)
+		}
+	}
+}
+
+func TestCompactChunks_DropsStoredUploads(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	uploadID := fmt.Sprintf("compact-%d", time.Now().UnixNano())
+	pendingID := uploadID + "-pending"
+	author := "hivemoji-test"
+	t.Cleanup(func() {
+		for _, id := range []string{uploadID, pendingID} {
+			_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunks WHERE upload_id=$1`, id)
+			_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunk_sets WHERE upload_id=$1`, id)
+		}
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author=$1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author=$1`, author)
+	})
+
+	chunk := func(id string, seq int) ChunkPayload {
+		return ChunkPayload{ID: id, Kind: "main", Seq: seq, Total: 2, Name: "compact", Author: author, Version: 2, Mime: "image/png", Data: []byte{byte(seq)}}
+	}
+	var set *AssembledSet
+	for seq := 1; seq <= 2; seq++ {
+		var err error
+		if set, err = store.SaveChunk(ctx, chunk(uploadID, seq)); err != nil {
+			t.Fatalf("SaveChunk seq %d: %v", seq, err)
+		}
+	}
+	if set == nil {
+		t.Fatalf("expected the upload to complete")
+	}
+	// An upload still in progress keeps its chunks.
+	if _, err := store.SaveChunk(ctx, chunk(pendingID, 1)); err != nil {
+		t.Fatalf("SaveChunk pending: %v", err)
+	}
+
+	// Not stored as an asset yet: nothing to compact.
+	if _, _, err := store.CompactChunks(ctx, 0); err != nil {
+		t.Fatalf("CompactChunks: %v", err)
+	}
+	if _, err := store.GetChunkSet(ctx, uploadID, "main"); err != nil {
+		t.Fatalf("expected the assembled set to survive before it is stored, got %v", err)
+	}
+
+	if err := store.UpsertFromChunks(ctx, set, nil); err != nil {
+		t.Fatalf("UpsertFromChunks: %v", err)
+	}
+	sets, chunks, err := store.CompactChunks(ctx, 0)
+	if err != nil {
+		t.Fatalf("CompactChunks: %v", err)
+	}
+	if sets < 1 || chunks < 2 {
+		t.Fatalf("expected the stored upload to be compacted, got %d sets and %d chunks", sets, chunks)
+	}
+
+	var left int
+	if err := store.pool.QueryRow(ctx, `SELECT count(*) FROM hivemoji_chunks WHERE upload_id=$1`, uploadID).Scan(&left); err != nil {
+		t.Fatalf("count chunks: %v", err)
+	}
+	if left != 0 {
+		t.Fatalf("expected no chunks left after compaction, got %d", left)
+	}
+	if _, err := store.GetChunkSet(ctx, uploadID, "main"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected compacted set to read as not found, got %v", err)
+	}
+	status, err := store.GetUploadStatus(ctx, uploadID, "main")
+	if err != nil || !status.Completed {
+		t.Fatalf("expected upload status to remain completed, got %+v, %v", status, err)
+	}
+	asset, err := store.GetAsset(ctx, author, "compact")
+	if err != nil || len(asset.Data) != 2 {
+		t.Fatalf("expected the stored emoji to keep its bytes, got %+v, %v", asset, err)
+	}
+
+	if err := store.pool.QueryRow(ctx, `SELECT count(*) FROM hivemoji_chunks WHERE upload_id=$1`, pendingID).Scan(&left); err != nil {
+		t.Fatalf("count pending chunks: %v", err)
+	}
+	if left != 1 {
+		t.Fatalf("expected the in-progress upload to keep its chunk, got %d", left)
+	}
+}
+
+func TestCleanupIncomplete_KeepsCompletedSets(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	stale := fmt.Sprintf("stale-%d", time.Now().UnixNano())
+	done := stale + "-done"
+	t.Cleanup(func() {
+		for _, id := range []string{stale, done} {
+			_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunks WHERE upload_id=$1`, id)
+			_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_chunk_sets WHERE upload_id=$1`, id)
+		}
+	})
+
+	chunk := func(id string, seq, total int) ChunkPayload {
+		return ChunkPayload{ID: id, Kind: "main", Seq: seq, Total: total, Name: "wave", Author: "hivemoji-test", Version: 2, Mime: "image/png", Data: []byte{byte(seq)}}
+	}
+	if _, err := store.SaveChunk(ctx, chunk(stale, 1, 2)); err != nil {
+		t.Fatalf("SaveChunk stale: %v", err)
+	}
+	if _, err := store.SaveChunk(ctx, chunk(done, 1, 1)); err != nil {
+		t.Fatalf("SaveChunk done: %v", err)
+	}
+
+	sets, chunks, err := store.CleanupIncomplete(ctx, 0)
+	if err != nil {
+		t.Fatalf("CleanupIncomplete: %v", err)
+	}
+	if sets < 1 || chunks < 1 {
+		t.Fatalf("expected the stale upload to be removed, got %d sets and %d chunks", sets, chunks)
+	}
+	if _, err := store.GetUploadStatus(ctx, stale, "main"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected stale upload to be gone, got %v", err)
+	}
+	set, err := store.GetChunkSet(ctx, done, "main")
+	if err != nil || len(set.Data) != 1 {
+		t.Fatalf("expected completed set to keep its data, got %+v, %v", set, err)
+	}
+}
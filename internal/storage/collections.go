@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Collection is a curated, ordered list of emojis that may span authors. Its items refer to
+// emojis by author and name, so an emoji deleted and registered again reappears in place.
+type Collection struct {
+	Slug        string
+	Title       string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// UpsertCollection creates the collection c.Slug or updates its title and description.
+func (s *Store) UpsertCollection(ctx context.Context, c Collection) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_collections (slug, title, description)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (slug) DO UPDATE SET
+            title = EXCLUDED.title,
+            description = EXCLUDED.description,
+            updated_at = now()
+    `, c.Slug, c.Title, c.Description)
+	return err
+}
+
+// DeleteCollection deletes a collection with its items, returning ErrNotFound if it doesn't exist.
+func (s *Store) DeleteCollection(ctx context.Context, slug string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_collections WHERE slug = $1`, slug)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddCollectionItem adds the base emoji author/name to a collection at position, or moves it
+// there if it is already listed. Items are ordered by position, then by when they were added.
+// It returns ErrNotFound when the collection or the emoji doesn't exist.
+func (s *Store) AddCollectionItem(ctx context.Context, slug, author, name string, position int) error {
+	return retryTx(ctx, func() error { return s.addCollectionItem(ctx, slug, author, name, position) })
+}
+
+func (s *Store) addCollectionItem(ctx context.Context, slug, author, name string, position int) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM hivemoji_collections WHERE slug = $1 FOR KEY SHARE)
+           AND EXISTS (SELECT 1 FROM hivemoji_assets WHERE author = $2 AND name = $3 AND variant = '')
+    `, slug, author, name).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO hivemoji_collection_items (slug, author, name, position)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (slug, author, name) DO UPDATE SET position = EXCLUDED.position
+    `, slug, author, name, position); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE hivemoji_collections SET updated_at = now() WHERE slug = $1`, slug); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveCollectionItem removes author/name from a collection, returning ErrNotFound if it
+// wasn't listed.
+func (s *Store) RemoveCollectionItem(ctx context.Context, slug, author, name string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_collection_items WHERE slug = $1 AND author = $2 AND name = $3`, slug, author, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetCollection returns a collection and its emojis in order. Items whose emoji isn't public,
+// e.g. deleted, expired or awaiting moderation, are left out. It returns ErrNotFound for
+// unknown slugs.
+func (s *Store) GetCollection(ctx context.Context, slug string, includeData bool) (*Collection, []Asset, error) {
+	var c Collection
+	err := s.pool.QueryRow(ctx, `
+        SELECT slug, title, description, created_at, updated_at FROM hivemoji_collections WHERE slug = $1
+    `, slug).Scan(&c.Slug, &c.Title, &c.Description, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+        SELECT %s
+        FROM hivemoji_assets
+        JOIN (
+            SELECT author AS item_author, name AS item_name, position, added_at
+            FROM hivemoji_collection_items WHERE slug = $1
+        ) items ON items.item_author = hivemoji_assets.author AND items.item_name = hivemoji_assets.name
+        WHERE %s
+        ORDER BY items.position, items.added_at, name
+    `, selectAssetColumns(includeData), publicAssets), slug)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, err := s.collectAssets(ctx, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &c, assets, nil
+}
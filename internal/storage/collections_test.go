@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollections(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	slug := fmt.Sprintf("test-%d", suffix)
+	alice := fmt.Sprintf("alice-%d", suffix)
+	bob := fmt.Sprintf("bob-%d", suffix)
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_collections WHERE slug = $1`, slug)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author = ANY($1)`, []string{alice, bob})
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author = ANY($1)`, []string{alice, bob})
+	})
+	for _, reg := range []RegisterV1{
+		{Name: "wave", Author: alice, Mime: "image/png", Data: []byte("wave")},
+		{Name: "smile", Author: bob, Mime: "image/png", Data: []byte("smile")},
+	} {
+		if err := store.UpsertV1(ctx, reg); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	if err := store.AddCollectionItem(ctx, slug, alice, "wave", 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing collection, got %v", err)
+	}
+	if err := store.UpsertCollection(ctx, Collection{Slug: slug, Title: "Picks"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := store.AddCollectionItem(ctx, slug, alice, "missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing emoji, got %v", err)
+	}
+	if err := store.AddCollectionItem(ctx, slug, alice, "wave", 2); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := store.AddCollectionItem(ctx, slug, bob, "smile", 1); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	names := func() []string {
+		t.Helper()
+		c, assets, err := store.GetCollection(ctx, slug, false)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if c.Title != "Picks" {
+			t.Fatalf("unexpected title %q", c.Title)
+		}
+		var out []string
+		for _, a := range assets {
+			out = append(out, a.Name)
+		}
+		return out
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"smile", "wave"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+
+	if err := store.AddCollectionItem(ctx, slug, alice, "wave", 0); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"wave", "smile"}) {
+		t.Fatalf("unexpected order after moving %v", got)
+	}
+
+	if err := store.RemoveCollectionItem(ctx, slug, bob, "smile"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := store.RemoveCollectionItem(ctx, slug, bob, "smile"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound removing twice, got %v", err)
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"wave"}) {
+		t.Fatalf("unexpected items after removal %v", got)
+	}
+
+	if err := store.DeleteCollection(ctx, slug); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, err := store.GetCollection(ctx, slug, false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
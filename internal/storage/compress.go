@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionZstd is the codec name stored for zstd-compressed image bytes.
+const CompressionZstd = "zstd"
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// SetCompression selects how new image bytes are compressed at rest: "" or "none" stores them
+// as-is, "zstd" compresses them. Rows written with either setting stay readable.
+func (s *Store) SetCompression(codec string) error {
+	switch codec {
+	case "", "none":
+		s.compression = ""
+	case CompressionZstd:
+		s.compression = CompressionZstd
+	default:
+		return fmt.Errorf("unknown compression %q (want none or zstd)", codec)
+	}
+	return nil
+}
+
+// compressImages encodes data and fallback with the configured codec. It returns the inputs
+// unchanged and an empty codec when compression is off or would not save space, which is
+// common for already-compressed WebP and GIF.
+func (s *Store) compressImages(data, fallback []byte) ([]byte, []byte, string) {
+	if s.compression != CompressionZstd {
+		return data, fallback, ""
+	}
+	cdata := zstdEncoder.EncodeAll(data, nil)
+	var cfallback []byte
+	if len(fallback) > 0 {
+		cfallback = zstdEncoder.EncodeAll(fallback, nil)
+	}
+	if len(cdata)+len(cfallback) >= len(data)+len(fallback) {
+		return data, fallback, ""
+	}
+	return cdata, cfallback, CompressionZstd
+}
+
+// decompressImages restores the asset's image bytes stored with codec.
+func (a *Asset) decompressImages(codec string) error {
+	switch codec {
+	case "":
+		return nil
+	case CompressionZstd:
+		data, err := zstdDecoder.DecodeAll(a.Data, nil)
+		if err != nil {
+			return fmt.Errorf("decompress %s data: %w", a.Name, err)
+		}
+		a.Data = data
+		if len(a.FallbackData) > 0 {
+			fallback, err := zstdDecoder.DecodeAll(a.FallbackData, nil)
+			if err != nil {
+				return fmt.Errorf("decompress %s fallback: %w", a.Name, err)
+			}
+			a.FallbackData = fallback
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown compression %q for %s", codec, a.Name)
+	}
+}
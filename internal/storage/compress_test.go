@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// testPNG renders a flat-colour emoji-sized PNG, the case where compression at rest pays off.
+func testPNG(tb testing.TB) []byte {
+	tb.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x / 16 * 32), G: 200, B: uint8(y / 16 * 32), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		tb.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressImages_RoundTrip(t *testing.T) {
+	raw := testPNG(t)
+	fallback := []byte("GIF89a fallback fallback fallback fallback fallback fallback")
+
+	s := &Store{}
+	if err := s.SetCompression(CompressionZstd); err != nil {
+		t.Fatalf("SetCompression error: %v", err)
+	}
+	data, fb, codec := s.compressImages(raw, fallback)
+	if codec != CompressionZstd {
+		t.Fatalf("expected zstd for a compressible PNG, got %q", codec)
+	}
+	if len(data) >= len(raw) {
+		t.Fatalf("expected compressed data to be smaller: %d >= %d", len(data), len(raw))
+	}
+
+	asset := Asset{Name: "wave", Data: data, FallbackData: fb}
+	if err := asset.decompressImages(codec); err != nil {
+		t.Fatalf("decompressImages error: %v", err)
+	}
+	if !bytes.Equal(asset.Data, raw) || !bytes.Equal(asset.FallbackData, fallback) {
+		t.Fatalf("round trip changed the image bytes")
+	}
+}
+
+func TestCompressImages_Passthrough(t *testing.T) {
+	raw := testPNG(t)
+
+	off := &Store{}
+	if data, _, codec := off.compressImages(raw, nil); codec != "" || !bytes.Equal(data, raw) {
+		t.Fatalf("expected bytes untouched with compression off, got codec %q", codec)
+	}
+
+	// Incompressible input is stored as-is even with zstd enabled.
+	on := &Store{compression: CompressionZstd}
+	noise := []byte{0x5a, 0x13, 0xf1, 0x08, 0x9c, 0x77, 0x21, 0xee}
+	if data, fb, codec := on.compressImages(noise, nil); codec != "" || !bytes.Equal(data, noise) || fb != nil {
+		t.Fatalf("expected incompressible bytes to be stored raw, got codec %q", codec)
+	}
+
+	// Uncompressed rows written before compression was enabled still read back.
+	legacy := Asset{Name: "old", Data: raw}
+	if err := legacy.decompressImages(""); err != nil || !bytes.Equal(legacy.Data, raw) {
+		t.Fatalf("expected legacy row to read unchanged, err %v", err)
+	}
+	if err := (&Asset{Name: "bad", Data: raw}).decompressImages(CompressionZstd); err == nil {
+		t.Fatalf("expected error decoding non-zstd bytes")
+	}
+	if err := on.SetCompression("lz4"); err == nil {
+		t.Fatalf("expected unknown codec to be rejected")
+	}
+}
+
+// BenchmarkCompressImages reports the stored size relative to the raw PNG (lower is better).
+func BenchmarkCompressImages(b *testing.B) {
+	raw := testPNG(b)
+	s := &Store{compression: CompressionZstd}
+	var stored int
+	b.SetBytes(int64(len(raw)))
+	for i := 0; i < b.N; i++ {
+		data, _, _ := s.compressImages(raw, nil)
+		stored = len(data)
+	}
+	b.ReportMetric(float64(stored)/float64(len(raw)), "stored/raw")
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotentResponse is the recorded outcome of a request sent with an Idempotency-Key.
+type IdempotentResponse struct {
+	Key         string
+	Method      string
+	Path        string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// GetIdempotentResponse returns the response recorded for key within ttl, or ErrNotFound.
+func (s *Store) GetIdempotentResponse(ctx context.Context, key string, ttl time.Duration) (*IdempotentResponse, error) {
+	var resp IdempotentResponse
+	err := s.pool.QueryRow(ctx, `
+        SELECT key, method, path, status, content_type, body, created_at
+        FROM hivemoji_idempotency
+        WHERE key = $1 AND created_at > $2
+    `, key, time.Now().Add(-ttl)).Scan(&resp.Key, &resp.Method, &resp.Path, &resp.Status, &resp.ContentType, &resp.Body, &resp.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveIdempotentResponse records resp, replacing an expired record under the same key.
+func (s *Store) SaveIdempotentResponse(ctx context.Context, resp IdempotentResponse) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_idempotency (key, method, path, status, content_type, body, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, now())
+        ON CONFLICT (key) DO UPDATE SET
+            method = EXCLUDED.method,
+            path = EXCLUDED.path,
+            status = EXCLUDED.status,
+            content_type = EXCLUDED.content_type,
+            body = EXCLUDED.body,
+            created_at = EXCLUDED.created_at
+    `, resp.Key, resp.Method, resp.Path, resp.Status, resp.ContentType, resp.Body)
+	return err
+}
+
+// PurgeIdempotentResponses deletes records older than ttl and reports how many were removed.
+func (s *Store) PurgeIdempotentResponses(ctx context.Context, ttl time.Duration) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_idempotency WHERE created_at < $1`, time.Now().Add(-ttl))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
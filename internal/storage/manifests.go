@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Manifest is a v2 register announcing an upload before its chunks arrive. It is resolved
+// once the upload has been stored as an emoji.
+type Manifest struct {
+	UploadID   string     `db:"upload_id"`
+	Name       string     `db:"name"`
+	Author     string     `db:"author"`
+	Mime       *string    `db:"mime"`
+	Width      *int       `db:"width"`
+	Height     *int       `db:"height"`
+	Animated   bool       `db:"animated"`
+	Total      *int       `db:"total"`
+	FirstBlock *int64     `db:"first_block"`
+	Resolved   bool       `db:"resolved"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ResolvedAt *time.Time `db:"resolved_at"`
+}
+
+// UpsertManifest records an announced upload. A repeated announcement updates the expected
+// mime, dimensions and chunk count but keeps the block it was first seen in. A manifest for
+// an upload that is already stored starts out resolved.
+func (s *Store) UpsertManifest(ctx context.Context, m Manifest) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_manifests (upload_id, name, author, mime, width, height, animated, total, first_block, resolved, resolved_at)
+        SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, stored, CASE WHEN stored THEN now() END
+        FROM (SELECT EXISTS (SELECT 1 FROM hivemoji_assets WHERE upload_id = $1) AS stored) a
+        ON CONFLICT (upload_id) DO UPDATE SET
+            name = EXCLUDED.name,
+            author = EXCLUDED.author,
+            mime = EXCLUDED.mime,
+            width = EXCLUDED.width,
+            height = EXCLUDED.height,
+            animated = EXCLUDED.animated,
+            total = EXCLUDED.total,
+            first_block = COALESCE(hivemoji_manifests.first_block, EXCLUDED.first_block)
+    `, m.UploadID, m.Name, m.Author, m.Mime, m.Width, m.Height, m.Animated, m.Total, m.FirstBlock)
+	return err
+}
+
+// resolveManifest marks the manifest of uploadID, if any, as stored.
+func resolveManifest(ctx context.Context, tx pgx.Tx, uploadID string) error {
+	_, err := tx.Exec(ctx, `
+        UPDATE hivemoji_manifests SET resolved = true, resolved_at = now()
+        WHERE upload_id = $1 AND NOT resolved
+    `, uploadID)
+	return err
+}
+
+// ListPendingManifests returns up to limit unresolved manifests, most recently announced first.
+func (s *Store) ListPendingManifests(ctx context.Context, limit int) ([]Manifest, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT upload_id, name, author, mime, width, height, animated, total, first_block, resolved, created_at, resolved_at
+        FROM hivemoji_manifests
+        WHERE NOT resolved
+        ORDER BY first_block DESC NULLS LAST, created_at DESC
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Manifest])
+}
+
+// PurgeStaleManifests deletes unresolved manifests announced longer than olderThan ago whose
+// upload has no incomplete chunk set left: it was never started, was purged by
+// CleanupIncomplete, or completed without being stored (e.g. rejected by moderation). It
+// returns how many were deleted.
+func (s *Store) PurgeStaleManifests(ctx context.Context, olderThan time.Duration) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+        DELETE FROM hivemoji_manifests m
+        WHERE NOT m.resolved AND m.created_at < $1
+          AND NOT EXISTS (
+              SELECT 1 FROM hivemoji_chunk_sets s WHERE s.upload_id = m.upload_id AND NOT s.completed
+          )
+    `, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestManifests_PendingUntilPurged(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	id := fmt.Sprintf("manifest-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_manifests WHERE upload_id=$1`, id)
+	})
+
+	first, later, total := int64(10), int64(20), 3
+	if err := store.UpsertManifest(ctx, Manifest{UploadID: id, Name: "wave", Author: "hivemoji-test", FirstBlock: &first}); err != nil {
+		t.Fatalf("UpsertManifest: %v", err)
+	}
+	if err := store.UpsertManifest(ctx, Manifest{UploadID: id, Name: "wave", Author: "hivemoji-test", Total: &total, FirstBlock: &later}); err != nil {
+		t.Fatalf("UpsertManifest again: %v", err)
+	}
+
+	pending, err := store.ListPendingManifests(ctx, 200)
+	if err != nil {
+		t.Fatalf("ListPendingManifests: %v", err)
+	}
+	var found *Manifest
+	for i := range pending {
+		if pending[i].UploadID == id {
+			found = &pending[i]
+		}
+	}
+	if found == nil || found.Total == nil || *found.Total != 3 || found.FirstBlock == nil || *found.FirstBlock != first {
+		t.Fatalf("expected the updated manifest to keep its first block, got %+v", found)
+	}
+
+	if _, err := store.PurgeStaleManifests(ctx, 0); err != nil {
+		t.Fatalf("PurgeStaleManifests: %v", err)
+	}
+	var n int
+	if err := store.pool.QueryRow(ctx, `SELECT count(*) FROM hivemoji_manifests WHERE upload_id=$1`, id).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected the stale manifest to be purged, got %d, %v", n, err)
+	}
+}
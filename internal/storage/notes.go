@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IngestNotes returns the notes the processor recorded when it last stored an emoji, or
+// ErrNotFound. Emojis stored before notes were recorded report an empty list.
+func (s *Store) IngestNotes(ctx context.Context, author, name string) ([]string, error) {
+	var notes []string
+	err := s.pool.QueryRow(ctx, `
+        SELECT ingest_notes FROM hivemoji_assets WHERE author = $1 AND name = $2 AND variant = ''
+    `, author, name).Scan(&notes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if notes == nil {
+		notes = []string{}
+	}
+	return notes, nil
+}
+
+// ingestNotesJSON encodes notes for the jsonb column, storing an empty array rather than null.
+func ingestNotesJSON(notes []string) string {
+	if len(notes) == 0 {
+		return "[]"
+	}
+	b, err := json.Marshal(notes)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import "testing"
+
+func TestIngestNotesJSON(t *testing.T) {
+	if got := ingestNotesJSON(nil); got != "[]" {
+		t.Fatalf("expected empty array for nil notes, got %s", got)
+	}
+	got := ingestNotesJSON([]string{`mime "image/PNG" normalized to "image/png"`})
+	if want := `["mime \"image/PNG\" normalized to \"image/png\""]`; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when a page cursor was not produced by Cursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects one keyset page of a listing ordered by name, then author. The zero
+// After starts at the beginning.
+type Page struct {
+	After PageKey
+	Limit int
+}
+
+// PageKey is the (name, author) position of the last emoji on the previous page.
+type PageKey struct {
+	Name   string
+	Author string
+}
+
+// Cursor encodes k as an opaque, URL-safe string for clients to pass back.
+func (k PageKey) Cursor() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(k.Name + "\x00" + k.Author))
+}
+
+// ParseCursor decodes a cursor produced by PageKey.Cursor.
+func ParseCursor(cursor string) (PageKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PageKey{}, ErrInvalidCursor
+	}
+	name, author, ok := strings.Cut(string(raw), "\x00")
+	if !ok || name == "" {
+		return PageKey{}, ErrInvalidCursor
+	}
+	return PageKey{Name: name, Author: author}, nil
+}
+
+// KeyOf returns the page position of a.
+func KeyOf(a Asset) PageKey {
+	key := PageKey{Name: a.Name}
+	if a.Author != nil {
+		key.Author = *a.Author
+	}
+	return key
+}
+
+// ListAssetsPage returns up to page.Limit public emojis matching filter that sort after
+// page.After, ordered by name then author. Seeking on (name, author) instead of OFFSET keeps
+// every page as cheap as the first.
+func (s *Store) ListAssetsPage(ctx context.Context, filter AssetFilter, page Page, includeData bool) ([]Asset, error) {
+	if page.Limit <= 0 {
+		return nil, errors.New("page limit must be positive")
+	}
+
+	clause, args := filter.where(nil)
+	query := fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE %s%s", selectAssetColumns(includeData), publicAssets, clause)
+	if page.After.Name != "" {
+		args = append(args, page.After.Name, page.After.Author)
+		query += fmt.Sprintf(" AND (name, author) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, page.Limit)
+	query += fmt.Sprintf(" ORDER BY name, author LIMIT $%d", len(args))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
+
+// ListAssetsPaged returns up to limit public emojis ordered by name then author, skipping the
+// first offset, along with how many public emojis there are in total.
+func (s *Store) ListAssetsPaged(ctx context.Context, includeData bool, limit, offset int) ([]Asset, int64, error) {
+	if limit <= 0 || offset < 0 {
+		return nil, 0, errors.New("page limit must be positive and offset not negative")
+	}
+
+	var total int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM hivemoji_assets WHERE "+publicAssets).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE %s ORDER BY name, author LIMIT $1 OFFSET $2", selectAssetColumns(includeData), publicAssets), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	assets, err := s.collectAssets(ctx, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
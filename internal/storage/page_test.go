@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, key := range []PageKey{
+		{Name: "wave", Author: "mrtats"},
+		{Name: "smile", Author: ""},
+		{Name: "ünïcode_name", Author: "a.b-c"},
+	} {
+		got, err := ParseCursor(key.Cursor())
+		if err != nil {
+			t.Fatalf("ParseCursor(%+v) error: %v", key, err)
+		}
+		if got != key {
+			t.Fatalf("expected %+v, got %+v", key, got)
+		}
+	}
+
+	for _, bad := range []string{"!!!", "d2F2ZQ", ""} {
+		if _, err := ParseCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("ParseCursor(%q): expected ErrInvalidCursor, got %v", bad, err)
+		}
+	}
+}
+
+func TestListAssetsPaged(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	author := fmt.Sprintf("hivemoji-paged-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author=$1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author=$1`, author)
+	})
+	before, err := store.Count(ctx, AssetFilter{})
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := store.UpsertV1(ctx, RegisterV1{Name: name, Author: author, Mime: "image/png", Data: []byte(name)}); err != nil {
+			t.Fatalf("UpsertV1 %s: %v", name, err)
+		}
+	}
+
+	assets, total, err := store.ListAssetsPaged(ctx, false, 2, 0)
+	if err != nil {
+		t.Fatalf("ListAssetsPaged: %v", err)
+	}
+	if total != before.Total+3 || len(assets) != min(2, int(total)) {
+		t.Fatalf("expected total %d and a 2-emoji page, got %d and %d", before.Total+3, total, len(assets))
+	}
+	if assets, _, err := store.ListAssetsPaged(ctx, false, 10, int(total)); err != nil || len(assets) != 0 {
+		t.Fatalf("expected an empty page past the end, got %d, %v", len(assets), err)
+	}
+	if _, _, err := store.ListAssetsPaged(ctx, false, 0, 0); err == nil {
+		t.Fatal("expected a zero limit to fail")
+	}
+}
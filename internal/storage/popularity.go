@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Search orders accepted by SearchAssets.
+const (
+	SearchSortName    = "name"
+	SearchSortPopular = "popular"
+)
+
+// EmojiRef identifies a base emoji by author and stored name.
+type EmojiRef struct {
+	Author string
+	Name   string
+}
+
+// AddFetchCounts adds counts to the emojis' running fetch totals. Counts may name emojis
+// that have since been deleted; their totals are simply never read.
+func (s *Store) AddFetchCounts(ctx context.Context, counts map[EmojiRef]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	authors := make([]string, 0, len(counts))
+	names := make([]string, 0, len(counts))
+	fetches := make([]int64, 0, len(counts))
+	for ref, n := range counts {
+		authors = append(authors, ref.Author)
+		names = append(names, ref.Name)
+		fetches = append(fetches, n)
+	}
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO hivemoji_fetch_counts (author, name, fetches)
+        SELECT * FROM unnest($1::text[], $2::text[], $3::bigint[])
+        ON CONFLICT (author, name) DO UPDATE
+        SET fetches = hivemoji_fetch_counts.fetches + EXCLUDED.fetches
+    `, authors, names, fetches)
+	return err
+}
+
+// SearchAssets returns up to limit public base emojis whose name contains query or which are
+// tagged with it, both case-insensitively. Results are ordered by name and author, or with
+// SearchSortPopular by fetch count, most fetched first.
+func (s *Store) SearchAssets(ctx context.Context, query, sort string, limit int, includeData bool) ([]Asset, error) {
+	var order string
+	switch sort {
+	case "", SearchSortName:
+		order = "name, author"
+	case SearchSortPopular:
+		order = "fetches DESC, name, author"
+	default:
+		return nil, fmt.Errorf("unknown search sort %q", sort)
+	}
+
+	query = strings.ToLower(query)
+	rows, err := s.pool.Query(ctx, `
+        SELECT `+selectAssetColumns(includeData)+`,
+            COALESCE((
+                SELECT f.fetches FROM hivemoji_fetch_counts f
+                WHERE f.author = hivemoji_assets.author AND f.name = hivemoji_assets.name
+            ), 0) AS fetches
+        FROM hivemoji_assets
+        WHERE (lower(name) LIKE $1 OR $2 = ANY(tags)) AND `+publicAssets+`
+        ORDER BY `+order+`
+        LIMIT $3
+    `, "%"+likePrefixPattern(query), query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
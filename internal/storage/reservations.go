@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// unofficialColumn selects whether another author has reserved an asset's name, for
+// scanning into Asset.Unofficial.
+const unofficialColumn = `EXISTS (SELECT 1 FROM hivemoji_name_reservations r WHERE r.name = hivemoji_assets.name AND r.author <> hivemoji_assets.author) AS unofficial`
+
+// ReserveName reserves name globally for author, recording the block of the reserve op. Only
+// the author who first registered the name may reserve it: first registration is the block of
+// their earliest recorded revision, or of the stored emoji when no revision was recorded.
+// It returns the name's owner afterwards, which is "" when author wasn't eligible and nobody
+// holds it, and another author when the name was already reserved.
+func (s *Store) ReserveName(ctx context.Context, name, author string, block int64) (string, error) {
+	_, err := s.pool.Exec(ctx, `
+        WITH first AS (
+            SELECT a.author, COALESCE(
+                (SELECT min(v.registered_block) FROM hivemoji_asset_versions v
+                 WHERE v.author = a.author AND v.name = a.name AND v.variant = ''),
+                a.registered_block
+            ) AS block
+            FROM hivemoji_assets a
+            WHERE a.name = $1 AND a.variant = ''
+        )
+        INSERT INTO hivemoji_name_reservations (name, author, reserved_block)
+        SELECT $1, $2, $3 FROM first f
+        WHERE f.author = $2 AND NOT EXISTS (
+            SELECT 1 FROM first o
+            WHERE o.author <> $2 AND o.block IS NOT NULL AND (f.block IS NULL OR o.block < f.block)
+        )
+        ON CONFLICT (name) DO NOTHING
+    `, name, author, nullIfZero(block))
+	if err != nil {
+		return "", err
+	}
+	return s.NameOwner(ctx, name)
+}
+
+// NameOwner returns the author holding a reservation on name, or "" when it is unreserved.
+func (s *Store) NameOwner(ctx context.Context, name string) (string, error) {
+	var owner string
+	err := s.pool.QueryRow(ctx, `SELECT author FROM hivemoji_name_reservations WHERE name = $1`, name).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return owner, err
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// txAttempts bounds how many times a transaction aborted by a serialization failure or
+// deadlock is run in total.
+const txAttempts = 5
+
+// txRetryBackoff is the wait before the first retry; it doubles with each further attempt.
+var txRetryBackoff = 10 * time.Millisecond
+
+// retryTx runs op, a function that begins, runs and commits one transaction, and runs it again
+// when Postgres aborted the transaction with a serialization failure (40001) or deadlock
+// (40P01). Concurrent ingest can hit either when two writers touch the same emoji; the ON
+// CONFLICT upserts resolve the common race, this covers the rest. op must have no effects
+// outside its transaction.
+func retryTx(ctx context.Context, op func() error) error {
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt == txAttempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether err is a transaction abort that succeeds when simply retried.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
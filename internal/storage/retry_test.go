@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryTx(t *testing.T) {
+	txRetryBackoff = time.Millisecond
+	t.Cleanup(func() { txRetryBackoff = 10 * time.Millisecond })
+	ctx := context.Background()
+
+	serialization := fmt.Errorf("upsert: %w", &pgconn.PgError{Code: "40001"})
+	calls := 0
+	err := retryTx(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return serialization
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	if err := retryTx(ctx, func() error { calls++; return deadlock }); !errors.Is(err, deadlock) || calls != txAttempts {
+		t.Fatalf("expected the deadlock after %d attempts, got %v after %d", txAttempts, err, calls)
+	}
+
+	calls = 0
+	unique := &pgconn.PgError{Code: "23505"}
+	if err := retryTx(ctx, func() error { calls++; return unique }); !errors.Is(err, unique) || calls != 1 {
+		t.Fatalf("expected other errors not to be retried, got %v after %d calls", err, calls)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	if err := retryTx(cancelled, func() error { calls++; return serialization }); err == nil || calls != 1 {
+		t.Fatalf("expected a cancelled context to stop retrying, got %v after %d calls", err, calls)
+	}
+}
+
+func TestUpsertV1_ConcurrentSameEmoji(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	const writers = 8
+	author := fmt.Sprintf("hivemoji-race-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author=$1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author=$1`, author)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := store.UpsertV1(ctx, RegisterV1{
+				Name:            "wave",
+				Author:          author,
+				Mime:            "image/png",
+				Data:            []byte{byte(i)},
+				RegisteredBlock: int64(100 + i),
+			})
+			if err != nil {
+				t.Errorf("UpsertV1 writer %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	versions, err := store.ListAssetVersions(ctx, author, "wave")
+	if err != nil {
+		t.Fatalf("ListAssetVersions: %v", err)
+	}
+	if len(versions) != writers {
+		t.Fatalf("expected %d revisions, got %d", writers, len(versions))
+	}
+	for i, v := range versions {
+		if v.Revision != i+1 {
+			t.Fatalf("expected consecutive revisions, got %d at position %d", v.Revision, i)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// assetColumns are the hivemoji_assets columns every listing selects; assetDataColumns are
+// added when image bytes are asked for.
+const (
+	assetColumns     = "name, version, author, upload_id, mime, width, height, animated, loop, checksum, fallback_mime, featured, description, tags, expires_at, cover, license, attribution, chunk_count, registered_block, " + unofficialColumn
+	assetDataColumns = ", data, fallback_data, compression, data_ref, fallback_ref"
+)
+
+// selectAssetColumns returns the select list for an Asset query.
+func selectAssetColumns(includeData bool) string {
+	if includeData {
+		return assetColumns + assetDataColumns
+	}
+	return assetColumns
+}
+
+// assetRow is an Asset as selected from hivemoji_assets, along with the codec its image
+// bytes were stored with and, for images kept in the BlobStore, their blob keys.
+type assetRow struct {
+	Asset
+	Compression string  `db:"compression"`
+	DataRef     *string `db:"data_ref"`
+	FallbackRef *string `db:"fallback_ref"`
+}
+
+// scanAsset maps the current row onto an Asset by column name, leaving fields for columns
+// the query didn't select at their zero value, and loads its image bytes.
+func (s *Store) scanAsset(ctx context.Context, row pgx.CollectableRow) (Asset, error) {
+	r, err := pgx.RowToStructByNameLax[assetRow](row)
+	if err != nil {
+		return Asset{}, err
+	}
+	return s.resolveImages(ctx, r)
+}
+
+// collectAssets reads every row into an Asset like scanAsset and closes rows.
+func (s *Store) collectAssets(ctx context.Context, rows pgx.Rows) ([]Asset, error) {
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[assetRow])
+	if err != nil {
+		return nil, err
+	}
+	assets := make([]Asset, 0, len(records))
+	for _, r := range records {
+		asset, err := s.resolveImages(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, nil
+}
+
+// collectOneAsset reads the only row into an Asset like scanAsset, returning pgx.ErrNoRows
+// when there is none.
+func (s *Store) collectOneAsset(ctx context.Context, rows pgx.Rows) (Asset, error) {
+	r, err := pgx.CollectOneRow(rows, pgx.RowToStructByNameLax[assetRow])
+	if err != nil {
+		return Asset{}, err
+	}
+	return s.resolveImages(ctx, r)
+}
+
+// resolveImages fetches r's image bytes from the BlobStore when they were stored there and
+// decompresses them.
+func (s *Store) resolveImages(ctx context.Context, r assetRow) (Asset, error) {
+	if err := s.loadBlobs(ctx, &r); err != nil {
+		return Asset{}, err
+	}
+	if err := r.Asset.decompressImages(r.Compression); err != nil {
+		return Asset{}, err
+	}
+	return r.Asset, nil
+}
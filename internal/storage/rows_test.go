@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeRows serves text-format values through the same pgtype decoding the driver uses, so
+// scans behave as they would against Postgres without needing a database.
+type fakeRows struct {
+	fields []pgconn.FieldDescription
+	values [][]*string // nil is NULL
+	row    int
+	m      *pgtype.Map
+}
+
+func newFakeRows(columns []string, oids []uint32, values ...[]*string) *fakeRows {
+	r := &fakeRows{values: values, row: -1, m: pgtype.NewMap()}
+	for i, c := range columns {
+		r.fields = append(r.fields, pgconn.FieldDescription{Name: c, DataTypeOID: oids[i], Format: pgtype.TextFormatCode})
+	}
+	return r
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *fakeRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.row++
+	return r.row < len(r.values)
+}
+
+func (r *fakeRows) RawValues() [][]byte {
+	raw := make([][]byte, len(r.fields))
+	for i, v := range r.values[r.row] {
+		if v != nil {
+			raw[i] = []byte(*v)
+		}
+	}
+	return raw
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) == 1 {
+		if rs, ok := dest[0].(pgx.RowScanner); ok {
+			return rs.ScanRow(r)
+		}
+	}
+	for i, raw := range r.RawValues() {
+		if err := r.m.Scan(r.fields[i].DataTypeOID, pgtype.TextFormatCode, raw, dest[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assetRowColumns mirrors selectAssetColumns(true), with each column's type.
+var (
+	assetRowColumns = []string{"name", "version", "author", "upload_id", "mime", "width", "height", "animated", "loop", "checksum", "fallback_mime", "featured", "description", "tags", "expires_at", "cover", "license", "attribution", "chunk_count", "registered_block", "unofficial", "data", "fallback_data", "compression"}
+	assetRowOIDs    = []uint32{pgtype.TextOID, pgtype.Int4OID, pgtype.TextOID, pgtype.TextOID, pgtype.TextOID, pgtype.Int4OID, pgtype.Int4OID, pgtype.BoolOID, pgtype.Int4OID, pgtype.TextOID, pgtype.TextOID, pgtype.BoolOID, pgtype.TextOID, pgtype.TextArrayOID, pgtype.TimestamptzOID, pgtype.BoolOID, pgtype.TextOID, pgtype.TextOID, pgtype.Int4OID, pgtype.Int8OID, pgtype.BoolOID, pgtype.ByteaOID, pgtype.ByteaOID, pgtype.TextOID}
+)
+
+// scanAssetByPosition is the positional scan the listing queries used before collectAssets.
+func scanAssetByPosition(rows pgx.Rows) (Asset, error) {
+	var asset Asset
+	var compression string
+	if err := rows.Scan(&asset.Name, &asset.Version, &asset.Author, &asset.UploadID, &asset.Mime, &asset.Width, &asset.Height, &asset.Animated, &asset.Loop, &asset.Checksum, &asset.FallbackMime, &asset.Featured, &asset.Description, &asset.Tags, &asset.ExpiresAt, &asset.Cover, &asset.License, &asset.Attribution, &asset.ChunkCount, &asset.RegisteredBlock, &asset.Unofficial, &asset.Data, &asset.FallbackData, &compression); err != nil {
+		return Asset{}, err
+	}
+	return asset, asset.decompressImages(compression)
+}
+
+func bytea(b []byte) *string {
+	s := `\x` + hex.EncodeToString(b)
+	return &s
+}
+
+func TestCollectAssets_MatchesPositionalScan(t *testing.T) {
+	str := func(s string) *string { return &s }
+	compressed := zstdEncoder.EncodeAll([]byte("png bytes"), nil)
+	full := []*string{
+		str("wave"), str("3"), str("mrtats"), str("up1"), str("image/gif"), str("32"), str("24"), str("t"), str("0"),
+		str(strings.Repeat("ab", 32)), str("image/png"), str("t"), str("waving hand"), str("{hands,greeting}"),
+		str("2030-01-02 03:04:05+00"), str("t"), str("CC-BY-4.0"), str("art by alice"), str("4"), str("12345"), str("t"),
+		bytea(compressed), bytea(zstdEncoder.EncodeAll([]byte("fallback"), nil)), str(CompressionZstd),
+	}
+	sparse := []*string{
+		str("plain"), str("1"), nil, nil, str("image/png"), nil, nil, str("f"), nil,
+		nil, nil, str("f"), nil, nil,
+		nil, str("f"), nil, nil, nil, nil, str("f"),
+		bytea([]byte("raw")), nil, str(""),
+	}
+
+	got, err := (&Store{}).collectAssets(context.Background(), newFakeRows(assetRowColumns, assetRowOIDs, full, sparse))
+	if err != nil {
+		t.Fatalf("collectAssets: %v", err)
+	}
+	var want []Asset
+	rows := newFakeRows(assetRowColumns, assetRowOIDs, full, sparse)
+	for rows.Next() {
+		asset, err := scanAssetByPosition(rows)
+		if err != nil {
+			t.Fatalf("positional scan: %v", err)
+		}
+		want = append(want, asset)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mapped rows differ from positional scan:\n got %+v\nwant %+v", got, want)
+	}
+
+	if string(got[0].Data) != "png bytes" || string(got[0].FallbackData) != "fallback" {
+		t.Fatalf("expected decompressed image bytes, got %q and %q", got[0].Data, got[0].FallbackData)
+	}
+	if expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC); got[0].ExpiresAt == nil || !got[0].ExpiresAt.Equal(expires) {
+		t.Fatalf("expected expires_at %v, got %v", expires, got[0].ExpiresAt)
+	}
+	if got[1].Author != nil || got[1].Width != nil || got[1].Checksum != nil || got[1].Tags != nil || got[1].FallbackData != nil {
+		t.Fatalf("expected NULL columns to stay nil, got %+v", got[1])
+	}
+}
+
+func TestCollectAssets_MetadataOnly(t *testing.T) {
+	str := func(s string) *string { return &s }
+	n := len(assetColumnsList())
+	if !reflect.DeepEqual(assetColumnsList(), assetRowColumns[:n]) {
+		t.Fatalf("assetColumns selects %v, test rows use %v", assetColumnsList(), assetRowColumns[:n])
+	}
+	row := []*string{str("wave"), str("2"), str("mrtats")}
+	for len(row) < n {
+		row = append(row, nil)
+	}
+	// Booleans are NOT NULL in the table.
+	for i, c := range assetColumnsList() {
+		if c == "animated" || c == "featured" || c == "cover" || c == "unofficial" {
+			row[i] = str("f")
+		} else if c == "mime" {
+			row[i] = str("image/png")
+		}
+	}
+
+	got, err := (&Store{}).collectAssets(context.Background(), newFakeRows(assetColumnsList(), assetRowOIDs[:n], row))
+	if err != nil {
+		t.Fatalf("collectAssets: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "wave" || got[0].Version != 2 || *got[0].Author != "mrtats" || got[0].Data != nil {
+		t.Fatalf("unexpected asset %+v", got)
+	}
+}
+
+// assetColumnsList splits assetColumns into column names, as Postgres reports them.
+func assetColumnsList() []string {
+	var names []string
+	for _, c := range strings.Split(assetColumns, ", ") {
+		if i := strings.LastIndex(c, " AS "); i >= 0 {
+			c = c[i+len(" AS "):]
+		}
+		names = append(names, c)
+	}
+	return names
+}
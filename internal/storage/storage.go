@@ -13,9 +13,33 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// visibleAssets restricts asset queries to emojis public endpoints may serve: approved, not
+// expired and not waiting for their block to be confirmed.
+const visibleAssets = `moderation_status = 'approved' AND (expires_at IS NULL OR expires_at > now()) AND NOT pending_confirmation`
+
+// publicAssets restricts asset queries to the visible emojis public endpoints may list: base
+// emojis only, as variants are reached through their base emoji.
+const publicAssets = visibleAssets + ` AND variant = ''`
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrAssemble is returned when buffered chunks cannot be assembled into an image.
+var ErrAssemble = errors.New("cannot assemble upload")
+
+// ErrChunkTotal is returned by SaveChunk for chunks declaring more chunks than the store accepts.
+var ErrChunkTotal = errors.New("chunk total exceeds limit")
+
+// DefaultMaxChunks caps the total a chunk may declare when no limit is set with SetMaxChunks.
+// It is far above what any image within the custom_json size limits needs.
+const DefaultMaxChunks = 10000
+
 // Store wraps DB access for hivemoji data.
 type Store struct {
-	pool *pgxpool.Pool
+	pool        *pgxpool.Pool
+	compression string
+	maxChunks   int
+	blobs       BlobStore
 }
 
 // NewStore constructs a Store from a pgx pool.
@@ -75,6 +99,98 @@ func (s *Store) EnsureSchema(ctx context.Context) error {
             created_at timestamptz NOT NULL DEFAULT now(),
             updated_at timestamptz NOT NULL DEFAULT now(),
             PRIMARY KEY (upload_id, kind)
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_idempotency (
+            key text PRIMARY KEY,
+            method text NOT NULL,
+            path text NOT NULL,
+            status int NOT NULL,
+            content_type text NOT NULL DEFAULT '',
+            body bytea,
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_asset_versions (
+            author text NOT NULL,
+            name text NOT NULL,
+            variant text NOT NULL DEFAULT '',
+            revision int NOT NULL,
+            version int NOT NULL,
+            mime text NOT NULL,
+            width int NOT NULL DEFAULT 0,
+            height int NOT NULL DEFAULT 0,
+            animated boolean NOT NULL DEFAULT false,
+            size int NOT NULL,
+            checksum text NOT NULL,
+            fallback_mime text,
+            fallback_size int NOT NULL DEFAULT 0,
+            registered_block bigint,
+            created_at timestamptz NOT NULL DEFAULT now(),
+            PRIMARY KEY (author, name, variant, revision)
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_name_reservations (
+            name text PRIMARY KEY,
+            author text NOT NULL,
+            reserved_block bigint,
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_banned_checksums (
+            checksum text PRIMARY KEY,
+            reason text NOT NULL DEFAULT '',
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_audit_log (
+            id bigserial PRIMARY KEY,
+            action text NOT NULL,
+            author text NOT NULL,
+            name text NOT NULL,
+            target text NOT NULL DEFAULT '',
+            block_num bigint,
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_collections (
+            slug text PRIMARY KEY,
+            title text NOT NULL DEFAULT '',
+            description text NOT NULL DEFAULT '',
+            created_at timestamptz NOT NULL DEFAULT now(),
+            updated_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_collection_items (
+            slug text NOT NULL REFERENCES hivemoji_collections (slug) ON DELETE CASCADE,
+            author text NOT NULL,
+            name text NOT NULL,
+            position int NOT NULL DEFAULT 0,
+            added_at timestamptz NOT NULL DEFAULT now(),
+            PRIMARY KEY (slug, author, name)
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_blobs (
+            key text PRIMARY KEY,
+            data bytea NOT NULL,
+            created_at timestamptz NOT NULL DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_fetch_counts (
+            author text NOT NULL,
+            name text NOT NULL,
+            fetches bigint NOT NULL DEFAULT 0,
+            PRIMARY KEY (author, name)
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_manifests (
+            upload_id text PRIMARY KEY,
+            name text NOT NULL,
+            author text NOT NULL,
+            mime text,
+            width int,
+            height int,
+            animated boolean NOT NULL DEFAULT false,
+            total int,
+            first_block bigint,
+            resolved boolean NOT NULL DEFAULT false,
+            created_at timestamptz NOT NULL DEFAULT now(),
+            resolved_at timestamptz
+        )`,
+		`CREATE TABLE IF NOT EXISTS hivemoji_block_ids (
+            block_num bigint PRIMARY KEY,
+            block_id text NOT NULL,
+            recorded_at timestamptz NOT NULL DEFAULT now()
         )`,
 	}
 
@@ -89,8 +205,37 @@ func (s *Store) EnsureSchema(ctx context.Context) error {
 		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS author text`,
 		`UPDATE hivemoji_assets SET author = COALESCE(author, '')`,
 		`UPDATE hivemoji_chunk_sets SET author = COALESCE(author, '')`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS variant text NOT NULL DEFAULT ''`,
+		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS variant text NOT NULL DEFAULT ''`,
 		`ALTER TABLE hivemoji_assets DROP CONSTRAINT IF EXISTS hivemoji_assets_pkey`,
-		`ALTER TABLE hivemoji_assets ADD CONSTRAINT hivemoji_assets_pkey PRIMARY KEY (author, name)`,
+		`ALTER TABLE hivemoji_assets ADD CONSTRAINT hivemoji_assets_pkey PRIMARY KEY (author, name, variant)`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_assets_checksum_idx ON hivemoji_assets (lower(checksum))`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS featured boolean NOT NULL DEFAULT false`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS featured_order int NOT NULL DEFAULT 0`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS description text`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS moderation_status text NOT NULL DEFAULT 'approved'`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_assets_name_prefix_idx ON hivemoji_assets (lower(name) text_pattern_ops)`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS expires_at timestamptz`,
+		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS expires_at timestamptz`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS cover boolean NOT NULL DEFAULT false`,
+		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS cover boolean NOT NULL DEFAULT false`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS license text`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS attribution text`,
+		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS license text`,
+		`ALTER TABLE hivemoji_chunk_sets ADD COLUMN IF NOT EXISTS attribution text`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS compression text NOT NULL DEFAULT ''`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS ingest_notes jsonb NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS registered_block bigint`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS pending_confirmation boolean NOT NULL DEFAULT false`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS chunk_count int`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_assets_name_author_idx ON hivemoji_assets (name, author)`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_assets_pending_confirmation_idx ON hivemoji_assets (registered_block) WHERE pending_confirmation`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_assets_registered_block_idx ON hivemoji_assets (registered_block)`,
+		`CREATE INDEX IF NOT EXISTS hivemoji_audit_log_name_idx ON hivemoji_audit_log (name)`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS data_ref text`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS fallback_ref text`,
+		`ALTER TABLE hivemoji_assets ADD COLUMN IF NOT EXISTS blob_bytes bigint NOT NULL DEFAULT 0`,
 	}
 
 	for _, stmt := range alters {
@@ -114,48 +259,114 @@ type RegisterV1 struct {
 	Loop         *int
 	FallbackMime string
 	FallbackData []byte
+	ExpiresAt    *time.Time
+	Cover        bool
+	// License and Attribution credit the emoji for reuse; empty stores NULL.
+	License     string
+	Attribution string
+	// Variant names a variant of the emoji (e.g. a skin tone); empty is the base emoji.
+	Variant string
+	// ModerationStatus overrides the stored status when set; empty keeps the current one.
+	ModerationStatus string
+	// IngestNotes records what the processor changed or dropped while ingesting the payload.
+	IngestNotes []string
+	// RegisteredBlock is the block the register was read from; zero stores NULL.
+	RegisteredBlock int64
+	// PendingConfirmation withholds the emoji from public endpoints until ConfirmAssets passes its block.
+	PendingConfirmation bool
 }
 
 // ChunkPayload captures a v2 chunk message after decoding.
 type ChunkPayload struct {
-	ID       string
-	Author   string
-	Name     string
-	Version  int
-	Mime     string
-	Width    int
-	Height   int
-	Animated bool
-	Loop     *int
-	Checksum string
-	Kind     string // main | fallback
-	Seq      int
-	Total    int
-	Data     []byte
+	ID        string
+	Author    string
+	Name      string
+	Version   int
+	Mime      string
+	Width     int
+	Height    int
+	Animated  bool
+	Loop      *int
+	Checksum  string
+	Kind      string // main | fallback
+	Seq       int
+	Total     int
+	Data      []byte
+	ExpiresAt *time.Time
+	Cover     bool
+	Variant   string
+	// License and Attribution are kept from the first chunk declaring them.
+	License     string
+	Attribution string
 }
 
 // AssembledSet represents a completed set of chunks.
 type AssembledSet struct {
-	UploadID string
-	Kind     string
-	Name     string
-	Author   string
-	Version  int
-	Mime     string
-	Width    int
-	Height   int
-	Animated bool
-	Loop     *int
-	Checksum string
-	Data     []byte
+	UploadID  string
+	Kind      string
+	Name      string
+	Author    string
+	Version   int
+	Mime      string
+	Width     int
+	Height    int
+	Animated  bool
+	Loop      *int
+	Checksum  string
+	Data      []byte
+	ExpiresAt *time.Time
+	Cover     bool
+	Variant   string
+	// License and Attribution are empty when no chunk declared them.
+	License     string
+	Attribution string
+	// Total is the number of chunks the set was assembled from.
+	Total int
+
+	// ModerationStatus is set by the processor before UpsertFromChunks; it is not persisted on the chunk set.
+	ModerationStatus string
+	// IngestNotes is set by the processor before UpsertFromChunks; it is not persisted on the chunk set.
+	IngestNotes []string
+	// RegisteredBlock and PendingConfirmation are set by the processor before UpsertFromChunks,
+	// like ModerationStatus; see RegisterV1.
+	RegisteredBlock     int64
+	PendingConfirmation bool
 }
 
 // UpsertV1 stores or replaces an emoji registered via protocol v1.
 func (s *Store) UpsertV1(ctx context.Context, payload RegisterV1) error {
-	_, err := s.pool.Exec(ctx, `
-        INSERT INTO hivemoji_assets (name, version, author, upload_id, mime, width, height, data, animated, loop, fallback_mime, fallback_data, checksum, updated_at)
-        VALUES ($1, 1, $2, NULL, $3, $4, $5, $6, $7, $8, $9, $10, NULL, now())
-        ON CONFLICT (author, name) DO UPDATE SET
+	img, err := s.storeImages(ctx, payload.Data, payload.FallbackData)
+	if err != nil {
+		return err
+	}
+	var replaced []string
+	err = retryTx(ctx, func() (err error) {
+		replaced, err = s.upsertV1(ctx, payload, img)
+		return err
+	})
+	if err != nil {
+		s.releaseBlobs(ctx, img.refs())
+		return err
+	}
+	s.releaseBlobs(ctx, replaced)
+	return nil
+}
+
+func (s *Store) upsertV1(ctx context.Context, payload RegisterV1, img storedImages) ([]string, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	replaced, err := s.replacedBlobs(ctx, tx, payload.Author, payload.Name, payload.Variant)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+        INSERT INTO hivemoji_assets (name, version, author, upload_id, mime, width, height, data, animated, loop, fallback_mime, fallback_data, checksum, moderation_status, expires_at, cover, compression, ingest_notes, registered_block, pending_confirmation, chunk_count, variant, data_ref, fallback_ref, blob_bytes, license, attribution, updated_at)
+        VALUES ($1, 1, $2, NULL, $3, $4, $5, $6, $7, $8, $9, $10, $19, COALESCE($11, 'approved'), $12, $13, $14, $15, $16, $17, NULL, $18, $20, $21, $22, $23, $24, now())
+        ON CONFLICT (author, name, variant) DO UPDATE SET
             version = EXCLUDED.version,
             author = EXCLUDED.author,
             upload_id = EXCLUDED.upload_id,
@@ -167,22 +378,102 @@ func (s *Store) UpsertV1(ctx context.Context, payload RegisterV1) error {
             loop = EXCLUDED.loop,
             fallback_mime = EXCLUDED.fallback_mime,
             fallback_data = EXCLUDED.fallback_data,
+            checksum = EXCLUDED.checksum,
+            moderation_status = COALESCE($11, hivemoji_assets.moderation_status),
+            expires_at = EXCLUDED.expires_at,
+            cover = EXCLUDED.cover,
+            compression = EXCLUDED.compression,
+            ingest_notes = EXCLUDED.ingest_notes,
+            registered_block = EXCLUDED.registered_block,
+            pending_confirmation = EXCLUDED.pending_confirmation,
+            chunk_count = EXCLUDED.chunk_count,
+            data_ref = EXCLUDED.data_ref,
+            fallback_ref = EXCLUDED.fallback_ref,
+            blob_bytes = EXCLUDED.blob_bytes,
+            license = EXCLUDED.license,
+            attribution = EXCLUDED.attribution,
             updated_at = now()
-    `, payload.Name, payload.Author, payload.Mime, payload.Width, payload.Height, payload.Data, payload.Animated, payload.Loop, nullIfEmpty(payload.FallbackMime), nullBytes(payload.FallbackData))
-	return err
+    `, payload.Name, payload.Author, payload.Mime, payload.Width, payload.Height, img.data, payload.Animated, payload.Loop, nullIfEmpty(payload.FallbackMime), nullBytes(img.fallback), nullIfEmpty(payload.ModerationStatus), payload.ExpiresAt, payload.Cover, img.compression, ingestNotesJSON(payload.IngestNotes), nullIfZero(payload.RegisteredBlock), payload.PendingConfirmation, payload.Variant, sha256Hex(payload.Data), img.dataRef, img.fallbackRef, img.blobBytes, nullIfEmpty(payload.License), nullIfEmpty(payload.Attribution))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordVersion(ctx, tx, AssetVersion{
+		Author:          payload.Author,
+		Name:            payload.Name,
+		Variant:         payload.Variant,
+		Version:         1,
+		Mime:            payload.Mime,
+		Width:           payload.Width,
+		Height:          payload.Height,
+		Animated:        payload.Animated,
+		Size:            len(payload.Data),
+		Checksum:        sha256Hex(payload.Data),
+		FallbackMime:    nullIfEmpty(payload.FallbackMime),
+		FallbackSize:    len(payload.FallbackData),
+		RegisteredBlock: nullIfZero(payload.RegisteredBlock),
+	}); err != nil {
+		return nil, fmt.Errorf("record version: %w", err)
+	}
+	return replaced, tx.Commit(ctx)
 }
 
-// DeleteEmoji deletes a stored emoji by name.
+// DeleteEmoji deletes a stored emoji by name, together with all of its variants.
 func (s *Store) DeleteEmoji(ctx context.Context, author, name string) error {
 	if strings.TrimSpace(author) == "" {
 		return errors.New("author is required for delete")
 	}
-	_, err := s.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author = $1 AND name = $2`, author, name)
+	rows, err := s.pool.Query(ctx, `DELETE FROM hivemoji_assets WHERE author = $1 AND name = $2 RETURNING data_ref, fallback_ref`, author, name)
+	if err != nil {
+		return err
+	}
+	refs, err := deletedBlobs(rows)
+	s.releaseBlobs(ctx, refs)
+	return err
+}
+
+// DeleteVariant deletes one variant of a stored emoji, leaving the base emoji and other variants.
+func (s *Store) DeleteVariant(ctx context.Context, author, name, variant string) error {
+	if strings.TrimSpace(author) == "" {
+		return errors.New("author is required for delete")
+	}
+	rows, err := s.pool.Query(ctx, `DELETE FROM hivemoji_assets WHERE author = $1 AND name = $2 AND variant = $3 RETURNING data_ref, fallback_ref`, author, name, variant)
+	if err != nil {
+		return err
+	}
+	refs, err := deletedBlobs(rows)
+	s.releaseBlobs(ctx, refs)
 	return err
 }
 
 // SaveChunk records a chunk and assembles the set when complete. It returns the completed set if this call closed it.
+// A chunk declaring a total over the limit set with SetMaxChunks is rejected with ErrChunkTotal before its set
+// is created, so it can't hold storage until the incomplete-upload purge.
 func (s *Store) SaveChunk(ctx context.Context, chunk ChunkPayload) (*AssembledSet, error) {
+	if limit := s.chunkLimit(); chunk.Total > limit {
+		return nil, fmt.Errorf("%w: upload %s kind %s declares %d chunks, limit is %d", ErrChunkTotal, chunk.ID, chunk.Kind, chunk.Total, limit)
+	}
+	var assembled *AssembledSet
+	err := retryTx(ctx, func() (err error) {
+		assembled, err = s.saveChunk(ctx, chunk)
+		return err
+	})
+	return assembled, err
+}
+
+// SetMaxChunks sets the largest total SaveChunk accepts; zero or less means DefaultMaxChunks.
+func (s *Store) SetMaxChunks(n int) {
+	s.maxChunks = n
+}
+
+func (s *Store) chunkLimit() int {
+	if s.maxChunks > 0 {
+		return s.maxChunks
+	}
+	return DefaultMaxChunks
+}
+
+func (s *Store) saveChunk(ctx context.Context, chunk ChunkPayload) (*AssembledSet, error) {
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return nil, err
@@ -191,8 +482,8 @@ func (s *Store) SaveChunk(ctx context.Context, chunk ChunkPayload) (*AssembledSe
 
 	// Upsert chunk set metadata (without data until complete).
 	_, err = tx.Exec(ctx, `
-        INSERT INTO hivemoji_chunk_sets (upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, total, completed)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,false)
+        INSERT INTO hivemoji_chunk_sets (upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, total, expires_at, cover, variant, license, attribution, completed)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,false)
         ON CONFLICT (upload_id, kind) DO UPDATE SET
             name = EXCLUDED.name,
             author = EXCLUDED.author,
@@ -204,12 +495,23 @@ func (s *Store) SaveChunk(ctx context.Context, chunk ChunkPayload) (*AssembledSe
             loop = EXCLUDED.loop,
             checksum = EXCLUDED.checksum,
             total = EXCLUDED.total,
+            expires_at = COALESCE(EXCLUDED.expires_at, hivemoji_chunk_sets.expires_at),
+            cover = EXCLUDED.cover OR hivemoji_chunk_sets.cover,
+            license = COALESCE(hivemoji_chunk_sets.license, EXCLUDED.license),
+            attribution = COALESCE(hivemoji_chunk_sets.attribution, EXCLUDED.attribution),
+            variant = EXCLUDED.variant,
             updated_at = now()
-    `, chunk.ID, chunk.Kind, chunk.Name, chunk.Author, chunk.Version, chunk.Mime, chunk.Width, chunk.Height, chunk.Animated, chunk.Loop, chunk.Checksum, chunk.Total)
+    `, chunk.ID, chunk.Kind, chunk.Name, chunk.Author, chunk.Version, chunk.Mime, chunk.Width, chunk.Height, chunk.Animated, chunk.Loop, chunk.Checksum, chunk.Total, chunk.ExpiresAt, chunk.Cover, chunk.Variant, nullIfEmpty(chunk.License), nullIfEmpty(chunk.Attribution))
 	if err != nil {
 		return nil, fmt.Errorf("upsert chunk set: %w", err)
 	}
 
+	// Hold the chunk set row until commit so concurrent chunks of the same upload take turns
+	// and exactly one of them sees the final count.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM hivemoji_chunk_sets WHERE upload_id=$1 AND kind=$2 FOR UPDATE`, chunk.ID, chunk.Kind); err != nil {
+		return nil, fmt.Errorf("lock chunk set: %w", err)
+	}
+
 	// Insert chunk if not already present.
 	_, err = tx.Exec(ctx, `
         INSERT INTO hivemoji_chunks (upload_id, kind, seq, total, data)
@@ -244,6 +546,45 @@ func (s *Store) SaveChunk(ctx context.Context, chunk ChunkPayload) (*AssembledSe
 	return assembled, nil
 }
 
+// AssembleUpload re-runs assembly for a buffered chunk set, e.g. one whose assembly failed
+// during ingest. It returns ErrNotFound for unknown sets and wraps ErrAssemble when the
+// buffered chunks are incomplete or don't match the declared checksum.
+func (s *Store) AssembleUpload(ctx context.Context, uploadID, kind string) (*AssembledSet, error) {
+	var assembled *AssembledSet
+	err := retryTx(ctx, func() (err error) {
+		assembled, err = s.assembleUpload(ctx, uploadID, kind)
+		return err
+	})
+	return assembled, err
+}
+
+func (s *Store) assembleUpload(ctx context.Context, uploadID, kind string) (*AssembledSet, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM hivemoji_chunk_sets WHERE upload_id=$1 AND kind=$2)
+    `, uploadID, kind).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	assembled, err := s.assembleChunks(ctx, tx, uploadID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return assembled, nil
+}
+
 // assembleChunks concatenates ordered chunks and marks the set complete.
 func (s *Store) assembleChunks(ctx context.Context, tx pgx.Tx, uploadID, kind string) (*AssembledSet, error) {
 	rows, err := tx.Query(ctx, `
@@ -268,24 +609,25 @@ func (s *Store) assembleChunks(ctx context.Context, tx pgx.Tx, uploadID, kind st
 	}
 
 	if len(parts) == 0 {
-		return nil, errors.New("no chunks to assemble")
+		return nil, fmt.Errorf("%w: no chunks for %s/%s", ErrAssemble, uploadID, kind)
 	}
 
 	// Fetch metadata.
 	var set AssembledSet
 	var expectedTotal int
 	err = tx.QueryRow(ctx, `
-        SELECT upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, total
+        SELECT upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, expires_at, cover, variant, COALESCE(license, ''), COALESCE(attribution, ''), total
         FROM hivemoji_chunk_sets
         WHERE upload_id=$1 AND kind=$2
-    `, uploadID, kind).Scan(&set.UploadID, &set.Kind, &set.Name, &set.Author, &set.Version, &set.Mime, &set.Width, &set.Height, &set.Animated, &set.Loop, &set.Checksum, &expectedTotal)
+    `, uploadID, kind).Scan(&set.UploadID, &set.Kind, &set.Name, &set.Author, &set.Version, &set.Mime, &set.Width, &set.Height, &set.Animated, &set.Loop, &set.Checksum, &set.ExpiresAt, &set.Cover, &set.Variant, &set.License, &set.Attribution, &expectedTotal)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(parts) != expectedTotal {
-		return nil, fmt.Errorf("chunk count mismatch for %s/%s: have %d want %d", uploadID, kind, len(parts), expectedTotal)
+		return nil, fmt.Errorf("%w: chunk count mismatch for %s/%s: have %d want %d", ErrAssemble, uploadID, kind, len(parts), expectedTotal)
 	}
+	set.Total = expectedTotal
 
 	var buf []byte
 	for _, part := range parts {
@@ -296,7 +638,7 @@ func (s *Store) assembleChunks(ctx context.Context, tx pgx.Tx, uploadID, kind st
 	if set.Checksum != "" {
 		hash := sha256.Sum256(buf)
 		if !strings.EqualFold(set.Checksum, hex.EncodeToString(hash[:])) {
-			return nil, fmt.Errorf("checksum mismatch for upload %s kind %s", uploadID, kind)
+			return nil, fmt.Errorf("%w: checksum mismatch for upload %s kind %s", ErrAssemble, uploadID, kind)
 		}
 	}
 
@@ -310,8 +652,24 @@ func (s *Store) assembleChunks(ctx context.Context, tx pgx.Tx, uploadID, kind st
 	return &set, nil
 }
 
+// CleanupIncompleteChunks deletes incomplete chunk sets older than the given age, with their
+// chunks, and returns how many sets were removed.
+func (s *Store) CleanupIncompleteChunks(ctx context.Context, olderThan time.Duration) (int, error) {
+	sets, _, err := s.CleanupIncomplete(ctx, olderThan)
+	return int(sets), err
+}
+
 // CleanupIncomplete deletes incomplete chunk sets (and their chunks) older than the given age.
 func (s *Store) CleanupIncomplete(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
+	var sets, chunks int64
+	err := retryTx(ctx, func() (err error) {
+		sets, chunks, err = s.cleanupIncomplete(ctx, olderThan)
+		return err
+	})
+	return sets, chunks, err
+}
+
+func (s *Store) cleanupIncomplete(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
 	cutoff := time.Now().Add(-olderThan)
 
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
@@ -353,16 +711,133 @@ func (s *Store) CleanupIncomplete(ctx context.Context, olderThan time.Duration)
 	return deletedSets, deletedChunks, nil
 }
 
+// CompactChunks drops the buffered bytes of completed uploads that have been stored as an asset:
+// their chunk rows are deleted and the chunk set keeps only its metadata. Sets updated within
+// olderThan are left alone, as are uploads with another kind still incomplete, so a fallback
+// finishing after its main can still read the main's bytes. It returns the number of sets
+// compacted and chunks deleted.
+func (s *Store) CompactChunks(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
+	var sets, chunks int64
+	err := retryTx(ctx, func() (err error) {
+		sets, chunks, err = s.compactChunks(ctx, olderThan)
+		return err
+	})
+	return sets, chunks, err
+}
+
+func (s *Store) compactChunks(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var compactedSets, deletedChunks int64
+	err = tx.QueryRow(ctx, `
+        WITH finalized AS (
+            SELECT s.upload_id, s.kind FROM hivemoji_chunk_sets s
+            WHERE s.completed = true AND s.updated_at < $1
+              AND (s.data IS NOT NULL OR EXISTS (
+                  SELECT 1 FROM hivemoji_chunks c WHERE c.upload_id = s.upload_id AND c.kind = s.kind
+              ))
+              AND EXISTS (SELECT 1 FROM hivemoji_assets a WHERE a.upload_id = s.upload_id)
+              AND NOT EXISTS (
+                  SELECT 1 FROM hivemoji_chunk_sets o WHERE o.upload_id = s.upload_id AND o.completed = false
+              )
+            FOR UPDATE OF s
+        ),
+        deleted_chunks AS (
+            DELETE FROM hivemoji_chunks c
+            USING finalized f
+            WHERE c.upload_id = f.upload_id AND c.kind = f.kind
+            RETURNING 1
+        ),
+        compacted_sets AS (
+            UPDATE hivemoji_chunk_sets s2 SET data = NULL
+            FROM finalized f
+            WHERE s2.upload_id = f.upload_id AND s2.kind = f.kind
+            RETURNING 1
+        )
+        SELECT
+            COALESCE((SELECT count(*) FROM compacted_sets), 0) AS sets,
+            COALESCE((SELECT count(*) FROM deleted_chunks), 0) AS chunks
+    `, cutoff).Scan(&compactedSets, &deletedChunks)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return compactedSets, deletedChunks, nil
+}
+
+// UploadStats summarizes chunk uploads that have not completed yet.
+type UploadStats struct {
+	InFlight     int64
+	Chunks       int64
+	OldestUpdate time.Time // zero when nothing is in flight
+}
+
+// UploadStats aggregates incomplete chunk sets and all stored chunks.
+func (s *Store) UploadStats(ctx context.Context) (UploadStats, error) {
+	var stats UploadStats
+	var oldest *time.Time
+	err := s.pool.QueryRow(ctx, `
+        SELECT
+            (SELECT count(*) FROM hivemoji_chunk_sets WHERE completed = false),
+            (SELECT min(updated_at) FROM hivemoji_chunk_sets WHERE completed = false),
+            (SELECT count(*) FROM hivemoji_chunks)
+    `).Scan(&stats.InFlight, &oldest, &stats.Chunks)
+	if err != nil {
+		return UploadStats{}, err
+	}
+	if oldest != nil {
+		stats.OldestUpdate = *oldest
+	}
+	return stats, nil
+}
+
 // UpsertFromChunks saves an assembled set (and optional fallback) into the assets table.
 func (s *Store) UpsertFromChunks(ctx context.Context, main *AssembledSet, fallback *AssembledSet) error {
 	if main == nil {
 		return errors.New("main set is required")
 	}
+	img, err := s.storeImages(ctx, main.Data, fallbackData(fallback))
+	if err != nil {
+		return err
+	}
+	var replaced []string
+	err = retryTx(ctx, func() (err error) {
+		replaced, err = s.upsertFromChunks(ctx, main, fallback, img)
+		return err
+	})
+	if err != nil {
+		s.releaseBlobs(ctx, img.refs())
+		return err
+	}
+	s.releaseBlobs(ctx, replaced)
+	return nil
+}
 
-	_, err := s.pool.Exec(ctx, `
-        INSERT INTO hivemoji_assets (name, version, author, upload_id, mime, width, height, data, animated, loop, fallback_mime, fallback_data, checksum, updated_at)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13, now())
-        ON CONFLICT (author, name) DO UPDATE SET
+func (s *Store) upsertFromChunks(ctx context.Context, main *AssembledSet, fallback *AssembledSet, img storedImages) ([]string, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	replaced, err := s.replacedBlobs(ctx, tx, main.Author, main.Name, main.Variant)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+        INSERT INTO hivemoji_assets (name, version, author, upload_id, mime, width, height, data, animated, loop, fallback_mime, fallback_data, checksum, moderation_status, expires_at, cover, compression, ingest_notes, registered_block, pending_confirmation, chunk_count, variant, data_ref, fallback_ref, blob_bytes, license, attribution, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13, COALESCE($14, 'approved'), $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, now())
+        ON CONFLICT (author, name, variant) DO UPDATE SET
             version = EXCLUDED.version,
             author = EXCLUDED.author,
             upload_id = EXCLUDED.upload_id,
@@ -375,23 +850,62 @@ func (s *Store) UpsertFromChunks(ctx context.Context, main *AssembledSet, fallba
             fallback_mime = EXCLUDED.fallback_mime,
             fallback_data = EXCLUDED.fallback_data,
             checksum = EXCLUDED.checksum,
+            moderation_status = COALESCE($14, hivemoji_assets.moderation_status),
+            expires_at = EXCLUDED.expires_at,
+            cover = EXCLUDED.cover,
+            compression = EXCLUDED.compression,
+            ingest_notes = EXCLUDED.ingest_notes,
+            registered_block = EXCLUDED.registered_block,
+            pending_confirmation = EXCLUDED.pending_confirmation,
+            chunk_count = EXCLUDED.chunk_count,
+            data_ref = EXCLUDED.data_ref,
+            fallback_ref = EXCLUDED.fallback_ref,
+            blob_bytes = EXCLUDED.blob_bytes,
+            license = EXCLUDED.license,
+            attribution = EXCLUDED.attribution,
             updated_at = now()
-    `, main.Name, main.Version, main.Author, main.UploadID, main.Mime, main.Width, main.Height, main.Data, main.Animated, main.Loop, fallbackMime(fallback), fallbackData(fallback), main.Checksum)
-	return err
+    `, main.Name, main.Version, main.Author, main.UploadID, main.Mime, main.Width, main.Height, img.data, main.Animated, main.Loop, fallbackMime(fallback), nullBytes(img.fallback), main.Checksum, nullIfEmpty(main.ModerationStatus), main.ExpiresAt, main.Cover, img.compression, ingestNotesJSON(main.IngestNotes), nullIfZero(main.RegisteredBlock), main.PendingConfirmation, nullIfZero(int64(main.Total)), main.Variant, img.dataRef, img.fallbackRef, img.blobBytes, nullIfEmpty(main.License), nullIfEmpty(main.Attribution))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveManifest(ctx, tx, main.UploadID); err != nil {
+		return nil, fmt.Errorf("resolve manifest: %w", err)
+	}
+
+	if err := recordVersion(ctx, tx, AssetVersion{
+		Author:          main.Author,
+		Name:            main.Name,
+		Variant:         main.Variant,
+		Version:         main.Version,
+		Mime:            main.Mime,
+		Width:           main.Width,
+		Height:          main.Height,
+		Animated:        main.Animated,
+		Size:            len(main.Data),
+		Checksum:        sha256Hex(main.Data),
+		FallbackMime:    fallbackMime(fallback),
+		FallbackSize:    len(fallbackData(fallback)),
+		RegisteredBlock: nullIfZero(main.RegisteredBlock),
+	}); err != nil {
+		return nil, fmt.Errorf("record version: %w", err)
+	}
+	return replaced, tx.Commit(ctx)
 }
 
-// GetChunkSet returns a completed chunk set if available.
+// GetChunkSet returns a completed chunk set, or ErrNotFound if it is missing, incomplete or
+// compacted by CompactChunks.
 func (s *Store) GetChunkSet(ctx context.Context, uploadID, kind string) (*AssembledSet, error) {
 	row := s.pool.QueryRow(ctx, `
-        SELECT upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, data
+        SELECT upload_id, kind, name, author, version, mime, width, height, animated, loop, checksum, expires_at, cover, variant, COALESCE(license, ''), COALESCE(attribution, ''), total, data
         FROM hivemoji_chunk_sets
-        WHERE upload_id=$1 AND kind=$2 AND completed=true
+        WHERE upload_id=$1 AND kind=$2 AND completed=true AND data IS NOT NULL
     `, uploadID, kind)
 
 	var set AssembledSet
-	if err := row.Scan(&set.UploadID, &set.Kind, &set.Name, &set.Author, &set.Version, &set.Mime, &set.Width, &set.Height, &set.Animated, &set.Loop, &set.Checksum, &set.Data); err != nil {
+	if err := row.Scan(&set.UploadID, &set.Kind, &set.Name, &set.Author, &set.Version, &set.Mime, &set.Width, &set.Height, &set.Animated, &set.Loop, &set.Checksum, &set.ExpiresAt, &set.Cover, &set.Variant, &set.License, &set.Attribution, &set.Total, &set.Data); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
@@ -427,123 +941,136 @@ func (s *Store) LastBlock(ctx context.Context) (int64, error) {
 	return n, nil
 }
 
+// SetReprocessedFrom records t as the HIVE_REPROCESS_FROM value ingestion already rewound to.
+func (s *Store) SetReprocessedFrom(ctx context.Context, t time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+        INSERT INTO sync_state (key, value, updated_at)
+        VALUES ('reprocessed_from', $1, now())
+        ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = now()
+    `, t.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// ReprocessedFrom returns the HIVE_REPROCESS_FROM value last rewound to, or the zero time.
+func (s *Store) ReprocessedFrom(ctx context.Context) (time.Time, error) {
+	var value string
+	err := s.pool.QueryRow(ctx, `SELECT value FROM sync_state WHERE key='reprocessed_from'`).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
 // FetchAsset returns a stored emoji asset.
 type Asset struct {
-	Name         string
-	Version      int
-	Author       *string
-	UploadID     *string
-	Mime         string
-	Width        *int
-	Height       *int
-	Animated     bool
-	Loop         *int
-	Checksum     *string
-	FallbackMime *string
-	Data         []byte
-	FallbackData []byte
+	Name         string     `db:"name"`
+	Version      int        `db:"version"`
+	Author       *string    `db:"author"`
+	UploadID     *string    `db:"upload_id"`
+	Mime         string     `db:"mime"`
+	Width        *int       `db:"width"`
+	Height       *int       `db:"height"`
+	Animated     bool       `db:"animated"`
+	Loop         *int       `db:"loop"`
+	Checksum     *string    `db:"checksum"`
+	FallbackMime *string    `db:"fallback_mime"`
+	Featured     bool       `db:"featured"`
+	Description  *string    `db:"description"`
+	Tags         []string   `db:"tags"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	// Cover marks the emoji as the thumbnail of its packs.
+	Cover bool `db:"cover"`
+	// License and Attribution are nil unless the author declared them.
+	License     *string `db:"license"`
+	Attribution *string `db:"attribution"`
+	// ChunkCount is how many v2 chunks the emoji was assembled from; nil for v1 emojis.
+	ChunkCount *int `db:"chunk_count"`
+	// RegisteredBlock is the block the emoji was last registered in; nil for emojis stored
+	// before blocks were recorded.
+	RegisteredBlock *int64 `db:"registered_block"`
+	// Unofficial is set when another author has reserved this emoji's name.
+	Unofficial bool `db:"unofficial"`
+	// ModerationStatus is only populated by GetAsset; listings return approved assets only.
+	ModerationStatus string `db:"moderation_status"`
+	// PendingConfirmation is only populated by GetAsset; listings return confirmed assets only.
+	PendingConfirmation bool `db:"pending_confirmation"`
+	// Variant is empty for base emojis; see ListVariants.
+	Variant string `db:"variant"`
+	// Revision and Historical are only populated by ListAssetHistory. Revision is nil for
+	// emojis stored before revisions were recorded.
+	Revision   *int `db:"revision"`
+	Historical bool `db:"historical"`
+	// UpdatedAt is only populated by GetAsset.
+	UpdatedAt time.Time `db:"updated_at"`
+	// Fetches is only populated by SearchAssets.
+	Fetches      *int64 `db:"fetches"`
+	Data         []byte `db:"data"`
+	FallbackData []byte `db:"fallback_data"`
+}
+
+// Expired reports whether the emoji's expiry has passed at now. Emojis stay visible up to,
+// but not including, their expires_at instant.
+func (a Asset) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
 }
 
-// GetAsset retrieves an emoji by author and name.
+// GetAsset retrieves an emoji by author and name, returning ErrNotFound if it does not exist.
 func (s *Store) GetAsset(ctx context.Context, author, name string) (*Asset, error) {
-	row := s.pool.QueryRow(ctx, `
-        SELECT name, version, author, upload_id, mime, width, height, animated, loop, checksum, fallback_mime, data, fallback_data
-        FROM hivemoji_assets WHERE author=$1 AND name=$2
-    `, author, name)
-
-	var asset Asset
-	var uploadID *string
-	var authorPtr *string
-	var width *int
-	var height *int
-	var loop *int
-	var checksum *string
-	var fallbackMime *string
-	var data []byte
-	var fallbackData []byte
-
-	if err := row.Scan(&asset.Name, &asset.Version, &authorPtr, &uploadID, &asset.Mime, &width, &height, &asset.Animated, &loop, &checksum, &fallbackMime, &data, &fallbackData); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
+	return s.GetAssetVariant(ctx, author, name, "")
+}
+
+// GetAssetVariant retrieves one variant of an emoji like GetAsset; an empty variant is the base emoji.
+func (s *Store) GetAssetVariant(ctx context.Context, author, name, variant string) (*Asset, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT `+selectAssetColumns(true)+`, variant, moderation_status, pending_confirmation, updated_at
+        FROM hivemoji_assets WHERE author=$1 AND name=$2 AND variant=$3
+    `, author, name, variant)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.collectOneAsset(ctx, rows)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
-	asset.UploadID = uploadID
-	asset.Author = authorPtr
-	asset.Width = width
-	asset.Height = height
-	asset.Loop = loop
-	asset.Checksum = checksum
-	asset.FallbackMime = fallbackMime
-	asset.Data = data
-	asset.FallbackData = fallbackData
 	return &asset, nil
 }
 
-// ListAssets fetches all stored emoji metadata (without binary payloads unless requested).
-func (s *Store) ListAssets(ctx context.Context, includeData bool) ([]Asset, error) {
-	cols := "name, version, author, upload_id, mime, width, height, animated, loop, checksum, fallback_mime"
-	if includeData {
-		cols += ", data, fallback_data"
+// RandomAsset picks one public emoji at random, optionally limited to author, including its
+// image bytes. It returns ErrNotFound when there is nothing to pick from.
+func (s *Store) RandomAsset(ctx context.Context, author string) (*Asset, error) {
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM hivemoji_assets WHERE %s AND ($1 = '' OR author = $1)
+        ORDER BY random() LIMIT 1
+    `, selectAssetColumns(true), publicAssets)
+
+	rows, err := s.pool.Query(ctx, query, author)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.collectOneAsset(ctx, rows)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
 	}
-	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets ORDER BY name", cols))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return &asset, nil
+}
 
-	var assets []Asset
-	for rows.Next() {
-		var asset Asset
-		if includeData {
-			var uploadID *string
-			var author *string
-			var width *int
-			var height *int
-			var loop *int
-			var checksum *string
-			var fallbackMime *string
-			var data []byte
-			var fallbackData []byte
-
-			if err := rows.Scan(&asset.Name, &asset.Version, &author, &uploadID, &asset.Mime, &width, &height, &asset.Animated, &loop, &checksum, &fallbackMime, &data, &fallbackData); err != nil {
-				return nil, err
-			}
-			asset.UploadID = uploadID
-			asset.Author = author
-			asset.Width = width
-			asset.Height = height
-			asset.Loop = loop
-			asset.Checksum = checksum
-			asset.FallbackMime = fallbackMime
-			asset.Data = data
-			asset.FallbackData = fallbackData
-		} else {
-			var uploadID *string
-			var author *string
-			var width *int
-			var height *int
-			var loop *int
-			var checksum *string
-			var fallbackMime *string
-
-			if err := rows.Scan(&asset.Name, &asset.Version, &author, &uploadID, &asset.Mime, &width, &height, &asset.Animated, &loop, &checksum, &fallbackMime); err != nil {
-				return nil, err
-			}
-			asset.UploadID = uploadID
-			asset.Author = author
-			asset.Width = width
-			asset.Height = height
-			asset.Loop = loop
-			asset.Checksum = checksum
-			asset.FallbackMime = fallbackMime
-		}
-		assets = append(assets, asset)
-	}
-	if err := rows.Err(); err != nil {
+// ListAssets fetches all stored emoji metadata (without binary payloads unless requested).
+func (s *Store) ListAssets(ctx context.Context, includeData bool) ([]Asset, error) {
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE %s ORDER BY name", selectAssetColumns(includeData), publicAssets))
+	if err != nil {
 		return nil, err
 	}
-	return assets, nil
+	return s.collectAssets(ctx, rows)
 }
 
 // ListAssetsByAuthor fetches emojis for a specific author.
@@ -552,70 +1079,268 @@ func (s *Store) ListAssetsByAuthor(ctx context.Context, author string, includeDa
 		return nil, errors.New("author is required")
 	}
 
-	cols := "name, version, author, upload_id, mime, width, height, animated, loop, checksum, fallback_mime"
-	if includeData {
-		cols += ", data, fallback_data"
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE author=$1 AND %s ORDER BY name", selectAssetColumns(includeData), publicAssets), author)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
+
+// ListAssetsByAuthors fetches the emojis of any of authors, ordered by author then name.
+func (s *Store) ListAssetsByAuthors(ctx context.Context, authors []string, includeData bool) ([]Asset, error) {
+	if len(authors) == 0 {
+		return nil, errors.New("at least one author is required")
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE author = ANY($1) AND %s ORDER BY author, name", selectAssetColumns(includeData), publicAssets), authors)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
+
+// GetAssetsByChecksum fetches all emojis (across authors) whose checksum matches.
+func (s *Store) GetAssetsByChecksum(ctx context.Context, checksum string, includeData bool) ([]Asset, error) {
+	if strings.TrimSpace(checksum) == "" {
+		return nil, errors.New("checksum is required")
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE lower(checksum)=lower($1) AND %s ORDER BY author, name", selectAssetColumns(includeData), publicAssets), checksum)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
+
+// AssetMetadataUpdate carries correctable asset fields; nil fields are left unchanged.
+type AssetMetadataUpdate struct {
+	Mime        *string
+	Width       *int
+	Height      *int
+	Description *string
+	Tags        *[]string
+}
+
+// UpdateAssetMetadata applies a sparse metadata correction to a stored emoji.
+func (s *Store) UpdateAssetMetadata(ctx context.Context, author, name string, update AssetMetadataUpdate) error {
+	sets := []string{"updated_at = now()"}
+	args := []any{author, name}
+	add := func(col string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+	if update.Mime != nil {
+		add("mime", *update.Mime)
+	}
+	if update.Width != nil {
+		add("width", *update.Width)
+	}
+	if update.Height != nil {
+		add("height", *update.Height)
+	}
+	if update.Description != nil {
+		add("description", nullIfEmpty(*update.Description))
+	}
+	if update.Tags != nil {
+		tags := *update.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		add("tags", tags)
+	}
+
+	tag, err := s.pool.Exec(ctx, fmt.Sprintf("UPDATE hivemoji_assets SET %s WHERE author=$1 AND name=$2 AND variant=''", strings.Join(sets, ", ")), args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ConfirmAssets makes emojis registered at or before block visible to public endpoints and
+// reports how many were promoted.
+func (s *Store) ConfirmAssets(ctx context.Context, block int64) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+        UPDATE hivemoji_assets SET pending_confirmation=false, updated_at=now()
+        WHERE pending_confirmation AND registered_block <= $1
+    `, block)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SetModerationStatus records a moderation verdict for an emoji.
+func (s *Store) SetModerationStatus(ctx context.Context, author, name, status string) error {
+	tag, err := s.pool.Exec(ctx, `
+        UPDATE hivemoji_assets SET moderation_status=$3, updated_at=now() WHERE author=$1 AND name=$2 AND variant=''
+    `, author, name, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetFeatured flags or unflags an emoji as featured with the given display order.
+func (s *Store) SetFeatured(ctx context.Context, author, name string, featured bool, order int) error {
+	tag, err := s.pool.Exec(ctx, `
+        UPDATE hivemoji_assets SET featured=$3, featured_order=$4 WHERE author=$1 AND name=$2 AND variant=''
+    `, author, name, featured, order)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
 	}
+	return nil
+}
 
-	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE author=$1 ORDER BY name", cols), author)
+// ListFeatured fetches featured emojis in display order.
+func (s *Store) ListFeatured(ctx context.Context, includeData bool) ([]Asset, error) {
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE featured AND %s ORDER BY featured_order, author, name", selectAssetColumns(includeData), publicAssets))
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
+
+// Suggestion is a lightweight autocomplete match.
+type Suggestion struct {
+	Name     string
+	Author   string
+	Mime     string
+	Animated bool
+}
+
+// AutocompleteNames returns up to limit emojis whose name starts with prefix (case-insensitive), most recently updated first.
+func (s *Store) AutocompleteNames(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT name, COALESCE(author, ''), mime, COALESCE(animated, false)
+        FROM hivemoji_assets
+        WHERE lower(name) LIKE $1 AND `+publicAssets+`
+        ORDER BY updated_at DESC, name
+        LIMIT $2
+    `, likePrefixPattern(strings.ToLower(prefix)), limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var assets []Asset
+	var out []Suggestion
 	for rows.Next() {
-		var asset Asset
-		if includeData {
-			var uploadID *string
-			var auth *string
-			var width *int
-			var height *int
-			var loop *int
-			var checksum *string
-			var fallbackMime *string
-			var data []byte
-			var fallbackData []byte
-
-			if err := rows.Scan(&asset.Name, &asset.Version, &auth, &uploadID, &asset.Mime, &width, &height, &asset.Animated, &loop, &checksum, &fallbackMime, &data, &fallbackData); err != nil {
-				return nil, err
-			}
-			asset.Author = auth
-			asset.UploadID = uploadID
-			asset.Width = width
-			asset.Height = height
-			asset.Loop = loop
-			asset.Checksum = checksum
-			asset.FallbackMime = fallbackMime
-			asset.Data = data
-			asset.FallbackData = fallbackData
-		} else {
-			var uploadID *string
-			var auth *string
-			var width *int
-			var height *int
-			var loop *int
-			var checksum *string
-			var fallbackMime *string
-
-			if err := rows.Scan(&asset.Name, &asset.Version, &auth, &uploadID, &asset.Mime, &width, &height, &asset.Animated, &loop, &checksum, &fallbackMime); err != nil {
-				return nil, err
-			}
-			asset.Author = auth
-			asset.UploadID = uploadID
-			asset.Width = width
-			asset.Height = height
-			asset.Loop = loop
-			asset.Checksum = checksum
-			asset.FallbackMime = fallbackMime
+		var sug Suggestion
+		if err := rows.Scan(&sug.Name, &sug.Author, &sug.Mime, &sug.Animated); err != nil {
+			return nil, err
 		}
-		assets = append(assets, asset)
+		out = append(out, sug)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return out, nil
+}
+
+// AuthorsOfName returns the authors with a public emoji called name, most recently updated first.
+func (s *Store) AuthorsOfName(ctx context.Context, name string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT author
+        FROM hivemoji_assets
+        WHERE name = $1 AND author IS NOT NULL AND `+publicAssets+`
+        ORDER BY updated_at DESC, author
+    `, name)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
 
-	return assets, nil
+// likePrefixPattern escapes LIKE metacharacters in prefix and appends a trailing wildcard.
+func likePrefixPattern(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+// AssetFilter narrows Count and ListAssetsPage to a subset of the public emojis. Zero values match everything.
+type AssetFilter struct {
+	Author  string
+	Authors []string // any of these authors; ignored when empty
+	// FromBlock and ToBlock, when non-zero, keep emojis registered within the inclusive block
+	// range; emojis without a recorded block never match.
+	FromBlock int64
+	ToBlock   int64
+}
+
+// where returns the SQL conditions for f, each prefixed with AND, appending their
+// arguments to args.
+func (f AssetFilter) where(args []any) (string, []any) {
+	var clause string
+	if f.Author != "" {
+		args = append(args, f.Author)
+		clause += fmt.Sprintf(" AND author = $%d", len(args))
+	}
+	if len(f.Authors) > 0 {
+		args = append(args, f.Authors)
+		clause += fmt.Sprintf(" AND author = ANY($%d)", len(args))
+	}
+	if f.FromBlock > 0 {
+		args = append(args, f.FromBlock)
+		clause += fmt.Sprintf(" AND registered_block >= $%d", len(args))
+	}
+	if f.ToBlock > 0 {
+		args = append(args, f.ToBlock)
+		clause += fmt.Sprintf(" AND registered_block <= $%d", len(args))
+	}
+	return clause, args
+}
+
+// AssetCount summarizes the emojis matching an AssetFilter.
+type AssetCount struct {
+	Total        int64
+	LastModified time.Time // zero when nothing matches
+}
+
+// Count returns how many public emojis match filter and when the newest of them changed,
+// without reading any emoji rows.
+func (s *Store) Count(ctx context.Context, filter AssetFilter) (AssetCount, error) {
+	clause, args := filter.where(nil)
+	query := `SELECT COUNT(*), MAX(updated_at) FROM hivemoji_assets WHERE ` + publicAssets + clause
+
+	var count AssetCount
+	var lastModified *time.Time
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&count.Total, &lastModified); err != nil {
+		return AssetCount{}, err
+	}
+	if lastModified != nil {
+		count.LastModified = *lastModified
+	}
+	return count, nil
+}
+
+// AuthorUsage is an author's storage footprint.
+type AuthorUsage struct {
+	// Bytes is the size of every visible image the author has stored, variants and fallback
+	// images included, as kept at rest (after compression).
+	Bytes    int64
+	Emojis   int64
+	Animated int64
+}
+
+// AuthorUsage totals the bytes stored for author's visible emojis and counts them, and the
+// animated ones among them, like the public listings do: variants add to Bytes only.
+func (s *Store) AuthorUsage(ctx context.Context, author string) (AuthorUsage, error) {
+	var usage AuthorUsage
+	err := s.pool.QueryRow(ctx, `
+        SELECT COALESCE(SUM(octet_length(data) + COALESCE(octet_length(fallback_data), 0) + blob_bytes), 0),
+               COUNT(*) FILTER (WHERE variant = ''),
+               COUNT(*) FILTER (WHERE variant = '' AND animated)
+        FROM hivemoji_assets
+        WHERE author = $1 AND `+visibleAssets, author).Scan(&usage.Bytes, &usage.Emojis, &usage.Animated)
+	return usage, err
 }
 
 // GetAuthorLastModified returns the most recent updated_at timestamp for an author's emojis.
@@ -644,6 +1369,13 @@ func nullIfEmpty(value string) *string {
 	return &value
 }
 
+func nullIfZero(n int64) *int64 {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
 func nullBytes(b []byte) []byte {
 	if len(b) == 0 {
 		return nil
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLikePrefixPattern(t *testing.T) {
+	cases := map[string]string{
+		"par":      "par%",
+		"100%":     `100\%%`,
+		"snake_ca": `snake\_ca%`,
+		`back\`:    `back\\%`,
+	}
+	for in, want := range cases {
+		if got := likePrefixPattern(in); got != want {
+			t.Errorf("likePrefixPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAssetExpired(t *testing.T) {
+	expiry := time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC)
+	event := Asset{ExpiresAt: &expiry}
+
+	if event.Expired(expiry.Add(-time.Nanosecond)) {
+		t.Errorf("expected emoji to be visible just before expiry")
+	}
+	if !event.Expired(expiry) {
+		t.Errorf("expected emoji to expire exactly at expires_at")
+	}
+	if !event.Expired(expiry.Add(time.Hour)) {
+		t.Errorf("expected emoji to stay expired after expires_at")
+	}
+	if (Asset{}).Expired(expiry) {
+		t.Errorf("expected emoji without expiry never to expire")
+	}
+}
+
+func TestVerifyAsset(t *testing.T) {
+	data := []byte("hello emoji")
+	sum := sha256.Sum256(data)
+	good := hex.EncodeToString(sum[:])
+	upper := strings.ToUpper(good)
+	bad := strings.Repeat("0", 64)
+	author := "mrtats"
+
+	zstd := &Store{compression: CompressionZstd}
+	compressed, _, codec := zstd.compressImages(bytes.Repeat(data, 20), nil)
+	if codec != CompressionZstd {
+		t.Fatalf("expected repetitive data to compress")
+	}
+	compressedSum := sha256.Sum256(bytes.Repeat(data, 20))
+	compressedGood := hex.EncodeToString(compressedSum[:])
+
+	tests := []struct {
+		name         string
+		asset        Asset
+		codec        string
+		wantChecked  bool
+		wantMismatch bool
+	}{
+		{"matching", Asset{Name: "wave", Author: &author, Checksum: &good, Data: data}, "", true, false},
+		{"case-insensitive", Asset{Name: "wave", Author: &author, Checksum: &upper, Data: data}, "", true, false},
+		{"mismatch", Asset{Name: "wave", Author: &author, Checksum: &bad, Data: data}, "", true, true},
+		{"no checksum", Asset{Name: "wave", Author: &author, Data: data}, "", false, false},
+		{"compressed", Asset{Name: "wave", Author: &author, Checksum: &compressedGood, Data: compressed}, codec, true, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mismatch, checked, err := verifyAsset(tc.asset, tc.codec)
+			if err != nil {
+				t.Fatalf("verifyAsset error: %v", err)
+			}
+			if checked != tc.wantChecked || (mismatch != nil) != tc.wantMismatch {
+				t.Fatalf("checked=%t mismatch=%+v, want checked=%t mismatch=%t", checked, mismatch, tc.wantChecked, tc.wantMismatch)
+			}
+			if mismatch != nil && (mismatch.Actual != good || mismatch.Stored != bad || mismatch.Author != "mrtats") {
+				t.Fatalf("unexpected mismatch %+v", mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// StreamAssets calls fn with every public base emoji matching filter, ordered by name, then
+// author, reading them from one cursor instead of loading the whole list. It stops at the
+// first error from fn or when ctx is done; either way the query is cancelled and its
+// connection returned to the pool before it returns.
+func (s *Store) StreamAssets(ctx context.Context, filter AssetFilter, includeData bool, fn func(Asset) error) error {
+	clause, args := filter.where(nil)
+	query := fmt.Sprintf("SELECT %s FROM hivemoji_assets WHERE %s%s ORDER BY name, author", selectAssetColumns(includeData), publicAssets, clause)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		asset, err := s.scanAsset(ctx, rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(asset); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStreamAssets_Cancel(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	author := fmt.Sprintf("stream-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author = $1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author = $1`, author)
+	})
+	for i := 0; i < 5; i++ {
+		if err := store.UpsertV1(ctx, RegisterV1{Name: fmt.Sprintf("e%d", i), Author: author, Mime: "image/png", Data: []byte{byte(i)}}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var seen int
+	err := store.StreamAssets(streamCtx, AssetFilter{Author: author}, true, func(a Asset) error {
+		seen++
+		if seen == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if seen != 2 {
+		t.Fatalf("expected the stream to stop after the second emoji, got %d", seen)
+	}
+	if n := store.pool.Stat().AcquiredConns(); n != 0 {
+		t.Fatalf("expected the connection back in the pool, %d still acquired", n)
+	}
+}
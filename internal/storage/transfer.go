@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNameTaken is returned by TransferEmoji when the receiving author already has an emoji
+// with the transferred name.
+var ErrNameTaken = errors.New("name already taken")
+
+// Audit log actions.
+const (
+	AuditTransfer = "transfer"
+)
+
+// AuditEntry is one ownership change recorded in the audit log. Author is who performed the
+// action; Target is the account it was performed towards, e.g. the new owner of a transfer.
+type AuditEntry struct {
+	ID        int64
+	Action    string
+	Author    string
+	Name      string
+	Target    string
+	Block     *int64
+	CreatedAt time.Time
+}
+
+// TransferEmoji moves an emoji, with all its variants, from one author to another, along with
+// its recorded revisions, any name reservation from holds and its places in collections. It
+// returns ErrNotFound when from has no emoji called name, and ErrNameTaken when to already has
+// one. The transfer is recorded in the audit log with the block of the transfer op.
+func (s *Store) TransferEmoji(ctx context.Context, name, from, to string, block int64) error {
+	return retryTx(ctx, func() error { return s.transferEmoji(ctx, name, from, to, block) })
+}
+
+func (s *Store) transferEmoji(ctx context.Context, name, from, to string, block int64) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `SELECT variant FROM hivemoji_assets WHERE author = $1 AND name = $2 FOR UPDATE`, from, name)
+	if err != nil {
+		return err
+	}
+	found := rows.Next()
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	var taken bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM hivemoji_assets WHERE author = $1 AND name = $2)`, to, name).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrNameTaken
+	}
+
+	if _, err := tx.Exec(ctx, `
+        UPDATE hivemoji_assets SET author = $3, updated_at = now()
+        WHERE author = $1 AND name = $2
+    `, from, name, to); err != nil {
+		return err
+	}
+
+	// Revisions the receiver kept from an emoji of the same name it has since deleted would
+	// collide with the incoming history; the transferred emoji's history replaces them.
+	if _, err := tx.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author = $1 AND name = $2`, to, name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+        UPDATE hivemoji_asset_versions SET author = $3
+        WHERE author = $1 AND name = $2
+    `, from, name, to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+        UPDATE hivemoji_name_reservations SET author = $3
+        WHERE name = $2 AND author = $1
+    `, from, name, to); err != nil {
+		return err
+	}
+
+	// Collections listing both emojis would end up listing the same one twice; the receiver's
+	// deleted emoji gives way to the transferred one.
+	if _, err := tx.Exec(ctx, `
+        DELETE FROM hivemoji_collection_items stale
+        WHERE stale.author = $3 AND stale.name = $2 AND EXISTS (
+            SELECT 1 FROM hivemoji_collection_items i
+            WHERE i.slug = stale.slug AND i.author = $1 AND i.name = $2
+        )
+    `, from, name, to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+        UPDATE hivemoji_collection_items SET author = $3
+        WHERE author = $1 AND name = $2
+    `, from, name, to); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO hivemoji_audit_log (action, author, name, target, block_num)
+        VALUES ($1, $2, $3, $4, $5)
+    `, AuditTransfer, from, name, to, nullIfZero(block)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListAuditLog returns up to limit audit log entries, most recent first.
+func (s *Store) ListAuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT id, action, author, name, target, block_num, created_at
+        FROM hivemoji_audit_log
+        ORDER BY id DESC
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Author, &e.Name, &e.Target, &e.Block, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ListNameAudit returns every audit log entry for emojis called name, oldest first, whoever
+// performed them.
+func (s *Store) ListNameAudit(ctx context.Context, name string) ([]AuditEntry, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT id, action, author, name, target, block_num, created_at
+        FROM hivemoji_audit_log
+        WHERE name = $1
+        ORDER BY id
+    `, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Author, &e.Name, &e.Target, &e.Block, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTransferEmoji(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	from := fmt.Sprintf("from-%d", suffix)
+	to := fmt.Sprintf("to-%d", suffix)
+	other := fmt.Sprintf("other-%d", suffix)
+	for _, reg := range []RegisterV1{
+		{Name: "wave", Author: from, Mime: "image/png", Data: []byte("base"), RegisteredBlock: 10},
+		{Name: "wave", Author: from, Mime: "image/png", Data: []byte("dark"), Variant: "dark", RegisteredBlock: 11},
+		{Name: "smile", Author: other, Mime: "image/png", Data: []byte("smile"), RegisteredBlock: 12},
+		{Name: "smile", Author: from, Mime: "image/png", Data: []byte("smile2"), RegisteredBlock: 13},
+	} {
+		if err := store.UpsertV1(ctx, reg); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+
+	if err := store.TransferEmoji(ctx, "missing", from, to, 20); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.TransferEmoji(ctx, "smile", from, other, 21); !errors.Is(err, ErrNameTaken) {
+		t.Fatalf("expected ErrNameTaken, got %v", err)
+	}
+
+	if err := store.TransferEmoji(ctx, "wave", from, to, 22); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if _, err := store.GetAsset(ctx, from, "wave"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the old owner to lose the emoji, got %v", err)
+	}
+	if _, err := store.GetAsset(ctx, to, "wave"); err != nil {
+		t.Fatalf("expected the new owner to have the emoji: %v", err)
+	}
+	var variants int
+	if err := store.pool.QueryRow(ctx, `SELECT count(*) FROM hivemoji_assets WHERE author = $1 AND name = 'wave'`, to).Scan(&variants); err != nil || variants != 2 {
+		t.Fatalf("expected both variants to move, got %d (%v)", variants, err)
+	}
+	versions, err := store.ListAssetVersions(ctx, to, "wave")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("expected the revision history to move, got %d (%v)", len(versions), err)
+	}
+
+	entries, err := store.ListAuditLog(ctx, 10)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) == 0 || entries[0].Action != AuditTransfer || entries[0].Author != from || entries[0].Target != to {
+		t.Fatalf("expected the transfer at the head of the audit log, got %+v", entries)
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UploadStatus reports how far a v2 chunk upload has got.
+type UploadStatus struct {
+	UploadID  string
+	Kind      string
+	Total     int
+	Completed bool
+	// Missing lists the seq numbers (1-based) not received yet, in order.
+	Missing []int
+}
+
+// GetUploadStatus returns which chunks of an upload are still missing, or ErrNotFound when
+// no chunk of it has been seen.
+func (s *Store) GetUploadStatus(ctx context.Context, uploadID, kind string) (*UploadStatus, error) {
+	status := UploadStatus{UploadID: uploadID, Kind: kind}
+	err := s.pool.QueryRow(ctx, `
+        SELECT total, completed FROM hivemoji_chunk_sets WHERE upload_id=$1 AND kind=$2
+    `, uploadID, kind).Scan(&status.Total, &status.Completed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status.Completed {
+		status.Missing = []int{}
+		return &status, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT seq FROM hivemoji_chunks WHERE upload_id=$1 AND kind=$2`, uploadID, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var present []int
+	for rows.Next() {
+		var seq int
+		if err := rows.Scan(&seq); err != nil {
+			return nil, err
+		}
+		present = append(present, seq)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status.Missing = missingSeqs(status.Total, present)
+	return &status, nil
+}
+
+// missingSeqs returns the seqs in 1..total absent from present.
+func missingSeqs(total int, present []int) []int {
+	seen := make(map[int]bool, len(present))
+	for _, seq := range present {
+		seen[seq] = true
+	}
+	missing := []int{}
+	for seq := 1; seq <= total; seq++ {
+		if !seen[seq] {
+			missing = append(missing, seq)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingSeqs(t *testing.T) {
+	cases := []struct {
+		total   int
+		present []int
+		want    []int
+	}{
+		{total: 4, present: []int{1, 3}, want: []int{2, 4}},
+		{total: 3, present: []int{3, 1, 2}, want: []int{}},
+		{total: 2, present: nil, want: []int{1, 2}},
+	}
+	for _, tc := range cases {
+		if got := missingSeqs(tc.total, tc.present); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("missingSeqs(%d, %v) = %v, want %v", tc.total, tc.present, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListVariants fetches the visible variants of an emoji, the base emoji (empty Variant) first
+// and the rest ordered by variant. It returns an empty list when none are visible.
+func (s *Store) ListVariants(ctx context.Context, author, name string, includeData bool) ([]Asset, error) {
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s, variant FROM hivemoji_assets WHERE author = $1 AND name = $2 AND %s ORDER BY variant", selectAssetColumns(includeData), visibleAssets), author, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
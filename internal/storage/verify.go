@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ChecksumMismatch is a stored emoji whose checksum does not match its image bytes.
+type ChecksumMismatch struct {
+	Author  string
+	Name    string
+	Variant string
+	Stored  string
+	Actual  string
+}
+
+// VerifyReport summarises a VerifyChecksums run.
+type VerifyReport struct {
+	// Checked counts emojis that carry a checksum; v1 registers store none and are counted in Unchecked.
+	Checked    int
+	Unchecked  int
+	Mismatches []ChecksumMismatch
+	// Repaired counts mismatches whose stored checksum was replaced with the recomputed one.
+	Repaired int
+}
+
+// VerifyChecksums recomputes the sha256 of every stored emoji, optionally scoped to one author,
+// and reports those whose checksum disagrees with their bytes. With repair set, mismatched
+// checksums are overwritten unless the row changed since it was read.
+func (s *Store) VerifyChecksums(ctx context.Context, author string, repair bool) (*VerifyReport, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT author, name, variant, checksum, data, compression, data_ref, fallback_ref
+        FROM hivemoji_assets
+        WHERE ($1 = '' OR author = $1)
+        ORDER BY author, name, variant
+    `, author)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &VerifyReport{}
+	for rows.Next() {
+		r, err := pgx.RowToStructByNameLax[assetRow](rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.loadBlobs(ctx, &r); err != nil {
+			return nil, err
+		}
+		mismatch, checked, err := verifyAsset(r.Asset, r.Compression)
+		if err != nil {
+			return nil, err
+		}
+		if !checked {
+			report.Unchecked++
+			continue
+		}
+		report.Checked++
+		if mismatch != nil {
+			report.Mismatches = append(report.Mismatches, *mismatch)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !repair {
+		return report, nil
+	}
+	for _, m := range report.Mismatches {
+		tag, err := s.pool.Exec(ctx, `
+            UPDATE hivemoji_assets SET checksum = $3, updated_at = now()
+            WHERE author = $1 AND name = $2 AND variant = $5 AND checksum = $4
+        `, m.Author, m.Name, m.Actual, m.Stored, m.Variant)
+		if err != nil {
+			return report, fmt.Errorf("repair %s/%s: %w", m.Author, m.Name, err)
+		}
+		report.Repaired += int(tag.RowsAffected())
+	}
+	return report, nil
+}
+
+// verifyAsset compares a's stored checksum with the sha256 of its decompressed bytes. It
+// reports checked=false for assets without a checksum.
+func verifyAsset(a Asset, codec string) (mismatch *ChecksumMismatch, checked bool, err error) {
+	if a.Checksum == nil || *a.Checksum == "" {
+		return nil, false, nil
+	}
+	if err := a.decompressImages(codec); err != nil {
+		return nil, false, err
+	}
+	sum := sha256.Sum256(a.Data)
+	actual := hex.EncodeToString(sum[:])
+	if strings.EqualFold(actual, *a.Checksum) {
+		return nil, true, nil
+	}
+	var author string
+	if a.Author != nil {
+		author = *a.Author
+	}
+	return &ChecksumMismatch{Author: author, Name: a.Name, Variant: a.Variant, Stored: *a.Checksum, Actual: actual}, true, nil
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AssetVersion describes one stored revision of an emoji. Only metadata is kept; the image
+// bytes of earlier revisions are not retained. Revision counts up from 1 for each
+// (author, name, variant), while Version is the protocol version the revision arrived with.
+type AssetVersion struct {
+	Author          string
+	Name            string
+	Variant         string
+	Revision        int
+	Version         int
+	Mime            string
+	Width           int
+	Height          int
+	Animated        bool
+	Size            int
+	Checksum        string
+	FallbackMime    *string
+	FallbackSize    int
+	RegisteredBlock *int64
+	CreatedAt       time.Time
+}
+
+// recordVersion appends v as the next revision of its emoji. Replaying the block that stored
+// the latest revision (same checksum and block) records nothing.
+func recordVersion(ctx context.Context, tx pgx.Tx, v AssetVersion) error {
+	_, err := tx.Exec(ctx, `
+        INSERT INTO hivemoji_asset_versions (author, name, variant, revision, version, mime, width, height, animated, size, checksum, fallback_mime, fallback_size, registered_block)
+        SELECT $1, $2, $3, latest.revision + 1, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+        FROM (
+            SELECT COALESCE(max(revision), 0) AS revision
+            FROM hivemoji_asset_versions
+            WHERE author = $1 AND name = $2 AND variant = $3
+        ) latest
+        WHERE NOT EXISTS (
+            SELECT 1 FROM hivemoji_asset_versions
+            WHERE author = $1 AND name = $2 AND variant = $3 AND revision = latest.revision
+              AND checksum = $10 AND registered_block IS NOT DISTINCT FROM $13
+        )
+    `, v.Author, v.Name, v.Variant, v.Version, v.Mime, v.Width, v.Height, v.Animated, v.Size, v.Checksum, v.FallbackMime, v.FallbackSize, v.RegisteredBlock)
+	return err
+}
+
+// ListAssetVersions returns the recorded revisions of a base emoji, oldest first.
+func (s *Store) ListAssetVersions(ctx context.Context, author, name string) ([]AssetVersion, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT `+versionColumns+`
+        FROM hivemoji_asset_versions
+        WHERE author = $1 AND name = $2 AND variant = ''
+        ORDER BY revision
+    `, author, name)
+	if err != nil {
+		return nil, err
+	}
+	return scanVersions(rows)
+}
+
+// VersionQuery narrows ListAssetVersionsPage. Zero values match everything.
+type VersionQuery struct {
+	// Before keeps revisions older than this revision number, for paging back through history.
+	Before int
+	// Since and Until keep revisions recorded at or after Since and before Until.
+	Since time.Time
+	Until time.Time
+	// Limit caps how many revisions are returned; zero returns all of them.
+	Limit int
+}
+
+// ListAssetVersionsPage returns the revisions of a base emoji matching q, newest first, along
+// with the emoji's latest revision number whatever q selects (zero if none was recorded).
+func (s *Store) ListAssetVersionsPage(ctx context.Context, author, name string, q VersionQuery) ([]AssetVersion, int, error) {
+	args := []any{author, name}
+	var clause string
+	if q.Before > 0 {
+		args = append(args, q.Before)
+		clause += fmt.Sprintf(" AND revision < $%d", len(args))
+	}
+	if !q.Since.IsZero() {
+		args = append(args, q.Since)
+		clause += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !q.Until.IsZero() {
+		args = append(args, q.Until)
+		clause += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	var limit string
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		limit = fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	var current int
+	err := s.pool.QueryRow(ctx, `
+        SELECT COALESCE(max(revision), 0)
+        FROM hivemoji_asset_versions
+        WHERE author = $1 AND name = $2 AND variant = ''
+    `, author, name).Scan(&current)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.pool.Query(ctx, `
+        SELECT `+versionColumns+`
+        FROM hivemoji_asset_versions
+        WHERE author = $1 AND name = $2 AND variant = ''`+clause+`
+        ORDER BY revision DESC`+limit, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, current, nil
+}
+
+const versionColumns = `author, name, variant, revision, version, mime, width, height, animated, size, checksum, fallback_mime, fallback_size, registered_block, created_at`
+
+func scanVersions(rows pgx.Rows) ([]AssetVersion, error) {
+	defer rows.Close()
+	var versions []AssetVersion
+	for rows.Next() {
+		var v AssetVersion
+		if err := rows.Scan(&v.Author, &v.Name, &v.Variant, &v.Revision, &v.Version, &v.Mime, &v.Width, &v.Height, &v.Animated, &v.Size, &v.Checksum, &v.FallbackMime, &v.FallbackSize, &v.RegisteredBlock, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ListAssetHistory returns the public base emojis matching filter together with their earlier
+// recorded revisions, flattened into one list ordered by name, author and newest revision
+// first. Earlier revisions carry only the metadata kept by revision history and are marked
+// Historical; current emojis carry their latest revision number. Image data is never read.
+func (s *Store) ListAssetHistory(ctx context.Context, filter AssetFilter) ([]Asset, error) {
+	clause, args := filter.where(nil)
+	latestRevision := `(SELECT max(v.revision) FROM hivemoji_asset_versions v
+                WHERE v.author = hivemoji_assets.author AND v.name = hivemoji_assets.name AND v.variant = '')`
+	query := fmt.Sprintf(`
+        SELECT name, version, author, mime, width, height, animated, checksum, fallback_mime, registered_block, revision, historical
+        FROM (
+            SELECT name, version, author, mime, width, height, animated, checksum, fallback_mime, registered_block,
+                   %[3]s AS revision, false AS historical
+            FROM hivemoji_assets
+            WHERE %[1]s%[2]s
+            UNION ALL
+            SELECT v.name, v.version, v.author, v.mime, NULLIF(v.width, 0), NULLIF(v.height, 0), v.animated, v.checksum,
+                   v.fallback_mime, v.registered_block, v.revision, true
+            FROM hivemoji_asset_versions v
+            JOIN (
+                SELECT author AS current_author, name AS current_name, %[3]s AS current_revision
+                FROM hivemoji_assets
+                WHERE %[1]s%[2]s
+            ) latest ON latest.current_author = v.author AND latest.current_name = v.name
+            WHERE v.variant = '' AND v.revision < latest.current_revision
+        ) history
+        ORDER BY name, author, historical, revision DESC
+    `, publicAssets, clause, latestRevision)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectAssets(ctx, rows)
+}
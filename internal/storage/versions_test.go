@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestListAssetVersionsPage(t *testing.T) {
+	store := testStore(t)
+	ctx := context.Background()
+
+	author := fmt.Sprintf("hivemoji-versions-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_assets WHERE author=$1`, author)
+		_, _ = store.pool.Exec(ctx, `DELETE FROM hivemoji_asset_versions WHERE author=$1`, author)
+	})
+	for i := 0; i < 5; i++ {
+		err := store.UpsertV1(ctx, RegisterV1{Name: "wave", Author: author, Mime: "image/png", Data: []byte{byte(i)}, RegisteredBlock: int64(100 + i)})
+		if err != nil {
+			t.Fatalf("UpsertV1 %d: %v", i, err)
+		}
+	}
+
+	var got []int
+	before := 0
+	for page := 0; page < 5; page++ {
+		versions, current, err := store.ListAssetVersionsPage(ctx, author, "wave", VersionQuery{Before: before, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListAssetVersionsPage: %v", err)
+		}
+		if current != 5 {
+			t.Fatalf("expected current revision 5, got %d", current)
+		}
+		if len(versions) == 0 {
+			break
+		}
+		for _, v := range versions {
+			got = append(got, v.Revision)
+		}
+		before = versions[len(versions)-1].Revision
+	}
+	if fmt.Sprint(got) != "[5 4 3 2 1]" {
+		t.Fatalf("expected revisions newest first across pages, got %v", got)
+	}
+
+	versions, _, err := store.ListAssetVersionsPage(ctx, author, "wave", VersionQuery{Until: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("ListAssetVersionsPage until: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("expected no revisions recorded over an hour ago, got %d", len(versions))
+	}
+}
@@ -0,0 +1,177 @@
+// Package transcode converts stored emoji images into formats some clients render better.
+package transcode
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/gif"
+)
+
+var (
+	// ErrStatic is returned when the source needs no conversion because it is not animated
+	// or is already a PNG; callers serve the original bytes.
+	ErrStatic = errors.New("source is static or already png")
+	// ErrUnsupported is returned when an animated source cannot be converted.
+	ErrUnsupported = errors.New("conversion not supported")
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// ToAPNG converts an animated image to APNG. Animated GIFs and WebPs are converted frame by
+// frame.
+func ToAPNG(data []byte, mime string) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("%s: converter panic: %v", mime, r)
+		}
+	}()
+
+	switch mime {
+	case "image/png":
+		return nil, ErrStatic
+	case "image/gif":
+		return gifToAPNG(data)
+	case "image/webp":
+		animated, err := webpAnimated(data)
+		if err != nil {
+			return nil, err
+		}
+		if !animated {
+			return nil, ErrStatic
+		}
+		return webpToAPNG(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, mime)
+	}
+}
+
+// apngFrame is one full-canvas frame shown for delayNum/delayDen seconds.
+type apngFrame struct {
+	img      *image.NRGBA
+	delayNum uint16
+	delayDen uint16
+}
+
+func gifToAPNG(data []byte) ([]byte, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gif: %w", err)
+	}
+	if len(g.Image) < 2 {
+		return nil, ErrStatic
+	}
+
+	frames := make([]apngFrame, 0, len(g.Image))
+	for i, img := range compositeGIF(g) {
+		var delay int
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		frames = append(frames, apngFrame{img: img, delayNum: uint16(delay), delayDen: 100})
+	}
+
+	// GIF LoopCount is 0 for forever and -1 for a single play; APNG counts total plays.
+	plays := 0
+	switch {
+	case g.LoopCount < 0:
+		plays = 1
+	case g.LoopCount > 0:
+		plays = g.LoopCount + 1
+	}
+	return encodeAPNG(frames, plays)
+}
+
+// encodeAPNG writes full-canvas RGBA frames as an APNG whose first frame is also the
+// default image, so viewers without APNG support show it as a still.
+func encodeAPNG(frames []apngFrame, plays int) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("apng: no frames")
+	}
+	b := frames[0].img.Bounds()
+	width, height := uint32(b.Dx()), uint32(b.Dy())
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // truecolour with alpha
+	writeChunk(&buf, "IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(plays))
+	writeChunk(&buf, "acTL", actl)
+
+	var seq uint32
+	for i, f := range frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], width)
+		binary.BigEndian.PutUint32(fctl[8:], height)
+		// x/y offsets stay 0: every frame covers the whole canvas.
+		binary.BigEndian.PutUint16(fctl[20:], f.delayNum)
+		binary.BigEndian.PutUint16(fctl[22:], f.delayDen)
+		// dispose_op 0 (none) and blend_op 0 (source) replace the canvas with each frame.
+		writeChunk(&buf, "fcTL", fctl)
+		seq++
+
+		pixels, err := compressPixels(f.img)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			writeChunk(&buf, "IDAT", pixels)
+			continue
+		}
+		fdat := make([]byte, 4, 4+len(pixels))
+		binary.BigEndian.PutUint32(fdat, seq)
+		writeChunk(&buf, "fdAT", append(fdat, pixels...))
+		seq++
+	}
+
+	writeChunk(&buf, "IEND", nil)
+	return buf.Bytes(), nil
+}
+
+// compressPixels zlib-compresses img's rows, each prefixed with filter type 0 (none).
+func compressPixels(img *image.NRGBA) ([]byte, error) {
+	b := img.Bounds()
+	rowLen := b.Dx() * 4
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+	for y := 0; y < b.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+rowLen]
+		if _, err := zw.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+	buf.Write(header[:])
+	buf.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
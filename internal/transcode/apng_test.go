@@ -0,0 +1,317 @@
+package transcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// animatedGIF encodes a 4x4 GIF whose frames are filled with the given colours.
+func animatedGIF(t *testing.T, loopCount int, fills ...color.Color) []byte {
+	t.Helper()
+	g := &gif.GIF{LoopCount: loopCount}
+	for _, fill := range fills {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		for i := range frame.Pix {
+			frame.Pix[i] = uint8(frame.Palette.Index(fill))
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// bitWriter packs bits least significant first, as VP8L reads them.
+type bitWriter struct {
+	buf  []byte
+	nbit uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nbit%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>i&1) << (w.nbit % 8)
+		w.nbit++
+	}
+}
+
+// solidVP8L encodes a lossless WebP bitstream of a w x h image filled with c. Every prefix
+// code holds a single symbol, so the pixels themselves take no bits.
+func solidVP8L(w, h int, c color.NRGBA) []byte {
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	bw.write(1, 1) // alpha is used
+	bw.write(0, 3) // version
+	bw.write(0, 1) // no transforms
+	bw.write(0, 1) // no colour cache
+	bw.write(0, 1) // no meta prefix codes
+	for _, sym := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		bw.write(1, 1) // simple code
+		bw.write(0, 1) // one symbol
+		bw.write(1, 1) // 8-bit symbol
+		bw.write(uint32(sym), 8)
+	}
+	return bw.buf
+}
+
+func riffBytes(typ string, data []byte) []byte {
+	var buf bytes.Buffer
+	writeRIFFChunk(&buf, typ, data)
+	return buf.Bytes()
+}
+
+type webpFrame struct {
+	x, y, w, h int
+	fill       color.NRGBA
+	flags      byte
+}
+
+// animatedWebP builds a w x h animated WebP of solid lossless frames shown for 100ms each.
+func animatedWebP(w, h int, loopCount uint16, frames ...webpFrame) []byte {
+	vp8x := make([]byte, 10)
+	vp8x[0] = vp8xAnimation | vp8xAlpha
+	putUint24(vp8x[4:], w-1)
+	putUint24(vp8x[7:], h-1)
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint16(anim[4:], loopCount)
+
+	body := []byte("WEBP")
+	body = append(body, riffBytes("VP8X", vp8x)...)
+	body = append(body, riffBytes("ANIM", anim)...)
+	for _, f := range frames {
+		anmf := make([]byte, 16)
+		putUint24(anmf[0:], f.x/2)
+		putUint24(anmf[3:], f.y/2)
+		putUint24(anmf[6:], f.w-1)
+		putUint24(anmf[9:], f.h-1)
+		putUint24(anmf[12:], 100)
+		anmf[15] = f.flags
+		anmf = append(anmf, riffBytes("VP8L", solidVP8L(f.w, f.h, f.fill))...)
+		body = append(body, riffBytes("ANMF", anmf)...)
+	}
+	return riffBytes("RIFF", body)
+}
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func readChunks(t *testing.T, data []byte) []pngChunk {
+	t.Helper()
+	if !bytes.HasPrefix(data, pngSignature) {
+		t.Fatalf("missing png signature")
+	}
+	var chunks []pngChunk
+	rest := data[len(pngSignature):]
+	for len(rest) >= 12 {
+		n := binary.BigEndian.Uint32(rest[:4])
+		chunks = append(chunks, pngChunk{typ: string(rest[4:8]), data: rest[8 : 8+n]})
+		rest = rest[12+n:]
+	}
+	return chunks
+}
+
+// decodeFrame rebuilds a plain PNG from IHDR and one frame's pixel data.
+func decodeFrame(t *testing.T, ihdr, pixels []byte) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writeChunk(&buf, "IHDR", ihdr)
+	writeChunk(&buf, "IDAT", pixels)
+	writeChunk(&buf, "IEND", nil)
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode frame: %v", err)
+	}
+	return img
+}
+
+func TestToAPNG_AnimatedGIF(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	out, err := ToAPNG(animatedGIF(t, 2, red, blue), "image/gif")
+	if err != nil {
+		t.Fatalf("ToAPNG error: %v", err)
+	}
+
+	// Viewers without APNG support see the first frame.
+	still, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode default image: %v", err)
+	}
+	if r, g, b, a := still.At(1, 1).RGBA(); r>>8 != 0xff || g != 0 || b != 0 || a>>8 != 0xff {
+		t.Fatalf("expected red first frame, got %v", still.At(1, 1))
+	}
+
+	var ihdr, fdat []byte
+	var seqs []uint32
+	counts := map[string]int{}
+	for _, c := range readChunks(t, out) {
+		counts[c.typ]++
+		switch c.typ {
+		case "IHDR":
+			ihdr = c.data
+		case "acTL":
+			if frames, plays := binary.BigEndian.Uint32(c.data), binary.BigEndian.Uint32(c.data[4:]); frames != 2 || plays != 3 {
+				t.Fatalf("expected 2 frames and 3 plays, got %d and %d", frames, plays)
+			}
+		case "fcTL":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+			if num, den := binary.BigEndian.Uint16(c.data[20:]), binary.BigEndian.Uint16(c.data[22:]); num != 10 || den != 100 {
+				t.Fatalf("expected 10/100s delay, got %d/%d", num, den)
+			}
+		case "fdAT":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+			fdat = c.data[4:]
+		}
+	}
+	if counts["acTL"] != 1 || counts["fcTL"] != 2 || counts["IDAT"] != 1 || counts["fdAT"] != 1 {
+		t.Fatalf("unexpected chunk layout %v", counts)
+	}
+	for i, seq := range seqs {
+		if seq != uint32(i) {
+			t.Fatalf("expected consecutive sequence numbers, got %v", seqs)
+		}
+	}
+
+	second := decodeFrame(t, ihdr, fdat)
+	if r, g, b, _ := second.At(2, 2).RGBA(); r != 0 || g != 0 || b>>8 != 0xff {
+		t.Fatalf("expected blue second frame, got %v", second.At(2, 2))
+	}
+}
+
+func TestToAPNG_AnimatedWebP(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	blue := color.NRGBA{B: 0xff, A: 0xff}
+	clear := color.NRGBA{G: 0xff}
+	src := animatedWebP(4, 4, 2,
+		webpFrame{w: 4, h: 4, fill: red},
+		webpFrame{x: 2, y: 2, w: 2, h: 2, fill: blue, flags: anmfDisposeToBackground},
+		webpFrame{w: 2, h: 2, fill: clear},
+		webpFrame{w: 2, h: 2, fill: clear, flags: anmfNoBlend},
+	)
+	out, err := ToAPNG(src, "image/webp")
+	if err != nil {
+		t.Fatalf("ToAPNG error: %v", err)
+	}
+
+	var ihdr []byte
+	var frames [][]byte
+	for _, c := range readChunks(t, out) {
+		switch c.typ {
+		case "IHDR":
+			ihdr = c.data
+		case "acTL":
+			if n, plays := binary.BigEndian.Uint32(c.data), binary.BigEndian.Uint32(c.data[4:]); n != 4 || plays != 2 {
+				t.Fatalf("expected 4 frames and 2 plays, got %d and %d", n, plays)
+			}
+		case "fcTL":
+			if num, den := binary.BigEndian.Uint16(c.data[20:]), binary.BigEndian.Uint16(c.data[22:]); num != 100 || den != 1000 {
+				t.Fatalf("expected 100/1000s delay, got %d/%d", num, den)
+			}
+		case "IDAT":
+			frames = append(frames, c.data)
+		case "fdAT":
+			frames = append(frames, c.data[4:])
+		}
+	}
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(frames))
+	}
+
+	tests := []struct {
+		frame int
+		x, y  int
+		want  color.NRGBA
+	}{
+		{0, 3, 3, red},
+		{1, 0, 0, red},
+		{1, 3, 3, blue},
+		// The second frame is disposed to the background, and a transparent frame blends away.
+		{2, 3, 3, color.NRGBA{}},
+		{2, 0, 0, red},
+		// Without blending the transparent frame replaces what is under it.
+		{3, 0, 0, color.NRGBA{}},
+		{3, 2, 0, red},
+	}
+	for _, tc := range tests {
+		img := decodeFrame(t, ihdr, frames[tc.frame])
+		got := color.NRGBAModel.Convert(img.At(tc.x, tc.y)).(color.NRGBA)
+		if got.A == 0 {
+			got = color.NRGBA{}
+		}
+		if got != tc.want {
+			t.Fatalf("frame %d at (%d,%d): expected %v, got %v", tc.frame, tc.x, tc.y, tc.want, got)
+		}
+	}
+}
+
+func TestToAPNG_StaticAndUnsupported(t *testing.T) {
+	vp8x := func(flags byte) []byte {
+		data := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00")
+		data = append(data, flags, 0, 0, 0, 3, 0, 0, 3, 0, 0)
+		binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		mime string
+		want error
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n"), "image/png", ErrStatic},
+		{"single-frame gif", animatedGIF(t, 0, color.Black), "image/gif", ErrStatic},
+		{"simple webp", []byte("RIFF\x0c\x00\x00\x00WEBPVP8 \x00\x00\x00\x00"), "image/webp", ErrStatic},
+		{"extended still webp", vp8x(0x10), "image/webp", ErrStatic},
+		{"unknown mime", []byte("x"), "image/bmp", ErrUnsupported},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ToAPNG(tc.data, tc.mime); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	if _, err := ToAPNG([]byte("GIF89a"), "image/gif"); err == nil || errors.Is(err, ErrStatic) {
+		t.Fatalf("expected truncated gif to fail, got %v", err)
+	}
+	if _, err := ToAPNG(vp8x(vp8xAnimation), "image/webp"); err == nil || errors.Is(err, ErrStatic) {
+		t.Fatalf("expected animated webp without frames to fail, got %v", err)
+	}
+}
+
+func TestCache_EvictsOldest(t *testing.T) {
+	src := animatedGIF(t, 0, color.White, color.Black)
+	cache := NewCache(2)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := cache.APNG(key, src, "image/gif"); err != nil {
+			t.Fatalf("APNG error: %v", err)
+		}
+	}
+	if _, ok := cache.entries["a"]; ok || len(cache.entries) != 2 {
+		t.Fatalf("expected oldest entry evicted, have %d entries", len(cache.entries))
+	}
+	if _, err := cache.APNG("bad", []byte("RIFF"), "image/webp"); err == nil {
+		t.Fatalf("expected malformed webp to fail")
+	}
+	if _, ok := cache.entries["bad"]; ok {
+		t.Fatalf("expected errors not to be cached")
+	}
+}
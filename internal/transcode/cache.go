@@ -0,0 +1,50 @@
+package transcode
+
+import "sync"
+
+// Cache keeps recent APNG conversions so repeated requests skip the frame-by-frame work.
+// It holds at most max entries and evicts the oldest first. A nil Cache converts without caching.
+type Cache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string][]byte
+	order   []string
+}
+
+// NewCache returns a cache holding up to max conversions.
+func NewCache(max int) *Cache {
+	return &Cache{max: max, entries: make(map[string][]byte)}
+}
+
+// APNG returns the cached conversion for key, converting data with ToAPNG on a miss. Keys
+// must change whenever the source bytes do; errors are not cached.
+func (c *Cache) APNG(key string, data []byte, mime string) ([]byte, error) {
+	if c == nil {
+		return ToAPNG(data, mime)
+	}
+
+	c.mu.Lock()
+	out, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return out, nil
+	}
+
+	out, err := ToAPNG(data, mime)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		for len(c.order) >= c.max && len(c.order) > 0 {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = out
+	return out, nil
+}
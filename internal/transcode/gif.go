@@ -0,0 +1,184 @@
+package transcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+)
+
+// gifCanvas returns the logical screen of g, falling back to the first frame's bounds.
+func gifCanvas(g *gif.GIF) image.Rectangle {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	return bounds
+}
+
+// compositeGIF renders every frame of g onto its canvas, applying each frame's disposal,
+// and returns what a viewer shows while that frame is displayed.
+func compositeGIF(g *gif.GIF) []*image.NRGBA {
+	bounds := gifCanvas(g)
+	canvas := image.NewNRGBA(bounds)
+	frames := make([]*image.NRGBA, 0, len(g.Image))
+	for i, src := range g.Image {
+		var previous *image.NRGBA
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewNRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, src.Bounds(), src, src.Bounds().Min, draw.Over)
+		snapshot := image.NewNRGBA(bounds)
+		copy(snapshot.Pix, canvas.Pix)
+		frames = append(frames, snapshot)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, src.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			copy(canvas.Pix, previous.Pix)
+		}
+	}
+	return frames
+}
+
+// OptimizeGIF re-encodes a GIF so each frame only stores the pixels that changed since the
+// previous one, with unchanged pixels left transparent and a palette holding just the colours
+// the frame uses. Timing, loop count and what each frame looks like are preserved. Callers
+// should keep the original when the result is not smaller. ErrUnsupported is returned when
+// a frame would need more than 255 colours.
+func OptimizeGIF(data []byte) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("image/gif: optimizer panic: %v", r)
+		}
+	}()
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gif: %w", err)
+	}
+	bounds := gifCanvas(g)
+	targets := compositeGIF(g)
+	blank := image.NewNRGBA(bounds)
+
+	// A frame can't turn opaque pixels transparent by drawing over the canvas, so the frame
+	// before it is widened to the full canvas and disposed to background instead.
+	clears := make([]bool, len(targets))
+	for i := 1; i < len(targets); i++ {
+		clears[i] = clearsPixels(targets[i-1], targets[i])
+	}
+
+	opt := &gif.GIF{
+		LoopCount: g.LoopCount,
+		Config:    image.Config{Width: bounds.Dx(), Height: bounds.Dy()},
+	}
+	for i, target := range targets {
+		state := blank
+		if i > 0 && !clears[i] {
+			state = targets[i-1]
+		}
+		rect := changedRect(state, target)
+		if i+1 < len(targets) && clears[i+1] {
+			rect = bounds
+		}
+		frame, err := deltaFrame(state, target, rect)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		disposal := byte(gif.DisposalNone)
+		if i+1 < len(targets) && clears[i+1] {
+			disposal = gif.DisposalBackground
+		}
+		var delay int
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		opt.Image = append(opt.Image, frame)
+		opt.Delay = append(opt.Delay, delay)
+		opt.Disposal = append(opt.Disposal, disposal)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, opt); err != nil {
+		return nil, fmt.Errorf("gif: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// samePixel compares two NRGBA pixels, treating every fully transparent pixel as equal.
+func samePixel(a, b []uint8) bool {
+	if a[3] == 0 && b[3] == 0 {
+		return true
+	}
+	return a[0] == b[0] && a[1] == b[1] && a[2] == b[2] && a[3] == b[3]
+}
+
+// clearsPixels reports whether going from prev to next turns any visible pixel transparent.
+func clearsPixels(prev, next *image.NRGBA) bool {
+	for i := 0; i < len(prev.Pix); i += 4 {
+		if prev.Pix[i+3] != 0 && next.Pix[i+3] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// changedRect returns the smallest rectangle covering every pixel that differs between
+// state and target. Identical frames still get a 1x1 rectangle so the frame and its delay
+// are kept.
+func changedRect(state, target *image.NRGBA) image.Rectangle {
+	b := target.Bounds()
+	rect := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			off := target.PixOffset(x, y)
+			if samePixel(state.Pix[off:off+4], target.Pix[off:off+4]) {
+				continue
+			}
+			rect = rect.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	if rect.Empty() {
+		rect = image.Rect(b.Min.X, b.Min.Y, b.Min.X+1, b.Min.Y+1)
+	}
+	return rect
+}
+
+// deltaFrame builds the paletted frame covering rect that turns state into target. Index 0
+// is transparent and used for every pixel that is already correct.
+func deltaFrame(state, target *image.NRGBA, rect image.Rectangle) (*image.Paletted, error) {
+	pal := color.Palette{color.NRGBA{}}
+	index := make(map[color.NRGBA]uint8)
+	frame := image.NewPaletted(rect, nil)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			off := target.PixOffset(x, y)
+			px := target.Pix[off : off+4]
+			if samePixel(state.Pix[off:off+4], px) || px[3] == 0 {
+				continue
+			}
+			c := color.NRGBA{R: px[0], G: px[1], B: px[2], A: px[3]}
+			idx, ok := index[c]
+			if !ok {
+				if len(pal) == 256 {
+					return nil, fmt.Errorf("%w: more than 255 colours", ErrUnsupported)
+				}
+				idx = uint8(len(pal))
+				index[c] = idx
+				pal = append(pal, c)
+			}
+			frame.Pix[frame.PixOffset(x, y)] = idx
+		}
+	}
+	frame.Palette = pal
+	return frame, nil
+}
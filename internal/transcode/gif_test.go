@@ -0,0 +1,123 @@
+package transcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+// movingDotGIF encodes a 32x32 animation over a white background where a dot moves one
+// pixel per frame, every frame stored in full with the 256-colour Plan9 palette.
+func movingDotGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{LoopCount: 3}
+	plan9 := color.Palette(palette.Plan9)
+	white, black := uint8(plan9.Index(color.White)), uint8(plan9.Index(color.Black))
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 32, 32), palette.Plan9)
+		for p := range frame.Pix {
+			frame.Pix[p] = white
+		}
+		frame.SetColorIndex(i, i, black)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 5+i)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// assertSameAnimation checks that two GIFs show the same pixels with the same timing.
+func assertSameAnimation(t *testing.T, original, optimized []byte) {
+	t.Helper()
+	want, err := gif.DecodeAll(bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("decode original: %v", err)
+	}
+	got, err := gif.DecodeAll(bytes.NewReader(optimized))
+	if err != nil {
+		t.Fatalf("decode optimized: %v", err)
+	}
+	if len(got.Image) != len(want.Image) {
+		t.Fatalf("expected %d frames, got %d", len(want.Image), len(got.Image))
+	}
+	if got.LoopCount != want.LoopCount {
+		t.Fatalf("expected loop count %d, got %d", want.LoopCount, got.LoopCount)
+	}
+	for i := range want.Delay {
+		if got.Delay[i] != want.Delay[i] {
+			t.Fatalf("frame %d: expected delay %d, got %d", i, want.Delay[i], got.Delay[i])
+		}
+	}
+	wantFrames, gotFrames := compositeGIF(want), compositeGIF(got)
+	for i := range wantFrames {
+		for p := 0; p < len(wantFrames[i].Pix); p += 4 {
+			if !samePixel(wantFrames[i].Pix[p:p+4], gotFrames[i].Pix[p:p+4]) {
+				t.Fatalf("frame %d renders differently after optimization at byte %d", i, p)
+			}
+		}
+	}
+}
+
+func TestOptimizeGIF_DiffsFrames(t *testing.T) {
+	original := movingDotGIF(t, 8)
+	optimized, err := OptimizeGIF(original)
+	if err != nil {
+		t.Fatalf("OptimizeGIF error: %v", err)
+	}
+	if len(optimized) >= len(original) {
+		t.Fatalf("expected optimized gif to be smaller, got %d >= %d bytes", len(optimized), len(original))
+	}
+	assertSameAnimation(t, original, optimized)
+
+	g, err := gif.DecodeAll(bytes.NewReader(optimized))
+	if err != nil {
+		t.Fatalf("decode optimized: %v", err)
+	}
+	if b := g.Image[1].Bounds(); b != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("expected second frame to cover only the moved dot, got %v", b)
+	}
+	if n := len(g.Image[0].Palette); n > 4 {
+		t.Fatalf("expected first frame palette to shrink to the colours used, got %d entries", n)
+	}
+}
+
+func TestOptimizeGIF_ClearsTransparentPixels(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.White, color.Black}
+	g := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+		// Frame 1 is fully opaque; frames 0 and 2 are transparent except one pixel.
+		if i == 1 {
+			for p := range frame.Pix {
+				frame.Pix[p] = 1
+			}
+		}
+		frame.SetColorIndex(i, 0, 2)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+
+	optimized, err := OptimizeGIF(buf.Bytes())
+	if err != nil {
+		t.Fatalf("OptimizeGIF error: %v", err)
+	}
+	assertSameAnimation(t, buf.Bytes(), optimized)
+}
+
+func TestOptimizeGIF_Invalid(t *testing.T) {
+	if _, err := OptimizeGIF([]byte("not a gif")); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected a decode error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package transcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/webp"
+)
+
+// vp8xAnimation is the VP8X flag bit set on animated WebP files.
+const vp8xAnimation = 0x02
+
+// vp8xAlpha is the VP8X flag bit set on files whose frames carry an alpha channel.
+const vp8xAlpha = 0x10
+
+// ANMF flag bits: disposeToBackground clears the frame's area once it has been shown, and
+// noBlend draws the frame over the canvas without alpha blending.
+const (
+	anmfDisposeToBackground = 0x01
+	anmfNoBlend             = 0x02
+)
+
+// webpAnimated reports whether a WebP file declares animation in its VP8X header. Simple
+// (VP8/VP8L-only) files are always static.
+func webpAnimated(data []byte) (bool, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return false, errors.New("webp: missing RIFF/WEBP header")
+	}
+	if len(data) < 20 {
+		return false, errors.New("webp: truncated chunk header")
+	}
+	if string(data[12:16]) != "VP8X" {
+		return false, nil
+	}
+	if binary.LittleEndian.Uint32(data[16:20]) < 10 || len(data) < 30 {
+		return false, errors.New("webp: short VP8X chunk")
+	}
+	return data[20]&vp8xAnimation != 0, nil
+}
+
+type riffChunk struct {
+	typ  string
+	data []byte
+}
+
+// riffChunks splits a run of RIFF chunks, dropping the pad byte after odd-sized ones.
+func riffChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("webp: truncated chunk header")
+		}
+		n := binary.LittleEndian.Uint32(data[4:8])
+		if uint64(n) > uint64(len(data)-8) {
+			return nil, fmt.Errorf("webp: %s chunk overruns the file", data[:4])
+		}
+		chunks = append(chunks, riffChunk{typ: string(data[:4]), data: data[8 : 8+n]})
+		data = data[8+n:]
+		if n%2 == 1 && len(data) > 0 {
+			data = data[1:]
+		}
+	}
+	return chunks, nil
+}
+
+// uint24 reads a little-endian 24-bit field.
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// webpToAPNG composites the ANMF frames of an animated WebP onto its canvas, honouring
+// each frame's offset, blending and disposal, and encodes the result as APNG.
+func webpToAPNG(data []byte) ([]byte, error) {
+	chunks, err := riffChunks(data[12:])
+	if err != nil {
+		return nil, err
+	}
+	vp8x := chunks[0].data
+	canvas := image.NewNRGBA(image.Rect(0, 0, uint24(vp8x[4:])+1, uint24(vp8x[7:])+1))
+
+	plays := 0
+	var frames []apngFrame
+	var dispose image.Rectangle
+	for _, c := range chunks[1:] {
+		switch c.typ {
+		case "ANIM":
+			if len(c.data) < 6 {
+				return nil, errors.New("webp: short ANIM chunk")
+			}
+			// WebP and APNG both count plays with 0 meaning forever.
+			plays = int(binary.LittleEndian.Uint16(c.data[4:6]))
+		case "ANMF":
+			if len(c.data) < 16 {
+				return nil, errors.New("webp: short ANMF chunk")
+			}
+			x, y := uint24(c.data[0:])*2, uint24(c.data[3:])*2
+			duration := uint24(c.data[12:])
+			flags := c.data[15]
+
+			img, err := decodeWebPFrame(c.data[16:], uint24(c.data[6:])+1, uint24(c.data[9:])+1)
+			if err != nil {
+				return nil, fmt.Errorf("webp: frame %d: %w", len(frames)+1, err)
+			}
+
+			draw.Draw(canvas, dispose, image.Transparent, image.Point{}, draw.Src)
+			dispose = image.Rectangle{}
+			r := img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(x, y))
+			op := draw.Over
+			if flags&anmfNoBlend != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, r, img, img.Bounds().Min, op)
+			if flags&anmfDisposeToBackground != 0 {
+				dispose = r
+			}
+
+			frame := image.NewNRGBA(canvas.Bounds())
+			copy(frame.Pix, canvas.Pix)
+			frames = append(frames, apngFrame{img: frame, delayNum: uint16(min(duration, 0xffff)), delayDen: 1000})
+		}
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("webp: animation has no frames")
+	}
+	if len(frames) < 2 {
+		return nil, ErrStatic
+	}
+	return encodeAPNG(frames, plays)
+}
+
+// decodeWebPFrame decodes the bitstream (and optional ALPH chunk) of one ANMF frame by
+// wrapping it as a standalone still WebP file.
+func decodeWebPFrame(data []byte, width, height int) (image.Image, error) {
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	var alpha bool
+	for _, c := range chunks {
+		alpha = alpha || c.typ == "ALPH"
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	if alpha {
+		vp8x := make([]byte, 10)
+		vp8x[0] = vp8xAlpha
+		putUint24(vp8x[4:], width-1)
+		putUint24(vp8x[7:], height-1)
+		writeRIFFChunk(&body, "VP8X", vp8x)
+	}
+	body.Write(data)
+
+	var file bytes.Buffer
+	var header [8]byte
+	copy(header[:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(body.Len()))
+	file.Write(header[:])
+	file.Write(body.Bytes())
+	return webp.Decode(&file)
+}
+
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func writeRIFFChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var header [8]byte
+	copy(header[:4], typ)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+	buf.Write(header[:])
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
@@ -0,0 +1,119 @@
+// Package webhook notifies an external endpoint about emojis stored by the processor.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Event describes a stored emoji registration. It carries metadata only, never image bytes.
+type Event struct {
+	Author              string     `json:"author"`
+	Name                string     `json:"name"`
+	Variant             string     `json:"variant,omitempty"`
+	Version             int        `json:"version"`
+	Mime                string     `json:"mime"`
+	Width               int        `json:"width,omitempty"`
+	Height              int        `json:"height,omitempty"`
+	Animated            bool       `json:"animated"`
+	Checksum            string     `json:"checksum,omitempty"`
+	UploadID            string     `json:"upload_id,omitempty"`
+	FallbackMime        string     `json:"fallback_mime,omitempty"`
+	ModerationStatus    string     `json:"moderation_status,omitempty"`
+	PendingConfirmation bool       `json:"pending_confirmation,omitempty"`
+	Block               int64      `json:"block"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+}
+
+// Notifier posts events to a webhook from a single background worker. Events are queued
+// without blocking the caller and dropped when the queue is full, so a slow or failing
+// endpoint never holds up ingestion.
+type Notifier struct {
+	url     string
+	retries int
+	backoff time.Duration
+	http    *http.Client
+	queue   chan Event
+}
+
+// NewNotifier builds a notifier holding up to queueSize pending events. Each attempt is
+// bounded by timeout and failed attempts are retried up to retries additional times.
+func NewNotifier(url string, timeout time.Duration, retries, queueSize int) *Notifier {
+	return &Notifier{
+		url:     url,
+		retries: retries,
+		backoff: 500 * time.Millisecond,
+		http:    &http.Client{Timeout: timeout},
+		queue:   make(chan Event, queueSize),
+	}
+}
+
+// Notify queues ev for delivery. It reports false, after logging, when the queue is full and
+// the event was dropped.
+func (n *Notifier) Notify(ev Event) bool {
+	select {
+	case n.queue <- ev:
+		return true
+	default:
+		log.Printf("register webhook: queue full; dropping event name=%s author=%s", ev.Name, ev.Author)
+		return false
+	}
+}
+
+// Run delivers queued events until ctx is cancelled. Events still queued at that point are discarded.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-n.queue:
+			if err := n.deliver(ctx, ev); err != nil {
+				log.Printf("register webhook name=%s author=%s: %v", ev.Name, ev.Author, err)
+			}
+		}
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for attempt := 0; attempt <= n.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.backoff * time.Duration(attempt)):
+			}
+		}
+		if lastErr = n.postOnce(ctx, body); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed after %d attempts: %w", n.retries+1, lastErr)
+}
+
+func (n *Notifier) postOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
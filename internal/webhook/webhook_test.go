@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifier_RetriesThenDelivers(t *testing.T) {
+	var calls atomic.Int32
+	delivered := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %v", r.Method, r.Header)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		delivered <- ev
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(srv.URL, time.Second, 2, 4)
+	n.backoff = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	if !n.Notify(Event{Author: "mrtats", Name: "wave", Version: 2, Mime: "image/png", Block: 100}) {
+		t.Fatalf("expected event to be queued")
+	}
+	select {
+	case ev := <-delivered:
+		if ev.Author != "mrtats" || ev.Name != "wave" || ev.Block != 100 {
+			t.Fatalf("unexpected event delivered: %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("event was not delivered")
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls.Load())
+	}
+}
+
+func TestNotifier_DropsWhenQueueFull(t *testing.T) {
+	n := NewNotifier("http://127.0.0.1:0", time.Second, 0, 1)
+	if !n.Notify(Event{Name: "wave"}) {
+		t.Fatalf("expected first event to be queued")
+	}
+	if n.Notify(Event{Name: "smile"}) {
+		t.Fatalf("expected event to be dropped while the queue is full")
+	}
+}